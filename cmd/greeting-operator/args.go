@@ -0,0 +1,21 @@
+package main
+
+import "strings"
+
+// argsFlag holds the container arguments, one per flag or per line of the environment variable, each
+// passed as is: no shell splits them on their spaces, nor the flag on their commas.
+type argsFlag struct {
+	args []string
+}
+
+func (f *argsFlag) Set(value string) error {
+	f.args = append(f.args, strings.Split(strings.TrimSuffix(value, "\n"), "\n")...)
+	return nil
+}
+
+func (f *argsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.args, "\n")
+}
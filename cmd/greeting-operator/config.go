@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"edb-challenge/pkg/operator"
+	cli "github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultConfigFile is the file written by `config init` when none is given.
+const defaultConfigFile = "greeting-operator.yaml"
+
+// configFileSkippedFlags are the global flags that cannot be set from the configuration file.
+var configFileSkippedFlags = map[string]bool{"config": true, "help": true}
+
+// applyConfigFile sets the global flags from the keys of a YAML file, named after the flags,
+// and returns the instances it lists.
+// Flags already set on the command line or through their environment variable keep their value,
+// and unknown keys are rejected.
+func applyConfigFile(cliCtx *cli.Context, path string) ([]operator.Instance, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err = yaml.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	var instances []operator.Instance
+	if value, ok := values[operator.InstancesKey]; ok {
+		if instances, err = operator.ParseInstances(value); err != nil {
+			return nil, fmt.Errorf("config file %s: %s: %w", path, operator.InstancesKey, err)
+		}
+		delete(values, operator.InstancesKey)
+	}
+
+	flags := configFileFlags(cliCtx.App)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := flags[key]; !ok {
+			return nil, fmt.Errorf("config file %s: unknown key %q", path, key)
+		}
+		if cliCtx.IsSet(key) {
+			continue
+		}
+
+		items, err := configValues(values[key])
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: key %q: %w", path, key, err)
+		}
+		for _, item := range items {
+			if err = cliCtx.Set(key, item); err != nil {
+				return nil, fmt.Errorf("config file %s: key %q: %w", path, key, err)
+			}
+		}
+	}
+
+	return instances, nil
+}
+
+// configFileFlags returns the global flags that can be set from the configuration file, by name.
+func configFileFlags(app *cli.App) map[string]cli.DocGenerationFlag {
+	flags := make(map[string]cli.DocGenerationFlag, len(app.Flags))
+	for _, f := range app.Flags {
+		doc, ok := f.(cli.DocGenerationFlag)
+		if !ok {
+			continue
+		}
+
+		name := f.Names()[0]
+		if !configFileSkippedFlags[name] {
+			flags[name] = doc
+		}
+	}
+	return flags
+}
+
+// configValues returns the flag values of a YAML value, a list setting a repeatable flag several times.
+func configValues(value interface{}) ([]string, error) {
+	if list, ok := value.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for _, v := range list {
+			item, err := configScalar(v)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	item, err := configScalar(value)
+	if err != nil {
+		return nil, err
+	}
+	return []string{item}, nil
+}
+
+func configScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v, expected a string, a number, a boolean or a list of them", value)
+	}
+}
+
+// WriteConfigTemplate writes a starter configuration file documenting every settable flag with its default value.
+func WriteConfigTemplate(w io.Writer, app *cli.App) error {
+	flags := configFileFlags(app)
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# Configuration of the greeting operator, passed with --config.")
+	fmt.Fprintln(&buf, "# Keys are named after the flags. Flags and environment variables take precedence over this file.")
+
+	for _, f := range app.Flags {
+		name := f.Names()[0]
+		doc, ok := flags[name]
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "\n# %s.\n", doc.GetUsage())
+		if slice, repeatable := f.(*cli.StringSliceFlag); repeatable {
+			var values []string
+			if slice.Value != nil {
+				for _, v := range slice.Value.Value() {
+					values = append(values, strconv.Quote(v))
+				}
+			}
+			fmt.Fprintf(&buf, "# %s: [%s]\n", name, strings.Join(values, ", "))
+			continue
+		}
+
+		if generic, isGeneric := f.(*cli.GenericFlag); isGeneric {
+			if args, repeatable := generic.Value.(*argsFlag); repeatable {
+				values := make([]string, 0, len(args.args))
+				for _, v := range args.args {
+					values = append(values, strconv.Quote(v))
+				}
+				fmt.Fprintf(&buf, "# %s: [%s]\n", name, strings.Join(values, ", "))
+				continue
+			}
+		}
+
+		value := doc.GetDefaultText()
+		if _, isString := f.(*cli.StringFlag); isString {
+			// The default text of the string flags is already quoted.
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&buf, "# %s: %s\n", name, value)
+	}
+
+	fmt.Fprintf(&buf, "\n# Greeting servers to deploy in standalone mode, each overriding the keys above.\n")
+	fmt.Fprintf(&buf, "# %s:\n", operator.InstancesKey)
+	fmt.Fprintf(&buf, "#   - instance: team-a\n")
+	fmt.Fprintf(&buf, "#     namespace: team-a\n")
+	fmt.Fprintf(&buf, "#     image: \"greeting:latest\"\n")
+	fmt.Fprintf(&buf, "#     port: 80\n")
+	fmt.Fprintf(&buf, "#     replicas: 2\n")
+	fmt.Fprintf(&buf, "#     name: Team A\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func runConfigInit(cliCtx *cli.Context) error {
+	path := cliCtx.Args().First()
+	if path == "" {
+		path = defaultConfigFile
+	}
+
+	if path == "-" {
+		return WriteConfigTemplate(os.Stdout, cliCtx.App)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if cliCtx.Bool("force") {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("create config file: %w", err)
+	}
+	defer file.Close()
+
+	if err = WriteConfigTemplate(file, cliCtx.App); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+
+	"edb-challenge/pkg/operator"
+	cli "github.com/urfave/cli/v2"
+)
+
+// newClusterConnection reads the connection flags, rejecting the ones that cannot be combined.
+func newClusterConnection(cliCtx *cli.Context) (operator.ClusterConnection, error) {
+	conn := operator.ClusterConnection{
+		Server:    cliCtx.String("server"),
+		Token:     cliCtx.String("token"),
+		TokenFile: cliCtx.String("token-file"),
+		CAFile:    cliCtx.String("ca-file"),
+		Insecure:  cliCtx.Bool("insecure-skip-tls-verify"),
+
+		Kubeconfig: cliCtx.String("kubeconfig"),
+		Context:    cliCtx.String("context"),
+	}
+
+	var errs []error
+	if conn.Token != "" && conn.TokenFile != "" {
+		errs = append(errs, errors.New("--token and --token-file cannot be used together"))
+	}
+	if conn.CAFile != "" && conn.Insecure {
+		errs = append(errs, errors.New("--ca-file and --insecure-skip-tls-verify cannot be used together"))
+	}
+	kubeconfig := conn.Kubeconfig != "" || conn.Context != ""
+	if kubeconfig && (conn.Server != "" || conn.Token != "" || conn.TokenFile != "" || conn.CAFile != "" || conn.Insecure) {
+		errs = append(errs, errors.New("--kubeconfig and --context cannot be used with --server, --token, --token-file, --ca-file and --insecure-skip-tls-verify, the kubeconfig brings its own"))
+	} else if conn.Server == "" && (conn.Token != "" || conn.TokenFile != "" || conn.CAFile != "" || conn.Insecure) {
+		errs = append(errs, errors.New("--token, --token-file, --ca-file and --insecure-skip-tls-verify require --server, the in-cluster configuration brings its own"))
+	}
+
+	return conn, errors.Join(errs...)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"edb-challenge/pkg/operator"
+)
+
+// resolveDigestValue is the value of --resolve-digest, which resolves strictly when passed without value.
+type resolveDigestValue struct {
+	mode string
+}
+
+func (v *resolveDigestValue) Set(value string) error {
+	switch value {
+	case operator.ResolveDigestOff, operator.ResolveDigestOn, operator.ResolveDigestBestEffort:
+		v.mode = value
+		return nil
+	}
+	return fmt.Errorf("expected %s, %s or %s", operator.ResolveDigestOff, operator.ResolveDigestOn, operator.ResolveDigestBestEffort)
+}
+
+func (v *resolveDigestValue) String() string {
+	return v.mode
+}
+
+// IsBoolFlag lets the flag be passed without value.
+func (v *resolveDigestValue) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+	"k8s.io/klog/v2"
+)
+
+// configureLogging sets the level and format of the logs from the flags,
+// and routes the client-go logs through the same logger.
+func configureLogging(cliCtx *cli.Context) error {
+	level, err := log.ParseLevel(cliCtx.String("log-level"))
+	if err != nil {
+		return fmt.Errorf("log level: %w", err)
+	}
+	log.SetLevel(level)
+
+	switch format := cliCtx.String("log-format"); format {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	// klog drops the verbose logs before they reach the sink unless its verbosity is raised.
+	klogFlags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(klogFlags)
+	verbosity := "0"
+	if level >= log.DebugLevel {
+		verbosity = "4"
+	}
+	if err = klogFlags.Set("v", verbosity); err != nil {
+		return fmt.Errorf("klog verbosity: %w", err)
+	}
+
+	klog.SetLogger(logr.New(&klogSink{logger: log.NewEntry(log.StandardLogger())}))
+	return nil
+}
+
+// klogSink is a logr sink writing the client-go logs with logrus.
+// Verbose client-go logs are written at the debug level.
+type klogSink struct {
+	logger *log.Entry
+}
+
+func (s *klogSink) Init(logr.RuntimeInfo) {}
+
+func (s *klogSink) Enabled(level int) bool {
+	if level > 0 {
+		return s.logger.Logger.IsLevelEnabled(log.DebugLevel)
+	}
+	return s.logger.Logger.IsLevelEnabled(log.InfoLevel)
+}
+
+func (s *klogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	entry := s.logger.WithFields(klogFields(keysAndValues))
+	if level > 0 {
+		entry.Debug(msg)
+		return
+	}
+	entry.Info(msg)
+}
+
+func (s *klogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.logger.WithFields(klogFields(keysAndValues)).WithError(err).Error(msg)
+}
+
+func (s *klogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &klogSink{logger: s.logger.WithFields(klogFields(keysAndValues))}
+}
+
+func (s *klogSink) WithName(name string) logr.LogSink {
+	if current, ok := s.logger.Data["logger"]; ok {
+		name = fmt.Sprintf("%v/%s", current, name)
+	}
+	return &klogSink{logger: s.logger.WithField("logger", name)}
+}
+
+// klogFields converts logr key/value pairs into logrus fields.
+func klogFields(keysAndValues []interface{}) log.Fields {
+	fields := log.Fields{"source": "client-go"}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	return fields
+}
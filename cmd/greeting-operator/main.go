@@ -2,279 +2,1239 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	"edb-challenge/pkg/operator"
+	"edb-challenge/pkg/version"
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 	apps "k8s.io/api/apps/v1"
 	api "k8s.io/api/core/v1"
-	kerror "k8s.io/apimachinery/pkg/api/errors"
-	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
 func main() {
+	// The flags default to the configuration of the operator package, so that both cannot drift.
+	defaults := operator.DefaultConfig()
+
 	app := cli.NewApp()
 	app.Name = "greeting-operator"
 	app.Usage = "Automatically expose a greeting server"
+	app.Version = version.Get().String()
 	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Usage:   "YAML file setting the flags, keyed by flag name, overridden by the flags and environment variables",
+			Aliases: []string{"c"},
+			EnvVars: []string{"CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:  "kubeconfig",
+			Usage: "Kubeconfig file to connect with, the KUBECONFIG files or ~/.kube/config outside of a pod when empty",
+		},
+		&cli.StringFlag{
+			Name:    "context",
+			Usage:   "Kubeconfig context to connect with, instead of its current context",
+			EnvVars: []string{"KUBE_CONTEXT"},
+		},
+		&cli.StringFlag{
+			Name:    "server",
+			Usage:   "URL of the API server, instead of the in-cluster configuration",
+			EnvVars: []string{"KUBE_SERVER"},
+		},
+		&cli.StringFlag{
+			Name:    "token",
+			Usage:   "Bearer token authenticating to the API server given by --server",
+			EnvVars: []string{"KUBE_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "token-file",
+			Usage:   "File holding the bearer token, re-read when rejected so that rotated tokens keep working",
+			EnvVars: []string{"KUBE_TOKEN_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "ca-file",
+			Usage:   "Certificate authority of the API server given by --server",
+			EnvVars: []string{"KUBE_CA_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:    "insecure-skip-tls-verify",
+			Usage:   "Skip the verification of the API server certificate",
+			EnvVars: []string{"KUBE_INSECURE_SKIP_TLS_VERIFY"},
+		},
+		&cli.StringFlag{
+			Name:    "log-level",
+			Usage:   "Level of the logs (debug, info, warn or error)",
+			Value:   "info",
+			EnvVars: []string{"LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:    "log-format",
+			Usage:   "Format of the logs (text or json)",
+			Value:   "text",
+			EnvVars: []string{"LOG_FORMAT"},
+		},
 		&cli.StringFlag{
 			Name:    "image",
 			Usage:   "Greeting server image",
-			Value:   "greeting:latest",
+			Value:   defaults.Image,
 			Aliases: []string{"i"},
 			EnvVars: []string{"IMAGE"},
 		},
+		&cli.StringFlag{
+			Name:    "image-pull-secret",
+			Usage:   "Docker config secret of the namespace pulling the image, also used to resolve its digest",
+			EnvVars: []string{"IMAGE_PULL_SECRET"},
+		},
+		&cli.GenericFlag{
+			Name:    "resolve-digest",
+			Usage:   "Pin the image to the digest of its tag at deploy time (true, false or best-effort to deploy the tag when the registry fails)",
+			Value:   &resolveDigestValue{mode: defaults.ResolveDigest},
+			EnvVars: []string{"RESOLVE_DIGEST"},
+		},
 		&cli.IntFlag{
 			Name:    "port",
 			Usage:   "Port used by the service",
-			Value:   80,
+			Value:   defaults.Port,
 			Aliases: []string{"p"},
 			EnvVars: []string{"PORT"},
 		},
-		&cli.StringFlag{
+		&cli.IntFlag{
+			Name:    "grpc-port",
+			Usage:   "Port of the gRPC services of the greeting server on its container and service, disabled when 0",
+			EnvVars: []string{"GRPC_PORT"},
+		},
+		&cli.StringSliceFlag{
 			Name:    "namespace",
-			Usage:   "Kubernetes namespace used to create resources",
-			Value:   api.NamespaceDefault,
+			Usage:   "Kubernetes namespace used to create resources, repeated or comma-separated to deploy to several namespaces",
+			Value:   cli.NewStringSlice(defaults.Namespace),
 			Aliases: []string{"n"},
 			EnvVars: []string{"NAMESPACE"},
 		},
+		&cli.BoolFlag{
+			Name:    "create-namespace",
+			Usage:   "Create the namespace when it does not exist, otherwise it must already exist",
+			Value:   defaults.CreateNamespace,
+			EnvVars: []string{"CREATE_NAMESPACE"},
+		},
+		&cli.StringFlag{
+			Name:    "namespace-quota-cpu",
+			Usage:   "CPU requested and limited by the pods of the namespace at most, in a ResourceQuota, such as 4 or 2500m",
+			EnvVars: []string{"NAMESPACE_QUOTA_CPU"},
+		},
+		&cli.StringFlag{
+			Name:    "namespace-quota-memory",
+			Usage:   "Memory requested and limited by the pods of the namespace at most, in a ResourceQuota, such as 8Gi",
+			EnvVars: []string{"NAMESPACE_QUOTA_MEMORY"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "default-container-limits",
+			Usage:   "Limits given by a LimitRange to the containers of the namespace without limits, formatted as resource=quantity for cpu, memory or ephemeral-storage (repeatable)",
+			EnvVars: []string{"DEFAULT_CONTAINER_LIMITS"},
+		},
+		&cli.BoolFlag{
+			Name:    "manage-namespace-policies",
+			Usage:   "Manage the ResourceQuota and the LimitRange in a namespace not created by the operator too",
+			EnvVars: []string{"MANAGE_NAMESPACE_POLICIES"},
+		},
 		&cli.UintFlag{
 			Name:    "replicas",
 			Usage:   "Number of greeting server replicas",
-			Value:   1,
+			Value:   defaults.Replicas,
 			Aliases: []string{"r"},
 			EnvVars: []string{"REPLICAS"},
 		},
+		&cli.UintFlag{
+			Name:    "max-replicas",
+			Usage:   "Maximum number of greeting server replicas, 0 for no maximum",
+			Value:   defaults.MaxReplicas,
+			EnvVars: []string{"MAX_REPLICAS"},
+		},
 		&cli.StringFlag{
 			Name:    "name",
 			Usage:   "Greeting name",
-			Value:   "anonymous",
+			Value:   defaults.Name,
 			EnvVars: []string{"NAME"},
 		},
+		&cli.BoolFlag{
+			Name:    "show-host",
+			Usage:   "Make the greeting server tell its pod name and version in the greeting message",
+			Value:   defaults.ShowHost,
+			EnvVars: []string{"SHOW_HOST"},
+		},
+		&cli.StringFlag{
+			Name:    "greeting-template",
+			Usage:   "Template of the greeting message, with the .Name, .Hostname, .Count and .Now fields, the server default when empty",
+			EnvVars: []string{"GREETING_TEMPLATE"},
+		},
+		&cli.StringFlag{
+			Name:    "auth-token",
+			Usage:   "Bearer token required by the greeting server on /greet, kept in a Secret, open when empty",
+			EnvVars: []string{"AUTH_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "name-from-configmap",
+			Usage:   "ConfigMap key holding the greeting name, formatted as namespace/name:key, followed in watch mode",
+			EnvVars: []string{"NAME_FROM_CONFIGMAP"},
+		},
+		&cli.StringFlag{
+			Name:    "service-type",
+			Usage:   "Type of the service exposing the greeting server",
+			Value:   string(defaults.ServiceType),
+			EnvVars: []string{"SERVICE_TYPE"},
+		},
+		&cli.StringFlag{
+			Name:    "workload",
+			Usage:   "Kind of workload running the greeting server (deployment or daemonset)",
+			Value:   defaults.Workload,
+			Aliases: []string{"w"},
+			EnvVars: []string{"WORKLOAD"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "init-container",
+			Usage:   "Init container run before the greeting server, formatted as name=image[:command] (repeatable)",
+			EnvVars: []string{"INIT_CONTAINERS"},
+		},
+		&cli.StringFlag{
+			Name:    "shared-volume-path",
+			Usage:   "Path where the volume shared with the init containers is mounted",
+			Value:   defaults.SharedVolumePath,
+			EnvVars: []string{"SHARED_VOLUME_PATH"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "sidecar",
+			Usage:   "Sidecar container running next to the greeting server, formatted as name=image (repeatable)",
+			EnvVars: []string{"SIDECARS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "sidecar-env",
+			Usage:   "Sidecar environment variable, formatted as name:KEY=VALUE (repeatable)",
+			EnvVars: []string{"SIDECAR_ENV"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "sidecar-port",
+			Usage:   "Sidecar container port, formatted as name:port (repeatable)",
+			EnvVars: []string{"SIDECAR_PORTS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "mount-configmap",
+			Usage:   "ConfigMap mounted into the greeting server, formatted as name:/path (repeatable)",
+			EnvVars: []string{"MOUNT_CONFIGMAPS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "topology-spread",
+			Usage:   "Topology spread constraint, formatted as key=maxSkew[:whenUnsatisfiable] (repeatable)",
+			EnvVars: []string{"TOPOLOGY_SPREADS"},
+		},
+		&cli.StringFlag{
+			Name:    "container-command",
+			Usage:   "Executable replacing the entrypoint of the greeting server image, the image one being kept when empty",
+			EnvVars: []string{"CONTAINER_COMMAND"},
+		},
+		&cli.GenericFlag{
+			Name:    "container-arg",
+			Usage:   "Argument of the greeting server container, passed as is (repeatable, one per line in the environment variable)",
+			Value:   &argsFlag{},
+			EnvVars: []string{"CONTAINER_ARGS"},
+		},
+		&cli.StringFlag{
+			Name:    "liveness-probe-type",
+			Usage:   "Type of the liveness and readiness probes of the greeting server (http, tcp on the gRPC port when set, exec or none)",
+			Value:   defaults.ProbeType,
+			EnvVars: []string{"LIVENESS_PROBE_TYPE"},
+		},
+		&cli.GenericFlag{
+			Name:    "liveness-exec-command",
+			Usage:   "Command run by the exec probes, passed as is (repeatable, one per argument, one per line in the environment variable)",
+			Value:   &argsFlag{},
+			EnvVars: []string{"LIVENESS_EXEC_COMMAND"},
+		},
+		&cli.StringFlag{
+			Name:    "readiness-path",
+			Usage:   "Path of the readiness probe of the greeting server, no probe when empty",
+			Value:   defaults.ReadinessPath,
+			EnvVars: []string{"READINESS_PATH"},
+		},
+		&cli.IntFlag{
+			Name:    "min-ready-seconds",
+			Usage:   "Seconds a new pod must be ready before being considered available",
+			EnvVars: []string{"MIN_READY_SECONDS"},
+		},
+		&cli.IntFlag{
+			Name:    "progress-deadline-seconds",
+			Usage:   "Seconds after which a stalled rollout is considered failed (0 for the Kubernetes default)",
+			EnvVars: []string{"PROGRESS_DEADLINE_SECONDS"},
+		},
+		&cli.StringFlag{
+			Name:    "strategy",
+			Usage:   "Strategy replacing the deployment pods (RollingUpdate, or Recreate for the images which cannot run old and new versions side by side)",
+			Value:   string(defaults.Strategy),
+			EnvVars: []string{"STRATEGY"},
+		},
+		&cli.BoolFlag{
+			Name:    "allow-recreate",
+			Usage:   "Delete the deployment and its pods, and create it anew, when its immutable selector changes instead of failing",
+			EnvVars: []string{"ALLOW_RECREATE"},
+		},
+		&cli.DurationFlag{
+			Name:    "shutdown-delay",
+			Usage:   "Time the greeting server keeps serving once its pod is terminating, while the endpoints are updated, before draining",
+			Value:   defaults.ShutdownDelay,
+			EnvVars: []string{"SHUTDOWN_DELAY"},
+		},
+		&cli.Int64Flag{
+			Name:    "termination-grace-period-seconds",
+			Usage:   "Seconds given to the pods to shut down (0 for the shutdown delay, the 20s drain of the greeting server and a 5s margin)",
+			EnvVars: []string{"TERMINATION_GRACE_PERIOD_SECONDS"},
+		},
+		&cli.BoolFlag{
+			Name:    "adopt",
+			Usage:   "Take over existing resources not created by the operator instead of refusing to update them",
+			EnvVars: []string{"ADOPT"},
+		},
+		&cli.BoolFlag{
+			Name:    "legacy-update",
+			Usage:   "Create and update resources instead of using server-side apply, for clusters not supporting it",
+			EnvVars: []string{"LEGACY_UPDATE"},
+		},
+		&cli.BoolFlag{
+			Name:    "wait",
+			Usage:   "Wait for the greeting server to be rolled out and reachable",
+			EnvVars: []string{"WAIT"},
+		},
+		&cli.DurationFlag{
+			Name:    "timeout",
+			Usage:   "Maximum duration to wait for the rollout",
+			Value:   defaults.WaitTimeout,
+			EnvVars: []string{"TIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:    "expose",
+			Usage:   "Expose the greeting service outside of the cluster with an OpenShift route (none or route)",
+			Value:   defaults.Expose,
+			EnvVars: []string{"EXPOSE"},
+		},
+		&cli.StringFlag{
+			Name:    "route-host",
+			Usage:   "Host of the route, generated by the router when empty",
+			EnvVars: []string{"ROUTE_HOST"},
+		},
+		&cli.BoolFlag{
+			Name:    "route-tls-edge",
+			Usage:   "Terminate TLS at the router, redirecting plain HTTP",
+			EnvVars: []string{"ROUTE_TLS_EDGE"},
+		},
+		&cli.BoolFlag{
+			Name:    "service-monitor",
+			Usage:   "Create a Prometheus Operator ServiceMonitor scraping the greeting server",
+			EnvVars: []string{"SERVICE_MONITOR"},
+		},
+		&cli.StringFlag{
+			Name:    "metrics-path",
+			Usage:   "Path of the greeting server metrics scraped by the ServiceMonitor",
+			Value:   defaults.MetricsPath,
+			EnvVars: []string{"METRICS_PATH"},
+		},
+		&cli.DurationFlag{
+			Name:    "scrape-interval",
+			Usage:   "Interval between two scrapes of the ServiceMonitor",
+			Value:   defaults.ScrapeInterval,
+			EnvVars: []string{"SCRAPE_INTERVAL"},
+		},
+		&cli.BoolFlag{
+			Name:    "rollback-on-failure",
+			Usage:   "Roll the deployment back to its previous revision when --wait detects a failed rollout",
+			EnvVars: []string{"ROLLBACK_ON_FAILURE"},
+		},
+		&cli.IntFlag{
+			Name:    "crash-loop-restarts",
+			Usage:   "Restarts of a crash looping pod after which the rollout is failed, with --rollback-on-failure",
+			Value:   int(defaults.CrashLoopRestarts),
+			EnvVars: []string{"CRASH_LOOP_RESTARTS"},
+		},
+		&cli.BoolFlag{
+			Name:    "wait-for-ip",
+			Usage:   "Wait for the load balancer service to be given an address, even without --wait",
+			EnvVars: []string{"WAIT_FOR_IP"},
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Usage:   "Format printed once installed: the greeting server URLs as text, or a summary of the resources as json or yaml, the logs staying on stderr",
+			Value:   defaults.Output,
+			EnvVars: []string{"OUTPUT"},
+		},
+		&cli.BoolFlag{
+			Name:    "verify",
+			Usage:   "Check that the greeting server answers on /health and greets with its name once installed",
+			EnvVars: []string{"VERIFY"},
+		},
+		&cli.DurationFlag{
+			Name:    "verify-timeout",
+			Usage:   "Maximum duration of the verification",
+			Value:   defaults.VerifyTimeout,
+			EnvVars: []string{"VERIFY_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:    "watch",
+			Usage:   "Keep running and restore the managed resources when they drift or disappear",
+			EnvVars: []string{"WATCH"},
+		},
+		&cli.DurationFlag{
+			Name:    "resync-period",
+			Usage:   "Interval of the full reconciliation in watch mode",
+			Value:   defaults.ResyncPeriod,
+			EnvVars: []string{"RESYNC_PERIOD"},
+		},
+		&cli.IntFlag{
+			Name:    "max-concurrent-reconciles",
+			Usage:   "Number of keys reconciled at once in watch and controller modes",
+			Value:   defaults.MaxConcurrentReconciles,
+			EnvVars: []string{"MAX_CONCURRENT_RECONCILES"},
+		},
+		&cli.BoolFlag{
+			Name:    "cleanup-on-interrupt",
+			Usage:   "Delete the resources created by the run when interrupted before they are all in place",
+			EnvVars: []string{"CLEANUP_ON_INTERRUPT"},
+		},
+		&cli.BoolFlag{
+			Name:    "prune",
+			Usage:   "Delete the resources labeled as managed by the operator in the namespace that are not desired anymore",
+			EnvVars: []string{"PRUNE"},
+		},
+		&cli.BoolFlag{
+			Name:    "prune-dry-run",
+			Usage:   "Print the resources that would be pruned without deleting them",
+			EnvVars: []string{"PRUNE_DRY_RUN"},
+		},
+		&cli.BoolFlag{
+			Name:    "replace",
+			Usage:   "Delete the resources of the other instances of the namespace once the new ones are healthy",
+			EnvVars: []string{"REPLACE"},
+		},
+		&cli.IntFlag{
+			Name:    "retry-max-attempts",
+			Usage:   "Maximum number of calls to the API when they fail with a transient error",
+			Value:   defaults.RetryMaxAttempts,
+			EnvVars: []string{"RETRY_MAX_ATTEMPTS"},
+		},
+		&cli.DurationFlag{
+			Name:    "retry-max-duration",
+			Usage:   "Maximum duration spent retrying a call to the API, 0 for no limit",
+			Value:   defaults.RetryMaxDuration,
+			EnvVars: []string{"RETRY_MAX_DURATION"},
+		},
+		&cli.DurationFlag{
+			Name:    "request-timeout",
+			Usage:   "Maximum duration of a single API call, 0 for no limit",
+			EnvVars: []string{"REQUEST_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:    "deadline",
+			Usage:   "Maximum duration of the creation of the resources, the wait included, 0 for no limit",
+			EnvVars: []string{"DEADLINE"},
+		},
+		&cli.BoolFlag{
+			Name:    "standalone",
+			Usage:   "Create a single greeting server from the flags instead of reconciling the Greeting resources",
+			EnvVars: []string{"STANDALONE"},
+		},
+		&cli.StringFlag{
+			Name:    "watch-namespace",
+			Usage:   "Namespace of the reconciled Greeting resources, every namespace when empty",
+			EnvVars: []string{"WATCH_NAMESPACE"},
+		},
+		&cli.StringFlag{
+			Name:    "namespace-selector",
+			Usage:   "Label selector of the namespaces whose Greeting resources are reconciled, every namespace when empty",
+			EnvVars: []string{"NAMESPACE_SELECTOR"},
+		},
+		&cli.IntFlag{
+			Name:    "webhook-port",
+			Usage:   "Port of the validating admission webhook server, disabled when 0",
+			EnvVars: []string{"WEBHOOK_PORT"},
+		},
+		&cli.StringFlag{
+			Name:    "webhook-cert-dir",
+			Usage:   "Directory holding the tls.crt and tls.key files of the webhook server",
+			Value:   "/tmp/k8s-webhook-server/serving-certs",
+			EnvVars: []string{"WEBHOOK_CERT_DIR"},
+		},
+		&cli.StringFlag{
+			Name:    "health-addr",
+			Usage:   "Address of the /healthz and /readyz probe endpoints, disabled when empty",
+			EnvVars: []string{"HEALTH_ADDR"},
+		},
+		&cli.StringFlag{
+			Name:    "metrics-addr",
+			Usage:   "Address of the Prometheus metrics endpoint, disabled when empty",
+			EnvVars: []string{"METRICS_ADDR"},
+		},
+		&cli.BoolFlag{
+			Name:    "leader-elect",
+			Usage:   "Reconcile only while holding the leader lease, so that several operator replicas can run",
+			EnvVars: []string{"LEADER_ELECT"},
+		},
+		&cli.StringFlag{
+			Name:    "leader-elect-namespace",
+			Usage:   "Namespace of the leader lease",
+			Value:   api.NamespaceDefault,
+			EnvVars: []string{"LEADER_ELECT_NAMESPACE", "POD_NAMESPACE"},
+		},
+		&cli.StringFlag{
+			Name:    "leader-elect-name",
+			Usage:   "Name of the leader lease",
+			Value:   "greeting-operator",
+			EnvVars: []string{"LEADER_ELECT_NAME"},
+		},
+		&cli.StringFlag{
+			Name:    "leader-elect-identity",
+			Usage:   "Identity of this operator replica, the hostname when empty",
+			EnvVars: []string{"LEADER_ELECT_IDENTITY", "POD_NAME"},
+		},
+		&cli.DurationFlag{
+			Name:    "leader-elect-lease-duration",
+			Usage:   "Duration after which followers take over a lease that is not renewed",
+			Value:   15 * time.Second,
+			EnvVars: []string{"LEADER_ELECT_LEASE_DURATION"},
+		},
+		&cli.DurationFlag{
+			Name:    "leader-elect-renew-deadline",
+			Usage:   "Duration the leader retries renewing its lease before giving up",
+			Value:   10 * time.Second,
+			EnvVars: []string{"LEADER_ELECT_RENEW_DEADLINE"},
+		},
+		&cli.DurationFlag{
+			Name:    "leader-elect-retry-period",
+			Usage:   "Interval between two attempts to acquire or renew the lease",
+			Value:   2 * time.Second,
+			EnvVars: []string{"LEADER_ELECT_RETRY_PERIOD"},
+		},
+		&cli.BoolFlag{
+			Name:    "leader-elect-reelect",
+			Usage:   "Re-enter candidacy after losing leadership instead of exiting",
+			EnvVars: []string{"LEADER_ELECT_REELECT"},
+		},
+		&cli.BoolFlag{
+			Name:    "diff-only",
+			Usage:   "Print the changes that would be made to the live resources and exit without writing",
+			EnvVars: []string{"DIFF_ONLY"},
+		},
+		&cli.BoolFlag{
+			Name:    "server-dry-run",
+			Usage:   "Send every write to the API server as a dry-run, logging the objects it would store without persisting them",
+			EnvVars: []string{"SERVER_DRY_RUN"},
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Usage:   "Print the resources as YAML instead of creating them, without connecting to the cluster",
+			EnvVars: []string{"DRY_RUN"},
+		},
+		&cli.BoolFlag{
+			Name:    "host-port",
+			Usage:   "Expose the daemonset pods through a host port instead of a service",
+			EnvVars: []string{"HOST_PORT"},
+		},
 	}
+	app.Before = configureLogging
 	app.Action = run
+	app.Commands = []*cli.Command{
+		{
+			Name:  "delete",
+			Usage: "Delete every resource managed by the operator",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "delete-namespace",
+					Usage: "Also delete the namespace, if it was created by the operator",
+				},
+				&cli.BoolFlag{
+					Name:  "wait",
+					Usage: "Wait for the resources to be fully deleted",
+				},
+				&cli.StringSliceFlag{
+					Name:  "instance",
+					Usage: "Instance of the configuration file to delete, every instance when not set (repeatable)",
+				},
+			},
+			Action: runDelete,
+		},
+		{
+			Name:  "pause",
+			Usage: "Pause the rollouts of the managed deployment, reconciliation is suspended until resumed",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "instance",
+					Usage: "Instance of the configuration file to pause, every instance when not set (repeatable)",
+				},
+			},
+			Action: func(cliCtx *cli.Context) error {
+				return runSetPaused(cliCtx, true)
+			},
+		},
+		{
+			Name:  "resume",
+			Usage: "Resume the rollouts of the managed deployment",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "instance",
+					Usage: "Instance of the configuration file to resume, every instance when not set (repeatable)",
+				},
+			},
+			Action: func(cliCtx *cli.Context) error {
+				return runSetPaused(cliCtx, false)
+			},
+		},
+		{
+			Name:  "restart",
+			Usage: "Roll the pods of the managed deployment, refused while paused",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "wait",
+					Usage: "Wait for the restarted deployment to be rolled out",
+				},
+				&cli.StringSliceFlag{
+					Name:  "instance",
+					Usage: "Instance of the configuration file to restart, every instance when not set (repeatable)",
+				},
+			},
+			Action: runRestart,
+		},
+		{
+			Name:  "canary",
+			Usage: "Run a new image on a fraction of the traffic next to the stable deployment",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "image",
+					Usage: "Image tried by the canary",
+				},
+				&cli.IntFlag{
+					Name:  "weight",
+					Usage: "Percentage of the traffic sent to the canary, from 1 to 99",
+					Value: 10,
+				},
+				&cli.StringSliceFlag{
+					Name:  "instance",
+					Usage: "Instance of the configuration file to try the image on, every instance when not set (repeatable)",
+				},
+			},
+			Action: runCanary,
+			Subcommands: []*cli.Command{
+				{
+					Name:  "promote",
+					Usage: "Roll the stable deployment to the image of the canary, then delete the canary",
+					Action: func(cliCtx *cli.Context) error {
+						return runCanaryAction(cliCtx, (*operator.Operator).PromoteCanary)
+					},
+				},
+				{
+					Name:  "abort",
+					Usage: "Delete the canary, leaving the stable deployment untouched",
+					Action: func(cliCtx *cli.Context) error {
+						return runCanaryAction(cliCtx, (*operator.Operator).AbortCanary)
+					},
+				},
+			},
+		},
+		{
+			Name:  "export",
+			Usage: "Write the live managed resources as clean YAML files, one per object",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "dir",
+					Usage: "Directory the files are written to",
+					Value: "manifests",
+				},
+				&cli.BoolFlag{
+					Name:  "kustomize",
+					Usage: "Also write a kustomization.yaml listing the files",
+				},
+				&cli.StringSliceFlag{
+					Name:  "instance",
+					Usage: "Instance of the configuration file to export, every instance when not set (repeatable)",
+				},
+			},
+			Action: runExport,
+		},
+		{
+			Name:  "status",
+			Usage: "Summarize the state of the managed resources",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "output",
+					Usage:   "Output format (text or json)",
+					Value:   "text",
+					Aliases: []string{"o"},
+				},
+				&cli.StringSliceFlag{
+					Name:  "instance",
+					Usage: "Instance of the configuration file to summarize, every instance when not set (repeatable)",
+				},
+			},
+			Action: runStatus,
+		},
+		{
+			Name:  "webhook",
+			Usage: "Validating admission webhook helpers",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "manifests",
+					Usage: "Print the ValidatingWebhookConfiguration of the Greeting resources",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "service-name",
+							Usage: "Name of the service in front of the webhook server",
+							Value: "greeting-operator-webhook",
+						},
+						&cli.StringFlag{
+							Name:  "service-namespace",
+							Usage: "Namespace of the service in front of the webhook server",
+							Value: api.NamespaceDefault,
+						},
+						&cli.IntFlag{
+							Name:  "service-port",
+							Usage: "Port of the service in front of the webhook server",
+							Value: 443,
+						},
+						&cli.StringFlag{
+							Name:  "ca-bundle-file",
+							Usage: "PEM file of the CA that signed the webhook server certificate",
+						},
+					},
+					Action: runWebhookManifests,
+				},
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "Configuration file helpers",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "init",
+					Usage:     "Write a starter configuration file documenting every setting, - for the standard output",
+					ArgsUsage: "[path]",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "force",
+							Usage: "Overwrite the file when it exists",
+						},
+					},
+					Action: runConfigInit,
+				},
+			},
+		},
+		{
+			Name:  "version",
+			Usage: "Print the build metadata and the version of the cluster, when reachable",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "output",
+					Usage:   "Output format (text or json)",
+					Value:   operator.OutputText,
+					Aliases: []string{"o"},
+				},
+			},
+			Action: runVersion,
+		},
+		{
+			Name:  "crd",
+			Usage: "Print the CustomResourceDefinition of the Greeting resource",
+			Action: func(cliCtx *cli.Context) error {
+				return operator.WriteGreetingCRD(os.Stdout)
+			},
+		},
+	}
 
-	if err := app.Run(os.Args); err != nil {
+	// The context is cancelled on Ctrl-C and when the pod is terminated.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		var interrupted *operator.InterruptedError
+		if errors.As(err, &interrupted) {
+			log.WithError(err).Error("Greeting operator interrupted")
+			stop()
+			os.Exit(interrupted.ExitCode())
+		}
 		log.WithError(err).Fatal("Unable to start greeting operator")
 	}
 }
 
 func run(cliCtx *cli.Context) error {
-	config := &GreetingOperatorConfig{
-		Image:     cliCtx.String("image"),
-		Namespace: cliCtx.String("namespace"),
-		Replicas:  cliCtx.Uint("replicas"),
-		Name:      cliCtx.String("name"),
+	config, err := newConfig(cliCtx)
+	if err != nil {
+		return err
 	}
 
-	operator, err := NewGreetingOperator(config)
+	instances, err := config.InstanceConfigs()
 	if err != nil {
-		return fmt.Errorf("creating operator: %w", err)
+		return err
 	}
 
-	if err = operator.Start(cliCtx.Context); err != nil {
-		return fmt.Errorf("start operator: %w", err)
+	// Every problem is reported before any request is sent to the cluster.
+	for _, instance := range instances {
+		if err = instance.Validate(); err != nil {
+			if len(instances) > 1 {
+				return fmt.Errorf("invalid configuration of instance %s/%s:\n%w", instance.Namespace, instance.ResourceName, err)
+			}
+			return fmt.Errorf("invalid configuration:\n%w", err)
+		}
 	}
 
-	return nil
-}
+	if cliCtx.Bool("diff-only") {
+		return operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+			op, err := operator.NewForConfig(config)
+			if err != nil {
+				return fmt.Errorf("creating operator: %w", err)
+			}
+			defer op.Close()
 
-// GreetingOperatorConfig is the configration required to create the GreetingOperator.
-type GreetingOperatorConfig struct {
-	// Image to use to create the greeting server.
-	Image string
-	// Port on which the greeting server is reachable.
-	Port int
-	// Namespace is which the resources are created.
-	Namespace string
-	// Number of greeting server replicas.
-	Replicas uint
-	// Name of the greeting server.
-	Name string
-}
+			if _, err = op.Diff(cliCtx.Context, os.Stdout); err != nil {
+				return fmt.Errorf("diff resources: %w", err)
+			}
+			return nil
+		})
+	}
+
+	if cliCtx.Bool("dry-run") {
+		return operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+			op, err := operator.NewOffline(config)
+			if err != nil {
+				return fmt.Errorf("creating operator: %w", err)
+			}
+
+			return op.Render(os.Stdout)
+		})
+	}
+
+	if addr := cliCtx.String("health-addr"); addr != "" {
+		health, err := operator.NewHealthServer(addr, config.Connection)
+		if err != nil {
+			return fmt.Errorf("creating health server: %w", err)
+		}
+
+		go func() {
+			if err := health.Run(cliCtx.Context); err != nil {
+				log.WithError(err).Fatal("Health server failed")
+			}
+		}()
+	}
+
+	if addr := cliCtx.String("metrics-addr"); addr != "" {
+		go func() {
+			if err := operator.ServeMetrics(cliCtx.Context, addr); err != nil {
+				log.WithError(err).Fatal("Metrics server failed")
+			}
+		}()
+	}
 
-// GreetingOperator exposes a greeting server on kubernetes.
-type GreetingOperator struct {
-	image     string
-	port      int
-	namespace string
-	replicas  uint
-	name      string
-	client    *kubernetes.Clientset
+	if !cliCtx.Bool("standalone") {
+		if len(config.Instances) > 0 {
+			log.Warning("Instances of the configuration file are only deployed in standalone mode")
+		}
+		if len(config.Namespaces) > 0 {
+			log.Warning("Several namespaces are only deployed to in standalone mode, use --namespace-selector to select the namespaces of the Greeting resources")
+		}
+
+		controller, err := operator.NewGreetingController(config, cliCtx.String("watch-namespace"))
+		if err != nil {
+			return fmt.Errorf("creating controller: %w", err)
+		}
+
+		if port := cliCtx.Int("webhook-port"); port != 0 {
+			webhook := operator.NewWebhookServer(controller, fmt.Sprintf(":%d", port), cliCtx.String("webhook-cert-dir"))
+			go func() {
+				if err := webhook.Run(cliCtx.Context); err != nil {
+					log.WithError(err).Fatal("Webhook server failed")
+				}
+			}()
+		}
+
+		return runReconciler(cliCtx, config, controller.Run)
+	}
+
+	// Every instance is watched at the same time, the others are started one after the other.
+	return runReconciler(cliCtx, config, func(ctx context.Context) error {
+		return operator.ForEachInstance(instances, config.Watch, func(config *operator.Config) error {
+			op, err := operator.NewForConfig(config)
+			if err != nil {
+				return fmt.Errorf("creating operator: %w", err)
+			}
+			defer op.Close()
+
+			if err = op.Start(ctx); err != nil {
+				return fmt.Errorf("start operator: %w", err)
+			}
+			return nil
+		})
+	})
 }
 
-// NewGreetingOperator creates a GreetingOperator linked to the current cluster.
-func NewGreetingOperator(config *GreetingOperatorConfig) (*GreetingOperator, error) {
-	cfg, err := rest.InClusterConfig()
-	if err != nil {
-		return nil, fmt.Errorf("in cluster config: %w", err)
+// runReconciler runs the reconciliation, only while holding the leader lease when leader election is enabled.
+func runReconciler(cliCtx *cli.Context, config *operator.Config, reconcile func(context.Context) error) error {
+	if !cliCtx.Bool("leader-elect") {
+		return reconcile(cliCtx.Context)
+	}
+
+	identity := cliCtx.String("leader-elect-identity")
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("leader election identity: %w", err)
+		}
+		identity = hostname
 	}
 
-	client, err := kubernetes.NewForConfig(cfg)
+	return operator.RunLeaderElected(cliCtx.Context, operator.LeaderElectionConfig{
+		Namespace:     cliCtx.String("leader-elect-namespace"),
+		Name:          cliCtx.String("leader-elect-name"),
+		Identity:      identity,
+		LeaseDuration: cliCtx.Duration("leader-elect-lease-duration"),
+		RenewDeadline: cliCtx.Duration("leader-elect-renew-deadline"),
+		RetryPeriod:   cliCtx.Duration("leader-elect-retry-period"),
+		Reelect:       cliCtx.Bool("leader-elect-reelect"),
+		Connection:    config.Connection,
+	}, reconcile)
+}
+
+func runDelete(cliCtx *cli.Context) error {
+	_, instances, err := selectedInstances(cliCtx)
 	if err != nil {
-		return nil, fmt.Errorf("new k8s client: %w", err)
+		return err
 	}
 
-	op := GreetingOperator{
-		image:     config.Image,
-		port:      config.Port,
-		namespace: config.Namespace,
-		replicas:  config.Replicas,
-		name:      config.Name,
-		client:    client,
+	opts := operator.DeleteOptions{
+		Namespace: cliCtx.Bool("delete-namespace"),
+		Wait:      cliCtx.Bool("wait"),
 	}
 
-	return &op, nil
+	return operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+		op, err := operator.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("creating operator: %w", err)
+		}
+		defer op.Close()
+
+		if err = op.Delete(cliCtx.Context, opts); err != nil {
+			return fmt.Errorf("delete resources: %w", err)
+		}
+		return nil
+	})
 }
 
-// Start creates the k8s resources exposing a greeting server.
-func (o *GreetingOperator) Start(ctx context.Context) error {
-	if err := o.createNamespace(ctx); err != nil {
+func runSetPaused(cliCtx *cli.Context, paused bool) error {
+	_, instances, err := selectedInstances(cliCtx)
+	if err != nil {
 		return err
 	}
 
-	if err := o.createDeployment(ctx); err != nil {
+	return operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+		op, err := operator.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("creating operator: %w", err)
+		}
+		defer op.Close()
+
+		return op.SetPaused(cliCtx.Context, paused)
+	})
+}
+
+func runRestart(cliCtx *cli.Context) error {
+	_, instances, err := selectedInstances(cliCtx)
+	if err != nil {
 		return err
 	}
 
-	if err := o.createService(ctx); err != nil {
-		return err
+	return operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+		op, err := operator.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("creating operator: %w", err)
+		}
+		defer op.Close()
+
+		return op.Restart(cliCtx.Context, cliCtx.Bool("wait"))
+	})
+}
+
+func runCanary(cliCtx *cli.Context) error {
+	image := cliCtx.String("image")
+	if image == "" {
+		return errors.New("--image of the canary is required")
 	}
 
-	return nil
+	return runCanaryAction(cliCtx, func(op *operator.Operator, ctx context.Context) error {
+		return op.StartCanary(ctx, image, cliCtx.Int("weight"))
+	})
 }
 
-func (o *GreetingOperator) createNamespace(ctx context.Context) error {
-	log.WithField("namespace", o.namespace).Info("Creating namespace")
+// runCanaryAction calls action for the instances selected by the --instance flag of the canary command.
+// The configuration only comes from the global flags, --image of the canary command naming the canary image.
+func runCanaryAction(cliCtx *cli.Context, action func(*operator.Operator, context.Context) error) error {
+	canaryCtx, globalCtx := cliCtx, cliCtx
+	lineage := cliCtx.Lineage()
+	for i, c := range lineage {
+		if c.Command != nil && c.Command.Name == "canary" {
+			canaryCtx, globalCtx = c, lineage[i+1]
+		}
+	}
 
-	namespace := &api.Namespace{
-		ObjectMeta: meta.ObjectMeta{
-			Name: o.namespace,
-		},
+	_, instances, err := selectedInstances(globalCtx)
+	if err != nil {
+		return err
+	}
+	if instances, err = operator.FilterInstances(instances, canaryCtx.StringSlice("instance")); err != nil {
+		return err
 	}
 
-	if _, err := o.client.CoreV1().Namespaces().Create(ctx, namespace, meta.CreateOptions{}); err != nil {
-		if !kerror.IsAlreadyExists(err) {
-			return fmt.Errorf("create namespace: %w", err)
+	return operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+		op, err := operator.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("creating operator: %w", err)
 		}
+		defer op.Close()
+
+		return action(op, cliCtx.Context)
+	})
+}
+
+func runExport(cliCtx *cli.Context) error {
+	_, instances, err := selectedInstances(cliCtx)
+	if err != nil {
+		return err
 	}
 
-	log.WithField("namespace", o.namespace).Info("Namespace created")
+	return operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+		op, err := operator.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("creating operator: %w", err)
+		}
+		defer op.Close()
+
+		// Every instance gets its own directory, so that their files never collide.
+		dir := cliCtx.String("dir")
+		if len(instances) > 1 {
+			dir = filepath.Join(dir, config.Namespace, config.ResourceName)
+		}
+
+		files, err := op.Export(cliCtx.Context, dir, cliCtx.Bool("kustomize"))
+		if err != nil {
+			return fmt.Errorf("export resources: %w", err)
+		}
 
-	return nil
+		for _, file := range files {
+			fmt.Fprintln(os.Stdout, filepath.Join(dir, file))
+		}
+		return nil
+	})
 }
 
-func (o *GreetingOperator) createDeployment(ctx context.Context) error {
-	deploymentClient := o.client.AppsV1().Deployments(o.namespace)
-
-	objMeta := meta.ObjectMeta{
-		Name:   "greeting",
-		Labels: map[string]string{"app": "greeting"},
-	}
-
-	podTpl := api.PodTemplateSpec{
-		ObjectMeta: objMeta,
-		Spec: api.PodSpec{
-			Containers: []api.Container{{
-				Name:  "greeting",
-				Image: o.image,
-				Ports: []api.ContainerPort{{
-					Name:          "http",
-					Protocol:      api.ProtocolTCP,
-					ContainerPort: 80,
-				}},
-				Env: []api.EnvVar{{
-					Name:  "NAME",
-					Value: o.name,
-				}},
-				LivenessProbe: &api.Probe{
-					ProbeHandler: api.ProbeHandler{
-						HTTPGet: &api.HTTPGetAction{
-							Path: "/health",
-							Port: intstr.FromInt(80),
-						},
-					},
-					TimeoutSeconds: 3,
-				},
-				ImagePullPolicy: api.PullNever,
-			}},
-			RestartPolicy: api.RestartPolicyAlways,
-		},
+func runStatus(cliCtx *cli.Context) error {
+	output := cliCtx.String("output")
+	if output != operator.OutputText && output != operator.OutputJSON {
+		return fmt.Errorf("unknown output format %q", output)
 	}
 
-	var replicas int32 = 1
-	greetingDeployment := &apps.Deployment{
-		ObjectMeta: objMeta,
-		Spec: apps.DeploymentSpec{
-			Replicas: &replicas,
-			Selector: &meta.LabelSelector{MatchLabels: map[string]string{"app": "greeting"}},
-			Template: podTpl,
-		},
+	config, instances, err := selectedInstances(cliCtx)
+	if err != nil {
+		return err
 	}
 
-	log.Info("Creating deployment")
+	var statuses []*operator.Status
+	err = operator.ForEachInstance(instances, false, func(config *operator.Config) error {
+		op, err := operator.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("creating operator: %w", err)
+		}
 
-	var alreadyExists bool
-	_, err := deploymentClient.Create(ctx, greetingDeployment, meta.CreateOptions{})
-	if err != nil {
-		if !kerror.IsAlreadyExists(err) {
-			return fmt.Errorf("create deployment: %w", err)
+		status, err := op.Status(cliCtx.Context)
+		if err != nil {
+			return fmt.Errorf("fetch status: %w", err)
+		}
+		statuses = append(statuses, status)
+
+		if output == operator.OutputText {
+			if len(instances) > 1 {
+				fmt.Fprintf(os.Stdout, "== Instance %s/%s ==\n", config.Namespace, config.ResourceName)
+			}
+			if err = status.WriteTable(os.Stdout); err != nil {
+				return fmt.Errorf("write status: %w", err)
+			}
+		}
+
+		if !status.FullyAvailable() {
+			return fmt.Errorf("%s %q is not fully available", status.Workload.Kind, status.Workload.Name)
+		}
+		return nil
+	})
+
+	if output == operator.OutputJSON {
+		// A list is written as soon as instances or several namespaces are configured, whatever the filter.
+		var writeErr error
+		if len(config.Instances) == 0 && len(config.Namespaces) == 0 && len(statuses) == 1 {
+			writeErr = statuses[0].WriteJSON(os.Stdout)
 		} else {
-			alreadyExists = true
+			writeErr = operator.WriteStatusesJSON(os.Stdout, statuses)
+		}
+		if writeErr != nil {
+			return fmt.Errorf("write status: %w", writeErr)
 		}
 	}
 
-	if alreadyExists {
-		log.Info("Deployment already exists, updating current")
-		_, err = deploymentClient.Update(ctx, greetingDeployment, meta.UpdateOptions{})
-		if err != nil {
-			return fmt.Errorf("update deployment: %w", err)
-		}
+	return err
+}
+
+// selectedInstances returns the configuration along with the configurations of the instances
+// selected by the --instance flag.
+func selectedInstances(cliCtx *cli.Context) (*operator.Config, []*operator.Config, error) {
+	config, err := newConfig(cliCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instances, err := config.InstanceConfigs()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	log.Info("Deployment created")
-	return nil
+	instances, err = operator.FilterInstances(instances, cliCtx.StringSlice("instance"))
+	return config, instances, err
 }
 
-func (o *GreetingOperator) createService(ctx context.Context) error {
-	serviceClient := o.client.CoreV1().Services(o.namespace)
+// newConfig builds the operator configuration from the command line flags.
+func newConfig(cliCtx *cli.Context) (*operator.Config, error) {
+	var instances []operator.Instance
+	if path := cliCtx.String("config"); path != "" {
+		var err error
+		if instances, err = applyConfigFile(cliCtx, path); err != nil {
+			return nil, err
+		}
 
-	service := &api.Service{
-		ObjectMeta: meta.ObjectMeta{Name: "greeting"},
-		Spec: api.ServiceSpec{
-			Selector: map[string]string{"app": "greeting"},
-			Type:     api.ServiceTypeLoadBalancer,
-			Ports: []api.ServicePort{{
-				Name:       "http",
-				Protocol:   api.ProtocolTCP,
-				Port:       80,
-				TargetPort: intstr.FromInt(o.port),
-			}},
-		},
+		// The logging flags may have been set by the file.
+		if err = configureLogging(cliCtx); err != nil {
+			return nil, err
+		}
 	}
 
-	var alreadyExists bool
-	_, err := serviceClient.Create(ctx, service, meta.CreateOptions{})
+	connection, err := newClusterConnection(cliCtx)
 	if err != nil {
-		if !kerror.IsAlreadyExists(err) {
-			return fmt.Errorf("create service: %w", err)
-		} else {
-			alreadyExists = true
-		}
+		return nil, err
+	}
+
+	initContainers, err := operator.ParseInitContainers(cliCtx.StringSlice("init-container"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing init containers: %w", err)
+	}
+
+	sidecars, err := operator.ParseSidecars(cliCtx.StringSlice("sidecar"), cliCtx.StringSlice("sidecar-env"), cliCtx.StringSlice("sidecar-port"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing sidecars: %w", err)
+	}
+
+	configMapMounts, err := operator.ParseConfigMapMounts(cliCtx.StringSlice("mount-configmap"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing configmap mounts: %w", err)
 	}
 
-	if alreadyExists {
-		log.Info("Service already exists, updating current")
-		_, err = serviceClient.Update(ctx, service, meta.UpdateOptions{})
+	topologySpreads, err := operator.ParseTopologySpreads(cliCtx.StringSlice("topology-spread"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing topology spreads: %w", err)
+	}
+
+	namespaceQuota, err := operator.NamespaceQuota(cliCtx.String("namespace-quota-cpu"), cliCtx.String("namespace-quota-memory"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing namespace quota: %w", err)
+	}
+	containerLimits, err := operator.ParseContainerLimits(cliCtx.StringSlice("default-container-limits"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing default container limits: %w", err)
+	}
+
+	opts := []operator.Option{
+		operator.WithImage(cliCtx.String("image")),
+		operator.WithImagePullSecret(cliCtx.String("image-pull-secret")),
+		operator.WithResolveDigest(cliCtx.String("resolve-digest")),
+		operator.WithPort(cliCtx.Int("port")),
+		operator.WithGRPCPort(cliCtx.Int("grpc-port")),
+		operator.WithNamespaces(cliCtx.StringSlice("namespace")...),
+		operator.WithNamespaceSelector(cliCtx.String("namespace-selector")),
+		operator.WithCreateNamespace(cliCtx.Bool("create-namespace")),
+		operator.WithNamespacePolicies(namespaceQuota, containerLimits, cliCtx.Bool("manage-namespace-policies")),
+		operator.WithMaxReplicas(cliCtx.Uint("max-replicas")),
+		operator.WithName(cliCtx.String("name")),
+		operator.WithGreetingTemplate(cliCtx.String("greeting-template")),
+		operator.WithShowHost(cliCtx.Bool("show-host")),
+		operator.WithAuthToken(cliCtx.String("auth-token")),
+		operator.WithWorkload(cliCtx.String("workload")),
+		operator.WithHostPort(cliCtx.Bool("host-port")),
+		operator.WithServiceType(api.ServiceType(cliCtx.String("service-type"))),
+
+		operator.WithInitContainers(initContainers...),
+		operator.WithSharedVolumePath(cliCtx.String("shared-volume-path")),
+		operator.WithSidecars(sidecars...),
+		operator.WithConfigMapMounts(configMapMounts...),
+		operator.WithTopologySpreads(topologySpreads...),
+
+		operator.WithContainerArgs(cliCtx.Generic("container-arg").(*argsFlag).args...),
+		operator.WithProbe(cliCtx.String("liveness-probe-type"), cliCtx.Generic("liveness-exec-command").(*argsFlag).args...),
+		operator.WithReadinessPath(cliCtx.String("readiness-path")),
+		operator.WithMinReadySeconds(int32(cliCtx.Int("min-ready-seconds"))),
+		operator.WithProgressDeadlineSeconds(int32(cliCtx.Int("progress-deadline-seconds"))),
+		operator.WithStrategy(apps.DeploymentStrategyType(cliCtx.String("strategy"))),
+		operator.WithAllowRecreate(cliCtx.Bool("allow-recreate")),
+		operator.WithShutdownDelay(cliCtx.Duration("shutdown-delay")),
+		operator.WithTerminationGracePeriodSeconds(cliCtx.Int64("termination-grace-period-seconds")),
+		operator.WithLegacyUpdate(cliCtx.Bool("legacy-update")),
+		operator.WithServerDryRun(cliCtx.Bool("server-dry-run")),
+		operator.WithAdopt(cliCtx.Bool("adopt")),
+		operator.WithWait(cliCtx.Bool("wait")),
+		operator.WithWaitTimeout(cliCtx.Duration("timeout")),
+		operator.WithExpose(cliCtx.String("expose")),
+		operator.WithRoute(cliCtx.String("route-host"), cliCtx.Bool("route-tls-edge")),
+		operator.WithServiceMonitor(cliCtx.Bool("service-monitor"), cliCtx.String("metrics-path"), cliCtx.Duration("scrape-interval")),
+		operator.WithRollbackOnFailure(cliCtx.Bool("rollback-on-failure"), int32(cliCtx.Int("crash-loop-restarts"))),
+		operator.WithWaitForIP(cliCtx.Bool("wait-for-ip")),
+		operator.WithOutput(cliCtx.String("output")),
+		operator.WithVerify(cliCtx.Bool("verify"), cliCtx.Duration("verify-timeout")),
+		operator.WithWatch(cliCtx.Bool("watch"), cliCtx.Duration("resync-period")),
+		operator.WithMaxConcurrentReconciles(cliCtx.Int("max-concurrent-reconciles")),
+		operator.WithCleanupOnInterrupt(cliCtx.Bool("cleanup-on-interrupt")),
+		operator.WithPrune(cliCtx.Bool("prune"), cliCtx.Bool("prune-dry-run")),
+		operator.WithReplace(cliCtx.Bool("replace")),
+		operator.WithRetry(cliCtx.Int("retry-max-attempts"), cliCtx.Duration("retry-max-duration")),
+		operator.WithRequestTimeout(cliCtx.Duration("request-timeout")),
+		operator.WithDeadline(cliCtx.Duration("deadline")),
+		operator.WithInstances(instances...),
+		operator.WithConnection(connection),
+	}
+	if value := cliCtx.String("name-from-configmap"); value != "" {
+		source, err := operator.ParseNameSource(value)
 		if err != nil {
-			return fmt.Errorf("update service: %w", err)
+			return nil, fmt.Errorf("parsing name source: %w", err)
 		}
+		opts = append(opts, operator.WithNameFrom(source))
+	}
+	if command := cliCtx.String("container-command"); command != "" {
+		opts = append(opts, operator.WithContainerCommand(command))
+	}
+	// Unless explicitly set, the replicas are only set on creation and left to whoever scales the deployment.
+	if cliCtx.IsSet("replicas") {
+		opts = append(opts, operator.WithReplicas(cliCtx.Uint("replicas")))
+	}
+
+	config, err := operator.NewConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	if config.Workload == operator.WorkloadDaemonSet && cliCtx.IsSet("replicas") {
+		log.Warning("Replicas are ignored when running as a daemonset")
+	}
+	if config.Workload == operator.WorkloadDaemonSet && cliCtx.IsSet("strategy") {
+		log.Warning("Strategy is ignored when running as a daemonset")
 	}
 
-	log.Info("Service created")
-	return nil
+	return config, nil
 }
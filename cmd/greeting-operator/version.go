@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"edb-challenge/pkg/operator"
+	"edb-challenge/pkg/version"
+	cli "github.com/urfave/cli/v2"
+	"k8s.io/client-go/discovery"
+)
+
+// serverVersionTimeout bounds the lookup of the cluster version, which is best effort.
+const serverVersionTimeout = 5 * time.Second
+
+// VersionInfo is the version of the operator build and of the cluster it talks to.
+type VersionInfo struct {
+	version.Info
+	// Server is the version of the Kubernetes API server, empty when it is not reachable.
+	Server string `json:"server,omitempty"`
+	// ServerError tells why the API server version is unknown.
+	ServerError string `json:"serverError,omitempty"`
+}
+
+// runVersion prints the build metadata and, when a cluster is reachable, the API server version.
+func runVersion(cliCtx *cli.Context) error {
+	output := cliCtx.String("output")
+	if output != operator.OutputText && output != operator.OutputJSON {
+		return fmt.Errorf("unknown output format %q", output)
+	}
+
+	conn, err := newClusterConnection(cliCtx)
+	if err != nil {
+		return err
+	}
+
+	info := VersionInfo{Info: version.Get()}
+	if server, err := serverVersion(conn); err != nil {
+		info.ServerError = err.Error()
+	} else {
+		info.Server = server
+	}
+
+	if output == operator.OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+	return info.writeText(os.Stdout)
+}
+
+// writeText writes the version as aligned lines.
+func (i VersionInfo) writeText(w io.Writer) error {
+	if err := i.Info.WriteText(w); err != nil {
+		return err
+	}
+
+	server := i.Server
+	if server == "" {
+		server = "unreachable (" + i.ServerError + ")"
+	}
+	_, err := fmt.Fprintf(w, "Server:     %s\n", server)
+	return err
+}
+
+// serverVersion returns the version of the Kubernetes API server with the discovery client.
+func serverVersion(conn operator.ClusterConnection) (string, error) {
+	cfg, err := operator.NewClusterConfig(conn)
+	if err != nil {
+		return "", err
+	}
+	cfg.Timeout = serverVersionTimeout
+
+	client, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return "", fmt.Errorf("discovery client: %w", err)
+	}
+
+	info, err := client.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("server version: %w", err)
+	}
+	return info.GitVersion, nil
+}
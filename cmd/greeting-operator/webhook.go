@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"edb-challenge/pkg/operator"
+	cli "github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
+)
+
+func runWebhookManifests(cliCtx *cli.Context) error {
+	var caBundle []byte
+	if file := cliCtx.String("ca-bundle-file"); file != "" {
+		var err error
+		if caBundle, err = os.ReadFile(file); err != nil {
+			return fmt.Errorf("read ca bundle: %w", err)
+		}
+	}
+
+	configuration := operator.GreetingWebhookConfiguration(
+		cliCtx.String("service-name"),
+		cliCtx.String("service-namespace"),
+		int32(cliCtx.Int("service-port")),
+		caBundle,
+	)
+
+	raw, err := yaml.Marshal(configuration)
+	if err != nil {
+		return fmt.Errorf("marshal webhook configuration: %w", err)
+	}
+
+	_, err = os.Stdout.Write(raw)
+	return err
+}
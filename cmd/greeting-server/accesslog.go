@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"edb-challenge/pkg/clientip"
+	"edb-challenge/pkg/greeting"
+	log "github.com/sirupsen/logrus"
+)
+
+// accessLog logs one entry per request served by the handler at the level, the health probes
+// excepted when quiet.
+func accessLog(handler http.Handler, level log.Level, quietHealth bool) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if quietHealth && (req.URL.Path == "/health" || req.URL.Path == "/ready") {
+			handler.ServeHTTP(rw, req)
+			return
+		}
+
+		start := time.Now()
+		recorder := newResponseRecorder(rw)
+		handler.ServeHTTP(recorder, req)
+
+		greeting.RequestLog(req).WithFields(log.Fields{
+			"method":     req.Method,
+			"path":       req.URL.Path,
+			"status":     recorder.status,
+			"bytes":      recorder.bytes,
+			"duration":   time.Since(start),
+			"remote_ip":  clientip.Of(req),
+			"user_agent": req.UserAgent(),
+		}).Log(level, "Request served")
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"edb-challenge/pkg/clientip"
+	"edb-challenge/pkg/greeting"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+// startup is the configuration loaded and the listeners bound by the startup checks, served as is.
+type startup struct {
+	settings       serverSettings
+	accessLogLevel log.Level
+	tlsConfig      *tls.Config
+	certs          *certReloader
+	resolver       *clientip.Resolver
+	auth           *greeting.Auth
+	faults         *faultInjector
+	counters       greeting.Counters
+
+	listener        net.Listener
+	grpcListener    net.Listener
+	metricsListener net.Listener
+	healthListener  net.Listener
+	debugListener   net.Listener
+}
+
+// checkStartup loads the whole configuration and binds the listeners before anything is served, reporting
+// every problem at once rather than the first one: the templates failing to parse, the files missing or
+// unreadable, the TLS certificates not matching their key, the addresses already in use.
+// The listeners are closed on failure.
+func checkStartup(cliCtx *cli.Context) (*startup, error) {
+	s := &startup{}
+	var errs []error
+	var err error
+	if s.settings, err = loadSettings(cliCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if s.accessLogLevel, err = log.ParseLevel(cliCtx.String("access-log-level")); err != nil {
+		errs = append(errs, fmt.Errorf("parse access log level: %w", err))
+	}
+
+	addr := cliCtx.String("bind")
+	tlsGiven := cliCtx.IsSet("tls-cert") || cliCtx.IsSet("tls-key")
+	if socketPath(addr) != "" && (tlsGiven || cliCtx.IsSet("tls-client-ca")) {
+		errs = append(errs, errors.New("TLS is not supported on a Unix domain socket, the proxy in front of it terminates TLS"))
+	}
+	if cliCtx.Bool("h2c") && tlsGiven {
+		errs = append(errs, errors.New("h2c is HTTP/2 without TLS, over TLS HTTP/2 is negotiated already"))
+	}
+	if s.tlsConfig, s.certs, err = newTLSConfig(cliCtx.String("tls-cert"), cliCtx.String("tls-key"), cliCtx.String("tls-client-ca")); err != nil {
+		errs = append(errs, err)
+	}
+
+	if trusted := cliCtx.StringSlice("trusted-proxies"); cliCtx.Bool("trust-proxy") || len(trusted) > 0 {
+		if s.resolver, err = clientip.NewResolver(cliCtx.String("proxy-header"), trusted); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.auth, err = greeting.NewAuth(cliCtx.String("auth-token"), cliCtx.String("auth-token-file"), cliCtx.String("basic-auth")); err != nil {
+		errs = append(errs, err)
+	}
+	if s.faults, err = newFaultInjector(cliCtx.Bool("enable-fault-injection"), cliCtx.String("admin-token")); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.counters, err = loadCounters(cliCtx.String("counter-file")); err != nil {
+		errs = append(errs, err)
+	}
+
+	socketMode, err := parseSocketMode(cliCtx.String("socket-mode"))
+	if err != nil {
+		errs = append(errs, err)
+	} else if s.listener, err = listen(addr, socketMode); err != nil {
+		errs = append(errs, bindError("bind", addr, err))
+	}
+	s.grpcListener = bindTCP(&errs, "grpc-bind", cliCtx.String("grpc-bind"))
+	s.metricsListener = bindTCP(&errs, "metrics-bind", cliCtx.String("metrics-bind"))
+	s.healthListener = bindTCP(&errs, "health-bind", cliCtx.String("health-bind"))
+	if cliCtx.Bool("enable-pprof") || cliCtx.Bool("enable-fault-injection") {
+		s.debugListener = bindTCP(&errs, "debug-bind", cliCtx.String("debug-bind"))
+	}
+
+	if len(errs) > 0 {
+		s.close()
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return s, nil
+}
+
+// close closes the listeners bound, removing the Unix domain socket file. Closing them once served is harmless.
+func (s *startup) close() {
+	for _, listener := range []net.Listener{s.listener, s.grpcListener, s.metricsListener, s.healthListener, s.debugListener} {
+		if listener != nil {
+			listener.Close()
+		}
+	}
+}
+
+// bindTCP binds the address of the flag, nil when no address is given.
+func bindTCP(errs *[]error, flag, addr string) net.Listener {
+	if addr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		*errs = append(*errs, bindError(flag, addr, err))
+	}
+	return listener
+}
+
+// bindError tells why the address of the flag cannot be bound, with the usual causes.
+func bindError(flag, addr string, err error) error {
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		return fmt.Errorf("--%s %s: already used by another process: %w", flag, addr, err)
+	case errors.Is(err, syscall.EACCES):
+		return fmt.Errorf("--%s %s: the ports below 1024 require root or the CAP_NET_BIND_SERVICE capability: %w", flag, addr, err)
+	}
+	return fmt.Errorf("--%s %s: %w", flag, addr, err)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	cli "github.com/urfave/cli/v2"
+)
+
+// clientCommand calls a greeting server, exiting with an error on the answers out of the 2xx range so that
+// it can assert a deployment in CI.
+func clientCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "client",
+		Usage: "Call a greeting server",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "url",
+				Usage:   "Base URL of the greeting server",
+				Value:   "http://localhost",
+				EnvVars: []string{"GREETING_URL"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Usage:   "Time given to every call, connection included",
+				Value:   10 * time.Second,
+				EnvVars: []string{"GREETING_TIMEOUT"},
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "Bearer token sent to the servers requiring one on /greet",
+				EnvVars: []string{"GREETING_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:    "ca-cert",
+				Usage:   "CA file the certificate of the server must be signed by, the system ones when empty",
+				EnvVars: []string{"GREETING_CA_CERT"},
+			},
+			&cli.StringFlag{
+				Name:    "client-cert",
+				Usage:   "Certificate file presented to the servers requiring mutual TLS",
+				EnvVars: []string{"GREETING_CLIENT_CERT"},
+			},
+			&cli.StringFlag{
+				Name:    "client-key",
+				Usage:   "Private key file of the client certificate",
+				EnvVars: []string{"GREETING_CLIENT_KEY"},
+			},
+			&cli.BoolFlag{
+				Name:    "insecure-skip-tls-verify",
+				Usage:   "Skip the verification of the server certificate",
+				EnvVars: []string{"GREETING_INSECURE_SKIP_TLS_VERIFY"},
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:  "greet",
+				Usage: "Print the greeting of the server",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "Name of the caller to greet first",
+					},
+					&cli.StringFlag{
+						Name:  "lang",
+						Usage: "Language of the greeting, the default language of the server when not translated",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the whole greeting as JSON",
+					},
+				},
+				Action: func(cliCtx *cli.Context) error {
+					client, err := newClient(cliCtx)
+					if err != nil {
+						return err
+					}
+					answer, err := client.Greet(cliCtx.Context, cliCtx.String("name"), cliCtx.String("lang"))
+					if err != nil {
+						return err
+					}
+					if cliCtx.Bool("json") {
+						return printJSON(answer)
+					}
+					fmt.Println(answer.Message)
+					return nil
+				},
+			},
+			{
+				Name:  "health",
+				Usage: "Print the state of the server told by its health probe",
+				Action: func(cliCtx *cli.Context) error {
+					client, err := newClient(cliCtx)
+					if err != nil {
+						return err
+					}
+					status, err := client.Health(cliCtx.Context)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("%s: %s, version %s, up %s, %d greetings served\n", status.Status, status.Name, status.Version, status.Uptime, status.GreetingsServed)
+					return nil
+				},
+			},
+			{
+				Name:  "version",
+				Usage: "Print the build of the server",
+				Action: func(cliCtx *cli.Context) error {
+					client, err := newClient(cliCtx)
+					if err != nil {
+						return err
+					}
+					info, err := client.Version(cliCtx.Context)
+					if err != nil {
+						return err
+					}
+					return info.WriteText(os.Stdout)
+				},
+			},
+		},
+	}
+}
+
+// newClient returns the client of the server given by the flags of the client command.
+func newClient(cliCtx *cli.Context) (*greeting.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: cliCtx.Bool("insecure-skip-tls-verify")}
+	if path := cliCtx.String("ca-cert"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(raw) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", path)
+		}
+	}
+
+	certFile, keyFile := cliCtx.String("client-cert"), cliCtx.String("client-key")
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client certificate and key must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	httpClient := &http.Client{Transport: transport, Timeout: cliCtx.Duration("timeout")}
+	return greeting.NewClient(cliCtx.String("url"), greeting.WithHTTPClient(httpClient), greeting.WithBearerToken(cliCtx.String("token"))), nil
+}
+
+// printJSON prints the value as indented JSON.
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
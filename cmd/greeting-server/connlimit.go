@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// limitListener accepts up to a number of connections at once, closing the ones beyond the limit right away
+// rather than leaving them queued until a connection is released.
+type limitListener struct {
+	net.Listener
+	slots chan struct{}
+}
+
+// newLimitListener limits the connections accepted by the listener at once, returned as is without limit.
+func newLimitListener(listener net.Listener, limit int) net.Listener {
+	if limit <= 0 {
+		return listener
+	}
+	return &limitListener{Listener: listener, slots: make(chan struct{}, limit)}
+}
+
+// Accept returns the next connection within the limit, closing the ones beyond it.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case l.slots <- struct{}{}:
+			return &limitedConn{Conn: conn, release: func() { <-l.slots }}, nil
+		default:
+			rejectedConnections.Inc()
+			log.WithField("remote_addr", conn.RemoteAddr().String()).Debug("Connection limit reached, closing the connection")
+			conn.Close()
+		}
+	}
+}
+
+// limitedConn gives its slot back to the listener once closed.
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLimitListenerUnlimited(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if limited := newLimitListener(listener, 0); limited != listener {
+		t.Error("listener wrapped without limit")
+	}
+}
+
+func TestLimitListenerUnderLoad(t *testing.T) {
+	const limit, clients = 3, 20
+
+	var inFlight, peak atomic.Int64
+	started, release := make(chan struct{}, clients), make(chan struct{})
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		started <- struct{}{}
+		<-release
+	})
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := &http.Server{Handler: handler}
+	served := make(chan error, 1)
+	go func() { served <- httpServer.Serve(newLimitListener(tcpListener, limit)) }()
+	defer func() {
+		httpServer.Close()
+		<-served
+	}()
+	addr := tcpListener.Addr().String()
+	rejectedBefore := testutil.ToFloat64(rejectedConnections)
+
+	// Every client connects and sends its request at once, the ones beyond the limit are closed unanswered.
+	type result struct {
+		conn   net.Conn
+		status int
+		err    error
+	}
+	results := make(chan result, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			conn.SetDeadline(time.Now().Add(10 * time.Second))
+			if _, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: greeting\r\n\r\n"); err != nil {
+				results <- result{conn: conn, err: err}
+				return
+			}
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				results <- result{conn: conn, err: err}
+				return
+			}
+			resp.Body.Close()
+			results <- result{conn: conn, status: resp.StatusCode}
+		}()
+	}
+
+	for i := 0; i < limit; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d requests served, want %d", i, limit)
+		}
+	}
+	// The rejected clients are told right away, while the accepted ones still hold their slot.
+	rejected := 0
+	for rejected < clients-limit {
+		select {
+		case r := <-results:
+			// The closed connection fails the read with EOF, or with a reset when the request is still unread.
+			if r.err == nil {
+				t.Fatalf("request answered %d while the handlers hold every slot", r.status)
+			}
+			if r.conn != nil {
+				r.conn.Close()
+			}
+			rejected++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d connections rejected, want %d", rejected, clients-limit)
+		}
+	}
+	if n := peak.Load(); n != limit {
+		t.Errorf("peak of %d requests served at once, want the limit of %d", n, limit)
+	}
+	if n := testutil.ToFloat64(rejectedConnections) - rejectedBefore; n != clients-limit {
+		t.Errorf("%v connections counted as rejected, want %d", n, clients-limit)
+	}
+
+	close(release)
+	wg.Wait()
+	close(results)
+	for r := range results {
+		if r.err != nil || r.status != http.StatusOK {
+			t.Errorf("accepted request = %d, %v, want %d", r.status, r.err, http.StatusOK)
+		}
+		r.conn.Close()
+	}
+
+	// Once closed, the connections give their slot back to the next clients.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: time.Second}
+	deadline := time.Now().Add(5 * time.Second)
+	for served := 0; served <= limit; {
+		resp, err := client.Get("http://" + addr)
+		if err == nil {
+			resp.Body.Close()
+			served++
+			continue
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("slots not released: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"edb-challenge/pkg/reqid"
+)
+
+// Headers of the answers to the CORS preflight requests.
+const (
+	corsAllowMethods = "GET, PUT, POST, OPTIONS"
+	corsAllowHeaders = "Accept, Accept-Language, Authorization, Content-Type, " + reqid.Header
+	corsMaxAge       = 10 * 60
+)
+
+// corsHandler lets the browsers call the handler from the allowed origins, "*" allowing all of them.
+// The handler is returned as is without allowed origin, sending no CORS header.
+func corsHandler(handler http.Handler, allowedOrigins []string) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return handler
+	}
+
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		header := rw.Header()
+		if !allowed["*"] {
+			header.Add("Vary", "Origin")
+		}
+
+		matched := origin != "" && (allowed["*"] || allowed[origin])
+		if matched {
+			if allowed["*"] {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			header.Set("Access-Control-Expose-Headers", reqid.Header)
+		}
+
+		// Preflight requests are answered here, whether the origin is allowed or not.
+		if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+			if matched {
+				header.Set("Access-Control-Allow-Methods", corsAllowMethods)
+				header.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				header.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			}
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		handler.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"edb-challenge/pkg/reqid"
+)
+
+func TestCORSHandler(t *testing.T) {
+	served := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name       string
+		allowed    []string
+		method     string
+		origin     string
+		preflight  bool
+		wantCode   int
+		wantOrigin string
+		wantVary   bool
+	}{
+		{name: "matching origin", allowed: []string{"https://app.example.com"}, origin: "https://app.example.com", wantCode: http.StatusTeapot, wantOrigin: "https://app.example.com", wantVary: true},
+		{name: "trailing slash allowed", allowed: []string{"https://app.example.com/"}, origin: "https://app.example.com", wantCode: http.StatusTeapot, wantOrigin: "https://app.example.com", wantVary: true},
+		{name: "other origin", allowed: []string{"https://app.example.com"}, origin: "https://evil.example.com", wantCode: http.StatusTeapot, wantVary: true},
+		{name: "no origin", allowed: []string{"https://app.example.com"}, wantCode: http.StatusTeapot, wantVary: true},
+		{name: "wildcard", allowed: []string{"*"}, origin: "https://any.example.com", wantCode: http.StatusTeapot, wantOrigin: "*"},
+		{name: "disabled", origin: "https://app.example.com", wantCode: http.StatusTeapot},
+		{name: "preflight", allowed: []string{"https://app.example.com"}, method: http.MethodOptions, origin: "https://app.example.com", preflight: true, wantCode: http.StatusNoContent, wantOrigin: "https://app.example.com", wantVary: true},
+		{name: "preflight of other origin", allowed: []string{"https://app.example.com"}, method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, wantCode: http.StatusNoContent, wantVary: true},
+		{name: "preflight wildcard", allowed: []string{"*"}, method: http.MethodOptions, origin: "https://any.example.com", preflight: true, wantCode: http.StatusNoContent, wantOrigin: "*"},
+		// An OPTIONS request without Access-Control-Request-Method is no preflight, it reaches the handler.
+		{name: "plain options", allowed: []string{"*"}, method: http.MethodOptions, origin: "https://any.example.com", wantCode: http.StatusTeapot, wantOrigin: "*"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/greet", nil)
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			if test.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+			rw := httptest.NewRecorder()
+			corsHandler(served, test.allowed).ServeHTTP(rw, req)
+
+			header := rw.Header()
+			if rw.Code != test.wantCode {
+				t.Errorf("status = %d, want %d", rw.Code, test.wantCode)
+			}
+			if origin := header.Get("Access-Control-Allow-Origin"); origin != test.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", origin, test.wantOrigin)
+			}
+			if vary := header.Get("Vary") == "Origin"; vary != test.wantVary {
+				t.Errorf("Vary: Origin = %t, want %t", vary, test.wantVary)
+			}
+
+			wantExpose, wantMethods, wantHeaders, wantMaxAge := "", "", "", ""
+			if test.wantOrigin != "" {
+				wantExpose = reqid.Header
+				if test.preflight {
+					wantMethods, wantHeaders, wantMaxAge = corsAllowMethods, corsAllowHeaders, strconv.Itoa(corsMaxAge)
+				}
+			}
+			for name, want := range map[string]string{
+				"Access-Control-Expose-Headers": wantExpose,
+				"Access-Control-Allow-Methods":  wantMethods,
+				"Access-Control-Allow-Headers":  wantHeaders,
+				"Access-Control-Max-Age":        wantMaxAge,
+			} {
+				if got := header.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
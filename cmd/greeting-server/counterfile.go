@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	log "github.com/sirupsen/logrus"
+)
+
+// loadCounters reads the counters persisted by a previous run, none without file yet. A corrupt file, such as
+// one cut short by a crash, is left for the next write to replace: the server starts from zero rather than
+// not at all.
+func loadCounters(path string) (greeting.Counters, error) {
+	var counters greeting.Counters
+	if path == "" {
+		return counters, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.WithField("path", path).Info("No counter file yet, starting from zero")
+		return counters, nil
+	}
+	if err != nil {
+		return counters, fmt.Errorf("read counter file: %w", err)
+	}
+	if err := json.Unmarshal(content, &counters); err != nil {
+		log.WithField("path", path).WithError(err).Warning("Corrupt counter file, starting from zero")
+		return greeting.Counters{}, nil
+	}
+	return counters, nil
+}
+
+// saveCounters writes the counters to a temporary file of the same directory renamed over the counter file,
+// so that a crash midway leaves the previous counters whole.
+func saveCounters(path string, counters greeting.Counters) error {
+	content, err := json.Marshal(counters)
+	if err != nil {
+		return fmt.Errorf("encode counters: %w", err)
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create counter file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write counter file: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("replace counter file: %w", err)
+	}
+	return nil
+}
+
+// counterSync persists the counters of the server, only when they changed since the last write.
+type counterSync struct {
+	path   string
+	server *greeting.Server
+	saved  greeting.Counters
+}
+
+func newCounterSync(path string, server *greeting.Server) *counterSync {
+	return &counterSync{path: path, server: server, saved: server.Counters()}
+}
+
+// save writes the counters when they changed.
+func (c *counterSync) save() error {
+	counters := c.server.Counters()
+	if reflect.DeepEqual(counters, c.saved) {
+		return nil
+	}
+	if err := saveCounters(c.path, counters); err != nil {
+		return err
+	}
+	c.saved = counters
+	return nil
+}
+
+// run writes the counters every interval until the context is done, the last write being left to the
+// shutdown. The writes are throttled so that serving the greetings never waits on the disk.
+func (c *counterSync) run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.save(); err != nil {
+				log.WithField("path", c.path).WithError(err).Warning("Unable to persist the counters")
+			}
+		}
+	}
+}
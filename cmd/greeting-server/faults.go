@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	log "github.com/sirupsen/logrus"
+)
+
+// faultInjector makes the server misbehave on demand, to test the probes and the rollouts against it.
+type faultInjector struct {
+	failHealth atomic.Bool
+	latency    atomic.Int64
+}
+
+// newFaultInjector returns the fault injector when enabled, which requires the admin token, nil otherwise.
+func newFaultInjector(enabled bool, adminToken string) (*faultInjector, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if adminToken == "" {
+		return nil, errors.New("fault injection requires an admin token")
+	}
+	return &faultInjector{}, nil
+}
+
+// withFaults wraps the handler with the fault injector, when any.
+func withFaults(f *faultInjector, handler http.Handler) http.Handler {
+	if f == nil {
+		return handler
+	}
+	return f.handler(handler)
+}
+
+// register serves the fault injection endpoints under /admin, to the requests bearing the token only.
+func (f *faultInjector) register(mux router, token string) {
+	mux.Handle("/admin/fail-health", greeting.RequireToken(token, postOnly(f.handleFailHealth)))
+	mux.Handle("/admin/recover", greeting.RequireToken(token, postOnly(f.handleRecover)))
+	mux.Handle("/admin/crash", greeting.RequireToken(token, postOnly(f.handleCrash)))
+	mux.Handle("/admin/latency", greeting.RequireToken(token, postOnly(f.handleLatency)))
+}
+
+// handler fails the probes while the health is failed, and delays the greetings by the injected latency.
+func (f *faultInjector) handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if f.failHealth.Load() && (req.URL.Path == "/health" || req.URL.Path == "/ready") {
+			greeting.Error(rw, req, http.StatusServiceUnavailable, "health failure injected")
+			return
+		}
+
+		if latency := time.Duration(f.latency.Load()); latency > 0 && isGreetPath(req.URL.Path) {
+			select {
+			case <-time.After(latency):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
+
+// handleFailHealth makes the health and readiness probes fail until recovered.
+func (f *faultInjector) handleFailHealth(rw http.ResponseWriter, req *http.Request) {
+	f.failHealth.Store(true)
+	greeting.RequestLog(req).Warning("Failing the health and readiness probes")
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// handleRecover clears the injected faults, the health failure and the latency.
+func (f *faultInjector) handleRecover(rw http.ResponseWriter, req *http.Request) {
+	f.failHealth.Store(false)
+	f.latency.Store(0)
+	greeting.RequestLog(req).Warning("Recovered from the injected faults")
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// handleCrash exits the process with the code query parameter, 1 by default, after the delay query
+// parameter when given.
+func (f *faultInjector) handleCrash(rw http.ResponseWriter, req *http.Request) {
+	code := 1
+	if value := req.URL.Query().Get("code"); value != "" {
+		var err error
+		if code, err = strconv.Atoi(value); err != nil || code < 0 || code > 125 {
+			greeting.Error(rw, req, http.StatusBadRequest, "code must be an integer between 0 and 125")
+			return
+		}
+	}
+
+	var delay time.Duration
+	if value := req.URL.Query().Get("delay"); value != "" {
+		var err error
+		if delay, err = time.ParseDuration(value); err != nil || delay < 0 {
+			greeting.Error(rw, req, http.StatusBadRequest, "delay must be a positive duration, such as 5s")
+			return
+		}
+	}
+
+	greeting.RequestLog(req).WithField("code", code).WithField("delay", delay).Warning("Crashing on demand")
+	rw.WriteHeader(http.StatusAccepted)
+	go func() {
+		time.Sleep(delay)
+		log.WithField("code", code).Warning("Crashed on demand")
+		os.Exit(code)
+	}()
+}
+
+// handleLatency delays every greeting by the ms query parameter, in milliseconds, 0 removing the latency.
+func (f *faultInjector) handleLatency(rw http.ResponseWriter, req *http.Request) {
+	ms, err := strconv.Atoi(req.URL.Query().Get("ms"))
+	if err != nil || ms < 0 {
+		greeting.Error(rw, req, http.StatusBadRequest, "ms must be a positive number of milliseconds")
+		return
+	}
+
+	f.latency.Store(int64(time.Duration(ms) * time.Millisecond))
+	greeting.RequestLog(req).WithField("latency", time.Duration(ms)*time.Millisecond).Warning("Injecting latency into the greetings")
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// postOnly serves the handler to the POST requests only.
+func postOnly(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			greeting.MethodNotAllowed(rw, req, http.MethodPost)
+			return
+		}
+		handler(rw, req)
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	"edb-challenge/pkg/greetingpb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcGreeter serves the greetings of the server over gRPC.
+type grpcGreeter struct {
+	greetingpb.UnimplementedGreeterServer
+	server *greeting.Server
+}
+
+// Greet answers the greeting of the server, in the language preferred by the accept-language metadata.
+func (g *grpcGreeter) Greet(ctx context.Context, req *greetingpb.GreetRequest) (*greetingpb.GreetResponse, error) {
+	if err := greeting.ValidateName(req.GetName()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var acceptLanguage string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		acceptLanguage = strings.Join(md.Get("accept-language"), ",")
+	}
+
+	greeted, _, err := g.server.Greet(req.GetName(), "", acceptLanguage)
+	if err != nil {
+		log.WithError(err).Warning("Unable to render greeting")
+		return nil, status.Error(codes.Internal, "unable to render greeting")
+	}
+	return &greetingpb.GreetResponse{Message: greeted.Message, ServerName: greeted.Name, Hostname: greeted.Hostname}, nil
+}
+
+// requireAuthRPC serves the greetings only to the calls bearing the credentials in their authorization
+// metadata, as the HTTP /greet endpoint. The health service stays open.
+func requireAuthRPC(auth *greeting.Auth) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if auth == nil || !strings.HasPrefix(info.FullMethod, "/greeting.v1.Greeter/") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		if !auth.Allows(&http.Request{Header: http.Header{"Authorization": md.Get("authorization")}}) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or missing credentials")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// newGRPCServer returns the gRPC server of the greetings and of the standard health service, served
+// over TLS with the TLS configuration when given.
+func newGRPCServer(server *greeting.Server, auth *greeting.Auth, tlsConfig *tls.Config) (*grpc.Server, *health.Server) {
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(requireAuthRPC(auth))}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+	greetingpb.RegisterGreeterServer(grpcServer, &grpcGreeter{server: server})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(greetingpb.Greeter_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	return grpcServer, healthServer
+}
+
+// serveGRPC runs the gRPC server on the listener until the context is done, then reports it as not serving
+// and keeps serving for the delay, as the HTTP server does, before stopping it gracefully within the timeout.
+// The remaining calls are cancelled once the timeout expires.
+func serveGRPC(ctx context.Context, server *grpc.Server, healthServer *health.Server, listener net.Listener, shutdownDelay, shutdownTimeout time.Duration) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	healthServer.Shutdown()
+	if shutdownDelay > 0 {
+		select {
+		case err := <-served:
+			return err
+		case <-time.After(shutdownDelay):
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		server.Stop()
+		return errors.New("drain in-flight gRPC calls: timeout expired")
+	}
+
+	log.Info("gRPC server stopped")
+	return <-served
+}
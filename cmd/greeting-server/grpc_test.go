@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	"edb-challenge/pkg/greetingpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGRPC serves the gRPC server of the greetings on an in-memory listener until the returned cancel
+// is called, and returns a connection to it and the result of serveGRPC.
+func startGRPC(t *testing.T, auth *greeting.Auth, shutdownDelay time.Duration) (*grpc.ClientConn, context.CancelFunc, <-chan error) {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer, healthServer := newGRPCServer(greeting.New(greeting.WithName("Greeter"), greeting.WithHostname("pod-1")), auth, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served, stopped := make(chan error, 1), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		served <- serveGRPC(ctx, grpcServer, healthServer, listener, shutdownDelay, 5*time.Second)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, cancel, served
+}
+
+func TestGRPCGreet(t *testing.T) {
+	conn, _, _ := startGRPC(t, nil, 0)
+	client := greetingpb.NewGreeterClient(conn)
+
+	tests := []struct {
+		name           string
+		caller         string
+		acceptLanguage string
+		wantCode       codes.Code
+		wantMessage    string
+	}{
+		{name: "default language", wantMessage: "I am Greeter"},
+		{name: "caller", caller: "Ada", wantMessage: "Hello Ada, I am Greeter"},
+		{name: "accept-language", acceptLanguage: "fr-CH, fr;q=0.9", wantMessage: "Je suis Greeter"},
+		{name: "invalid name", caller: "Ada\nLovelace", wantCode: codes.InvalidArgument},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.acceptLanguage != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "accept-language", test.acceptLanguage)
+			}
+			resp, err := client.Greet(ctx, &greetingpb.GreetRequest{Name: test.caller})
+			if code := status.Code(err); code != test.wantCode {
+				t.Fatalf("Greet() error = %v, want code %s", err, test.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if resp.GetMessage() != test.wantMessage || resp.GetServerName() != "Greeter" || resp.GetHostname() != "pod-1" {
+				t.Errorf("Greet() = %+v, want %q from Greeter on pod-1", resp, test.wantMessage)
+			}
+		})
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	auth, err := greeting.NewAuth("secret", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, _ := startGRPC(t, auth, 0)
+	client := greetingpb.NewGreeterClient(conn)
+
+	for authorization, want := range map[string]codes.Code{
+		"":              codes.Unauthenticated,
+		"Bearer wrong":  codes.Unauthenticated,
+		"Bearer secret": codes.OK,
+	} {
+		ctx := context.Background()
+		if authorization != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+		}
+		if _, err := client.Greet(ctx, &greetingpb.GreetRequest{}); status.Code(err) != want {
+			t.Errorf("Greet() with authorization %q = %v, want code %s", authorization, err, want)
+		}
+	}
+
+	// The health service stays open to the probes without credentials.
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health check = %v, %v, want serving", resp, err)
+	}
+}
+
+func TestServeGRPCShutdown(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	conn, cancel, served := startGRPC(t, nil, delay)
+	health := healthpb.NewHealthClient(conn)
+	request := &healthpb.HealthCheckRequest{Service: greetingpb.Greeter_ServiceDesc.ServiceName}
+
+	if resp, err := health.Check(context.Background(), request); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health check = %v, %v, want serving", resp, err)
+	}
+
+	cancel()
+	// During the delay the server reports not serving, yet still answers the calls.
+	deadline := time.Now().Add(delay / 2)
+	for {
+		resp, err := health.Check(context.Background(), request)
+		if err != nil {
+			t.Fatalf("health check during the delay: %v", err)
+		}
+		if resp.GetStatus() == healthpb.HealthCheckResponse_NOT_SERVING {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health check = %s, want not serving once shutting down", resp.GetStatus())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := greetingpb.NewGreeterClient(conn).Greet(context.Background(), &greetingpb.GreetRequest{}); err != nil {
+		t.Errorf("Greet() during the delay: %v", err)
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serveGRPC() = %v, want nil once stopped", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("gRPC server not stopped")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"edb-challenge/pkg/greeting"
+)
+
+// compressedTypes are the content types, or their prefix, of the bodies already compressed.
+var compressedTypes = []string{
+	"image/", "video/", "audio/",
+	"application/gzip", "application/zip", "application/zstd", "application/x-bzip2", "application/x-xz",
+	"application/octet-stream",
+}
+
+// gzipWriters reuses the compressors, each allocating about a megabyte of state.
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+
+// acceptsGzip tells whether the Accept-Encoding header accepts gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(value, 64); err != nil || q == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponse compresses the body once it reaches the minimum size, and writes it as is otherwise.
+// The headers are written once the choice is made.
+type gzipResponse struct {
+	http.ResponseWriter
+	minSize int
+
+	status   int
+	buf      bytes.Buffer
+	decided  bool
+	gz       *gzip.Writer
+	hijacked bool
+}
+
+func (r *gzipResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *gzipResponse) Write(body []byte) (int, error) {
+	if r.decided {
+		if r.gz != nil {
+			return r.gz.Write(body)
+		}
+		return r.ResponseWriter.Write(body)
+	}
+
+	r.buf.Write(body)
+	if r.buf.Len() >= r.minSize {
+		if err := r.decide(r.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(body), nil
+}
+
+// compressible tells whether the response may be compressed, given its status and headers.
+func (r *gzipResponse) compressible() bool {
+	if r.status != 0 && r.status != http.StatusOK {
+		return false
+	}
+	header := r.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(r.buf.Bytes())
+	}
+	for _, compressed := range compressedTypes {
+		if strings.HasPrefix(contentType, compressed) {
+			return false
+		}
+	}
+	return true
+}
+
+// decide writes the headers and the buffered body, compressed or not.
+func (r *gzipResponse) decide(compress bool) error {
+	r.decided = true
+	if compress {
+		r.Header().Set("Content-Encoding", "gzip")
+		r.Header().Del("Content-Length")
+		// The compressed body differs from the one the strong entity tag was computed from.
+		if etag := r.Header().Get("ETag"); strings.HasPrefix(etag, `"`) {
+			r.Header().Set("ETag", "W/"+etag)
+		}
+		r.gz = gzipWriters.Get().(*gzip.Writer)
+		r.gz.Reset(r.ResponseWriter)
+	}
+	if r.status != 0 {
+		r.ResponseWriter.WriteHeader(r.status)
+	}
+	if r.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if r.gz != nil {
+		_, err = r.gz.Write(r.buf.Bytes())
+	} else {
+		_, err = r.ResponseWriter.Write(r.buf.Bytes())
+	}
+	r.buf.Reset()
+	return err
+}
+
+// Flush sends the body written so far, compressed or not as it would be once complete, so that the
+// streamed responses are not held until the minimum size.
+func (r *gzipResponse) Flush() {
+	if !r.decided {
+		if err := r.decide(r.compressible()); err != nil {
+			return
+		}
+	}
+	if r.gz != nil && r.gz.Flush() != nil {
+		return
+	}
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped writer, to which http.ResponseController hands the deadlines.
+func (r *gzipResponse) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Hijack hands the connection over to the handler, such as a WebSocket, nothing being written once done.
+func (r *gzipResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// close writes the body smaller than the minimum size as is, and ends the compressed body.
+func (r *gzipResponse) close() error {
+	if r.hijacked {
+		return nil
+	}
+	if !r.decided {
+		if err := r.decide(false); err != nil {
+			return err
+		}
+	}
+	if r.gz != nil {
+		err := r.gz.Close()
+		gzipWriters.Put(r.gz)
+		return err
+	}
+	return nil
+}
+
+// gzipHandler compresses the responses of the handler of at least minSize bytes for the clients
+// accepting gzip, except the ones already compressed.
+func gzipHandler(handler http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Add("Vary", "Accept-Encoding")
+		if req.Method == http.MethodHead || !acceptsGzip(req.Header.Get("Accept-Encoding")) {
+			handler.ServeHTTP(rw, req)
+			return
+		}
+
+		response := &gzipResponse{ResponseWriter: rw, minSize: minSize}
+		handler.ServeHTTP(response, req)
+		if err := response.close(); err != nil {
+			greeting.RequestLog(req).WithError(err).Debug("Unable to write compressed response")
+		}
+	})
+}
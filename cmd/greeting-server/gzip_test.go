@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.8", true},
+		{"br;q=1.0, gzip;q=0.5, *;q=0.1", true},
+		{"*", true},
+		{"gzip;q=0", false},
+		{"gzip;q=0, *;q=0.5", true},
+		{"deflate, br", false},
+		{"gzip;q=invalid", false},
+	}
+
+	for _, test := range tests {
+		if got := acceptsGzip(test.header); got != test.want {
+			t.Errorf("acceptsGzip(%q) = %t, want %t", test.header, got, test.want)
+		}
+	}
+}
+
+// bodyHandler answers the body with the headers and the status.
+func bodyHandler(status int, header http.Header, body []byte) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		for key, values := range header {
+			rw.Header()[key] = values
+		}
+		rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		rw.WriteHeader(status)
+		if req.Method != http.MethodHead {
+			rw.Write(body)
+		}
+	})
+}
+
+// decodedBody returns the body of the response, decompressed when gzipped.
+func decodedBody(t *testing.T, rw *httptest.ResponseRecorder) []byte {
+	t.Helper()
+	if rw.Header().Get("Content-Encoding") != "gzip" {
+		return rw.Body.Bytes()
+	}
+	gz, err := gzip.NewReader(rw.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	return body
+}
+
+func TestGzipHandler(t *testing.T) {
+	const minSize = 64
+	large := []byte(strings.Repeat("Hello, I'm the greeting server. ", 8))
+	small := large[:minSize-1]
+	text := http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		status         int
+		header         http.Header
+		body           []byte
+		wantGzip       bool
+		wantETag       string
+	}{
+		{name: "compressed", body: large, header: text, wantGzip: true},
+		{name: "at the minimum size", body: large[:minSize], header: text, wantGzip: true},
+		{name: "below the minimum size", body: small, header: text},
+		{name: "not accepted", acceptEncoding: "identity", body: large, header: text},
+		{name: "refused", acceptEncoding: "gzip;q=0", body: large, header: text},
+		{name: "head", method: http.MethodHead, body: large, header: text},
+		{name: "error status", status: http.StatusInternalServerError, body: large, header: text},
+		{name: "image", body: large, header: http.Header{"Content-Type": {"image/png"}}},
+		{name: "archive", body: large, header: http.Header{"Content-Type": {"application/zip"}}},
+		{name: "already encoded", body: large, header: http.Header{"Content-Type": {"text/plain"}, "Content-Encoding": {"br"}}},
+		{name: "sniffed text", body: large, wantGzip: true},
+		{name: "sniffed binary", body: append([]byte("\x89PNG\r\n\x1a\n"), large...)},
+		{
+			name:     "strong entity tag weakened",
+			body:     large,
+			header:   http.Header{"Content-Type": {"text/plain"}, "Etag": {`"v1"`}},
+			wantGzip: true,
+			wantETag: `W/"v1"`,
+		},
+		{
+			name:     "weak entity tag kept",
+			body:     large,
+			header:   http.Header{"Content-Type": {"text/plain"}, "Etag": {`W/"v1"`}},
+			wantGzip: true,
+			wantETag: `W/"v1"`,
+		},
+		{
+			name:     "uncompressed entity tag kept",
+			body:     small,
+			header:   http.Header{"Content-Type": {"text/plain"}, "Etag": {`"v1"`}},
+			wantETag: `"v1"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method, status, acceptEncoding := test.method, test.status, test.acceptEncoding
+			if method == "" {
+				method = http.MethodGet
+			}
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if acceptEncoding == "" {
+				acceptEncoding = "gzip, deflate"
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+			rw := httptest.NewRecorder()
+			gzipHandler(bodyHandler(status, test.header, test.body), minSize).ServeHTTP(rw, req)
+
+			if rw.Code != status {
+				t.Errorf("status = %d, want %d", rw.Code, status)
+			}
+			if gzipped := rw.Header().Get("Content-Encoding") == "gzip"; gzipped != test.wantGzip {
+				t.Errorf("gzipped = %t, want %t", gzipped, test.wantGzip)
+			}
+			if test.wantGzip && rw.Header().Get("Content-Length") != "" {
+				t.Errorf("compressed response has the uncompressed Content-Length %s", rw.Header().Get("Content-Length"))
+			}
+			if etag := rw.Header().Get("ETag"); etag != test.wantETag {
+				t.Errorf("ETag = %q, want %q", etag, test.wantETag)
+			}
+			if vary := rw.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", vary)
+			}
+
+			wantBody := test.body
+			if method == http.MethodHead {
+				wantBody = nil
+			}
+			if body := decodedBody(t, rw); !bytes.Equal(body, wantBody) {
+				t.Errorf("body = %q, want %q", body, wantBody)
+			}
+		})
+	}
+}
+
+func TestGzipHandlerFlush(t *testing.T) {
+	flushed := make(chan []byte, 1)
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(rw, "data: hello\n\n")
+		http.NewResponseController(rw).Flush()
+		flushed <- append([]byte(nil), rw.(*gzipResponse).ResponseWriter.(*httptest.ResponseRecorder).Body.Bytes()...)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/greet/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+	gzipHandler(handler, 1024).ServeHTTP(rw, req)
+
+	// The event smaller than the minimum size is sent on the flush, compressed as the rest of the stream.
+	if sent := <-flushed; len(sent) == 0 {
+		t.Error("nothing sent on the flush")
+	}
+	if !rw.Flushed || rw.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("flushed %t, Content-Encoding %q, want the stream flushed compressed", rw.Flushed, rw.Header().Get("Content-Encoding"))
+	}
+	if body := decodedBody(t, rw); string(body) != "data: hello\n\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func BenchmarkGzipHandler(b *testing.B) {
+	for _, size := range []int{512, 4 << 10, 64 << 10} {
+		body := []byte(strings.Repeat("Hello, I'm the greeting server. ", size/32))
+		handler := gzipHandler(bodyHandler(http.StatusOK, http.Header{"Content-Type": {"text/plain"}}, body), 1024)
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2cDrainPoll is the interval the HTTP/2 requests in flight are polled at while draining, as done by
+// the HTTP server for its own connections.
+const h2cDrainPoll = 100 * time.Millisecond
+
+// h2cRequests counts the requests in flight over HTTP/2 cleartext. Their connections are hijacked from the
+// HTTP server, which does not wait for them when shutting down.
+type h2cRequests struct {
+	active atomic.Int64
+}
+
+// h2cHandler serves HTTP/2 without TLS along with HTTP/1.1, by prior knowledge or upgrade, as the proxies
+// of a service mesh speak to their backends. The HTTP/2 connections are sent GOAWAY when the HTTP server
+// shuts down, and their requests are counted for the drain to wait for them.
+func h2cHandler(server *http.Server, handler http.Handler) (http.Handler, *h2cRequests, error) {
+	h2Server := &http2.Server{IdleTimeout: server.IdleTimeout}
+	// The configuration prepares HTTP/2 over TLS as well, which the server does not serve along with h2c.
+	tlsConfig := server.TLSConfig
+	if err := http2.ConfigureServer(server, h2Server); err != nil {
+		return nil, nil, fmt.Errorf("configure HTTP/2: %w", err)
+	}
+	server.TLSConfig = tlsConfig
+
+	requests := &h2cRequests{}
+	counted := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.ProtoMajor == 2 {
+			requests.active.Add(1)
+			defer requests.active.Add(-1)
+		}
+		handler.ServeHTTP(rw, req)
+	})
+	return h2c.NewHandler(counted, h2Server), requests, nil
+}
+
+// wait returns once the requests in flight complete, or the context is done. It does nothing without h2c.
+func (r *h2cRequests) wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	ticker := time.NewTicker(h2cDrainPoll)
+	defer ticker.Stop()
+	for r.active.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d HTTP/2 requests still in flight: %w", r.active.Load(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
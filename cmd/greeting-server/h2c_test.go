@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	"golang.org/x/net/http2"
+)
+
+// h2cClient speaks HTTP/2 without TLS by prior knowledge, as the proxies of a service mesh do.
+var h2cClient = &http.Client{Transport: &http2.Transport{
+	AllowHTTP: true,
+	DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, addr)
+	},
+}}
+
+// startH2C serves the handler with h2c and serve on an httptest listener, returning its URL and the result
+// of serve.
+func startH2C(t *testing.T, ctx context.Context, handler http.Handler, shutdownTimeout time.Duration) (string, <-chan error) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(nil)
+	t.Cleanup(func() { server.Config.Close() })
+	h2cServed, inFlight, err := h2cHandler(server.Config, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = h2cServed
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, server.Config, inFlight, server.Listener, &greeting.Readiness{}, 0, shutdownTimeout)
+	}()
+	return "http://" + server.Listener.Addr().String(), served
+}
+
+// protoHandler answers the protocol the request was received with.
+var protoHandler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+	io.WriteString(rw, req.Proto)
+})
+
+func TestH2CHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, _ := startH2C(t, ctx, protoHandler, 5*time.Second)
+
+	for _, test := range []struct {
+		name   string
+		client *http.Client
+		want   string
+	}{
+		{"prior knowledge", h2cClient, "HTTP/2.0"},
+		{"HTTP/1.1", drainClient, "HTTP/1.1"},
+	} {
+		resp, err := test.client.Get(url)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != test.want || resp.Proto != test.want {
+			t.Errorf("%s: served over %q, answered over %s, %v, want %s", test.name, body, resp.Proto, err, test.want)
+		}
+	}
+}
+
+func TestH2CDrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, served := startH2C(t, ctx, slowHandler(started, release), 5*time.Second)
+
+	type result struct {
+		body  string
+		proto string
+		err   error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := h2cClient.Get(url)
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{string(body), resp.Proto, err}
+	}()
+	<-started
+
+	// The HTTP/2 connection is hijacked from the HTTP server, yet serve waits for its request.
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("serve() = %v with an HTTP/2 request in flight", err)
+	case <-time.After(3 * h2cDrainPoll):
+	}
+
+	close(release)
+	if r := <-slow; r.err != nil || r.body != "done" || r.proto != "HTTP/2.0" {
+		t.Errorf("request in flight = %q over %s, %v, want it completed over HTTP/2", r.body, r.proto, r.err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve() = %v, want nil once drained", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve() did not return once drained")
+	}
+}
+
+func TestH2CDrainTimeout(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, served := startH2C(t, ctx, slowHandler(started, release), 200*time.Millisecond)
+
+	go func() {
+		if resp, err := h2cClient.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	cancel()
+	select {
+	case err := <-served:
+		if err == nil || !strings.Contains(err.Error(), "1 HTTP/2 requests still in flight") {
+			t.Errorf("serve() = %v, want the HTTP/2 request told in flight", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve() did not return once the drain timed out")
+	}
+}
+
+func TestH2CRequestsWaitWithoutH2C(t *testing.T) {
+	var requests *h2cRequests
+	if err := requests.wait(context.Background()); err != nil {
+		t.Errorf("wait() without h2c = %v", err)
+	}
+}
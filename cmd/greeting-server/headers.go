@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// secureHeaders are the security headers sent with --secure-headers. The landing page holds its script
+// and style, hence the inline sources allowed by the policy.
+var secureHeaders = http.Header{
+	"X-Content-Type-Options":  {"nosniff"},
+	"X-Frame-Options":         {"DENY"},
+	"Referrer-Policy":         {"no-referrer"},
+	"Content-Security-Policy": {"default-src 'none'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; connect-src 'self'; img-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"},
+}
+
+// hstsHeader asks the browsers to keep to HTTPS for a year, sent with the security headers over TLS only.
+const hstsHeader = "max-age=31536000"
+
+// protectedHeaders frame the responses, and are set by the server only.
+var protectedHeaders = map[string]bool{"Content-Length": true, "Transfer-Encoding": true}
+
+// headerFlag holds the headers given as "Key: Value", one per flag or per line of the environment variable,
+// so that the values keep their commas. The values of the same key append, as repeated HTTP headers.
+type headerFlag struct {
+	header http.Header
+}
+
+func (f *headerFlag) Set(value string) error {
+	for _, line := range strings.Split(value, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, err := parseHeader(line)
+		if err != nil {
+			return err
+		}
+		if f.header == nil {
+			f.header = http.Header{}
+		}
+		f.header.Add(key, value)
+	}
+	return nil
+}
+
+func (f *headerFlag) String() string {
+	var lines []string
+	for _, key := range sortedHeaderKeys(f.header) {
+		for _, value := range f.header[key] {
+			lines = append(lines, key+": "+value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseHeader parses a "Key: Value" header, rejecting the invalid names and values, and the headers framing
+// the responses.
+func parseHeader(line string) (string, string, error) {
+	key, value, found := strings.Cut(line, ":")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !found || !httpguts.ValidHeaderFieldName(key) {
+		return "", "", fmt.Errorf("response header %q: expected Key: Value", line)
+	}
+	if !httpguts.ValidHeaderFieldValue(value) {
+		return "", "", fmt.Errorf("response header %s: invalid value", key)
+	}
+
+	key = http.CanonicalHeaderKey(key)
+	if protectedHeaders[key] {
+		return "", "", fmt.Errorf("response header %s is set by the server only", key)
+	}
+	return key, value, nil
+}
+
+// responseHeaders returns the headers added to every response: the security headers when asked, with HSTS
+// over TLS, replaced by the given headers of the same key.
+func responseHeaders(given http.Header, secure, overTLS bool) http.Header {
+	headers := http.Header{}
+	if secure {
+		for key, values := range secureHeaders {
+			headers[key] = values
+		}
+		if overTLS {
+			headers.Set("Strict-Transport-Security", hstsHeader)
+		}
+	}
+	for key, values := range given {
+		headers[key] = values
+	}
+	return headers
+}
+
+// headersHandler adds the headers to every response of the handler, before it writes its own.
+// The handler is returned as is without headers.
+func headersHandler(handler http.Handler, headers http.Header) http.Handler {
+	if len(headers) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		header := rw.Header()
+		for key, values := range headers {
+			header[key] = append(header[key], values...)
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
+
+// sortedHeaderKeys returns the keys of the header in increasing order.
+func sortedHeaderKeys(header http.Header) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
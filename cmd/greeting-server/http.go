@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// router mounts handlers, such as the debug endpoints, on the server or on a side listener.
+type router interface {
+	http.Handler
+	Handle(pattern string, handler http.Handler)
+}
+
+// isGreetPath tells whether the path is served by the greeting handler.
+func isGreetPath(path string) bool {
+	return path == "/greet" || strings.HasPrefix(path, "/greet/")
+}
+
+// startSideServer serves the handler on its own plain HTTP listener, until closed.
+func startSideServer(name string, listener net.Listener, handler http.Handler) *http.Server {
+	server := &http.Server{Handler: handler}
+
+	log.WithField("addr", listener.Addr().String()).Info("Serving " + name)
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Fatal("Unable to serve " + name)
+		}
+	}()
+	return server
+}
+
+// responseRecorder keeps the status code and the size of the body written by a handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	// wroteHeader tells whether the status code is already sent.
+	wroteHeader bool
+}
+
+func newResponseRecorder(rw http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: rw, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status, r.wroteHeader = status, true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(body []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(body)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap returns the wrapped writer, to which http.ResponseController hands the flushes and the deadlines.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Hijack hands the connection over to the handler, such as a WebSocket, recorded as switching protocols.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.status, r.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+// configureLogging sets the format of the logs from the flag, before anything is logged. The level is
+// applied with the settings, as the configuration file may override it.
+func configureLogging(cliCtx *cli.Context) error {
+	switch format := cliCtx.String("log-format"); format {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	return nil
+}
@@ -1,26 +1,36 @@
 package main
 
 import (
-	"fmt"
 	"net/http"
 	"os"
+	"time"
 
+	"edb-challenge/pkg/clientip"
+	"edb-challenge/pkg/version"
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 )
 
-func main() {
+// newApp returns the command line application serving the greetings.
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Name = "Greeting"
 	app.Usage = "Just another greeting server"
+	app.Version = version.Get().String()
 	app.Flags = []cli.Flag{
 		&cli.StringFlag{
 			Name:    "bind",
-			Usage:   "Binding address for HTTP server",
+			Usage:   "Binding address for HTTP server, or unix:///path/to/socket for a Unix domain socket",
 			Value:   ":80",
 			Aliases: []string{"b"},
 			EnvVars: []string{"BIND"},
 		},
+		&cli.StringFlag{
+			Name:    "socket-mode",
+			Usage:   "Permissions of the Unix domain socket file, in octal",
+			Value:   "0660",
+			EnvVars: []string{"SOCKET_MODE"},
+		},
 		&cli.StringFlag{
 			Name:    "name",
 			Usage:   "Greeting name for the server",
@@ -28,41 +38,309 @@ func main() {
 			Aliases: []string{"n"},
 			EnvVars: []string{"NAME"},
 		},
+		&cli.StringFlag{
+			Name:    "config-file",
+			Usage:   "YAML or JSON file of the name, greeting-template and log-level keys, taking precedence over the flags and reloaded on SIGHUP",
+			EnvVars: []string{"CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "log-level",
+			Usage:   "Level of the logs (trace, debug, info, warning, error)",
+			Value:   log.InfoLevel.String(),
+			EnvVars: []string{"LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:    "log-format",
+			Usage:   "Format of the logs (text or json)",
+			Value:   "text",
+			EnvVars: []string{"LOG_FORMAT"},
+		},
+		&cli.StringFlag{
+			Name:    "auth-token",
+			Usage:   "Bearer token required by /greet, which is open when no credentials are given",
+			EnvVars: []string{"AUTH_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "auth-token-file",
+			Usage:   "File holding the bearer token required by /greet, such as a mounted Secret",
+			EnvVars: []string{"AUTH_TOKEN_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "basic-auth",
+			Usage:   "Basic auth credentials accepted by /greet, as user:bcrypt-hash",
+			EnvVars: []string{"BASIC_AUTH"},
+		},
+		&cli.StringFlag{
+			Name:    "admin-token",
+			Usage:   "Bearer token required by the /name endpoint, which is disabled when empty",
+			EnvVars: []string{"ADMIN_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "stats-token",
+			Usage:   "Bearer token required by the /stats endpoint, which is open when empty",
+			EnvVars: []string{"STATS_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "counter-file",
+			Usage:   "JSON file persisting the greetings and requests counted across restarts, such as on a volume, none when empty",
+			EnvVars: []string{"COUNTER_FILE"},
+		},
+		&cli.DurationFlag{
+			Name:    "counter-sync-interval",
+			Usage:   "Time between the writes of the counter file, only written on shutdown when 0",
+			Value:   10 * time.Second,
+			EnvVars: []string{"COUNTER_SYNC_INTERVAL"},
+		},
+		&cli.BoolFlag{
+			Name:    "show-host",
+			Usage:   "Append the hostname, the pod name in Kubernetes, and the version to the greeting message",
+			EnvVars: []string{"SHOW_HOST"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-ui",
+			Usage:   "Answer 404 on / instead of the HTML landing page, for API-only deployments",
+			EnvVars: []string{"NO_UI"},
+		},
+		&cli.StringFlag{
+			Name:    "greeting-template",
+			Usage:   "Template of the greeting message in the default language, with the .Name, .Hostname, .Count and .Now fields",
+			EnvVars: []string{"GREETING_TEMPLATE"},
+		},
+		&cli.StringFlag{
+			Name:    "name-file",
+			Usage:   "File of the greeting name, such as a key of a mounted ConfigMap, taking precedence over the flags and the configuration file and reloaded on change",
+			EnvVars: []string{"NAME_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "template-file",
+			Usage:   "File of the greeting template in the default language, taking precedence over the flags and the configuration file and reloaded on change",
+			EnvVars: []string{"TEMPLATE_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "default-language",
+			Usage:   "Language of the greeting when the client accepts none of the translated ones",
+			Value:   "en",
+			EnvVars: []string{"DEFAULT_LANGUAGE"},
+		},
+		&cli.StringFlag{
+			Name:    "translations-file",
+			Usage:   "YAML file of greeting templates by language, extending or overriding the built-in ones",
+			EnvVars: []string{"TRANSLATIONS_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:    "h2c",
+			Usage:   "Serve HTTP/2 without TLS along with HTTP/1.1, by prior knowledge or upgrade, such as for the proxies of a service mesh",
+			EnvVars: []string{"H2C"},
+		},
+		&cli.DurationFlag{
+			Name:    "read-timeout",
+			Usage:   "Time given to read a whole request, body included (0 for no timeout)",
+			Value:   10 * time.Second,
+			EnvVars: []string{"READ_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:    "read-header-timeout",
+			Usage:   "Time given to read the headers of a request (0 for the read timeout)",
+			Value:   5 * time.Second,
+			EnvVars: []string{"READ_HEADER_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:    "write-timeout",
+			Usage:   "Time given to write a response, from the end of the request headers (0 for no timeout)",
+			Value:   10 * time.Second,
+			EnvVars: []string{"WRITE_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:    "idle-timeout",
+			Usage:   "Time a keep-alive connection waits for the next request (0 for the read timeout)",
+			Value:   time.Minute,
+			EnvVars: []string{"IDLE_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:    "max-header-bytes",
+			Usage:   "Maximum size of the request headers (0 for the 1MB default of Go)",
+			Value:   http.DefaultMaxHeaderBytes,
+			EnvVars: []string{"MAX_HEADER_BYTES"},
+		},
+		&cli.IntFlag{
+			Name:    "max-connections",
+			Usage:   "Connections served at once, the ones beyond being closed right away (0 for no limit)",
+			EnvVars: []string{"MAX_CONNECTIONS"},
+		},
+		&cli.BoolFlag{
+			Name:    "disable-keepalive",
+			Usage:   "Close every connection after its request, such as to debug the load balancing",
+			EnvVars: []string{"DISABLE_KEEPALIVE"},
+		},
+		&cli.DurationFlag{
+			Name:    "stream-interval",
+			Usage:   "Time between the greetings pushed by /greet/stream",
+			Value:   5 * time.Second,
+			EnvVars: []string{"STREAM_INTERVAL"},
+		},
+		&cli.IntFlag{
+			Name:    "max-streams",
+			Usage:   "Streams of /greet/stream served at once, the ones beyond being answered 503 (0 for no limit)",
+			Value:   100,
+			EnvVars: []string{"MAX_STREAMS"},
+		},
+		&cli.DurationFlag{
+			Name:    "startup-delay",
+			Usage:   "Time the server fails the readiness probe and the greetings after starting listening, simulating a slow start",
+			EnvVars: []string{"STARTUP_DELAY"},
+		},
+		&cli.DurationFlag{
+			Name:    "response-delay",
+			Usage:   "Latency added to every greeting, simulating a slow server",
+			EnvVars: []string{"RESPONSE_DELAY"},
+		},
+		&cli.DurationFlag{
+			Name:    "shutdown-timeout",
+			Usage:   "Time given to the in-flight requests to complete on SIGTERM or SIGINT, before their connections are closed",
+			Value:   20 * time.Second,
+			EnvVars: []string{"SHUTDOWN_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:    "shutdown-delay",
+			Usage:   "Time the server keeps serving with a failing readiness probe on SIGTERM or SIGINT, before draining",
+			EnvVars: []string{"SHUTDOWN_DELAY"},
+		},
+		&cli.StringFlag{
+			Name:    "grpc-bind",
+			Usage:   "Binding address for the gRPC greeting and health services, disabled when empty",
+			EnvVars: []string{"GRPC_BIND"},
+		},
+		&cli.StringFlag{
+			Name:    "metrics-bind",
+			Usage:   "Binding address for the /metrics endpoint, served by the HTTP server when empty",
+			EnvVars: []string{"METRICS_BIND"},
+		},
+		&cli.StringFlag{
+			Name:    "health-bind",
+			Usage:   "Binding address for plain HTTP /health and /ready endpoints, next to the ones of the HTTP server",
+			EnvVars: []string{"HEALTH_BIND"},
+		},
+		&cli.BoolFlag{
+			Name:    "enable-pprof",
+			Usage:   "Serve the pprof profiling endpoints under /debug/pprof",
+			EnvVars: []string{"ENABLE_PPROF"},
+		},
+		&cli.BoolFlag{
+			Name:    "enable-fault-injection",
+			Usage:   "Serve the /admin endpoints failing the probes, crashing or slowing down the server on demand, guarded by the admin token",
+			EnvVars: []string{"ENABLE_FAULT_INJECTION"},
+		},
+		&cli.StringFlag{
+			Name:    "debug-bind",
+			Usage:   "Binding address for the profiling and fault injection endpoints, served by the HTTP server when empty",
+			Value:   "localhost:6060",
+			EnvVars: []string{"DEBUG_BIND"},
+		},
+		&cli.StringFlag{
+			Name:    "tls-cert",
+			Usage:   "Certificate file served over TLS, reloaded when changed",
+			EnvVars: []string{"TLS_CERT"},
+		},
+		&cli.StringFlag{
+			Name:    "tls-key",
+			Usage:   "Private key file of the TLS certificate",
+			EnvVars: []string{"TLS_KEY"},
+		},
+		&cli.StringFlag{
+			Name:    "tls-client-ca",
+			Usage:   "CA file the client certificates must be signed by, requiring mutual TLS",
+			EnvVars: []string{"TLS_CLIENT_CA"},
+		},
+		&cli.BoolFlag{
+			Name:    "metrics-exclude-probes",
+			Usage:   "Keep the /health, /ready and /metrics requests out of the request metrics",
+			Value:   true,
+			EnvVars: []string{"METRICS_EXCLUDE_PROBES"},
+		},
+		&cli.StringFlag{
+			Name:    "otel-endpoint",
+			Usage:   "URL of the OTLP/HTTP endpoint receiving the traces, such as http://tempo:4318, the OTEL_EXPORTER_OTLP_* variables apply when empty",
+			EnvVars: []string{"OTEL_ENDPOINT"},
+		},
+		&cli.Float64Flag{
+			Name:    "rate-limit",
+			Usage:   "Requests per second allowed to every client IP, 0 for no limit",
+			EnvVars: []string{"RATE_LIMIT"},
+		},
+		&cli.IntFlag{
+			Name:    "rate-burst",
+			Usage:   "Requests a client IP may send at once above the rate limit",
+			Value:   10,
+			EnvVars: []string{"RATE_BURST"},
+		},
+		&cli.BoolFlag{
+			Name:    "trust-proxy",
+			Usage:   "Take the client IP from the header of the proxy in front of the server, whatever its address",
+			EnvVars: []string{"TRUST_PROXY"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "trusted-proxies",
+			Usage:   "CIDRs or IPs of the proxies whose header tells the client IP, the header of the other peers being ignored (implies --trust-proxy)",
+			EnvVars: []string{"TRUSTED_PROXIES"},
+		},
+		&cli.StringFlag{
+			Name:    "proxy-header",
+			Usage:   "Header the trusted proxies tell the client IP with (X-Forwarded-For or Forwarded)",
+			Value:   clientip.XForwardedFor,
+			EnvVars: []string{"PROXY_HEADER"},
+		},
+		&cli.GenericFlag{
+			Name:    "response-header",
+			Usage:   "Header added to every response, as \"Key: Value\" (repeatable, one per line in the variable)",
+			Value:   &headerFlag{},
+			EnvVars: []string{"RESPONSE_HEADERS"},
+		},
+		&cli.BoolFlag{
+			Name:    "secure-headers",
+			Usage:   "Add the X-Content-Type-Options, X-Frame-Options, Referrer-Policy and Content-Security-Policy headers, and HSTS over TLS, to every response",
+			EnvVars: []string{"SECURE_HEADERS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "cors-allow-origin",
+			Usage:   "Origin allowed to call the server from a browser, exact or * for any (repeatable)",
+			EnvVars: []string{"CORS_ALLOW_ORIGINS"},
+		},
+		&cli.BoolFlag{
+			Name:    "gzip",
+			Usage:   "Compress the responses for the clients accepting gzip",
+			EnvVars: []string{"GZIP"},
+		},
+		&cli.IntFlag{
+			Name:    "gzip-min-size",
+			Usage:   "Size in bytes below which the responses are not compressed",
+			Value:   1024,
+			EnvVars: []string{"GZIP_MIN_SIZE"},
+		},
+		&cli.StringFlag{
+			Name:    "access-log-level",
+			Usage:   "Level of the access log entries logged for every request",
+			Value:   log.InfoLevel.String(),
+			EnvVars: []string{"ACCESS_LOG_LEVEL"},
+		},
+		&cli.BoolFlag{
+			Name:    "quiet-health",
+			Usage:   "Leave the /health and /ready requests out of the access log",
+			EnvVars: []string{"QUIET_HEALTH"},
+		},
+		&cli.BoolFlag{
+			Name:    "check",
+			Usage:   "Check the configuration, the files and the addresses to bind, then exit without serving",
+			EnvVars: []string{"CHECK"},
+		},
 	}
-	app.Action = func(ctx *cli.Context) error {
-		addr := ctx.String("bind")
-		name := ctx.String("name")
-		server := GreetingServer{Name: name}
-		http.HandleFunc("/health", server.HandleHealthcheck)
-		http.HandleFunc("/greet", server.HandleGreet)
-		log.WithField("addr", addr).WithField("name", name).Info("Starting listening")
-		return http.ListenAndServe(addr, nil)
-	}
-
-	if err := app.Run(os.Args); err != nil {
-		log.WithError(err).Fatal("Unable to start application")
-	}
+	app.Before = configureLogging
+	app.Action = run
+	app.Commands = []*cli.Command{clientCommand()}
+	return app
 }
 
-// GreetingServer is capable of presenting itself thanks to HTTP handlers.
-type GreetingServer struct {
-	// Name is the server name.
-	Name string
-}
-
-// HandleGreet is a HTTP handler answering the server name.
-func (s GreetingServer) HandleGreet(rw http.ResponseWriter, req *http.Request) {
-	log.Debug("Greet")
-	body := fmt.Sprintf("I am %s", s.Name)
-	if _, err := rw.Write([]byte(body)); err != nil {
-		log.WithError(err).Warning("Unable to write greeting content")
-		rw.WriteHeader(http.StatusInternalServerError)
-		return
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		log.WithError(err).Fatal("Unable to start application")
 	}
 }
-
-// HandleHealthcheck returns 200 Ok.
-func (s GreetingServer) HandleHealthcheck(rw http.ResponseWriter, req *http.Request) {
-	log.Debug("Health check")
-	rw.WriteHeader(http.StatusOK)
-}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// probePaths are the routes of the probes and of the metrics, kept out of the request metrics on demand.
+var probePaths = map[string]bool{"/health": true, "/ready": true, "/metrics": true}
+
+var (
+	requestsTotal = promauto.With(greeting.MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: greeting.MetricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests, by route, method and status code.",
+	}, []string{"path", "method", "status"})
+
+	requestDuration = promauto.With(greeting.MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: greeting.MetricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests, by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"path", "method"})
+
+	requestsInFlight = promauto.With(greeting.MetricsRegistry).NewGauge(prometheus.GaugeOpts{
+		Namespace: greeting.MetricsNamespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests being served.",
+	})
+
+	openConnections = promauto.With(greeting.MetricsRegistry).NewGauge(prometheus.GaugeOpts{
+		Namespace: greeting.MetricsNamespace,
+		Name:      "http_open_connections",
+		Help:      "Number of HTTP connections open.",
+	})
+
+	rejectedConnections = promauto.With(greeting.MetricsRegistry).NewCounter(prometheus.CounterOpts{
+		Namespace: greeting.MetricsNamespace,
+		Name:      "http_rejected_connections_total",
+		Help:      "Number of HTTP connections closed for exceeding the connection limit.",
+	})
+
+	rateLimited = promauto.With(greeting.MetricsRegistry).NewCounter(prometheus.CounterOpts{
+		Namespace: greeting.MetricsNamespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of HTTP requests rejected for exceeding the rate limit of their client.",
+	})
+)
+
+// instrument records the requests served by the handler, labelled by the pattern of their route so that the
+// names given in the paths do not multiply the series. The probes are skipped when excluded.
+func instrument(handler http.Handler, route func(*http.Request) string, excludeProbes bool) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		path := route(req)
+		if path == "" {
+			path = "unmatched"
+		}
+		if excludeProbes && probePaths[path] {
+			handler.ServeHTTP(rw, req)
+			return
+		}
+
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+
+		start := time.Now()
+		recorder := newResponseRecorder(rw)
+		handler.ServeHTTP(recorder, req)
+
+		requestDuration.WithLabelValues(path, req.Method).Observe(time.Since(start).Seconds())
+		requestsTotal.WithLabelValues(path, req.Method, strconv.Itoa(recorder.status)).Inc()
+	})
+}
+
+// countConnections tracks the open connections of the HTTP server in the metrics and the stats.
+func countConnections(stats *greeting.Stats) func(net.Conn, http.ConnState) {
+	return func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			openConnections.Inc()
+		case http.StateHijacked, http.StateClosed:
+			openConnections.Dec()
+		}
+		stats.ConnState(conn, state)
+	}
+}
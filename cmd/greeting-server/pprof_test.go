@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"edb-challenge/pkg/greeting"
+)
+
+func TestRegisterPprof(t *testing.T) {
+	routers := []struct {
+		name      string
+		newRouter func() router
+	}{
+		{"HTTP server", func() router { return greeting.New() }},
+		{"debug listener", func() router { return http.NewServeMux() }},
+	}
+
+	for _, r := range routers {
+		for _, enabled := range []bool{true, false} {
+			mux := r.newRouter()
+			if enabled {
+				registerPprof(mux)
+			}
+
+			for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline"} {
+				rw := httptest.NewRecorder()
+				mux.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+
+				want := http.StatusNotFound
+				if enabled {
+					want = http.StatusOK
+				}
+				if rw.Code != want {
+					t.Errorf("%s, pprof enabled %t: GET %s = %d, want %d", r.name, enabled, path, rw.Code, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"edb-challenge/pkg/clientip"
+	"edb-challenge/pkg/greeting"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitIdle is the time after which the bucket of an idle client is forgotten. A client coming
+// back later starts over with a full bucket, as it would have refilled anyway.
+const rateLimitIdle = 3 * time.Minute
+
+// rateLimiter throttles every client to its own token bucket, keyed by client IP as resolved by clientip.
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu      sync.Mutex
+	clients map[string]*clientBucket
+}
+
+// clientBucket is the token bucket of a client, with the last time it was used.
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRateLimiter returns a limiter of limit requests per second and burst requests at once per client,
+// nil when the limit is not positive.
+func newRateLimiter(limit float64, burst int) *rateLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		limit:   rate.Limit(limit),
+		burst:   burst,
+		clients: map[string]*clientBucket{},
+	}
+}
+
+// allow takes a token from the bucket of the client, and returns the delay until the next one otherwise.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &clientBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = bucket
+	}
+	bucket.lastSeen = now
+	l.mu.Unlock()
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// The token is given back, only the served requests consume the bucket.
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// evict forgets the buckets of the clients idle since before the deadline.
+func (l *rateLimiter) evict(deadline time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for client, bucket := range l.clients {
+		if bucket.lastSeen.Before(deadline) {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// evictIdle forgets the idle clients every interval, until the context is done.
+func (l *rateLimiter) evictIdle(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.evict(now.Add(-rateLimitIdle))
+		}
+	}
+}
+
+// rateLimitHandler answers 429 to the clients exceeding their rate, the probes and the metrics excepted.
+// The handler is returned as is without limiter.
+func rateLimitHandler(handler http.Handler, limiter *rateLimiter) http.Handler {
+	if limiter == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if probePaths[req.URL.Path] {
+			handler.ServeHTTP(rw, req)
+			return
+		}
+
+		client := clientip.Of(req)
+		if ok, delay := limiter.allow(client, time.Now()); !ok {
+			rateLimited.Inc()
+			greeting.RequestLog(req).WithField("client", client).Debug("Rate limit exceeded")
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			greeting.Error(rw, req, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
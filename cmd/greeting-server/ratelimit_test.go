@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := newRateLimiter(1, 2)
+	now := time.Now()
+
+	steps := []struct {
+		client    string
+		at        time.Duration
+		want      bool
+		wantDelay time.Duration
+	}{
+		{"10.0.0.1", 0, true, 0},
+		{"10.0.0.1", 0, true, 0},
+		{"10.0.0.1", 0, false, time.Second},
+		// The refused requests do not consume the bucket.
+		{"10.0.0.1", 500 * time.Millisecond, false, 500 * time.Millisecond},
+		{"10.0.0.2", 500 * time.Millisecond, true, 0},
+		{"10.0.0.1", time.Second, true, 0},
+		{"10.0.0.1", time.Second, false, time.Second},
+	}
+	for i, step := range steps {
+		ok, delay := limiter.allow(step.client, now.Add(step.at))
+		if ok != step.want || delay != step.wantDelay {
+			t.Errorf("step %d: allow(%s, +%s) = %t, %s, want %t, %s", i, step.client, step.at, ok, delay, step.want, step.wantDelay)
+		}
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	if limiter := newRateLimiter(0, 10); limiter != nil {
+		t.Error("limiter without limit")
+	}
+	if limiter := newRateLimiter(1, 0); limiter == nil || limiter.burst != 1 {
+		t.Errorf("limiter burst = %v, want at least 1", limiter)
+	}
+}
+
+func TestRateLimiterEvict(t *testing.T) {
+	limiter := newRateLimiter(1, 1)
+	now := time.Now()
+	limiter.allow("idle", now.Add(-rateLimitIdle-time.Second))
+	limiter.allow("active", now)
+
+	limiter.evict(now.Add(-rateLimitIdle))
+	if _, ok := limiter.clients["idle"]; ok {
+		t.Error("idle client kept")
+	}
+	if _, ok := limiter.clients["active"]; !ok {
+		t.Error("active client evicted")
+	}
+	// Forgotten, the idle client starts over with a full bucket.
+	if ok, _ := limiter.allow("idle", now); !ok {
+		t.Error("evicted client refused")
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	const clients, goroutines, requests, burst = 8, 32, 50, 10
+	limiter := newRateLimiter(0.001, burst)
+	now := time.Now()
+
+	// Hammered at the same instant, every client is served its burst exactly, while the eviction runs
+	// alongside without forgetting anyone.
+	var allowed [clients]atomic.Int64
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	evicted := make(chan struct{})
+	go func() {
+		defer close(evicted)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				limiter.evict(now.Add(-time.Hour))
+			}
+		}
+	}()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				client := (g + i) % clients
+				if ok, _ := limiter.allow(fmt.Sprintf("10.0.0.%d", client), now); ok {
+					allowed[client].Add(1)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(done)
+	<-evicted
+
+	for client := range allowed {
+		if n := allowed[client].Load(); n != burst {
+			t.Errorf("client %d allowed %d requests, want the burst of %d", client, n, burst)
+		}
+	}
+
+	// Evicting the clients being served only starts them over, never losing track of the map.
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			limiter.evict(time.Now().Add(time.Hour))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			limiter.allow(fmt.Sprintf("10.0.1.%d", i%clients), time.Now())
+		}
+	}()
+	wg.Wait()
+	limiter.evict(time.Now().Add(time.Hour))
+	if n := len(limiter.clients); n != 0 {
+		t.Errorf("%d clients left once all evicted", n)
+	}
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	handler := rateLimitHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), newRateLimiter(0.5, 1))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw
+	}
+
+	if rw := request("/greet"); rw.Code != http.StatusOK {
+		t.Errorf("first request = %d, want %d", rw.Code, http.StatusOK)
+	}
+	rw := request("/greet")
+	if rw.Code != http.StatusTooManyRequests || rw.Header().Get("Retry-After") != "2" {
+		t.Errorf("second request = %d, Retry-After %q, want %d after 2 seconds", rw.Code, rw.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+	for path := range probePaths {
+		if rw := request(path); rw.Code != http.StatusOK {
+			t.Errorf("%s = %d, want the probes never limited", path, rw.Code)
+		}
+	}
+
+	mux := http.NewServeMux()
+	if got := rateLimitHandler(mux, nil); got != http.Handler(mux) {
+		t.Error("handler wrapped without limiter")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"edb-challenge/pkg/greeting"
+)
+
+// recoverHandler logs the panics of the handler as errors of the request, with their stack, and answers
+// 500 instead of dropping the connection. The status cannot change once sent, the response is then cut.
+func recoverHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		recorder := newResponseRecorder(rw)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// http.ErrAbortHandler aborts the response on purpose, without being logged by net/http either.
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			greeting.RequestLog(req).
+				WithField("panic", fmt.Sprint(recovered)).
+				WithField("stack", string(debug.Stack())).
+				Error("Handler panicked")
+			if recorder.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			greeting.Error(rw, req, http.StatusInternalServerError, "internal server error")
+		}()
+		handler.ServeHTTP(recorder, req)
+	})
+}
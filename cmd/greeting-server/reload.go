@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"edb-challenge/pkg/greeting"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// configFile is the content of the configuration file, each key overriding its flag when set.
+type configFile struct {
+	Name             string `json:"name,omitempty"`
+	GreetingTemplate string `json:"greeting-template,omitempty"`
+	LogLevel         string `json:"log-level,omitempty"`
+}
+
+// serverSettings are the parts of the configuration reloaded while serving.
+type serverSettings struct {
+	name         string
+	translations *greeting.Translations
+	logLevel     log.Level
+}
+
+// loadSettings reads the settings from the flags, the configuration and translations files, checking all of them
+// before any is applied. Every problem is reported at once.
+func loadSettings(cliCtx *cli.Context) (serverSettings, error) {
+	config := configFile{
+		Name:             cliCtx.String("name"),
+		GreetingTemplate: cliCtx.String("greeting-template"),
+		LogLevel:         cliCtx.String("log-level"),
+	}
+
+	var errs []error
+	if path := cliCtx.String("config-file"); path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if file.Name != "" {
+			config.Name = file.Name
+		}
+		if file.GreetingTemplate != "" {
+			config.GreetingTemplate = file.GreetingTemplate
+		}
+		if file.LogLevel != "" {
+			config.LogLevel = file.LogLevel
+		}
+	}
+
+	if path := cliCtx.String("name-file"); path != "" {
+		name, err := readValueFile(path)
+		if err == nil {
+			err = greeting.ValidateName(name)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("name file %s: %w", path, err))
+		} else {
+			config.Name = name
+		}
+	}
+	if path := cliCtx.String("template-file"); path != "" {
+		tmpl, err := readValueFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("template file %s: %w", path, err))
+		} else {
+			config.GreetingTemplate = tmpl
+		}
+	}
+
+	translations, err := greeting.LoadTranslations(cliCtx.String("translations-file"), cliCtx.String("default-language"), config.GreetingTemplate)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	level, err := log.ParseLevel(config.LogLevel)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("parse log level: %w", err))
+	}
+	if len(errs) > 0 {
+		return serverSettings{}, errors.Join(errs...)
+	}
+
+	return serverSettings{name: config.Name, translations: translations, logLevel: level}, nil
+}
+
+// readConfigFile reads the configuration file, keeping the valid keys of a file holding an invalid name.
+func readConfigFile(path string) (configFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return configFile{}, fmt.Errorf("read config file: %w", err)
+	}
+	var file configFile
+	if err = yaml.UnmarshalStrict(raw, &file); err != nil {
+		return configFile{}, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	if err = greeting.ValidateName(file.Name); err != nil {
+		name := file.Name
+		file.Name = ""
+		return file, fmt.Errorf("config file %s: name %q: %w", path, name, err)
+	}
+	return file, nil
+}
+
+// readValueFile returns the content of a file holding a single value, trimmed of the surrounding spaces,
+// such as the final newline. An empty file is rejected.
+func readValueFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(raw))
+	if value == "" {
+		return "", errors.New("empty file")
+	}
+	return value, nil
+}
+
+// apply switches the server to the settings at once.
+func apply(server *greeting.Server, settings serverSettings) {
+	server.Update(settings.name, settings.translations)
+	log.SetLevel(settings.logLevel)
+}
+
+// reload applies the settings and the TLS certificate read again, once all of them are valid.
+func reload(cliCtx *cli.Context, server *greeting.Server, certs *certReloader) error {
+	settings, err := loadSettings(cliCtx)
+	if err != nil {
+		return err
+	}
+	if certs != nil {
+		if _, err = certs.reload(); err != nil {
+			return err
+		}
+	}
+
+	apply(server, settings)
+	return nil
+}
+
+// reloadOnSignal calls reload whenever a signal is received, until the context is done.
+// A failed reload keeps the previous configuration.
+func reloadOnSignal(ctx context.Context, signals <-chan os.Signal, reload func() error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			if err := reload(); err != nil {
+				log.WithError(err).WithField("signal", sig).Error("Unable to reload the configuration, keeping the previous one")
+				continue
+			}
+			log.WithField("signal", sig).Info("Reloaded the configuration")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: Ada\n")
+
+	withFlags(t, []string{"--config-file", path}, func(cliCtx *cli.Context) error {
+		server := greeting.New(greeting.WithName("Ada"))
+		ctx, cancel := context.WithCancel(context.Background())
+		signals := make(chan os.Signal)
+		reloaded := make(chan error)
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			reloadOnSignal(ctx, signals, func() error {
+				err := reload(cliCtx, server, nil)
+				reloaded <- err
+				return err
+			})
+		}()
+
+		steps := []struct {
+			name, config string
+			wantName     string
+			wantLevel    log.Level
+			wantErr      bool
+		}{
+			{"name changed", "name: Grace\n", "Grace", log.InfoLevel, false},
+			{"level changed", "name: Grace\nlog-level: debug\n", "Grace", log.DebugLevel, false},
+			// A failed reload keeps the previous configuration, none of its keys being applied.
+			{"invalid level", "name: Linus\nlog-level: loud\n", "Grace", log.DebugLevel, true},
+			{"unknown key", "name: Linus\ncolour: blue\n", "Grace", log.DebugLevel, true},
+			{"invalid name", "name: \"Linus\\n\"\n", "Grace", log.DebugLevel, true},
+			{"fixed", "name: Linus\n", "Linus", log.InfoLevel, false},
+		}
+		for _, step := range steps {
+			writeFile(t, path, step.config)
+			signals <- syscall.SIGHUP
+			if err := <-reloaded; (err != nil) != step.wantErr {
+				t.Errorf("%s: reload = %v, want error %t", step.name, err, step.wantErr)
+			}
+			if name := server.Name(); name != step.wantName {
+				t.Errorf("%s: name = %q, want %q", step.name, name, step.wantName)
+			}
+			if level := log.GetLevel(); level != step.wantLevel {
+				t.Errorf("%s: log level = %s, want %s", step.name, level, step.wantLevel)
+			}
+		}
+
+		cancel()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Error("reloadOnSignal still running once the context is done")
+		}
+		return nil
+	})
+}
+
+func TestLoadSettings(t *testing.T) {
+	dir := t.TempDir()
+	configPath, namePath, templatePath := filepath.Join(dir, "config.yaml"), filepath.Join(dir, "name"), filepath.Join(dir, "template")
+	writeFile(t, configPath, "name: Config\ngreeting-template: 'Hi from {{.Name}}'\n")
+	writeFile(t, namePath, "  File\n")
+	writeFile(t, templatePath, "Hello from {{.Name}}\n")
+	emptyPath := filepath.Join(dir, "empty")
+	writeFile(t, emptyPath, "\n")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantName string
+		wantErrs []string
+	}{
+		{name: "flags", args: []string{"--name", "Flag"}, wantName: "Flag"},
+		{name: "config file over flags", args: []string{"--name", "Flag", "--config-file", configPath}, wantName: "Config"},
+		{name: "name file over config file", args: []string{"--config-file", configPath, "--name-file", namePath}, wantName: "File"},
+		{name: "template file", args: []string{"--template-file", templatePath}, wantName: "anonymous"},
+		{name: "empty name file", args: []string{"--name-file", emptyPath}, wantErrs: []string{"empty file"}},
+		{name: "missing config file", args: []string{"--config-file", filepath.Join(dir, "missing")}, wantErrs: []string{"read config file"}},
+		{
+			name:     "every problem",
+			args:     []string{"--name-file", emptyPath, "--log-level", "loud"},
+			wantErrs: []string{"empty file", "parse log level"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withFlags(t, test.args, func(cliCtx *cli.Context) error {
+				settings, err := loadSettings(cliCtx)
+				if len(test.wantErrs) > 0 {
+					for _, want := range test.wantErrs {
+						if err == nil || !strings.Contains(err.Error(), want) {
+							t.Errorf("loadSettings = %v, want %q", err, want)
+						}
+					}
+					return nil
+				}
+				if err != nil {
+					t.Fatalf("loadSettings: %v", err)
+				}
+				if settings.name != test.wantName {
+					t.Errorf("name = %q, want %q", settings.name, test.wantName)
+				}
+				return nil
+			})
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"edb-challenge/pkg/clientip"
+	"edb-challenge/pkg/greeting"
+	"edb-challenge/pkg/reqid"
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// run serves the greetings over HTTP, and gRPC when bound, until SIGTERM or SIGINT, reloading the
+// configuration on SIGHUP.
+func run(cliCtx *cli.Context) error {
+	addr := cliCtx.String("bind")
+	checked, err := checkStartup(cliCtx)
+	if err != nil {
+		return err
+	}
+	// Closing the listeners once served again is harmless, it only removes the socket file on early returns.
+	defer checked.close()
+	if cliCtx.Bool("check") {
+		log.WithField("addr", addr).Info("Configuration checked, the server can start")
+		return nil
+	}
+	current, tlsConfig, certs, auth, faults := checked.settings, checked.tlsConfig, checked.certs, checked.auth, checked.faults
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.WithError(err).Warning("Unable to get the hostname")
+	}
+	log.SetLevel(current.logLevel)
+
+	adminToken := cliCtx.String("admin-token")
+	metricsAddr := cliCtx.String("metrics-bind")
+	server := newGreetingServer(cliCtx, current, hostname, auth)
+	server.RestoreCounters(checked.counters)
+	readiness := server.Readiness()
+	if metricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", greeting.MetricsHandler())
+		defer startSideServer("metrics", checked.metricsListener, metricsMux).Close()
+	}
+	// The debug endpoints are served by the HTTP server without debug listener.
+	debugAddr := cliCtx.String("debug-bind")
+	var debugMux router = server
+	if debugAddr != "" {
+		debugMux = http.NewServeMux()
+	}
+	if cliCtx.Bool("enable-pprof") {
+		if debugAddr == "" {
+			log.Warning("Profiling enabled on /debug/pprof of the HTTP server, exposed to every client of the service")
+		} else {
+			log.WithField("addr", debugAddr).Warning("Profiling enabled on /debug/pprof")
+		}
+		registerPprof(debugMux)
+	}
+	if faults != nil {
+		if debugAddr == "" {
+			log.Warning("Fault injection enabled on /admin of the HTTP server, guarded by the admin token only")
+		} else {
+			log.WithField("addr", debugAddr).Warning("Fault injection enabled on /admin")
+		}
+		faults.register(debugMux, adminToken)
+	}
+	if checked.debugListener != nil {
+		defer startSideServer("debug endpoints", checked.debugListener, debugMux).Close()
+	}
+	headers := responseHeaders(cliCtx.Generic("response-header").(*headerFlag).header, cliCtx.Bool("secure-headers"), tlsConfig != nil)
+	if checked.healthListener != nil {
+		healthMux := http.NewServeMux()
+		healthMux.HandleFunc("/health", server.HandleHealthcheck)
+		healthMux.HandleFunc("/ready", readiness.HandleReady)
+		defer startSideServer("health", checked.healthListener, headersHandler(withFaults(faults, healthMux), headers)).Close()
+	}
+
+	ctx, stop := signal.NotifyContext(cliCtx.Context, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	// The signals and the file changes do not reload at once, so that the last settings read are the ones applied.
+	var reloading sync.Mutex
+	reloadSettings := func() error {
+		reloading.Lock()
+		defer reloading.Unlock()
+		return reload(cliCtx, server, certs)
+	}
+	go reloadOnSignal(ctx, hangups, reloadSettings)
+	var watched []string
+	for _, flag := range []string{"name-file", "template-file"} {
+		if path := cliCtx.String(flag); path != "" {
+			watched = append(watched, path)
+		}
+	}
+	if len(watched) > 0 {
+		if err := watchFiles(ctx, watched, reloadSettings); err != nil {
+			return err
+		}
+	}
+
+	log.WithField("addr", addr).WithField("name", current.name).WithField("tls", tlsConfig != nil).Info("Starting listening")
+	// The grace period of the pod must cover the whole shutdown, Kubernetes killing the server once it is over.
+	shutdownDelay, shutdownTimeout := cliCtx.Duration("shutdown-delay"), cliCtx.Duration("shutdown-timeout")
+	log.WithField("shutdown_delay", shutdownDelay).WithField("shutdown_timeout", shutdownTimeout).
+		WithField("total", shutdownDelay+shutdownTimeout).
+		Info("Shutting down takes up to the total, which terminationGracePeriodSeconds must exceed")
+	// Compressing within the instrumentation counts the compressed bytes.
+	startupDelay := cliCtx.Duration("startup-delay")
+	handler := startupHandler(withFaults(faults, server), readiness, startupDelay, cliCtx.Duration("response-delay"))
+	if cliCtx.Bool("gzip") {
+		handler = gzipHandler(handler, cliCtx.Int("gzip-min-size"))
+	}
+	// Recovering within the instrumentation counts and logs the panics as 500 answers.
+	handler = recoverHandler(handler)
+	limiter := newRateLimiter(cliCtx.Float64("rate-limit"), cliCtx.Int("rate-burst"))
+	if limiter != nil {
+		go limiter.evictIdle(ctx, time.Minute)
+	}
+	handler = corsHandler(rateLimitHandler(handler, limiter), cliCtx.StringSlice("cors-allow-origin"))
+	handler = countRequests(instrument(handler, server.Route, cliCtx.Bool("metrics-exclude-probes")), server.Stats(), server.Route)
+	handler = accessLog(handler, checked.accessLogLevel, cliCtx.Bool("quiet-health"))
+
+	// Without endpoint, requests are not traced at all.
+	var tracerProvider *sdktrace.TracerProvider
+	if otelEndpoint := cliCtx.String("otel-endpoint"); tracingConfigured(otelEndpoint) {
+		if tracerProvider, err = newTracerProvider(ctx, otelEndpoint); err != nil {
+			return err
+		}
+		handler = traceHandler(handler, server.Route, tracerProvider.Tracer("greeting-server"))
+	}
+	handler = reqid.Handler(clientip.Handler(headersHandler(handler, headers), checked.resolver))
+	httpServer := newHTTPServer(cliCtx, handler, tlsConfig, server.Stats())
+	httpServer.RegisterOnShutdown(server.CloseStreams)
+	var h2cInFlight *h2cRequests
+	if cliCtx.Bool("h2c") {
+		if httpServer.Handler, h2cInFlight, err = h2cHandler(httpServer, httpServer.Handler); err != nil {
+			return err
+		}
+	}
+
+	listener := newLimitListener(checked.listener, cliCtx.Int("max-connections"))
+
+	// Either server failing stops the other one.
+	serveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	grpcServed := make(chan error, 1)
+	if checked.grpcListener != nil {
+		grpcServer, healthServer := newGRPCServer(server, auth, tlsConfig)
+		log.WithField("addr", checked.grpcListener.Addr().String()).Info("Starting listening gRPC")
+		go func() {
+			err := serveGRPC(serveCtx, grpcServer, healthServer, checked.grpcListener, shutdownDelay, shutdownTimeout)
+			cancel()
+			grpcServed <- err
+		}()
+	} else {
+		grpcServed <- nil
+	}
+	var counters *counterSync
+	countersSynced := make(chan struct{})
+	if counterFile := cliCtx.String("counter-file"); counterFile != "" {
+		log.WithField("path", counterFile).WithField("greetings", checked.counters.Greetings).
+			WithField("requests", checked.counters.Requests).Info("Counters restored")
+		counters = newCounterSync(counterFile, server)
+		go func() {
+			defer close(countersSynced)
+			counters.run(serveCtx, cliCtx.Duration("counter-sync-interval"))
+		}()
+	} else {
+		close(countersSynced)
+	}
+	readiness.StartAfter(startupDelay)
+	err = serve(serveCtx, httpServer, h2cInFlight, listener, readiness, shutdownDelay, shutdownTimeout)
+	cancel()
+	err = errors.Join(err, <-grpcServed)
+
+	// The counters are written once more after the drain, counting the last requests served.
+	<-countersSynced
+	if counters != nil {
+		if saveErr := counters.save(); saveErr != nil {
+			log.WithField("path", counters.path).WithError(saveErr).Warning("Unable to persist the counters")
+		}
+	}
+
+	if tracerProvider != nil {
+		flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if flushErr := tracerProvider.Shutdown(flushCtx); flushErr != nil {
+			log.WithError(flushErr).Warning("Unable to flush the traces")
+		}
+	}
+	return err
+}
+
+// newGreetingServer returns the greeting server of the settings and of the flags, serving the metrics
+// unless they are served apart.
+func newGreetingServer(cliCtx *cli.Context, current serverSettings, hostname string, auth *greeting.Auth) *greeting.Server {
+	return greeting.New(
+		greeting.WithName(current.name),
+		greeting.WithTranslations(current.translations),
+		greeting.WithHostname(hostname),
+		greeting.WithShowHost(cliCtx.Bool("show-host")),
+		greeting.WithAuth(auth),
+		greeting.WithAdminToken(cliCtx.String("admin-token")),
+		greeting.WithMetrics(cliCtx.String("metrics-bind") == ""),
+		greeting.WithUI(!cliCtx.Bool("no-ui")),
+		greeting.WithStatsToken(cliCtx.String("stats-token")),
+		greeting.WithStreamInterval(cliCtx.Duration("stream-interval")),
+		greeting.WithMaxStreams(cliCtx.Int("max-streams")),
+	)
+}
+
+// newHTTPServer returns the HTTP server of the handler, with the timeouts and the limits of the flags.
+func newHTTPServer(cliCtx *cli.Context, handler http.Handler, tlsConfig *tls.Config, stats *greeting.Stats) *http.Server {
+	httpServer := &http.Server{
+		Addr:              cliCtx.String("bind"),
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       cliCtx.Duration("read-timeout"),
+		ReadHeaderTimeout: cliCtx.Duration("read-header-timeout"),
+		WriteTimeout:      cliCtx.Duration("write-timeout"),
+		IdleTimeout:       cliCtx.Duration("idle-timeout"),
+		MaxHeaderBytes:    cliCtx.Int("max-header-bytes"),
+		ConnState:         countConnections(stats),
+	}
+	if cliCtx.Bool("disable-keepalive") {
+		httpServer.SetKeepAlivesEnabled(false)
+	}
+	return httpServer
+}
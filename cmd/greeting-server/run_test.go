@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	cli "github.com/urfave/cli/v2"
+)
+
+// withFlags runs the action with the context of the command line of the server given the arguments.
+func withFlags(t *testing.T, args []string, action func(*cli.Context) error) {
+	t.Helper()
+	app := newApp()
+	app.Before = nil
+	app.Action = action
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	if err := app.Run(append([]string{"greeting-server"}, args...)); err != nil {
+		t.Fatalf("run %v: %v", args, err)
+	}
+}
+
+// httpSettings are the fields of the HTTP server set by the flags.
+type httpSettings struct {
+	addr                                         string
+	readTimeout, readHeaderTimeout, writeTimeout time.Duration
+	idleTimeout                                  time.Duration
+	maxHeaderBytes                               int
+}
+
+func httpSettingsOf(server *http.Server) httpSettings {
+	return httpSettings{server.Addr, server.ReadTimeout, server.ReadHeaderTimeout, server.WriteTimeout, server.IdleTimeout, server.MaxHeaderBytes}
+}
+
+func TestNewHTTPServer(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want httpSettings
+	}{
+		{
+			name: "defaults",
+			want: httpSettings{":80", 10 * time.Second, 5 * time.Second, 10 * time.Second, time.Minute, http.DefaultMaxHeaderBytes},
+		},
+		{
+			name: "flags",
+			args: []string{"--bind", "127.0.0.1:8080", "--read-timeout", "3s", "--read-header-timeout", "1s",
+				"--write-timeout", "4s", "--idle-timeout", "30s", "--max-header-bytes", "4096"},
+			want: httpSettings{"127.0.0.1:8080", 3 * time.Second, time.Second, 4 * time.Second, 30 * time.Second, 4096},
+		},
+		{
+			name: "no timeouts",
+			args: []string{"--read-timeout", "0", "--read-header-timeout", "0", "--write-timeout", "0", "--idle-timeout", "0", "--max-header-bytes", "0"},
+			want: httpSettings{addr: ":80"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+			withFlags(t, test.args, func(cliCtx *cli.Context) error {
+				got := newHTTPServer(cliCtx, http.NotFoundHandler(), tlsConfig, &greeting.Stats{})
+
+				if settings := httpSettingsOf(got); settings != test.want {
+					t.Errorf("server settings = %+v, want %+v", settings, test.want)
+				}
+				if got.TLSConfig != tlsConfig || got.Handler == nil || got.ConnState == nil {
+					t.Error("server misses the TLS configuration, the handler or the connection counter")
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestNewHTTPServerKeepAlive(t *testing.T) {
+	for _, disabled := range []bool{false, true} {
+		var args []string
+		if disabled {
+			args = []string{"--disable-keepalive"}
+		}
+		withFlags(t, args, func(cliCtx *cli.Context) error {
+			httpServer := newHTTPServer(cliCtx, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil, &greeting.Stats{})
+			server := httptest.NewUnstartedServer(httpServer.Handler)
+			server.Config = httpServer
+			server.Start()
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.Close != disabled {
+				t.Errorf("--disable-keepalive=%t: connection closed %t", disabled, resp.Close)
+			}
+			return nil
+		})
+	}
+}
+
+func TestNewGreetingServerUI(t *testing.T) {
+	for _, disabled := range []bool{false, true} {
+		var args []string
+		if disabled {
+			args = []string{"--no-ui"}
+		}
+		withFlags(t, args, func(cliCtx *cli.Context) error {
+			current, err := loadSettings(cliCtx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := newGreetingServer(cliCtx, current, "pod-1", nil)
+
+			want := map[string]int{"/": http.StatusOK, "/unknown": http.StatusNotFound, "/greet": http.StatusOK}
+			if disabled {
+				want["/"] = http.StatusNotFound
+			}
+			for path, code := range want {
+				rw := httptest.NewRecorder()
+				server.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+				if rw.Code != code {
+					t.Errorf("--no-ui=%t: GET %s = %d, want %d", disabled, path, rw.Code, code)
+				}
+			}
+			return nil
+		})
+	}
+}
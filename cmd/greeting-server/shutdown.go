@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+	log "github.com/sirupsen/logrus"
+)
+
+// serve runs the server on the listener until the context is done, then fails the readiness probe and keeps
+// serving for the delay, before draining the in-flight requests within the timeout.
+// The remaining connections are closed once the timeout expires, and reported as an error. The HTTP/2
+// cleartext requests, nil without h2c, are drained along with the others.
+func serve(ctx context.Context, server *http.Server, h2cInFlight *h2cRequests, listener net.Listener, readiness *greeting.Readiness, shutdownDelay, shutdownTimeout time.Duration) error {
+	served := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			// The certificate is given by the TLS configuration.
+			served <- server.ServeTLS(listener, "", "")
+			return
+		}
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	readiness.ShutDown()
+	if shutdownDelay > 0 {
+		log.WithField("delay", shutdownDelay).WithField("drain_timeout", shutdownTimeout).Info("Shutting down, failing the readiness probe while the endpoints are updated")
+		select {
+		case err := <-served:
+			return err
+		case <-time.After(shutdownDelay):
+		}
+	}
+
+	log.WithField("timeout", shutdownTimeout).Info("Draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return errors.Join(fmt.Errorf("drain in-flight requests: %w", err), server.Close())
+	}
+	if err := h2cInFlight.wait(shutdownCtx); err != nil {
+		return fmt.Errorf("drain in-flight requests: %w", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	log.Info("Server stopped")
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+)
+
+// startServe serves the handler with serve on an httptest listener, returning its URL and the result of serve.
+func startServe(t *testing.T, ctx context.Context, handler http.Handler, readiness *greeting.Readiness, shutdownDelay, shutdownTimeout time.Duration) (string, <-chan error) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(handler)
+	t.Cleanup(func() { server.Config.Close() })
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, server.Config, nil, server.Listener, readiness, shutdownDelay, shutdownTimeout)
+	}()
+	return "http://" + server.Listener.Addr().String(), served
+}
+
+// drainClient opens a connection per request, the connections dialed ahead by a pooling client and left unused
+// holding the drain for 5 seconds.
+var drainClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+// slowHandler answers once released, telling when each request started.
+func slowHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		<-release
+		io.WriteString(rw, "done")
+	})
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	readiness := &greeting.Readiness{}
+	mux := http.NewServeMux()
+	mux.Handle("/slow", slowHandler(started, release))
+	mux.HandleFunc("/ready", readiness.HandleReady)
+	mux.HandleFunc("/fast", func(rw http.ResponseWriter, req *http.Request) { io.WriteString(rw, "fast") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, served := startServe(t, ctx, mux, readiness, 200*time.Millisecond, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := drainClient.Get(url + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{string(body), err}
+	}()
+	<-started
+
+	cancel()
+	// During the shutdown delay, the readiness probe fails while the requests are still served.
+	time.Sleep(50 * time.Millisecond)
+	resp, err := drainClient.Get(url + "/ready")
+	if err != nil {
+		t.Fatalf("readiness during the shutdown delay: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("readiness during the shutdown delay = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	resp, err = drainClient.Get(url + "/fast")
+	if err != nil {
+		t.Fatalf("request during the shutdown delay: %v", err)
+	}
+	resp.Body.Close()
+
+	// Released once the drain started, the in-flight request still completes.
+	time.Sleep(300 * time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned before the in-flight request completed: %v", err)
+	default:
+	}
+	close(release)
+
+	if r := <-slow; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request = %q, %v, want it completed", r.body, r.err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("serve: %v", err)
+	}
+}
+
+func TestServeDrainTimeout(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url, served := startServe(t, ctx, slowHandler(started, release), &greeting.Readiness{}, 0, 100*time.Millisecond)
+
+	go func() {
+		if resp, err := drainClient.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-served:
+		if err == nil || !strings.Contains(err.Error(), "drain in-flight requests") {
+			t.Errorf("serve = %v, want the drain timeout reported", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve still draining after its timeout")
+	}
+}
+
+func TestServeListenerClosed(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Listener.Close()
+
+	err := serve(context.Background(), server.Config, nil, server.Listener, &greeting.Readiness{}, 0, time.Second)
+	if err == nil {
+		t.Error("serve on a closed listener succeeded")
+	}
+}
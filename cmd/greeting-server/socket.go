@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unixScheme prefixes the binding addresses of Unix domain sockets.
+const unixScheme = "unix://"
+
+// socketPath returns the path of the Unix domain socket of the binding address, empty for a TCP address.
+func socketPath(addr string) string {
+	path, _ := strings.CutPrefix(addr, unixScheme)
+	if path == addr {
+		return ""
+	}
+	return path
+}
+
+// parseSocketMode parses the permissions of the socket file, in octal such as 0660.
+func parseSocketMode(mode string) (fs.FileMode, error) {
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0o777 {
+		return 0, fmt.Errorf("socket mode %q: expected octal permissions such as 0660", mode)
+	}
+	return fs.FileMode(perm), nil
+}
+
+// listen opens the listener of the binding address, a Unix domain socket of the mode for unix:// addresses
+// and TCP otherwise. A stale socket file left by a previous run is removed first, and the socket file is
+// removed once the listener is closed.
+func listen(addr string, mode fs.FileMode) (net.Listener, error) {
+	path := socketPath(addr)
+	if path == "" {
+		return net.Listen("tcp", addr)
+	}
+
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("stat socket: %w", err)
+	case info.Mode().Type() != fs.ModeSocket:
+		return nil, fmt.Errorf("%s exists and is not a socket", path)
+	default:
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, mode); err != nil {
+		return nil, errors.Join(fmt.Errorf("set socket mode: %w", err), listener.Close())
+	}
+	return listener, nil
+}
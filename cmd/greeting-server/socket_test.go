@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSocketPath(t *testing.T) {
+	for addr, want := range map[string]string{
+		":8080":                     "",
+		"127.0.0.1:8080":            "",
+		"unix:///run/greeting.sock": "/run/greeting.sock",
+		"unix://greeting.sock":      "greeting.sock",
+	} {
+		if path := socketPath(addr); path != want {
+			t.Errorf("socketPath(%q) = %q, want %q", addr, path, want)
+		}
+	}
+}
+
+func TestParseSocketMode(t *testing.T) {
+	for mode, want := range map[string]fs.FileMode{"0660": 0o660, "600": 0o600, "0777": 0o777} {
+		if got, err := parseSocketMode(mode); err != nil || got != want {
+			t.Errorf("parseSocketMode(%q) = %v, %v, want %v", mode, got, err, want)
+		}
+	}
+	for _, mode := range []string{"", "0800", "1777", "rw-rw----"} {
+		if _, err := parseSocketMode(mode); err == nil {
+			t.Errorf("parseSocketMode(%q) accepted", mode)
+		}
+	}
+}
+
+// unixClient returns a HTTP client dialing the Unix domain socket, whatever the host of the URL.
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greeting.sock")
+
+	// A socket file left behind by a crashed run is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listen(unixScheme+path, 0o600)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Type() != fs.ModeSocket || info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket file = %v, %v, want a socket of mode 0600", info, err)
+	}
+
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		io.WriteString(rw, "over the socket")
+	})}
+	served := make(chan error, 1)
+	go func() { served <- httpServer.Serve(listener) }()
+
+	client := unixClient(path)
+	resp, err := client.Get("http://greeting/greet")
+	if err != nil {
+		t.Fatalf("GET over the socket: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "over the socket" {
+		t.Errorf("body = %q", body)
+	}
+
+	client.CloseIdleConnections()
+	httpServer.Close()
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve() = %v", err)
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("socket file left once closed: %v", err)
+	}
+}
+
+func TestListenUnixNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greeting.sock")
+	writeFile(t, path, "not a socket")
+
+	if _, err := listen(unixScheme+path, 0o600); err == nil || !strings.Contains(err.Error(), "is not a socket") {
+		t.Errorf("listen() = %v, want the regular file refused", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("regular file removed: %v", err)
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+	listener, err := listen("127.0.0.1:0", 0o600)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	if network := listener.Addr().Network(); network != "tcp" {
+		t.Errorf("network = %s, want tcp", network)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"edb-challenge/pkg/greeting"
+)
+
+// startupHandler answers 503 to the greetings until the startup delay of the readiness elapsed, then delays
+// every greeting by the response delay. The handler is returned as is without delay.
+func startupHandler(handler http.Handler, readiness *greeting.Readiness, startupDelay, responseDelay time.Duration) http.Handler {
+	if startupDelay <= 0 && responseDelay <= 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !isGreetPath(req.URL.Path) {
+			handler.ServeHTTP(rw, req)
+			return
+		}
+
+		if left := readiness.StartingFor(); left > 0 {
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(left.Seconds()))))
+			greeting.Error(rw, req, http.StatusServiceUnavailable, "server starting")
+			return
+		}
+
+		if responseDelay > 0 {
+			select {
+			case <-time.After(responseDelay):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
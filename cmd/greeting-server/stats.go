@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+
+	"edb-challenge/pkg/greeting"
+)
+
+// countRequests records every request served by the handler in the stats, by route, with the status and
+// the size of its answer.
+func countRequests(handler http.Handler, stats *greeting.Stats, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		recorder := newResponseRecorder(rw)
+		handler.ServeHTTP(recorder, req)
+
+		path := route(req)
+		if path == "" {
+			path = "unmatched"
+		}
+		stats.Record(path, recorder.status, recorder.bytes)
+	})
+}
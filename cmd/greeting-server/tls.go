@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// certReloader serves the certificate of the files, reloaded whenever they change so that rotated
+// certificates are picked up without restarting.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+// newCertReloader loads the certificate of the files, failing when they cannot be loaded.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the certificate again when its files were modified since the last load.
+func (r *certReloader) reload() (*tls.Certificate, error) {
+	var modTimes [2]time.Time
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("stat TLS file: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && modTimes == r.modTimes {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	if r.cert != nil {
+		log.WithField("cert", r.certFile).Info("Reloaded TLS certificate")
+	}
+	r.cert, r.modTimes = &cert, modTimes
+	return r.cert, nil
+}
+
+// GetCertificate returns the current certificate, keeping the previous one when the files cannot be reloaded,
+// for instance while being rotated.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := r.reload()
+	if err != nil {
+		log.WithError(err).Warning("Unable to reload the TLS certificate, serving the previous one")
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.cert, nil
+	}
+	return cert, nil
+}
+
+// newTLSConfig returns the TLS configuration serving the certificate files and their reloader, nil
+// without certificate. With a client CA, the clients must present a certificate signed by it.
+func newTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, *certReloader, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return nil, nil, errors.New("--tls-client-ca requires --tls-cert and --tls-key")
+		}
+		return nil, nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, nil, errors.New("--tls-cert and --tls-key must be given together")
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read TLS client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificate found in TLS client CA %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, reloader, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"edb-challenge/pkg/reqid"
+	"edb-challenge/pkg/version"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingConfigured tells whether an OTLP endpoint is given, by flag or by the standard environment variables.
+func tracingConfigured(endpoint string) bool {
+	return endpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// newTracerProvider returns the provider exporting the spans over OTLP/HTTP to the endpoint, an URL such as
+// http://tempo:4318, or to the one of the OTEL_* environment variables when empty.
+func newTracerProvider(ctx context.Context, endpoint string) (*sdktrace.TracerProvider, error) {
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("OTLP endpoint %q must be an URL such as http://tempo:4318", endpoint)
+		}
+		opts = append(opts, otlptracehttp.WithEndpoint(u.Host))
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if path := strings.TrimSuffix(u.Path, "/"); path != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(path))
+		}
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP trace exporter: %w", err)
+	}
+
+	// The OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES variables override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName("greeting-server"), semconv.ServiceVersion(version.Version)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.WithError(err).Warning("Unable to export traces")
+	}))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res)), nil
+}
+
+// traceHandler serves the requests of the handler within a server span, child of the span of the
+// incoming traceparent header. The spans are named after the route pattern of the request.
+func traceHandler(handler http.Handler, route func(*http.Request) string, tracer trace.Tracer) http.Handler {
+	propagator := otel.GetTextMapPropagator()
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		pattern := route(req)
+		if pattern == "" {
+			pattern = "unmatched"
+		}
+
+		ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer.Start(ctx, req.Method+" "+pattern,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(req.Method),
+				semconv.HTTPRoute(pattern),
+				semconv.URLPath(req.URL.Path),
+				attribute.String("http.request_id", reqid.From(req.Context())),
+			),
+		)
+		defer span.End()
+
+		recorder := newResponseRecorder(rw)
+		handler.ServeHTTP(recorder, req.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPStatusCode(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchSettle is the time the events of the watched directories must settle before the files are read again,
+// as a ConfigMap update comes as a burst of them.
+const watchSettle = 100 * time.Millisecond
+
+// watchFiles calls reload whenever the content of one of the files changes, until the context is done.
+// A failed reload keeps the previous configuration, and is tried again on the next change.
+//
+// The directories of the files are watched rather than the files: Kubernetes updates a mounted ConfigMap
+// by swapping the symbolic link of its directory at once, leaving the files it replaces untouched.
+func watchFiles(ctx context.Context, paths []string, reload func() error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watch files: %w", err)
+	}
+	contents := map[string]string{}
+	for _, path := range paths {
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return fmt.Errorf("watch directory of %s: %w", path, err)
+		}
+		contents[path] = readContent(path)
+	}
+
+	go func() {
+		defer watcher.Close()
+		var settled <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				settled = time.After(watchSettle)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.WithError(err).Warning("Unable to watch the files")
+			case <-settled:
+				settled = nil
+				reloadChanged(contents, reload)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadChanged calls reload when the content of one of the files differs from the one last applied,
+// logging the previous and the new values of the files once applied.
+func reloadChanged(contents map[string]string, reload func() error) {
+	changed := map[string]string{}
+	for path, previous := range contents {
+		if current := readContent(path); current != previous {
+			changed[path] = current
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	if err := reload(); err != nil {
+		log.WithError(err).Error("Unable to reload the changed files, keeping the previous configuration")
+		return
+	}
+	for path, current := range changed {
+		log.WithField("file", path).
+			WithField("previous", strings.TrimSpace(contents[path])).
+			WithField("value", strings.TrimSpace(current)).
+			Info("Reloaded the changed file")
+		contents[path] = current
+	}
+}
+
+// readContent returns the content of the file, empty when it cannot be read: the reload tells why.
+func readContent(path string) string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
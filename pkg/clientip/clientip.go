@@ -0,0 +1,189 @@
+// Package clientip resolves the IP of the clients of the HTTP requests, taken from the header of the proxies
+// in front of the server when they are trusted, so that the logs and the rate limits tell the clients apart.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// The headers the proxies tell the client IP with.
+const (
+	XForwardedFor = "X-Forwarded-For"
+	Forwarded     = "Forwarded"
+)
+
+type contextKey struct{}
+
+// Resolver takes the client IP from the header of the proxies, when the peer of the request is one of them.
+// A nil resolver trusts no proxy, the client IP being the one of the peer.
+type Resolver struct {
+	header  string
+	trusted []netip.Prefix
+}
+
+// NewResolver returns the resolver of the client IP told by the header, X-Forwarded-For or Forwarded, of the
+// proxies in the trusted ranges, given as CIDRs or single IPs.
+//
+// Without ranges, every peer is trusted as the proxy in front of the server, and the client IP is the last
+// address of the header, the one it appended. The proxies beyond it, if any, cannot be told from the client.
+func NewResolver(header string, trusted []string) (*Resolver, error) {
+	header = http.CanonicalHeaderKey(header)
+	if header != XForwardedFor && header != Forwarded {
+		return nil, fmt.Errorf("proxy header %s: expected %s or %s", header, XForwardedFor, Forwarded)
+	}
+
+	r := &Resolver{header: header}
+	for _, cidr := range trusted {
+		prefix, err := parsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		r.trusted = append(r.trusted, prefix)
+	}
+	return r, nil
+}
+
+// Resolve returns the IP of the client of the request. The addresses of the header are walked from the
+// right, appended by the closest proxy, and the first one out of the trusted ranges is the client: the ones
+// on its left were given by the client itself, and may be spoofed. The header is ignored altogether when the
+// peer is not trusted, as anyone could send it.
+func (r *Resolver) Resolve(req *http.Request) string {
+	peer := peerAddr(req.RemoteAddr)
+	if !r.trustsPeer(peer) {
+		return addrString(peer, req.RemoteAddr)
+	}
+
+	client := peer
+	hops := r.hops(req.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := parseHop(hops[i])
+		if err != nil {
+			// An unknown or obfuscated address hides the ones on its left, the last trusted one is kept.
+			break
+		}
+		client = hop
+		if !r.trusts(hop) {
+			break
+		}
+	}
+	return addrString(client, req.RemoteAddr)
+}
+
+// trustsPeer tells whether the peer is a trusted proxy, every peer being one without ranges, the ones of a
+// Unix domain socket included.
+func (r *Resolver) trustsPeer(peer netip.Addr) bool {
+	return r != nil && (len(r.trusted) == 0 || r.trusts(peer))
+}
+
+// trusts tells whether the address belongs to the trusted ranges.
+func (r *Resolver) trusts(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// hops returns the addresses told by the header, from the furthest to the closest, the repeated headers
+// being joined in order.
+func (r *Resolver) hops(header http.Header) []string {
+	var hops []string
+	for _, value := range header.Values(r.header) {
+		for _, element := range strings.Split(value, ",") {
+			if r.header == XForwardedFor {
+				hops = append(hops, strings.TrimSpace(element))
+				continue
+			}
+			// Only the for parameter of the Forwarded elements tells the client, such as for="[2001:db8::1]:80".
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(key, "for") {
+					hop = strings.Trim(value, `"`)
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// Handler serves the requests with the IP of their client in the context, as resolved by the resolver.
+func Handler(handler http.Handler, resolver *Resolver) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		handler.ServeHTTP(rw, req.WithContext(WithIP(req.Context(), resolver.Resolve(req))))
+	})
+}
+
+// WithIP returns a copy of the context holding the client IP.
+func WithIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// Of returns the client IP of the request held by its context, the address of the peer when none.
+func Of(req *http.Request) string {
+	if ip, ok := req.Context().Value(contextKey{}).(string); ok {
+		return ip
+	}
+	return addrString(peerAddr(req.RemoteAddr), req.RemoteAddr)
+}
+
+// parsePrefix parses a CIDR, or a single IP as the range of its own.
+func parsePrefix(cidr string) (netip.Prefix, error) {
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if prefix.Addr().Is4In6() {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+// parseHop parses an address of the header, with or without port, IPv6 ones being possibly bracketed.
+func parseHop(hop string) (netip.Addr, error) {
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap().WithZone(""), nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap().WithZone(""), nil
+}
+
+// peerAddr returns the IP of the peer address of a request, invalid when it has none, such as over a
+// Unix domain socket.
+func peerAddr(remoteAddr string) netip.Addr {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap().WithZone("")
+}
+
+// addrString returns the address, the remote address as is when invalid.
+func addrString(addr netip.Addr, remoteAddr string) string {
+	if !addr.IsValid() {
+		return remoteAddr
+	}
+	return addr.String()
+}
@@ -0,0 +1,154 @@
+package clientip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewResolver(t *testing.T) {
+	tests := []struct {
+		header  string
+		trusted []string
+		wantErr bool
+	}{
+		{header: "X-Forwarded-For"},
+		{header: "x-forwarded-for", trusted: []string{"10.0.0.0/8", " 192.168.1.1 ", "2001:db8::/32"}},
+		{header: "Forwarded", trusted: []string{"::ffff:10.0.0.0/104"}},
+		{header: "X-Real-IP", wantErr: true},
+		{header: "X-Forwarded-For", trusted: []string{"10.0.0.0/33"}, wantErr: true},
+		{header: "X-Forwarded-For", trusted: []string{"proxy.example.com"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		if _, err := NewResolver(test.header, test.trusted); (err != nil) != test.wantErr {
+			t.Errorf("NewResolver(%s, %q) error = %v, want error %t", test.header, test.trusted, err, test.wantErr)
+		}
+	}
+}
+
+// resolve resolves the client IP of a request from the peer, with the values of the header.
+func resolve(t *testing.T, resolver *Resolver, header, remoteAddr string, values ...string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for _, value := range values {
+		req.Header.Add(header, value)
+	}
+	return resolver.Resolve(req)
+}
+
+func TestResolveSpoofedHeader(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "2001:db8:1::/48"}
+
+	tests := []struct {
+		name   string
+		header string
+		// sentHeader carries the values, the header of the resolver when empty.
+		sentHeader string
+		remoteAddr string
+		values     []string
+		want       string
+	}{
+		// The clients reaching the server directly cannot pass for another one.
+		{name: "untrusted peer", header: XForwardedFor, remoteAddr: "203.0.113.7:4242", values: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "untrusted peer claiming a proxy", header: XForwardedFor, remoteAddr: "203.0.113.7:4242", values: []string{"198.51.100.1, 10.0.0.1"}, want: "203.0.113.7"},
+		{name: "untrusted peer repeating the header", header: XForwardedFor, remoteAddr: "203.0.113.7:4242", values: []string{"198.51.100.1", "10.0.0.1"}, want: "203.0.113.7"},
+		{name: "untrusted IPv6 peer", header: XForwardedFor, remoteAddr: "[2001:db8:2::7]:4242", values: []string{"198.51.100.1"}, want: "2001:db8:2::7"},
+		{name: "untrusted IPv4-mapped peer", header: XForwardedFor, remoteAddr: "[::ffff:203.0.113.7]:4242", values: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "untrusted peer with forwarded", header: Forwarded, remoteAddr: "203.0.113.7:4242", values: []string{"for=198.51.100.1"}, want: "203.0.113.7"},
+		{name: "untrusted unix socket peer", header: XForwardedFor, remoteAddr: "@", values: []string{"198.51.100.1"}, want: "@"},
+
+		// Behind a trusted proxy, the addresses given by the client on the left of its own are ignored.
+		{name: "trusted proxy", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", values: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "client prepending an address", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", values: []string{"6.6.6.6, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "client prepending a trusted address", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", values: []string{"10.0.0.66, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "chain of trusted proxies", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", values: []string{"6.6.6.6, 198.51.100.1, 10.1.0.1", "10.2.0.1"}, want: "198.51.100.1"},
+		{name: "only trusted hops", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", values: []string{"10.1.0.1, 10.2.0.1"}, want: "10.1.0.1"},
+		{name: "obfuscated hop", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", values: []string{"6.6.6.6, unknown, 10.2.0.1"}, want: "10.2.0.1"},
+		{name: "empty header", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", values: []string{""}, want: "10.0.0.1"},
+		{name: "no header", header: XForwardedFor, remoteAddr: "10.0.0.1:4242", want: "10.0.0.1"},
+		{name: "trusted IPv6 proxy", header: XForwardedFor, remoteAddr: "[2001:db8:1::1]:4242", values: []string{"[2001:db8:2::7]:80"}, want: "2001:db8:2::7"},
+		{
+			name:       "forwarded",
+			header:     Forwarded,
+			remoteAddr: "10.0.0.1:4242",
+			values:     []string{`for=6.6.6.6, for="[2001:db8:2::7]:80";proto=https;by=10.0.0.1`},
+			want:       "2001:db8:2::7",
+		},
+		{name: "forwarded without for", header: Forwarded, remoteAddr: "10.0.0.1:4242", values: []string{"proto=https"}, want: "10.0.0.1"},
+		// The resolver only reads its own header, the other one is left to the client.
+		{name: "other header", header: XForwardedFor, sentHeader: Forwarded, remoteAddr: "10.0.0.1:4242", values: []string{"for=6.6.6.6"}, want: "10.0.0.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolver, err := NewResolver(test.header, trusted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sentHeader := test.sentHeader
+			if sentHeader == "" {
+				sentHeader = test.header
+			}
+			if ip := resolve(t, resolver, sentHeader, test.remoteAddr, test.values...); ip != test.want {
+				t.Errorf("Resolve() = %s, want %s", ip, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveWithoutRanges(t *testing.T) {
+	resolver, err := NewResolver(XForwardedFor, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every peer is the proxy in front of the server, and only the address it appended is believed.
+	if ip := resolve(t, resolver, XForwardedFor, "203.0.113.7:4242", "6.6.6.6, 198.51.100.1"); ip != "198.51.100.1" {
+		t.Errorf("Resolve() = %s, want the last address", ip)
+	}
+	if ip := resolve(t, resolver, XForwardedFor, "@", "198.51.100.1"); ip != "198.51.100.1" {
+		t.Errorf("Resolve() over a Unix domain socket = %s, want the address of the proxy", ip)
+	}
+}
+
+func TestResolveNil(t *testing.T) {
+	var resolver *Resolver
+	if ip := resolve(t, resolver, XForwardedFor, "203.0.113.7:4242", "198.51.100.1"); ip != "203.0.113.7" {
+		t.Errorf("Resolve() without resolver = %s, want the peer", ip)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	resolver, err := NewResolver(XForwardedFor, []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen string
+	handler := Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		seen = Of(req)
+	}), resolver)
+
+	for remoteAddr, want := range map[string]string{"10.0.0.1:4242": "198.51.100.1", "203.0.113.7:4242": "203.0.113.7"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(XForwardedFor, "198.51.100.1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if seen != want {
+			t.Errorf("client IP from %s = %s, want %s", remoteAddr, seen, want)
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[::ffff:203.0.113.7]:4242"
+	req.Header.Set(XForwardedFor, "198.51.100.1")
+	if ip := Of(req); ip != "203.0.113.7" {
+		t.Errorf("Of() without resolved IP = %s, want the peer", ip)
+	}
+	if ip := Of(req.WithContext(WithIP(context.Background(), "198.51.100.1"))); ip != "198.51.100.1" {
+		t.Errorf("Of() = %s, want the resolved IP", ip)
+	}
+}
@@ -0,0 +1,97 @@
+package greeting
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"edb-challenge/pkg/clientip"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Auth holds the credentials accepted on /greet, a bearer token and or a basic auth user.
+type Auth struct {
+	token string
+	user  string
+	hash  []byte
+}
+
+// NewAuth returns the credentials accepted on /greet, nil when it is left open.
+// The token is given either as is or as a file, such as a mounted Secret, and the basic auth as user:bcrypt-hash.
+func NewAuth(token, tokenFile, basicAuth string) (*Auth, error) {
+	if token != "" && tokenFile != "" {
+		return nil, errors.New("auth token given both as is and as a file")
+	}
+	if tokenFile != "" {
+		raw, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read auth token file: %w", err)
+		}
+		if token = strings.TrimSpace(string(raw)); token == "" {
+			return nil, fmt.Errorf("auth token file %s is empty", tokenFile)
+		}
+	}
+
+	auth := &Auth{token: token}
+	if basicAuth != "" {
+		user, hash, found := strings.Cut(basicAuth, ":")
+		if !found || user == "" {
+			return nil, errors.New("basic auth: expected user:bcrypt-hash")
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("basic auth: invalid bcrypt hash: %w", err)
+		}
+		auth.user, auth.hash = user, []byte(hash)
+	}
+
+	if auth.token == "" && auth.user == "" {
+		return nil, nil
+	}
+	return auth, nil
+}
+
+// Allows tells whether the request bears the token or the basic auth credentials.
+func (a *Auth) Allows(req *http.Request) bool {
+	if bearer, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok && a.token != "" {
+		return subtle.ConstantTimeCompare([]byte(bearer), []byte(a.token)) == 1
+	}
+	if user, password, ok := req.BasicAuth(); ok && a.user != "" {
+		// The password is checked whatever the user, so that the user cannot be guessed from the timing.
+		matches := bcrypt.CompareHashAndPassword(a.hash, []byte(password)) == nil
+		return subtle.ConstantTimeCompare([]byte(user), []byte(a.user)) == 1 && matches
+	}
+	return false
+}
+
+// challenges returns the WWW-Authenticate challenges of the accepted schemes.
+func (a *Auth) challenges() []string {
+	var challenges []string
+	if a.token != "" {
+		challenges = append(challenges, "Bearer")
+	}
+	if a.user != "" {
+		challenges = append(challenges, `Basic realm="greeting", charset="UTF-8"`)
+	}
+	return challenges
+}
+
+// requireAuth serves the handler only to the requests bearing the credentials, when any are given.
+func requireAuth(auth *Auth, handler http.Handler) http.Handler {
+	if auth == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !auth.Allows(req) {
+			for _, challenge := range auth.challenges() {
+				rw.Header().Add("WWW-Authenticate", challenge)
+			}
+			RequestLog(req).WithField("client_ip", clientip.Of(req)).Debug("Unauthorized greeting")
+			Error(rw, req, http.StatusUnauthorized, "invalid or missing credentials")
+			return
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,45 @@
+package greeting
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxNameLength is the maximum length, in characters, of the names of the caller and of the server.
+const maxNameLength = 100
+
+// errCallerNotFound is returned for a path below /greet/ which is not a single name.
+var errCallerNotFound = errors.New("not found")
+
+// callerName returns the name of the caller to greet, given by the /greet/{name} path or the name query
+// parameter, empty when not given. Names too long or holding control characters are rejected, so that
+// they do not end up in the logs nor in the answer.
+func callerName(req *http.Request) (string, error) {
+	caller := req.URL.Query().Get("name")
+	if rest, ok := strings.CutPrefix(req.URL.Path, "/greet/"); ok && rest != "" {
+		if strings.Contains(rest, "/") {
+			return "", errCallerNotFound
+		}
+		caller = rest
+	}
+
+	if err := ValidateName(caller); err != nil {
+		return "", err
+	}
+	return caller, nil
+}
+
+// ValidateName rejects the names too long or holding control characters.
+func ValidateName(name string) error {
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return fmt.Errorf("name longer than %d characters", maxNameLength)
+	}
+	if !utf8.ValidString(name) || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return errors.New("name holds invalid characters")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package greeting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCallerName(t *testing.T) {
+	tests := []struct {
+		name, target string
+		want         string
+		err          string
+	}{
+		{name: "none", target: "/greet"},
+		{name: "query", target: "/greet?name=Ada", want: "Ada"},
+		{name: "path", target: "/greet/Ada", want: "Ada"},
+		{name: "escaped path", target: "/greet/Ada%20Lovelace", want: "Ada Lovelace"},
+		{name: "unicode", target: "/greet?name=%C3%89lodie", want: "Élodie"},
+		{name: "path over query", target: "/greet/Ada?name=Grace", want: "Ada"},
+		{name: "trailing slash", target: "/greet/?name=Grace", want: "Grace"},
+		{name: "nested path", target: "/greet/Ada/Lovelace", err: errCallerNotFound.Error()},
+		{name: "longest", target: "/greet?name=" + strings.Repeat("é", maxNameLength), want: strings.Repeat("é", maxNameLength)},
+		{name: "too long", target: "/greet?name=" + strings.Repeat("a", maxNameLength+1), err: "name longer than 100 characters"},
+		{name: "newline", target: "/greet?name=Ada%0AINFO%20forged", err: "name holds invalid characters"},
+		{name: "escape in path", target: "/greet/Ada%1B%5B31m", err: "name holds invalid characters"},
+		{name: "invalid UTF-8", target: "/greet?name=%FF", err: "name holds invalid characters"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			caller, err := callerName(httptest.NewRequest(http.MethodGet, test.target, nil))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("callerName(%s) = %q, %v, want error %q", test.target, caller, err, test.err)
+				}
+				return
+			}
+			if err != nil || caller != test.want {
+				t.Errorf("callerName(%s) = %q, %v, want %q", test.target, caller, err, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleGreetCaller(t *testing.T) {
+	server := New(WithName("Greeter"))
+
+	tests := []struct {
+		target   string
+		wantCode int
+		want     string
+	}{
+		{"/greet?name=Ada", http.StatusOK, "Hello Ada, "},
+		{"/greet/Ada", http.StatusOK, "Hello Ada, "},
+		{"/greet", http.StatusOK, "Greeter"},
+		{"/greet?name=" + strings.Repeat("a", maxNameLength+1), http.StatusBadRequest, "name longer than 100 characters"},
+		{"/greet/Ada%0A", http.StatusBadRequest, "name holds invalid characters"},
+		{"/greet?name=%07", http.StatusBadRequest, "name holds invalid characters"},
+		{"/greet/Ada/Lovelace", http.StatusNotFound, "no such path /greet/Ada/Lovelace"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.target, nil)
+		req.Header.Set("Accept", "text/plain")
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, req)
+
+		if rw.Code != test.wantCode || !strings.Contains(rw.Body.String(), test.want) {
+			t.Errorf("GET %s = %d %q, want %d with %q", test.target, rw.Code, rw.Body.String(), test.wantCode, test.want)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	if err := ValidateName(""); err != nil {
+		t.Errorf("ValidateName(\"\") = %v, want the empty name accepted", err)
+	}
+	if err := ValidateName("Ada\tLovelace"); err == nil {
+		t.Error("ValidateName accepted a tab")
+	}
+	if err := ValidateName("Ada Lovelace"); err != nil {
+		t.Errorf("ValidateName(Ada Lovelace) = %v", err)
+	}
+}
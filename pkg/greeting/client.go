@@ -0,0 +1,131 @@
+package greeting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"edb-challenge/pkg/version"
+)
+
+// maxAnswerBody is the maximum size of the answers read by the client.
+const maxAnswerBody = 1 << 20
+
+// Client calls the endpoints of a greeting server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// ClientOption configures the client built by NewClient.
+type ClientOption func(*Client)
+
+// WithHTTPClient sends the requests with the HTTP client, such as one of a custom transport,
+// http.DefaultClient by default.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearerToken sends the token along the requests, as required by the greetings of the servers given credentials.
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// NewClient returns the client of the greeting server at the base URL, such as http://greeting:8080.
+func NewClient(baseURL string, opts ...ClientOption) *Client {
+	c := &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// StatusError is returned for the answers out of the 2xx range.
+type StatusError struct {
+	Path string
+	Code int
+	// Body is the message of the error answer, or its body trimmed when not the JSON error of a greeting server.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s answered %d %q", e.Path, e.Code, e.Body)
+}
+
+// Greet returns the greeting of the caller, not greeted when empty, in the language when translated.
+func (c *Client) Greet(ctx context.Context, caller, language string) (Greeting, error) {
+	query := url.Values{}
+	if caller != "" {
+		query.Set("name", caller)
+	}
+	if language != "" {
+		query.Set("lang", language)
+	}
+
+	var greeting Greeting
+	err := c.get(ctx, "/greet", query, &greeting)
+	return greeting, err
+}
+
+// Health returns the state of the server answered by its health probe.
+func (c *Client) Health(ctx context.Context) (HealthStatus, error) {
+	var status HealthStatus
+	err := c.get(ctx, "/health", nil, &status)
+	return status, err
+}
+
+// Version returns the build of the server.
+func (c *Client) Version(ctx context.Context) (version.Info, error) {
+	var info version.Info
+	err := c.get(ctx, "/version", nil, &info)
+	return info, err
+}
+
+// get decodes the JSON answer of a GET request on the path into the answer.
+func (c *Client) get(ctx context.Context, path string, query url.Values, answer interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", path, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The error already tells the method and the URL.
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAnswerBody))
+	if err != nil {
+		return fmt.Errorf("read %s answer: %w", path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		statusErr := &StatusError{Path: path, Code: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+		var envelope errorEnvelope
+		if json.Unmarshal(body, &envelope) == nil && envelope.Error.Message != "" {
+			statusErr.Body = envelope.Error.Message
+		}
+		return statusErr
+	}
+	if err = json.Unmarshal(body, answer); err != nil {
+		return fmt.Errorf("decode %s answer: %w", path, err)
+	}
+	return nil
+}
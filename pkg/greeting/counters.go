@@ -0,0 +1,43 @@
+package greeting
+
+// Counters are the lifetime counters of the server, persisted by the program serving it so that a restart
+// carries them on.
+type Counters struct {
+	// Greetings are the greetings answered, counted by {{.Count}} and greetings_served_total.
+	Greetings    uint64            `json:"greetings"`
+	Requests     uint64            `json:"requests"`
+	Errors       uint64            `json:"errors"`
+	BytesWritten uint64            `json:"bytes_written"`
+	Paths        map[string]uint64 `json:"paths,omitempty"`
+}
+
+// Counters returns the lifetime counters of the server at once.
+func (s *Server) Counters() Counters {
+	stats := s.stats.snapshot()
+	return Counters{
+		Greetings:    s.count.Load(),
+		Requests:     stats.Requests,
+		Errors:       stats.Errors,
+		BytesWritten: stats.BytesWritten,
+		Paths:        stats.Paths,
+	}
+}
+
+// RestoreCounters adds the counters of a previous run to the ones of the server, answered by /stats and
+// /metrics, before it serves.
+func (s *Server) RestoreCounters(counters Counters) {
+	s.count.Add(counters.Greetings)
+	greetingsServed.Add(float64(counters.Greetings))
+
+	s.stats.requests.Add(counters.Requests)
+	s.stats.errors.Add(counters.Errors)
+	s.stats.bytes.Add(counters.BytesWritten)
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	if s.stats.paths == nil {
+		s.stats.paths = make(map[string]uint64, len(counters.Paths))
+	}
+	for route, count := range counters.Paths {
+		s.stats.paths[route] += count
+	}
+}
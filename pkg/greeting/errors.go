@@ -0,0 +1,81 @@
+package greeting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"edb-challenge/pkg/reqid"
+)
+
+// errorEnvelope is the JSON body of the error answers.
+type errorEnvelope struct {
+	Error errorDetail `json:"error"`
+}
+
+// errorDetail tells the error, with the ID of the request to look its logs up.
+type errorDetail struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error answers the error with its status code, in place of http.Error, so that every failure of the server
+// and of its middlewares has the same shape: the {"error":{"code","message","request_id"}} JSON envelope,
+// or the message as plain text for the clients preferring text.
+// The headers describing a body, set before the failure, are dropped.
+func Error(rw http.ResponseWriter, req *http.Request, code int, message string) {
+	header := rw.Header()
+	header.Del("Content-Encoding")
+	header.Del("Content-Language")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Add("Vary", "Accept")
+
+	body := []byte(message + "\n")
+	contentType := "text/plain; charset=utf-8"
+	if errorFormat(req) == formatJSON {
+		// The envelope holds a string and an integer only, it cannot fail to marshal.
+		body, _ = json.Marshal(errorEnvelope{Error: errorDetail{Code: code, Message: message, RequestID: reqid.From(req.Context())}})
+		contentType = "application/json"
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	rw.WriteHeader(code)
+	if _, err := rw.Write(body); err != nil {
+		RequestLog(req).WithError(err).Debug("Unable to write error")
+	}
+}
+
+// errorFormat returns the format of the error answers: JSON, unless the format query parameter or the
+// Accept header asks for text over JSON.
+func errorFormat(req *http.Request) string {
+	switch req.URL.Query().Get("format") {
+	case formatJSON:
+		return formatJSON
+	case formatText:
+		return formatText
+	}
+
+	if accept := req.Header.Get("Accept"); accept != "" && acceptQuality(accept, "text/plain") > acceptQuality(accept, "application/json") {
+		return formatText
+	}
+	return formatJSON
+}
+
+// notFound answers 404 Not Found.
+func notFound(rw http.ResponseWriter, req *http.Request) {
+	Error(rw, req, http.StatusNotFound, fmt.Sprintf("no such path %s", req.URL.Path))
+}
+
+// MethodNotAllowed answers 405 Method Not Allowed, with the allowed methods.
+func MethodNotAllowed(rw http.ResponseWriter, req *http.Request, allowed ...string) {
+	rw.Header().Set("Allow", strings.Join(allowed, ", "))
+	Error(rw, req, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed, expected %s", req.Method, strings.Join(allowed, " or ")))
+}
+
+// internalError answers 500 Internal Server Error, the cause being logged rather than told to the client.
+func internalError(rw http.ResponseWriter, req *http.Request) {
+	Error(rw, req, http.StatusInternalServerError, "internal server error")
+}
@@ -0,0 +1,132 @@
+package greeting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"edb-challenge/pkg/reqid"
+)
+
+func TestError(t *testing.T) {
+	statuses := []int{
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusMethodNotAllowed,
+		http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable,
+	}
+	formats := []struct {
+		name   string
+		target string
+		accept string
+		json   bool
+	}{
+		{name: "default", target: "/greet", json: true},
+		{name: "accept json", target: "/greet", accept: "application/json", json: true},
+		{name: "accept text", target: "/greet", accept: "text/plain", json: false},
+		{name: "accept both", target: "/greet", accept: "text/plain;q=0.5, application/json", json: true},
+		{name: "format json", target: "/greet?format=json", accept: "text/plain", json: true},
+		{name: "format text", target: "/greet?format=text", json: false},
+	}
+
+	for _, status := range statuses {
+		for _, format := range formats {
+			t.Run(fmt.Sprintf("%d %s", status, format.name), func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, format.target, nil)
+				if format.accept != "" {
+					req.Header.Set("Accept", format.accept)
+				}
+				req = req.WithContext(reqid.WithID(req.Context(), "req-42"))
+				rw := httptest.NewRecorder()
+				// The headers describing the body the handler meant to answer are dropped.
+				rw.Header().Set("Content-Encoding", "gzip")
+				rw.Header().Set("Content-Language", "fr")
+				message := http.StatusText(status)
+				Error(rw, req, status, message)
+
+				header := rw.Header()
+				wantType, wantBody := "text/plain; charset=utf-8", message+"\n"
+				if format.json {
+					wantType = "application/json"
+					wantBody = fmt.Sprintf(`{"error":{"code":%d,"message":%q,"request_id":"req-42"}}`, status, message)
+				}
+				if rw.Code != status || rw.Body.String() != wantBody {
+					t.Errorf("answer = %d %q, want %d %q", rw.Code, rw.Body.String(), status, wantBody)
+				}
+				for name, want := range map[string]string{
+					"Content-Type":           wantType,
+					"Content-Length":         strconv.Itoa(len(wantBody)),
+					"X-Content-Type-Options": "nosniff",
+					"Vary":                   "Accept",
+					"Content-Encoding":       "",
+					"Content-Language":       "",
+				} {
+					if got := header.Get(name); got != want {
+						t.Errorf("%s = %q, want %q", name, got, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestErrorWithoutRequestID(t *testing.T) {
+	rw := httptest.NewRecorder()
+	Error(rw, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusBadRequest, `invalid "name"`)
+
+	var envelope map[string]map[string]interface{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("body %q: %v", rw.Body.String(), err)
+	}
+	detail := envelope["error"]
+	if len(envelope) != 1 || len(detail) != 2 || detail["code"] != float64(http.StatusBadRequest) || detail["message"] != `invalid "name"` {
+		t.Errorf("envelope = %v, want the code and the message only", envelope)
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	tests := []struct {
+		name       string
+		answer     func(http.ResponseWriter, *http.Request)
+		wantCode   int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:     "not found",
+			answer:   notFound,
+			wantCode: http.StatusNotFound,
+			wantBody: "no such path /greet/Ada/Lovelace\n",
+		},
+		{
+			name:     "internal error",
+			answer:   internalError,
+			wantCode: http.StatusInternalServerError,
+			wantBody: "internal server error\n",
+		},
+		{
+			name: "method not allowed",
+			answer: func(rw http.ResponseWriter, req *http.Request) {
+				MethodNotAllowed(rw, req, http.MethodGet, http.MethodHead)
+			},
+			wantCode:   http.StatusMethodNotAllowed,
+			wantBody:   "method GET not allowed, expected GET or HEAD\n",
+			wantHeader: map[string]string{"Allow": "GET, HEAD"},
+		},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/greet/Ada/Lovelace?format=text", nil)
+		rw := httptest.NewRecorder()
+		test.answer(rw, req)
+		if rw.Code != test.wantCode || rw.Body.String() != test.wantBody {
+			t.Errorf("%s = %d %q, want %d %q", test.name, rw.Code, rw.Body.String(), test.wantCode, test.wantBody)
+		}
+		for name, want := range test.wantHeader {
+			if got := rw.Header().Get(name); got != want {
+				t.Errorf("%s: %s = %q, want %q", test.name, name, got, want)
+			}
+		}
+	}
+}
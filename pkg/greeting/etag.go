@@ -0,0 +1,47 @@
+package greeting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// greetingCacheControl lets the clients keep the greetings, revalidated on every request with their ETag,
+// as the name may change at any time.
+const greetingCacheControl = "no-cache"
+
+// greetingETag returns the entity tag of the greeting in the format, taken from everything it is rendered
+// from but its timestamp. The text greetings are identical byte for byte when their tag is, and get a strong
+// tag; the JSON ones differ by their timestamp, and get a weak one.
+func greetingETag(greeting Greeting, language, format string) string {
+	hash := sha256.New()
+	for _, part := range []string{format, language, greeting.Name, greeting.Caller, greeting.Message, greeting.Hostname, greeting.Version} {
+		hash.Write([]byte(part))
+		// The separator tells "ab", "c" from "a", "bc".
+		hash.Write([]byte{0})
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	if format == formatJSON {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// noneMatch tells whether the If-None-Match header matches the entity tag, comparing the tags weakly:
+// the compressed responses carry the weak counterpart of the strong tags.
+func noneMatch(ifNoneMatch, etag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "" {
+		return false
+	}
+	if ifNoneMatch == "*" {
+		return true
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package greeting
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNoneMatch(t *testing.T) {
+	const strong, weak = `"abc"`, `W/"abc"`
+
+	tests := []struct {
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{"", strong, false},
+		{"   ", strong, false},
+		{strong, strong, true},
+		{`"abd"`, strong, false},
+		{`"ABC"`, strong, false},
+		{`"ab"`, strong, false},
+		// The tags are compared weakly, either or both being weak.
+		{weak, strong, true},
+		{strong, weak, true},
+		{weak, weak, true},
+		{`W/"abd"`, weak, false},
+		{"*", strong, true},
+		{" * ", weak, true},
+		// Any tag of the list matches.
+		{`"x", "abc"`, strong, true},
+		{`"x",W/"abc" , "y"`, strong, true},
+		{` "x" , "y" `, strong, false},
+		{`"x",,"abc"`, strong, true},
+	}
+
+	for _, test := range tests {
+		if got := noneMatch(test.ifNoneMatch, test.etag); got != test.want {
+			t.Errorf("noneMatch(%q, %s) = %t, want %t", test.ifNoneMatch, test.etag, got, test.want)
+		}
+	}
+}
+
+func TestGreetingETag(t *testing.T) {
+	greeting := Greeting{Name: "Greeter", Message: "I am Greeter", Hostname: "pod-1", Version: "v1"}
+	etag := greetingETag(greeting, "en", formatText)
+	if strings.HasPrefix(etag, "W/") || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Errorf("text entity tag %s, want a strong one", etag)
+	}
+	if jsonETag := greetingETag(greeting, "en", formatJSON); !strings.HasPrefix(jsonETag, "W/") || strings.TrimPrefix(jsonETag, "W/") == etag {
+		t.Errorf("JSON entity tag %s, want a weak one of its own", jsonETag)
+	}
+
+	// The tag depends on all but the timestamp, and tells the fields apart.
+	later := greeting
+	later.Timestamp = later.Timestamp.Add(1)
+	if greetingETag(later, "en", formatText) != etag {
+		t.Error("entity tag changed with the timestamp")
+	}
+	for name, other := range map[string]Greeting{
+		"name":     {Name: "Other", Message: greeting.Message, Hostname: greeting.Hostname, Version: greeting.Version},
+		"caller":   {Name: greeting.Name, Caller: "Ada", Message: greeting.Message, Hostname: greeting.Hostname, Version: greeting.Version},
+		"hostname": {Name: greeting.Name, Message: greeting.Message, Hostname: "pod-2", Version: greeting.Version},
+		"version":  {Name: greeting.Name, Message: greeting.Message, Hostname: greeting.Hostname, Version: "v2"},
+		"shifted":  {Name: "Greete", Message: "r" + greeting.Message, Hostname: greeting.Hostname, Version: greeting.Version},
+	} {
+		if greetingETag(other, "en", formatText) == etag {
+			t.Errorf("entity tag unchanged with another %s", name)
+		}
+	}
+	if greetingETag(greeting, "fr", formatText) == etag {
+		t.Error("entity tag unchanged with another language")
+	}
+}
@@ -0,0 +1,102 @@
+package greeting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Greeting is the JSON body of a greeting.
+type Greeting struct {
+	Name      string    `json:"name"`
+	Caller    string    `json:"caller,omitempty"`
+	Message   string    `json:"message"`
+	Hostname  string    `json:"hostname"`
+	Version   string    `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Greet returns the greeting of the caller, not greeted when empty, in the requested language or the one
+// negotiated from the Accept-Language header, along with that language. The greeting is shared by the
+// HTTP handler and the other transports, such as gRPC.
+func (s *Server) Greet(caller, requested, acceptLanguage string) (Greeting, string, error) {
+	now := time.Now().UTC()
+	name, translations := s.current()
+	language, tmpl := translations.pick(requested, acceptLanguage)
+	message, err := renderMessage(tmpl, greetingData{Name: name, Hostname: s.hostname, Count: s.count.Add(1), Now: now})
+	if err != nil {
+		return Greeting{}, "", err
+	}
+	if s.showHost {
+		message += fmt.Sprintf(" (pod %s, %s)", s.hostname, s.build.Version)
+	}
+	if caller != "" {
+		message = "Hello " + caller + ", " + message
+	}
+
+	greetingsServed.Inc()
+	return Greeting{Name: name, Caller: caller, Message: message, Hostname: s.hostname, Version: s.build.Version, Timestamp: now}, language, nil
+}
+
+// HandleGreet is a HTTP handler answering the server name, as plain text or JSON.
+// The caller named by /greet/{name} or /greet?name= is greeted first.
+func (s *Server) HandleGreet(rw http.ResponseWriter, req *http.Request) {
+	RequestLog(req).Debug("Greet")
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		MethodNotAllowed(rw, req, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	caller, err := callerName(req)
+	if errors.Is(err, errCallerNotFound) {
+		notFound(rw, req)
+		return
+	}
+	if err != nil {
+		RequestLog(req).WithError(err).Debug("Invalid caller name")
+		Error(rw, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	greeting, language, err := s.Greet(caller, req.URL.Query().Get("lang"), req.Header.Get("Accept-Language"))
+	if err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to render greeting")
+		internalError(rw, req)
+		return
+	}
+
+	// The response is built fully before its header is sent, so that a failure can still change its status.
+	body := []byte(greeting.Message)
+	contentType := "text/plain; charset=utf-8"
+	format := responseFormat(req)
+	if format == formatJSON {
+		if body, err = json.Marshal(greeting); err != nil {
+			RequestLog(req).WithError(err).Warning("Unable to marshal greeting")
+			internalError(rw, req)
+			return
+		}
+		contentType = "application/json"
+	}
+
+	header := rw.Header()
+	header.Add("Vary", "Accept, Accept-Language")
+	header.Set("Content-Language", language)
+	header.Set("Cache-Control", greetingCacheControl)
+	etag := greetingETag(greeting, language, format)
+	header.Set("ETag", etag)
+	if noneMatch(req.Header.Get("If-None-Match"), etag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	rw.WriteHeader(http.StatusOK)
+
+	// The status is already sent, a failed write is only logged.
+	if _, err := rw.Write(body); err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to write greeting content")
+	}
+}
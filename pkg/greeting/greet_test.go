@@ -0,0 +1,175 @@
+package greeting
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// greet sends the request to the server over HTTP, so that only the headers sent along the status are seen,
+// and returns the response and its body.
+func greet(t *testing.T, server *Server, method, target string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	req, err := http.NewRequest(method, httpServer.URL+target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body: %v", method, target, err)
+	}
+	return resp, string(body)
+}
+
+func TestHandleGreetMethods(t *testing.T) {
+	server := New(WithName("Greeter"))
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		resp, body := greet(t, server, method, "/greet", nil)
+		wantBody := "I am Greeter"
+		if method == http.MethodHead {
+			wantBody = ""
+		}
+		if resp.StatusCode != http.StatusOK || body != wantBody {
+			t.Errorf("%s /greet = %d %q, want %d %q", method, resp.StatusCode, body, http.StatusOK, wantBody)
+		}
+		// HEAD tells the length of the body GET would answer.
+		if length := resp.Header.Get("Content-Length"); length != strconv.Itoa(len("I am Greeter")) {
+			t.Errorf("%s /greet: Content-Length = %q", method, length)
+		}
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions} {
+		resp, body := greet(t, server, method, "/greet", nil)
+		if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET, HEAD" {
+			t.Errorf("%s /greet = %d, Allow %q, want %d allowing GET, HEAD", method, resp.StatusCode, resp.Header.Get("Allow"), http.StatusMethodNotAllowed)
+		}
+		if !strings.Contains(body, "method "+method+" not allowed") {
+			t.Errorf("%s /greet: body = %q", method, body)
+		}
+	}
+}
+
+func TestHandleGreetHeaders(t *testing.T) {
+	server := New(WithName("Greeter"))
+
+	tests := []struct {
+		name            string
+		target          string
+		header          http.Header
+		wantContentType string
+		wantLanguage    string
+		wantWeak        bool
+	}{
+		{name: "text", target: "/greet", wantContentType: "text/plain; charset=utf-8", wantLanguage: "en"},
+		{name: "json", target: "/greet?format=json", wantContentType: "application/json", wantLanguage: "en", wantWeak: true},
+		{name: "accept json", target: "/greet", header: http.Header{"Accept": {"application/json"}}, wantContentType: "application/json", wantLanguage: "en", wantWeak: true},
+		{name: "negotiated language", target: "/greet", header: http.Header{"Accept-Language": {"de-CH, de;q=0.9"}}, wantContentType: "text/plain; charset=utf-8", wantLanguage: "de"},
+		{name: "requested language", target: "/greet?lang=fr", header: http.Header{"Accept-Language": {"de"}}, wantContentType: "text/plain; charset=utf-8", wantLanguage: "fr"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, body := greet(t, server, http.MethodGet, test.target, test.header)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+
+			// Every header is set before the status is written, a late one would not be sent at all.
+			for name, want := range map[string]string{
+				"Content-Type":     test.wantContentType,
+				"Content-Length":   strconv.Itoa(len(body)),
+				"Content-Language": test.wantLanguage,
+				"Cache-Control":    greetingCacheControl,
+				"Vary":             "Accept, Accept-Language",
+			} {
+				if got := resp.Header.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			etag := resp.Header.Get("ETag")
+			if weak := strings.HasPrefix(etag, "W/"); etag == "" || weak != test.wantWeak {
+				t.Errorf("ETag = %q, want weak %t", etag, test.wantWeak)
+			}
+
+			if test.wantContentType == "application/json" {
+				var greeting Greeting
+				if err := json.Unmarshal([]byte(body), &greeting); err != nil || greeting.Name != "Greeter" || greeting.Message != "I am Greeter" {
+					t.Errorf("body = %q, %v, want the JSON greeting of Greeter", body, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleGreetNotModified(t *testing.T) {
+	server := New(WithName("Greeter"))
+	resp, _ := greet(t, server, http.MethodGet, "/greet", nil)
+	etag := resp.Header.Get("ETag")
+
+	for ifNoneMatch, want := range map[string]int{
+		etag:                     http.StatusNotModified,
+		"W/" + etag:              http.StatusNotModified,
+		`"other", ` + etag:       http.StatusNotModified,
+		"*":                      http.StatusNotModified,
+		`"other"`:                http.StatusOK,
+		strings.ToUpper(etag):    http.StatusOK,
+		etag[:len(etag)-2] + `"`: http.StatusOK,
+	} {
+		resp, body := greet(t, server, http.MethodGet, "/greet", http.Header{"If-None-Match": {ifNoneMatch}})
+		if resp.StatusCode != want {
+			t.Errorf("If-None-Match %s = %d, want %d", ifNoneMatch, resp.StatusCode, want)
+			continue
+		}
+		if want != http.StatusNotModified {
+			continue
+		}
+		// The validators are sent, the headers of the body are not.
+		if resp.Header.Get("ETag") != etag || resp.Header.Get("Cache-Control") != greetingCacheControl {
+			t.Errorf("If-None-Match %s: ETag %q, Cache-Control %q", ifNoneMatch, resp.Header.Get("ETag"), resp.Header.Get("Cache-Control"))
+		}
+		if body != "" || resp.Header.Get("Content-Type") != "" {
+			t.Errorf("If-None-Match %s: body %q of type %q, want none", ifNoneMatch, body, resp.Header.Get("Content-Type"))
+		}
+	}
+
+	// Another name is another greeting.
+	server.SetName("Other")
+	if resp, _ := greet(t, server, http.MethodGet, "/greet", http.Header{"If-None-Match": {etag}}); resp.StatusCode != http.StatusOK {
+		t.Errorf("If-None-Match of the previous name = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestHandleGreetRenderError(t *testing.T) {
+	translations, err := LoadTranslations("", "en", "{{.Missing}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := New(WithTranslations(translations))
+
+	resp, body := greet(t, server, http.MethodGet, "/greet", http.Header{"Accept": {"text/plain"}})
+	if resp.StatusCode != http.StatusInternalServerError || body != "internal server error\n" {
+		t.Errorf("GET /greet = %d %q, want %d", resp.StatusCode, body, http.StatusInternalServerError)
+	}
+	// The failure happens before any header of the greeting is set.
+	for _, name := range []string{"ETag", "Content-Language", "Cache-Control"} {
+		if value := resp.Header.Get(name); value != "" {
+			t.Errorf("%s = %q, want none on a failure", name, value)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package greeting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// HealthStatus is the JSON body of the health endpoint.
+type HealthStatus struct {
+	Status          string `json:"status"`
+	Uptime          string `json:"uptime"`
+	Version         string `json:"version"`
+	Name            string `json:"name"`
+	GreetingsServed uint64 `json:"greetings_served"`
+}
+
+// HandleHealthcheck returns 200 Ok whenever the server answers, so that the liveness probe only restarts
+// a wedged server, with the state of the server as JSON or "ok" as plain text.
+func (s *Server) HandleHealthcheck(rw http.ResponseWriter, req *http.Request) {
+	RequestLog(req).Debug("Health check")
+	answer := HealthStatus{
+		Status:          "ok",
+		Uptime:          time.Since(s.started).Round(time.Second).String(),
+		Version:         s.build.Version,
+		Name:            s.Name(),
+		GreetingsServed: s.count.Load(),
+	}
+	writeAnswer(rw, req, http.StatusOK, answer, "ok\n")
+}
+
+// checkTemplates renders the greeting template of every language, failing on the first one which does not.
+// Templates are parsed when loaded, but some only fail once executed, such as the ones of unknown fields.
+func (s *Server) checkTemplates(context.Context) error {
+	name, translations := s.current()
+	data := greetingData{Name: name, Hostname: s.hostname, Now: time.Now().UTC()}
+	for _, language := range sortedKeys(translations.templates) {
+		if _, err := renderMessage(translations.templates[language], data); err != nil {
+			return fmt.Errorf("language %s: %w", language, err)
+		}
+	}
+	return nil
+}
+
+// writeAnswer answers with the status code, and the answer as JSON or the text, following the
+// format asked by the request.
+func writeAnswer(rw http.ResponseWriter, req *http.Request, code int, answer interface{}, text string) {
+	body := []byte(text)
+	contentType := "text/plain; charset=utf-8"
+	if responseFormat(req) == formatJSON {
+		var err error
+		if body, err = json.Marshal(answer); err != nil {
+			RequestLog(req).WithError(err).Warning("Unable to marshal answer")
+			internalError(rw, req)
+			return
+		}
+		contentType = "application/json"
+	}
+
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	rw.Header().Add("Vary", "Accept")
+	rw.WriteHeader(code)
+	if _, err := rw.Write(body); err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to write answer")
+	}
+}
+
+// sortedKeys returns the keys of the map in increasing order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
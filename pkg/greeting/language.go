@@ -0,0 +1,172 @@
+package greeting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// builtinTranslations are the greeting templates known without translations file, by language.
+var builtinTranslations = map[string]string{
+	"en": defaultGreetingTemplate,
+	"fr": "Je suis {{.Name}}",
+	"de": "Ich bin {{.Name}}",
+	"es": "Soy {{.Name}}",
+	"it": "Sono {{.Name}}",
+}
+
+// Translations are the greeting templates by language, lower-cased.
+type Translations struct {
+	templates       map[string]*template.Template
+	defaultLanguage string
+}
+
+// LoadTranslations returns the built-in translations, extended or overridden by the ones of the file
+// when given. The greeting template, when given, overrides the translation of the default language.
+// Every unreadable file and invalid template is reported at once.
+func LoadTranslations(path, defaultLanguage, greetingTemplate string) (*Translations, error) {
+	texts := make(map[string]string, len(builtinTranslations))
+	for language, text := range builtinTranslations {
+		texts[language] = text
+	}
+
+	var errs []error
+	if path != "" {
+		file, err := readTranslationsFile(path)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for language, text := range file {
+			texts[strings.ToLower(language)] = text
+		}
+	}
+
+	defaultLanguage = strings.ToLower(defaultLanguage)
+	if greetingTemplate != "" {
+		texts[defaultLanguage] = greetingTemplate
+	}
+	if _, ok := texts[defaultLanguage]; !ok {
+		errs = append(errs, fmt.Errorf("no translation for the default language %q", defaultLanguage))
+	}
+
+	t := &Translations{templates: make(map[string]*template.Template, len(texts)), defaultLanguage: defaultLanguage}
+	languages := make([]string, 0, len(texts))
+	for language := range texts {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		tmpl, err := parseGreetingTemplate(texts[language])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("language %s: %w", language, err))
+			continue
+		}
+		t.templates[language] = tmpl
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return t, nil
+}
+
+// readTranslationsFile reads the translations of the file, by language.
+func readTranslationsFile(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read translations file: %w", err)
+	}
+	var file map[string]string
+	if err = yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse translations file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// defaultTranslations returns the built-in translations, English being the default language.
+func defaultTranslations() *Translations {
+	t, err := LoadTranslations("", "en", "")
+	if err != nil {
+		// The built-in templates are parsed whatever the environment, they cannot fail.
+		panic(err)
+	}
+	return t
+}
+
+// match returns the translated language of the tag, matching its primary language subtag
+// when the tag itself has no translation.
+func (t *Translations) match(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if _, ok := t.templates[tag]; ok {
+		return tag, true
+	}
+	primary, _, _ := strings.Cut(tag, "-")
+	if _, ok := t.templates[primary]; ok {
+		return primary, true
+	}
+	return "", false
+}
+
+// pick returns the language of the greeting and its template: the requested language when translated,
+// otherwise the translated language preferred by the Accept-Language header, the default language
+// when none is.
+func (t *Translations) pick(requested, acceptLanguage string) (string, *template.Template) {
+	if language, ok := t.match(requested); ok {
+		return language, t.templates[language]
+	}
+
+	for _, tag := range acceptedLanguages(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		if language, ok := t.match(tag); ok {
+			return language, t.templates[language]
+		}
+	}
+	return t.defaultLanguage, t.templates[t.defaultLanguage]
+}
+
+// acceptedLanguages returns the language tags of the Accept-Language header by decreasing quality,
+// in the order of the header for equal qualities. The tags of quality 0 are left out.
+func acceptedLanguages(header string) []string {
+	type accepted struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []accepted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality > 0 {
+			languages = append(languages, accepted{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, 0, len(languages))
+	for _, language := range languages {
+		tags = append(tags, language.tag)
+	}
+	return tags
+}
@@ -0,0 +1,110 @@
+package greeting
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"fr", []string{"fr"}},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", []string{"fr-CH", "fr", "en", "de", "*"}},
+		// The tags are sorted by quality, keeping the order of the header for equal qualities.
+		{"en;q=0.5, de, fr;q=0.8, it", []string{"de", "it", "fr", "en"}},
+		{"es;q=0.9, en;q=0.9", []string{"es", "en"}},
+		// The tags of quality 0 are refused, the invalid qualities and the empty parts ignored.
+		{"fr;q=0, en", []string{"en"}},
+		{"de;q=high, , it ;q=0.3", []string{"it"}},
+	}
+
+	for _, test := range tests {
+		if got := acceptedLanguages(test.header); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("acceptedLanguages(%q) = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestPick(t *testing.T) {
+	translations := defaultTranslations()
+
+	tests := []struct {
+		name, requested, acceptLanguage string
+		want                            string
+	}{
+		{name: "default", want: "en"},
+		{name: "requested", requested: "fr", want: "fr"},
+		{name: "requested over header", requested: "de", acceptLanguage: "fr", want: "de"},
+		{name: "requested case insensitive", requested: "FR", want: "fr"},
+		{name: "requested unknown", requested: "nl", acceptLanguage: "es", want: "es"},
+		{name: "header", acceptLanguage: "it", want: "it"},
+		{name: "header by quality", acceptLanguage: "fr;q=0.5, de;q=0.9", want: "de"},
+		{name: "primary subtag", acceptLanguage: "fr-CH", want: "fr"},
+		{name: "first translated", acceptLanguage: "nl, pt;q=0.9, es;q=0.8", want: "es"},
+		{name: "none translated", acceptLanguage: "nl, pt", want: "en"},
+		// The wildcard prefers the default language over the tags of lower quality.
+		{name: "wildcard", acceptLanguage: "nl, *;q=0.9, fr;q=0.5", want: "en"},
+		{name: "spaced wildcard", acceptLanguage: "nl, * ;q=0.9, fr;q=0.5", want: "en"},
+		{name: "refused", acceptLanguage: "fr;q=0", want: "en"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			language, tmpl := translations.pick(test.requested, test.acceptLanguage)
+			if language != test.want || tmpl != translations.templates[test.want] {
+				t.Errorf("pick(%q, %q) = %s, want %s", test.requested, test.acceptLanguage, language, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadTranslations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "translations.yaml")
+	if err := os.WriteFile(path, []byte("NL: Ik ben {{.Name}}\nfr: Moi c'est {{.Name}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("nl: Ik ben {{.Name\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	translations, err := LoadTranslations(path, "NL", "")
+	if err != nil {
+		t.Fatalf("LoadTranslations: %v", err)
+	}
+	for language, want := range map[string]string{"nl": "Ik ben Ada", "fr": "Moi c'est Ada", "de": "Ich bin Ada"} {
+		tmpl, ok := translations.templates[language]
+		if !ok {
+			t.Errorf("no %s translation", language)
+			continue
+		}
+		if got, err := renderMessage(tmpl, greetingData{Name: "Ada"}); err != nil || got != want {
+			t.Errorf("%s greeting = %q, %v, want %q", language, got, err, want)
+		}
+	}
+	if language, _ := translations.pick("", "pt"); language != "nl" {
+		t.Errorf("fallback language = %s, want the default nl", language)
+	}
+
+	translations, err = LoadTranslations("", "de", "Servus, {{.Name}}")
+	if err != nil {
+		t.Fatalf("LoadTranslations: %v", err)
+	}
+	if got, _ := renderMessage(translations.templates["de"], greetingData{Name: "Ada"}); got != "Servus, Ada" {
+		t.Errorf("de greeting = %q, want the greeting template", got)
+	}
+
+	_, err = LoadTranslations(invalid, "pt", "")
+	for _, want := range []string{"language nl", `no translation for the default language "pt"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadTranslations(invalid) = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package greeting
+
+import (
+	"net/http"
+
+	"edb-challenge/pkg/reqid"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestLog returns the logger of the request, tagged with its ID.
+func RequestLog(req *http.Request) *log.Entry {
+	return log.WithField("request_id", reqid.From(req.Context()))
+}
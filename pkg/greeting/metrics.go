@@ -0,0 +1,36 @@
+package greeting
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsNamespace prefixes the name of every greeting server metric.
+const MetricsNamespace = "greeting_server"
+
+var (
+	// MetricsRegistry holds the metrics served by /metrics, to which the programs serving the greetings add their own.
+	MetricsRegistry = prometheus.NewRegistry()
+
+	greetingsServed = promauto.With(MetricsRegistry).NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "greetings_served_total",
+		Help:      "Number of greetings answered.",
+	})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// MetricsHandler serves the metrics of the greeting server.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,74 @@
+package greeting
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxNameBody is the maximum size of the body of a name update.
+const maxNameBody = 4096
+
+// nameUpdate is the JSON body of a name update, and of the answer to a name request.
+type nameUpdate struct {
+	Name     string `json:"name"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// RequireToken serves the handler only to the requests bearing the token.
+func RequireToken(token string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bearer, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			Error(rw, req, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
+
+// HandleName is a HTTP handler answering the server name on GET, and changing it on PUT or POST
+// with the previous name in the answer.
+func (s *Server) HandleName(rw http.ResponseWriter, req *http.Request) {
+	var answer nameUpdate
+	switch req.Method {
+	case http.MethodGet:
+		answer.Name = s.Name()
+
+	case http.MethodPut, http.MethodPost:
+		var update nameUpdate
+		if err := json.NewDecoder(io.LimitReader(req.Body, maxNameBody)).Decode(&update); err != nil {
+			Error(rw, req, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if update.Name == "" {
+			Error(rw, req, http.StatusBadRequest, "name is required")
+			return
+		}
+		if err := ValidateName(update.Name); err != nil {
+			Error(rw, req, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		answer.Name, answer.Previous = update.Name, s.SetName(update.Name)
+		RequestLog(req).WithField("previous", answer.Previous).WithField("name", answer.Name).Info("Changed greeting name")
+
+	default:
+		MethodNotAllowed(rw, req, http.MethodGet, http.MethodPut, http.MethodPost)
+		return
+	}
+
+	body, err := json.Marshal(answer)
+	if err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to marshal name")
+		internalError(rw, req)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if _, err := rw.Write(body); err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to write name content")
+	}
+}
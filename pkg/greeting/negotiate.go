@@ -0,0 +1,74 @@
+package greeting
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Formats of the responses.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// responseFormat returns the format of the response asked by the request: the format query parameter when
+// given, otherwise the media type preferred by the Accept header. Text is the default, including for
+// unknown formats and media types.
+func responseFormat(req *http.Request) string {
+	switch req.URL.Query().Get("format") {
+	case formatJSON:
+		return formatJSON
+	case formatText:
+		return formatText
+	}
+
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		return formatText
+	}
+	if acceptQuality(accept, "application/json") > acceptQuality(accept, "text/plain") {
+		return formatJSON
+	}
+	return formatText
+}
+
+// acceptQuality returns the quality given to the media type by the Accept header, taken from its most
+// specific matching range, 0 when not accepted.
+func acceptQuality(accept, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, 0
+	for _, accepted := range strings.Split(accept, ",") {
+		acceptedType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		var s int
+		switch acceptedType {
+		case mediaType:
+			s = 3
+		case mainType + "/*":
+			s = 2
+		case "*/*":
+			s = 1
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		quality, specificity = q, s
+	}
+
+	return quality
+}
@@ -0,0 +1,66 @@
+package greeting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptQuality(t *testing.T) {
+	tests := []struct {
+		accept, mediaType string
+		want              float64
+	}{
+		{"application/json", "application/json", 1},
+		{"application/json;q=0.9", "application/json", 0.9},
+		{"application/json; q=0.5", "application/json", 0.5},
+		{"text/plain, application/json;q=0.9", "text/plain", 1},
+		{"text/plain, application/json;q=0.9", "application/json", 0.9},
+		{"application/*;q=0.3", "application/json", 0.3},
+		{"*/*;q=0.1", "application/json", 0.1},
+		// The most specific range wins, whatever its order or quality.
+		{"application/json;q=0.2, */*", "application/json", 0.2},
+		{"*/*, application/*;q=0.4, application/json;q=0", "application/json", 0},
+		{"text/html", "application/json", 0},
+		{"", "application/json", 0},
+		// The invalid ranges and qualities are ignored.
+		{"application/json;q=high, text/plain", "application/json", 0},
+		{"application/json;;, */*;q=0.5", "application/json", 0.5},
+	}
+
+	for _, test := range tests {
+		if got := acceptQuality(test.accept, test.mediaType); got != test.want {
+			t.Errorf("acceptQuality(%q, %q) = %v, want %v", test.accept, test.mediaType, got, test.want)
+		}
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	tests := []struct {
+		name, target, accept string
+		want                 string
+	}{
+		{"default", "/", "", formatText},
+		{"json", "/", "application/json", formatJSON},
+		{"text preferred", "/", "text/plain, application/json;q=0.9", formatText},
+		{"json preferred", "/", "text/plain;q=0.5, application/json;q=0.9", formatJSON},
+		{"browser", "/", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", formatText},
+		{"any", "/", "*/*", formatText},
+		{"unknown", "/", "image/png", formatText},
+		{"query json", "/?format=json", "text/plain", formatJSON},
+		{"query text", "/?format=text", "application/json", formatText},
+		{"unknown query", "/?format=xml", "application/json", formatJSON},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			if got := responseFormat(req); got != test.want {
+				t.Errorf("responseFormat(%s, Accept: %q) = %s, want %s", test.target, test.accept, got, test.want)
+			}
+		})
+	}
+}
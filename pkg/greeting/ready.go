@@ -0,0 +1,119 @@
+package greeting
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// checkTimeout bounds the time given to every readiness check.
+const checkTimeout = 2 * time.Second
+
+// Readiness tells whether the server accepts traffic: it starts once the startup delay elapsed, and stops
+// once the shutdown begins or while one of the registered checks fails.
+type Readiness struct {
+	shuttingDown atomic.Bool
+	// readyAt is the time, in Unix nanoseconds, until which the server is starting.
+	readyAt atomic.Int64
+
+	mu     sync.RWMutex
+	checks map[string]func(context.Context) error
+}
+
+// readinessStatus is the JSON body of the readiness endpoint.
+type readinessStatus struct {
+	Status string `json:"status"`
+	// Checks are the results of the checks by name, "ok" or their error.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// StartAfter keeps the readiness probe failing for the delay, logging once it elapsed.
+func (r *Readiness) StartAfter(delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	r.readyAt.Store(time.Now().Add(delay).UnixNano())
+	log.WithField("delay", delay).Info("Starting, failing the readiness probe for the startup delay")
+	time.AfterFunc(delay, func() {
+		log.Info("Startup delay elapsed, ready")
+	})
+}
+
+// StartingFor returns the time left before the server started, 0 once started.
+func (r *Readiness) StartingFor() time.Duration {
+	if left := time.Until(time.Unix(0, r.readyAt.Load())); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// ShutDown makes the readiness probe fail, so that the pod is taken out of the service while draining.
+func (r *Readiness) ShutDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Register adds a check run by every readiness probe, failing it when returning an error. A check of the
+// same name is replaced.
+func (r *Readiness) Register(name string, check func(context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.checks == nil {
+		r.checks = map[string]func(context.Context) error{}
+	}
+	r.checks[name] = check
+}
+
+// check runs the registered checks in the order of their names, and returns their results along with
+// whether they all succeeded.
+func (r *Readiness) check(ctx context.Context) (map[string]string, bool) {
+	r.mu.RLock()
+	checks := make(map[string]func(context.Context) error, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	results := make(map[string]string, len(checks))
+	ok := true
+	for _, name := range sortedKeys(checks) {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := checks[name](checkCtx)
+		cancel()
+		if err != nil {
+			results[name], ok = err.Error(), false
+			continue
+		}
+		results[name] = "ok"
+	}
+	return results, ok
+}
+
+// HandleReady is a HTTP handler returning 200 Ok, or 503 Service Unavailable while starting, once the
+// shutdown began or while a check fails, with the results of the checks as JSON or plain text.
+func (r *Readiness) HandleReady(rw http.ResponseWriter, req *http.Request) {
+	RequestLog(req).Debug("Readiness check")
+
+	answer := readinessStatus{Status: "ok"}
+	code := http.StatusOK
+	if r.shuttingDown.Load() {
+		answer.Status, code = "shutting down", http.StatusServiceUnavailable
+	} else if r.StartingFor() > 0 {
+		answer.Status, code = "starting", http.StatusServiceUnavailable
+	} else if results, ok := r.check(req.Context()); !ok {
+		answer.Status, answer.Checks, code = "unavailable", results, http.StatusServiceUnavailable
+	} else {
+		answer.Checks = results
+	}
+
+	text := answer.Status + "\n"
+	for _, name := range sortedKeys(answer.Checks) {
+		if result := answer.Checks[name]; result != "ok" {
+			text += name + ": " + result + "\n"
+		}
+	}
+	writeAnswer(rw, req, code, answer, text)
+}
@@ -0,0 +1,107 @@
+package greeting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// probeReady returns the code and the JSON answer of the readiness endpoint.
+func probeReady(t *testing.T, readiness *Readiness) (int, readinessStatus) {
+	t.Helper()
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ready?format=json", nil)
+	readiness.HandleReady(rw, req)
+
+	var answer readinessStatus
+	if err := json.Unmarshal(rw.Body.Bytes(), &answer); err != nil {
+		t.Fatalf("decode readiness %q: %v", rw.Body.String(), err)
+	}
+	return rw.Code, answer
+}
+
+func TestReadinessTransitions(t *testing.T) {
+	readiness := &Readiness{}
+	failing := errors.New("database unreachable")
+	var checkErr error
+	readiness.Register("database", func(context.Context) error { return checkErr })
+
+	steps := []struct {
+		name       string
+		change     func()
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{"ready", func() {}, http.StatusOK, "ok", map[string]string{"database": "ok"}},
+		{"check failing", func() { checkErr = failing }, http.StatusServiceUnavailable, "unavailable", map[string]string{"database": failing.Error()}},
+		{"check recovered", func() { checkErr = nil }, http.StatusOK, "ok", map[string]string{"database": "ok"}},
+		{"check replaced", func() {
+			readiness.Register("database", func(context.Context) error { return failing })
+		}, http.StatusServiceUnavailable, "unavailable", map[string]string{"database": failing.Error()}},
+		// The shutdown wins over the checks, which are no longer run.
+		{"shutting down", readiness.ShutDown, http.StatusServiceUnavailable, "shutting down", nil},
+	}
+
+	for _, step := range steps {
+		step.change()
+		code, answer := probeReady(t, readiness)
+		if code != step.wantCode || answer.Status != step.wantStatus || !reflect.DeepEqual(answer.Checks, step.wantChecks) {
+			t.Errorf("%s: readiness = %d %+v, want %d %s %v", step.name, code, answer, step.wantCode, step.wantStatus, step.wantChecks)
+		}
+	}
+}
+
+func TestReadinessStartAfter(t *testing.T) {
+	readiness := &Readiness{}
+	readiness.StartAfter(100 * time.Millisecond)
+
+	if left := readiness.StartingFor(); left <= 0 || left > 100*time.Millisecond {
+		t.Errorf("StartingFor() = %s, want up to the startup delay", left)
+	}
+	if code, answer := probeReady(t, readiness); code != http.StatusServiceUnavailable || answer.Status != "starting" {
+		t.Errorf("readiness while starting = %d %s, want %d starting", code, answer.Status, http.StatusServiceUnavailable)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if left := readiness.StartingFor(); left != 0 {
+		t.Errorf("StartingFor() = %s once started, want 0", left)
+	}
+	if code, answer := probeReady(t, readiness); code != http.StatusOK || answer.Status != "ok" {
+		t.Errorf("readiness once started = %d %s, want %d ok", code, answer.Status, http.StatusOK)
+	}
+
+	// The shutdown also wins over the startup.
+	starting := &Readiness{}
+	starting.StartAfter(time.Hour)
+	starting.ShutDown()
+	if _, answer := probeReady(t, starting); answer.Status != "shutting down" {
+		t.Errorf("readiness shut down while starting = %s, want shutting down", answer.Status)
+	}
+}
+
+func TestReadinessNoDelay(t *testing.T) {
+	readiness := &Readiness{}
+	readiness.StartAfter(0)
+	if left := readiness.StartingFor(); left != 0 {
+		t.Errorf("StartingFor() = %s without a startup delay, want 0", left)
+	}
+}
+
+func TestReadinessText(t *testing.T) {
+	readiness := &Readiness{}
+	readiness.Register("cache", func(context.Context) error { return nil })
+	readiness.Register("database", func(context.Context) error { return errors.New("unreachable") })
+
+	rw := httptest.NewRecorder()
+	readiness.HandleReady(rw, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	// The text lists the failing checks only.
+	if want := "unavailable\ndatabase: unreachable\n"; rw.Code != http.StatusServiceUnavailable || rw.Body.String() != want {
+		t.Errorf("readiness = %d %q, want %d %q", rw.Code, rw.Body.String(), http.StatusServiceUnavailable, want)
+	}
+}
@@ -0,0 +1,250 @@
+// Package greeting serves the greetings of a named server over HTTP, along with its probes and metrics.
+package greeting
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"edb-challenge/pkg/version"
+)
+
+// defaultName is the server name when none is given.
+const defaultName = "anonymous"
+
+// Server is capable of presenting itself thanks to HTTP handlers.
+type Server struct {
+	hostname     string
+	build        version.Info
+	showHost     bool
+	started      time.Time
+	auth         *Auth
+	adminToken   string
+	serveMetrics bool
+	ui           bool
+	statsToken   string
+	stats        *Stats
+	readiness    *Readiness
+	mux          *http.ServeMux
+
+	streamInterval time.Duration
+	maxStreams     int
+	streams        atomic.Int64
+	// closing is closed to end the streams once the server shuts down.
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	// mu guards the name and the translations, changed while serving, and the channel closed once they are.
+	mu           sync.RWMutex
+	name         string
+	translations *Translations
+	changed      chan struct{}
+
+	count atomic.Uint64
+}
+
+// Option configures the server built by New.
+type Option func(*Server)
+
+// WithName sets the server name, anonymous by default.
+func WithName(name string) Option {
+	return func(s *Server) {
+		s.name = name
+	}
+}
+
+// WithHostname sets the host running the server, the pod name in Kubernetes.
+func WithHostname(hostname string) Option {
+	return func(s *Server) {
+		s.hostname = hostname
+	}
+}
+
+// WithBuild sets the build of the server, answered by /version and whose version is told by the greetings
+// and the health probe. It is the build of the running binary by default.
+func WithBuild(build version.Info) Option {
+	return func(s *Server) {
+		s.build = build
+	}
+}
+
+// WithShowHost appends the hostname and the version to the greeting message.
+func WithShowHost(showHost bool) Option {
+	return func(s *Server) {
+		s.showHost = showHost
+	}
+}
+
+// WithTranslations sets the greeting templates by language, the built-in ones by default.
+func WithTranslations(translations *Translations) Option {
+	return func(s *Server) {
+		s.translations = translations
+	}
+}
+
+// WithAuth requires the credentials on /greet, left open when nil.
+func WithAuth(auth *Auth) Option {
+	return func(s *Server) {
+		s.auth = auth
+	}
+}
+
+// WithAdminToken serves /name to the requests bearing the token, the endpoint being disabled without token.
+func WithAdminToken(token string) Option {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+// WithMetrics serves /metrics with the router, true by default. Turn it off to serve the metrics apart.
+func WithMetrics(serveMetrics bool) Option {
+	return func(s *Server) {
+		s.serveMetrics = serveMetrics
+	}
+}
+
+// WithUI serves the HTML landing page on /, true by default. Turn it off for API-only deployments.
+func WithUI(ui bool) Option {
+	return func(s *Server) {
+		s.ui = ui
+	}
+}
+
+// WithStatsToken serves /stats to the requests bearing the token only, the endpoint being open without token.
+func WithStatsToken(token string) Option {
+	return func(s *Server) {
+		s.statsToken = token
+	}
+}
+
+// WithStreamInterval sets the time between the greetings pushed by /greet/stream, 5 seconds by default.
+func WithStreamInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.streamInterval = interval
+	}
+}
+
+// WithMaxStreams caps the streams served at once, answering 503 beyond, without limit when not positive.
+func WithMaxStreams(maxStreams int) Option {
+	return func(s *Server) {
+		s.maxStreams = maxStreams
+	}
+}
+
+// WithReadiness answers /ready with the readiness, so that its owner can delay the start or begin the shutdown.
+func WithReadiness(readiness *Readiness) Option {
+	return func(s *Server) {
+		s.readiness = readiness
+	}
+}
+
+// New returns the server of the options, with its routes registered on its own router. The greeting template
+// is checked by every readiness probe.
+func New(opts ...Option) *Server {
+	s := &Server{name: defaultName, build: version.Get(), started: time.Now(), serveMetrics: true, ui: true, stats: &Stats{},
+		streamInterval: defaultStreamInterval, closing: make(chan struct{}), changed: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.translations == nil {
+		s.translations = defaultTranslations()
+	}
+	if s.readiness == nil {
+		s.readiness = &Readiness{}
+	}
+	s.readiness.Register("greeting-template", s.checkTemplates)
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/", s.HandleRoot)
+	s.mux.HandleFunc("/health", s.HandleHealthcheck)
+	s.mux.HandleFunc("/ready", s.readiness.HandleReady)
+	s.mux.HandleFunc("/version", s.HandleVersion)
+	s.mux.Handle("/greet", requireAuth(s.auth, http.HandlerFunc(s.HandleGreet)))
+	s.mux.Handle("/greet/", requireAuth(s.auth, http.HandlerFunc(s.HandleGreet)))
+	s.mux.Handle("/greet/stream", requireAuth(s.auth, http.HandlerFunc(s.HandleStream)))
+	if s.adminToken != "" {
+		s.mux.Handle("/name", RequireToken(s.adminToken, http.HandlerFunc(s.HandleName)))
+	}
+	stats := http.Handler(http.HandlerFunc(s.HandleStats))
+	if s.statsToken != "" {
+		stats = RequireToken(s.statsToken, stats)
+	}
+	s.mux.Handle("/stats", stats)
+	if s.serveMetrics {
+		s.mux.Handle("/metrics", MetricsHandler())
+	}
+	return s
+}
+
+// ServeHTTP serves the request with the router of the server.
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(rw, req)
+}
+
+// Handle registers an extra handler on the router, such as the debug endpoints.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Route returns the pattern of the route serving the request, empty when unmatched. Unlike the path, it does
+// not hold the names given by the callers.
+func (s *Server) Route(req *http.Request) string {
+	_, pattern := s.mux.Handler(req)
+	// The root pattern matches every path, only / itself is served.
+	if pattern == "/" && req.URL.Path != "/" {
+		return ""
+	}
+	return pattern
+}
+
+// Readiness returns the readiness answering /ready.
+func (s *Server) Readiness() *Readiness {
+	return s.readiness
+}
+
+// Name returns the server name.
+func (s *Server) Name() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.name
+}
+
+// SetName changes the server name, and returns the previous one.
+func (s *Server) SetName(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.name
+	s.name = name
+	s.notifyChange()
+	return previous
+}
+
+// Update switches the server name and the translations at once, such as on a configuration reload.
+func (s *Server) Update(name string, translations *Translations) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name, s.translations = name, translations
+	s.notifyChange()
+}
+
+// notifyChange wakes up the streams waiting for a change of the name or the translations, with the mutex held.
+func (s *Server) notifyChange() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// changes returns the channel closed on the next change of the name or the translations.
+func (s *Server) changes() <-chan struct{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.changed
+}
+
+// current returns the server name and the translations of the greeting message together.
+func (s *Server) current() (string, *Translations) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.name, s.translations
+}
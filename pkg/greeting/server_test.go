@@ -0,0 +1,143 @@
+package greeting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"edb-challenge/pkg/version"
+)
+
+// serve answers the request with the server, bearing the token when given.
+func serve(server *Server, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rw := httptest.NewRecorder()
+	server.ServeHTTP(rw, req)
+	return rw
+}
+
+func TestNewRoutes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{
+				"/":             "/",
+				"/unknown":      "",
+				"/health":       "/health",
+				"/ready":        "/ready",
+				"/version":      "/version",
+				"/greet":        "/greet",
+				"/greet/Ada":    "/greet/",
+				"/greet/stream": "/greet/stream",
+				"/stats":        "/stats",
+				"/metrics":      "/metrics",
+				"/name":         "",
+			},
+		},
+		{
+			name: "admin token",
+			opts: []Option{WithAdminToken("admin")},
+			want: map[string]string{"/name": "/name"},
+		},
+		{
+			name: "metrics served apart",
+			opts: []Option{WithMetrics(false)},
+			want: map[string]string{"/metrics": "", "/greet": "/greet"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := New(test.opts...)
+			for path, want := range test.want {
+				if route := server.Route(httptest.NewRequest(http.MethodGet, path, nil)); route != want {
+					t.Errorf("Route(%s) = %q, want %q", path, route, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServerAccessors(t *testing.T) {
+	readiness := &Readiness{}
+	server := New(WithReadiness(readiness))
+
+	if name := server.Name(); name != defaultName {
+		t.Errorf("Name() = %q, want %q by default", name, defaultName)
+	}
+	if previous := server.SetName("Greeter"); previous != defaultName || server.Name() != "Greeter" {
+		t.Errorf("SetName(Greeter) = %q, then Name() = %q", previous, server.Name())
+	}
+	if server.Readiness() != readiness {
+		t.Error("Readiness() is not the one given")
+	}
+	if server.Stats() == nil {
+		t.Error("Stats() = nil")
+	}
+
+	translations, err := LoadTranslations("", "en", "Hi from {{.Name}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Update("Other", translations)
+	if rw := serve(server, http.MethodGet, "/greet?format=text", ""); rw.Body.String() != "Hi from Other" {
+		t.Errorf("greeting once updated = %q", rw.Body.String())
+	}
+
+	server.Handle("/extra", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) { rw.WriteHeader(http.StatusTeapot) }))
+	if rw := serve(server, http.MethodGet, "/extra", ""); rw.Code != http.StatusTeapot {
+		t.Errorf("GET /extra = %d, want the extra handler", rw.Code)
+	}
+}
+
+func TestNewVersion(t *testing.T) {
+	build := version.Info{Version: "v1.2.3", Commit: "abc123"}
+	rw := serve(New(WithBuild(build)), http.MethodGet, "/version", "")
+
+	var got version.Info
+	if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil || got != build {
+		t.Errorf("GET /version = %d %q, want the build given", rw.Code, rw.Body.String())
+	}
+}
+
+func TestNameRoute(t *testing.T) {
+	if rw := serve(New(), http.MethodGet, "/name", "admin"); rw.Code != http.StatusNotFound {
+		t.Errorf("GET /name without admin token = %d, want %d", rw.Code, http.StatusNotFound)
+	}
+
+	server := New(WithName("Greeter"), WithAdminToken("admin"))
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "admin": http.StatusOK} {
+		rw := serve(server, http.MethodGet, "/name", token)
+		if rw.Code != want {
+			t.Errorf("GET /name with token %q = %d, want %d", token, rw.Code, want)
+		}
+		if want == http.StatusOK && !strings.Contains(rw.Body.String(), `"name":"Greeter"`) {
+			t.Errorf("GET /name = %q", rw.Body.String())
+		}
+	}
+}
+
+func TestStatsRoute(t *testing.T) {
+	if rw := serve(New(), http.MethodGet, "/stats", ""); rw.Code != http.StatusOK || rw.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("GET /stats without stats token = %d of type %q, want the open JSON stats", rw.Code, rw.Header().Get("Content-Type"))
+	}
+
+	server := New(WithStatsToken("stats"))
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "stats": http.StatusOK} {
+		if rw := serve(server, http.MethodGet, "/stats", token); rw.Code != want {
+			t.Errorf("GET /stats with token %q = %d, want %d", token, rw.Code, want)
+		}
+	}
+	if rw := serve(server, http.MethodPost, "/stats", "stats"); rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /stats = %d, want %d", rw.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,113 @@
+package greeting
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts the requests served, for a quick snapshot of how busy the server is without Prometheus.
+// It is safe for concurrent use.
+type Stats struct {
+	connections atomic.Int64
+
+	requests atomic.Uint64
+	errors   atomic.Uint64
+	bytes    atomic.Uint64
+
+	mu    sync.Mutex
+	paths map[string]uint64
+}
+
+// statsSnapshot is the JSON body of the stats endpoint.
+type statsSnapshot struct {
+	Started time.Time `json:"started"`
+	Uptime  string    `json:"uptime"`
+	// Connections are the connections open, when tracked by the HTTP server.
+	Connections int64 `json:"connections"`
+	// Greetings are the greetings answered, restored across restarts like the requests when persisted.
+	Greetings uint64 `json:"greetings"`
+	Requests  uint64 `json:"requests"`
+	// Errors are the answers of status 500 and above.
+	Errors       uint64            `json:"errors"`
+	BytesWritten uint64            `json:"bytes_written"`
+	Paths        map[string]uint64 `json:"paths"`
+}
+
+// Record counts a request served on the route, such as the pattern of the router rather than its path
+// so that the names of the callers do not multiply the counters, with the status and the bytes written.
+func (st *Stats) Record(route string, status, bytes int) {
+	st.requests.Add(1)
+	if status >= http.StatusInternalServerError {
+		st.errors.Add(1)
+	}
+	st.bytes.Add(uint64(bytes))
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.paths == nil {
+		st.paths = map[string]uint64{}
+	}
+	st.paths[route]++
+}
+
+// ConnState tracks the open connections, as the ConnState callback of the HTTP server.
+func (st *Stats) ConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		st.connections.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		st.connections.Add(-1)
+	}
+}
+
+// snapshot returns the counters at once.
+func (st *Stats) snapshot() statsSnapshot {
+	st.mu.Lock()
+	paths := make(map[string]uint64, len(st.paths))
+	for route, count := range st.paths {
+		paths[route] = count
+	}
+	st.mu.Unlock()
+
+	return statsSnapshot{
+		Connections:  st.connections.Load(),
+		Requests:     st.requests.Load(),
+		Errors:       st.errors.Load(),
+		BytesWritten: st.bytes.Load(),
+		Paths:        paths,
+	}
+}
+
+// Stats returns the request counters of the server, answered by /stats and fed by the program serving it.
+func (s *Server) Stats() *Stats {
+	return s.stats
+}
+
+// HandleStats is a HTTP handler answering the request counters, the start time and the uptime as JSON.
+func (s *Server) HandleStats(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		MethodNotAllowed(rw, req, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	answer := s.stats.snapshot()
+	answer.Greetings = s.count.Load()
+	answer.Started = s.started.UTC()
+	answer.Uptime = time.Since(s.started).Round(time.Second).String()
+	body, err := json.Marshal(answer)
+	if err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to marshal stats")
+		internalError(rw, req)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if _, err := rw.Write(body); err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to write stats content")
+	}
+}
@@ -0,0 +1,95 @@
+package greeting
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestStatsConcurrent(t *testing.T) {
+	const goroutines, requests = 16, 500
+	server := New()
+	stats := server.Stats()
+
+	// The counters are fed by every request at once, while /stats reads them.
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				status := http.StatusOK
+				if i%10 == 0 {
+					status = http.StatusServiceUnavailable
+				}
+				route := "/greet"
+				if g%2 == 1 {
+					route = "/health"
+				}
+				stats.ConnState(nil, http.StateNew)
+				stats.Record(route, status, 10)
+				stats.ConnState(nil, http.StateClosed)
+			}
+		}(g)
+	}
+	done := make(chan struct{})
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				serve(server, http.MethodGet, "/stats", "")
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+	<-read
+
+	var got statsSnapshot
+	rw := serve(server, http.MethodGet, "/stats", "")
+	if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET /stats = %q: %v", rw.Body.String(), err)
+	}
+	// The requests of the /stats readers are counted by the program serving the server, not by the server.
+	const total = goroutines * requests
+	if got.Requests != total || got.Errors != total/10 || got.BytesWritten != total*10 || got.Connections != 0 {
+		t.Errorf("stats = %+v, want %d requests, %d errors, %d bytes and no connection", got, total, total/10, total*10)
+	}
+	if got.Paths["/greet"] != total/2 || got.Paths["/health"] != total/2 || len(got.Paths) != 2 {
+		t.Errorf("paths = %v, want %d each for /greet and /health", got.Paths, total/2)
+	}
+}
+
+func TestStatsConnState(t *testing.T) {
+	var stats Stats
+	for _, state := range []http.ConnState{http.StateNew, http.StateNew, http.StateActive, http.StateIdle, http.StateNew, http.StateClosed, http.StateHijacked} {
+		stats.ConnState(nil, state)
+	}
+	if connections := stats.snapshot().Connections; connections != 1 {
+		t.Errorf("connections = %d, want 1 left open", connections)
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	server := New(WithName("Greeter"))
+	serve(server, http.MethodGet, "/greet", "")
+	server.Stats().Record("/greet", http.StatusOK, 12)
+	server.Stats().Record("/greet", http.StatusInternalServerError, 22)
+
+	rw := serve(server, http.MethodGet, "/stats", "")
+	var got statsSnapshot
+	if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET /stats = %q: %v", rw.Body.String(), err)
+	}
+	if got.Greetings != 1 || got.Requests != 2 || got.Errors != 1 || got.BytesWritten != 34 || got.Paths["/greet"] != 2 {
+		t.Errorf("stats = %+v", got)
+	}
+	if !got.Started.Equal(server.started.UTC()) || got.Uptime == "" {
+		t.Errorf("started %s, uptime %q, want the start of the server", got.Started, got.Uptime)
+	}
+}
@@ -0,0 +1,207 @@
+package greeting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/websocket"
+)
+
+// defaultStreamInterval is the time between the greetings pushed by /greet/stream.
+const defaultStreamInterval = 5 * time.Second
+
+// streamWriteTimeout is the time given to every greeting pushed to reach the caller, past which the stream ends.
+const streamWriteTimeout = 10 * time.Second
+
+// errStreamClosed ends a stream closed by the caller.
+var errStreamClosed = errors.New("stream closed by the caller")
+
+// HandleStream is a HTTP handler pushing the greeting every stream interval and as soon as the name or
+// the translations change, over a WebSocket when asked to upgrade, as server-sent events otherwise.
+// The caller named by ?name= is greeted first.
+func (s *Server) HandleStream(rw http.ResponseWriter, req *http.Request) {
+	RequestLog(req).Debug("Stream")
+	if req.Method != http.MethodGet {
+		MethodNotAllowed(rw, req, http.MethodGet)
+		return
+	}
+
+	caller := req.URL.Query().Get("name")
+	if err := ValidateName(caller); err != nil {
+		RequestLog(req).WithError(err).Debug("Invalid caller name")
+		Error(rw, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if streams := s.streams.Add(1); s.maxStreams > 0 && streams > int64(s.maxStreams) {
+		s.streams.Add(-1)
+		RequestLog(req).Debug("Too many streams")
+		rw.Header().Set("Retry-After", "1")
+		Error(rw, req, http.StatusServiceUnavailable, "too many streams")
+		return
+	}
+	defer s.streams.Add(-1)
+
+	push := func() ([]byte, error) {
+		greeting, _, err := s.Greet(caller, req.URL.Query().Get("lang"), req.Header.Get("Accept-Language"))
+		if err != nil {
+			return nil, fmt.Errorf("render greeting: %w", err)
+		}
+		return json.Marshal(greeting)
+	}
+
+	if isWebSocket(req) {
+		origin, err := sameOrigin(req)
+		if err != nil {
+			RequestLog(req).WithError(err).Debug("WebSocket origin refused")
+			Error(rw, req, http.StatusForbidden, err.Error())
+			return
+		}
+		websocket.Server{
+			Handshake: func(config *websocket.Config, _ *http.Request) error {
+				config.Origin = origin
+				return nil
+			},
+			Handler: func(conn *websocket.Conn) {
+				s.streamWebSocket(req, conn, push)
+			},
+		}.ServeHTTP(rw, req)
+		return
+	}
+	s.streamEvents(rw, req, push)
+}
+
+// streamWebSocket sends the greetings as text messages, reading the messages of the caller only to tell when
+// it goes away.
+func (s *Server) streamWebSocket(req *http.Request, conn *websocket.Conn, push func() ([]byte, error)) {
+	defer conn.Close()
+
+	// The hijacked connection keeps the deadlines of the HTTP server.
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		RequestLog(req).WithError(err).Debug("Unable to clear stream read deadline")
+	}
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var discarded []byte
+		for websocket.Message.Receive(conn, &discarded) == nil {
+		}
+	}()
+
+	err := s.stream(gone, func() error {
+		body, err := push()
+		if err != nil {
+			return err
+		}
+		if err := conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			return err
+		}
+		return websocket.Message.Send(conn, string(body))
+	})
+	if err == nil {
+		// The server shuts down, the caller is told to come back later.
+		_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		_ = conn.WriteClose(1001)
+	}
+	logStreamEnd(req, err)
+}
+
+// streamEvents sends the greetings as "greeting" server-sent events.
+func (s *Server) streamEvents(rw http.ResponseWriter, req *http.Request, push func() ([]byte, error)) {
+	controller := http.NewResponseController(rw)
+	header := rw.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// Asks the proxies, such as nginx, not to buffer the events.
+	header.Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+
+	err := s.stream(req.Context().Done(), func() error {
+		body, err := push()
+		if err != nil {
+			return err
+		}
+		// Extends the write timeout of the HTTP server, which would end the stream otherwise.
+		if err := controller.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := fmt.Fprintf(rw, "event: greeting\ndata: %s\n\n", body); err != nil {
+			return err
+		}
+		return controller.Flush()
+	})
+	logStreamEnd(req, err)
+}
+
+// stream sends the greeting at once, then every stream interval and on every change of the name or the
+// translations, until the caller is gone or the server shuts down, returning nil for the latter.
+func (s *Server) stream(gone <-chan struct{}, send func() error) error {
+	ticker := time.NewTicker(s.streamInterval)
+	defer ticker.Stop()
+
+	for {
+		// The channel is taken before the greeting so that a change in between is not missed.
+		changed := s.changes()
+		if err := send(); err != nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-changed:
+		case <-gone:
+			return errStreamClosed
+		case <-s.closing:
+			return nil
+		}
+	}
+}
+
+// CloseStreams ends the streams served and the ones to come, as the HTTP server shuts down: they would
+// keep it waiting otherwise.
+func (s *Server) CloseStreams() {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+}
+
+// logStreamEnd logs why the stream ended.
+func logStreamEnd(req *http.Request, err error) {
+	switch {
+	case err == nil:
+		RequestLog(req).Debug("Stream closed on shutdown")
+	case errors.Is(err, errStreamClosed):
+		RequestLog(req).Debug("Stream closed by the caller")
+	default:
+		RequestLog(req).WithError(err).Debug("Stream ended")
+	}
+}
+
+// isWebSocket tells whether the request asks to upgrade to a WebSocket.
+func isWebSocket(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		httpguts.HeaderValuesContainsToken(req.Header["Connection"], "upgrade")
+}
+
+// sameOrigin returns the origin of a WebSocket handshake, accepting the clients sending none, such as the
+// command line ones, and the pages served by the server itself, so that other sites cannot stream through
+// a browser.
+func sameOrigin(req *http.Request) (*url.URL, error) {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid origin: %w", err)
+	}
+	if !strings.EqualFold(parsed.Host, req.Host) {
+		return nil, fmt.Errorf("origin %s not allowed", origin)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,267 @@
+package greeting
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// startStreams serves the server over HTTP, ending its streams before the HTTP server is closed.
+func startStreams(t *testing.T, server *Server) *httptest.Server {
+	t.Helper()
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.CloseStreams()
+		httpServer.Close()
+	})
+	return httpServer
+}
+
+// dialStream opens a WebSocket to /greet/stream with the query, from the origin.
+func dialStream(t *testing.T, httpServer *httptest.Server, query, origin string) (*websocket.Conn, error) {
+	t.Helper()
+	config, err := websocket.NewConfig(strings.Replace(httpServer.URL, "http://", "ws://", 1)+"/greet/stream"+query, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := websocket.DialConfig(config)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, err
+}
+
+// receiveGreeting reads the next greeting pushed over the WebSocket.
+func receiveGreeting(t *testing.T, conn *websocket.Conn) Greeting {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message string
+	if err := websocket.Message.Receive(conn, &message); err != nil {
+		t.Fatalf("receive greeting: %v", err)
+	}
+	var greeting Greeting
+	if err := json.Unmarshal([]byte(message), &greeting); err != nil {
+		t.Fatalf("greeting %q: %v", message, err)
+	}
+	return greeting
+}
+
+// waitStreams waits until the server serves the number of streams.
+func waitStreams(t *testing.T, server *Server, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for server.streams.Load() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d streams served, want %d", server.streams.Load(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	server := New(WithName("Greeter"), WithStreamInterval(time.Hour))
+	httpServer := startStreams(t, server)
+
+	conn, err := dialStream(t, httpServer, "?name=Ada&lang=fr", httpServer.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if greeting := receiveGreeting(t, conn); greeting.Caller != "Ada" || greeting.Message != "Hello Ada, Je suis Greeter" {
+		t.Errorf("first greeting = %+v", greeting)
+	}
+
+	// A change of name is pushed at once, without waiting for the interval.
+	server.SetName("Other")
+	if greeting := receiveGreeting(t, conn); greeting.Name != "Other" || greeting.Message != "Hello Ada, Je suis Other" {
+		t.Errorf("greeting once renamed = %+v", greeting)
+	}
+
+	// The shutdown closes the WebSocket, telling the caller to come back later.
+	server.CloseStreams()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message string
+	if err := websocket.Message.Receive(conn, &message); !errors.Is(err, io.EOF) {
+		t.Errorf("receive once shutting down = %q, %v, want the WebSocket closed", message, err)
+	}
+	waitStreams(t, server, 0)
+}
+
+func TestStreamWebSocketInterval(t *testing.T) {
+	server := New(WithName("Greeter"), WithStreamInterval(10*time.Millisecond))
+	httpServer := startStreams(t, server)
+
+	conn, err := dialStream(t, httpServer, "", httpServer.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	var previous uint64
+	for i := 0; i < 3; i++ {
+		greeting := receiveGreeting(t, conn)
+		if greeting.Message != "I am Greeter" {
+			t.Errorf("greeting %d = %+v", i, greeting)
+		}
+		if count := server.count.Load(); count <= previous {
+			t.Errorf("greeting %d not counted", i)
+		} else {
+			previous = count
+		}
+	}
+
+	// The caller going away ends its stream.
+	conn.Close()
+	waitStreams(t, server, 0)
+}
+
+func TestStreamWebSocketOrigin(t *testing.T) {
+	server := New(WithStreamInterval(time.Hour))
+	httpServer := startStreams(t, server)
+
+	if _, err := dialStream(t, httpServer, "", "https://evil.example.com"); err == nil {
+		t.Error("WebSocket opened from another origin")
+	}
+	waitStreams(t, server, 0)
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		want    string
+		wantErr bool
+	}{
+		{origin: "", want: ""},
+		{origin: "http://greeting.example.com", want: "http://greeting.example.com"},
+		{origin: "https://GREETING.example.com", want: "https://GREETING.example.com"},
+		{origin: "http://greeting.example.com:8080", wantErr: true},
+		{origin: "https://evil.example.com", wantErr: true},
+		{origin: "://invalid", wantErr: true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://greeting.example.com/greet/stream", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		origin, err := sameOrigin(req)
+		if (err != nil) != test.wantErr {
+			t.Errorf("sameOrigin(%q) error = %v, want error %t", test.origin, err, test.wantErr)
+			continue
+		}
+		got := ""
+		if origin != nil {
+			got = origin.String()
+		}
+		if got != test.want {
+			t.Errorf("sameOrigin(%q) = %q, want %q", test.origin, got, test.want)
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	server := New(WithName("Greeter"), WithStreamInterval(time.Hour))
+	httpServer := startStreams(t, server)
+
+	resp, err := http.Get(httpServer.URL + "/greet/stream?name=Ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" || resp.Header.Get("X-Accel-Buffering") != "no" {
+		t.Fatalf("GET /greet/stream = %d of type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := bufio.NewReader(resp.Body)
+	readEvent := func() Greeting {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read event: %v", err)
+			}
+			if line == "\n" {
+				break
+			}
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+		if len(lines) != 2 || lines[0] != "event: greeting" || !strings.HasPrefix(lines[1], "data: ") {
+			t.Fatalf("event = %q", lines)
+		}
+		var greeting Greeting
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &greeting); err != nil {
+			t.Fatalf("event data %q: %v", lines[1], err)
+		}
+		return greeting
+	}
+
+	if greeting := readEvent(); greeting.Message != "Hello Ada, I am Greeter" {
+		t.Errorf("first event = %+v", greeting)
+	}
+	server.SetName("Other")
+	if greeting := readEvent(); greeting.Name != "Other" {
+		t.Errorf("event once renamed = %+v", greeting)
+	}
+
+	// The shutdown ends the response.
+	server.CloseStreams()
+	if rest, err := io.ReadAll(events); err != nil || len(rest) != 0 {
+		t.Errorf("rest of the stream = %q, %v, want its end", rest, err)
+	}
+}
+
+func TestStreamMaxStreams(t *testing.T) {
+	server := New(WithStreamInterval(time.Hour), WithMaxStreams(1))
+	httpServer := startStreams(t, server)
+
+	conn, err := dialStream(t, httpServer, "", httpServer.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	receiveGreeting(t, conn)
+
+	resp, err := http.Get(httpServer.URL + "/greet/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("stream beyond the limit = %d, Retry-After %q, want %d", resp.StatusCode, resp.Header.Get("Retry-After"), http.StatusServiceUnavailable)
+	}
+	if _, err := dialStream(t, httpServer, "", httpServer.URL); err == nil {
+		t.Error("WebSocket opened beyond the limit")
+	}
+
+	// The slot is given back once the stream ends.
+	conn.Close()
+	waitStreams(t, server, 0)
+	if conn, err = dialStream(t, httpServer, "", httpServer.URL); err != nil {
+		t.Fatalf("dial once the stream ended: %v", err)
+	}
+	receiveGreeting(t, conn)
+}
+
+func TestStreamRequests(t *testing.T) {
+	server := New()
+	for _, test := range []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodPost, "/greet/stream", http.StatusMethodNotAllowed},
+		{http.MethodHead, "/greet/stream", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/greet/stream?name=Ada%0A", http.StatusBadRequest},
+	} {
+		if rw := serve(server, test.method, test.target, ""); rw.Code != test.want {
+			t.Errorf("%s %s = %d, want %d", test.method, test.target, rw.Code, test.want)
+		}
+	}
+	if n := server.streams.Load(); n != 0 {
+		t.Errorf("%d streams counted for the refused requests", n)
+	}
+}
@@ -0,0 +1,45 @@
+package greeting
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultGreetingTemplate is the greeting message when no template is given.
+const defaultGreetingTemplate = "I am {{.Name}}"
+
+// greetingData holds the fields available to the greeting template.
+type greetingData struct {
+	// Name is the server name.
+	Name string
+	// Hostname is the host running the server.
+	Hostname string
+	// Count is the number of greetings served so far, this one included.
+	Count uint64
+	// Now is the time of the greeting.
+	Now time.Time
+}
+
+// parseGreetingTemplate parses the greeting template, so that a broken template fails at startup.
+func parseGreetingTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("greeting").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse greeting template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderMessage renders the greeting message of the data, "I am" followed by the name without template.
+func renderMessage(tmpl *template.Template, data greetingData) (string, error) {
+	if tmpl == nil {
+		return fmt.Sprintf("I am %s", data.Name), nil
+	}
+
+	var message strings.Builder
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", fmt.Errorf("execute greeting template: %w", err)
+	}
+	return message.String(), nil
+}
@@ -0,0 +1,56 @@
+package greeting
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//go:embed ui/index.html
+var uiFiles embed.FS
+
+// landingPage is the HTML page served on /, presenting the server with a button fetching its greeting.
+var landingPage = template.Must(template.ParseFS(uiFiles, "ui/index.html"))
+
+// landingData holds the fields shown by the landing page.
+type landingData struct {
+	Name     string
+	Hostname string
+	Version  string
+	Uptime   string
+}
+
+// HandleRoot is a HTTP handler answering the landing page on /, when enabled, and 404 Not Found on the
+// unknown paths.
+func (s *Server) HandleRoot(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" || !s.ui {
+		notFound(rw, req)
+		return
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		MethodNotAllowed(rw, req, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	data := landingData{
+		Name:     s.Name(),
+		Hostname: s.hostname,
+		Version:  s.build.Version,
+		Uptime:   time.Since(s.started).Round(time.Second).String(),
+	}
+	var page bytes.Buffer
+	if err := landingPage.Execute(&page, data); err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to render landing page")
+		internalError(rw, req)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.Header().Set("Content-Length", strconv.Itoa(page.Len()))
+	if _, err := rw.Write(page.Bytes()); err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to write landing page")
+	}
+}
@@ -0,0 +1,71 @@
+package greeting
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"edb-challenge/pkg/version"
+)
+
+func TestHandleRoot(t *testing.T) {
+	server := New(WithName("<b>Greeter</b>"), WithHostname("pod-1"), WithBuild(version.Info{Version: "v1.2.3"}))
+
+	rw := serve(server, http.MethodGet, "/", "")
+	if rw.Code != http.StatusOK || rw.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("GET / = %d of type %q, want the HTML landing page", rw.Code, rw.Header().Get("Content-Type"))
+	}
+	if length := rw.Header().Get("Content-Length"); length != strconv.Itoa(rw.Body.Len()) {
+		t.Errorf("Content-Length = %s, want %d", length, rw.Body.Len())
+	}
+	body := rw.Body.String()
+	// The name is set by the operators or through /name, it is escaped rather than trusted as HTML.
+	for _, want := range []string{"<h1>&lt;b&gt;Greeter&lt;/b&gt;</h1>", "<dd>pod-1</dd>", "<dd>v1.2.3</dd>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("landing page without %q", want)
+		}
+	}
+	if strings.Contains(body, "<b>Greeter</b>") {
+		t.Error("landing page with the name unescaped")
+	}
+
+	if rw := serve(server, http.MethodHead, "/", ""); rw.Code != http.StatusOK {
+		t.Errorf("HEAD / = %d, want %d", rw.Code, http.StatusOK)
+	}
+	if rw := serve(server, http.MethodPost, "/", ""); rw.Code != http.StatusMethodNotAllowed || rw.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST / = %d, Allow %q, want %d", rw.Code, rw.Header().Get("Allow"), http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandleRootNotFound(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		method string
+		target string
+	}{
+		{name: "unknown path", target: "/unknown"},
+		{name: "unknown nested path", target: "/unknown/path"},
+		{name: "unknown path posted", method: http.MethodPost, target: "/unknown"},
+		{name: "UI disabled", opts: []Option{WithUI(false)}, target: "/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rw := serve(New(test.opts...), method, test.target, "")
+			if rw.Code != http.StatusNotFound || !strings.Contains(rw.Body.String(), "no such path "+test.target) {
+				t.Errorf("%s %s = %d %q, want %d", method, test.target, rw.Code, rw.Body.String(), http.StatusNotFound)
+			}
+		})
+	}
+
+	// The API stays served without the landing page.
+	if rw := serve(New(WithUI(false)), http.MethodGet, "/greet", ""); rw.Code != http.StatusOK {
+		t.Errorf("GET /greet with UI disabled = %d, want %d", rw.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,29 @@
+package greeting
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// HandleVersion is a HTTP handler answering the build of the server as JSON, the very one whose version
+// is told by the greetings and the health probe.
+func (s *Server) HandleVersion(rw http.ResponseWriter, req *http.Request) {
+	RequestLog(req).Debug("Version")
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		MethodNotAllowed(rw, req, http.MethodGet, http.MethodHead)
+		return
+	}
+
+	body, err := json.Marshal(s.build)
+	if err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to marshal version")
+		internalError(rw, req)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if _, err := rw.Write(body); err != nil {
+		RequestLog(req).WithError(err).Warning("Unable to write version content")
+	}
+}
@@ -0,0 +1,114 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metaac "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// fieldManager is the name under which the operator owns the fields it applies.
+const fieldManager = "greeting-operator"
+
+// applyOptions returns the options of every server-side apply request.
+// Ownership is forced so that fields written by the legacy update path are taken over.
+func (o *Operator) applyOptions() meta.ApplyOptions {
+	return meta.ApplyOptions{FieldManager: fieldManager, Force: true, DryRun: o.dryRun()}
+}
+
+// meaningfulEmptyObjects are the fields whose empty object is a value, such as the emptyDir volume source.
+var meaningfulEmptyObjects = map[string]bool{"emptyDir": true}
+
+// convertApplyConfiguration converts a typed object into its apply configuration counterpart.
+// Both share the same JSON representation, and only the fields set on the typed object, i.e. the
+// fields managed by the operator, end up in the apply configuration. The empty structs of the typed
+// object, such as the container resources, are left out: applying them would own fields set by others.
+func convertApplyConfiguration(typed, applyConfiguration interface{}) error {
+	raw, err := json.Marshal(typed)
+	if err != nil {
+		return fmt.Errorf("marshal %T: %w", typed, err)
+	}
+
+	var fields interface{}
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return fmt.Errorf("unmarshal %T: %w", typed, err)
+	}
+	if raw, err = json.Marshal(pruneEmptyObjects(fields)); err != nil {
+		return fmt.Errorf("marshal %T: %w", typed, err)
+	}
+
+	if err = json.Unmarshal(raw, applyConfiguration); err != nil {
+		return fmt.Errorf("unmarshal %T: %w", applyConfiguration, err)
+	}
+
+	return nil
+}
+
+// pruneEmptyObjects removes the null fields and the empty objects, but the meaningful ones, recursively.
+func pruneEmptyObjects(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			field = pruneEmptyObjects(field)
+			if object, ok := field.(map[string]interface{}); field == nil || ok && len(object) == 0 && !meaningfulEmptyObjects[key] {
+				delete(value, key)
+				continue
+			}
+			value[key] = field
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = pruneEmptyObjects(value[i])
+		}
+	}
+	return value
+}
+
+// ownsField tells whether the field, given by the keys of its path in the managed fields, is owned by the
+// server-side apply of the manager.
+func ownsField(object meta.Object, manager string, path ...string) bool {
+	for _, entry := range object.GetManagedFields() {
+		if entry.Manager != manager || entry.Operation != meta.ManagedFieldsOperationApply || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		owned := true
+		for _, key := range path {
+			next, found := fields[key].(map[string]interface{})
+			if !found {
+				owned = false
+				break
+			}
+			fields = next
+		}
+		if owned {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerReferencesApplyConfiguration converts the owner references into their apply configuration counterpart.
+func ownerReferencesApplyConfiguration(refs []meta.OwnerReference) []*metaac.OwnerReferenceApplyConfiguration {
+	var configurations []*metaac.OwnerReferenceApplyConfiguration
+	for _, ref := range refs {
+		configuration := metaac.OwnerReference().
+			WithAPIVersion(ref.APIVersion).
+			WithKind(ref.Kind).
+			WithName(ref.Name).
+			WithUID(ref.UID)
+		if ref.Controller != nil {
+			configuration.WithController(*ref.Controller)
+		}
+		if ref.BlockOwnerDeletion != nil {
+			configuration.WithBlockOwnerDeletion(*ref.BlockOwnerDeletion)
+		}
+		configurations = append(configurations, configuration)
+	}
+
+	return configurations
+}
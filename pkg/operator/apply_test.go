@@ -0,0 +1,173 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// appliedPatch returns the last server-side apply request sent for the resource.
+func appliedPatch(t *testing.T, actions []ktesting.Action, resource string) []byte {
+	t.Helper()
+	var applied []byte
+	for _, action := range actions {
+		if patch, ok := action.(ktesting.PatchAction); ok && patch.GetPatchType() == types.ApplyPatchType && patch.GetResource().Resource == resource {
+			applied = patch.GetPatch()
+		}
+	}
+	if applied == nil {
+		t.Fatalf("no %s applied", resource)
+	}
+	return applied
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("unmarshal %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("unmarshal %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApplyDeploymentPayload(t *testing.T) {
+	client := newFakeClient()
+	op := newTestOperator(t, client)
+	if err := op.install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	assertJSONEqual(t, appliedPatch(t, client.Actions(), "deployments"), `{
+		"kind": "Deployment",
+		"apiVersion": "apps/v1",
+		"metadata": {
+			"name": "greeting",
+			"namespace": "default",
+			"labels": {"app": "greeting", "app.kubernetes.io/managed-by": "greeting-operator", "greeting.moutoum.dev/instance": "greeting"}
+		},
+		"spec": {
+			"replicas": 1,
+			"selector": {"matchLabels": {"app": "greeting"}},
+			"strategy": {"type": "RollingUpdate"},
+			"template": {
+				"metadata": {"name": "greeting", "labels": {"app": "greeting"}},
+				"spec": {
+					"containers": [{
+						"name": "greeting",
+						"image": "greeting:latest",
+						"ports": [{"name": "http", "containerPort": 80, "protocol": "TCP"}],
+						"env": [
+							{"name": "NAME", "value": "anonymous"},
+							{"name": "SHOW_HOST", "value": "true"},
+							{"name": "SHUTDOWN_DELAY", "value": "5s"}
+						],
+						"livenessProbe": {"httpGet": {"path": "/health", "port": 80}, "timeoutSeconds": 3},
+						"readinessProbe": {"httpGet": {"path": "/ready", "port": 80}, "timeoutSeconds": 3},
+						"imagePullPolicy": "Never"
+					}],
+					"restartPolicy": "Always",
+					"terminationGracePeriodSeconds": 30
+				}
+			}
+		}
+	}`)
+}
+
+func TestApplyDaemonSetPayload(t *testing.T) {
+	client := newFakeClient()
+	op := newTestOperator(t, client, WithWorkload(WorkloadDaemonSet), WithInitContainers(InitContainer{Name: "setup", Image: "busybox"}))
+	if err := op.install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	// No empty updateStrategy nor container resources, the emptyDir volume source being kept.
+	assertJSONEqual(t, appliedPatch(t, client.Actions(), "daemonsets"), `{
+		"kind": "DaemonSet",
+		"apiVersion": "apps/v1",
+		"metadata": {
+			"name": "greeting",
+			"namespace": "default",
+			"labels": {"app": "greeting", "app.kubernetes.io/managed-by": "greeting-operator", "greeting.moutoum.dev/instance": "greeting"}
+		},
+		"spec": {
+			"selector": {"matchLabels": {"app": "greeting"}},
+			"template": {
+				"metadata": {"name": "greeting", "labels": {"app": "greeting"}},
+				"spec": {
+					"volumes": [{"name": "shared", "emptyDir": {}}],
+					"initContainers": [{
+						"name": "setup",
+						"image": "busybox",
+						"volumeMounts": [{"name": "shared", "mountPath": "/cache"}]
+					}],
+					"containers": [{
+						"name": "greeting",
+						"image": "greeting:latest",
+						"ports": [{"name": "http", "containerPort": 80, "protocol": "TCP"}],
+						"env": [
+							{"name": "NAME", "value": "anonymous"},
+							{"name": "SHOW_HOST", "value": "true"},
+							{"name": "SHUTDOWN_DELAY", "value": "5s"}
+						],
+						"volumeMounts": [{"name": "shared", "mountPath": "/cache"}],
+						"livenessProbe": {"httpGet": {"path": "/health", "port": 80}, "timeoutSeconds": 3},
+						"readinessProbe": {"httpGet": {"path": "/ready", "port": 80}, "timeoutSeconds": 3},
+						"imagePullPolicy": "Never"
+					}],
+					"restartPolicy": "Always",
+					"terminationGracePeriodSeconds": 30
+				}
+			}
+		}
+	}`)
+}
+
+func TestApplyServicePayload(t *testing.T) {
+	client := newFakeClient()
+	op := newTestOperator(t, client)
+	if err := op.install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	assertJSONEqual(t, appliedPatch(t, client.Actions(), "services"), `{
+		"kind": "Service",
+		"apiVersion": "v1",
+		"metadata": {
+			"name": "greeting",
+			"namespace": "default",
+			"labels": {"app": "greeting", "app.kubernetes.io/managed-by": "greeting-operator", "greeting.moutoum.dev/instance": "greeting"}
+		},
+		"spec": {
+			"ports": [{"name": "http", "protocol": "TCP", "port": 80, "targetPort": "http"}],
+			"selector": {"app": "greeting"},
+			"type": "LoadBalancer"
+		}
+	}`)
+}
+
+func TestPruneEmptyObjects(t *testing.T) {
+	var fields interface{}
+	if err := json.Unmarshal([]byte(`{
+		"resources": {},
+		"securityContext": {"capabilities": {}},
+		"creationTimestamp": null,
+		"volumes": [{"name": "shared", "emptyDir": {}}],
+		"name": ""
+	}`), &fields); err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(pruneEmptyObjects(fields))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, got, `{"volumes": [{"name": "shared", "emptyDir": {}}], "name": ""}`)
+}
@@ -0,0 +1,275 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// trackLabel tells the canary pods apart from the stable ones, both selected by the service.
+	trackLabel  = "greeting.moutoum.dev/track"
+	trackCanary = "canary"
+	trackStable = "stable"
+
+	// canaryWeightAnnotation records the percentage of the traffic requested for the canary.
+	canaryWeightAnnotation = "greeting.moutoum.dev/canary-weight"
+)
+
+// Reasons of the events recorded on the canary deployment.
+const (
+	reasonCanaryStarted  = "CanaryStarted"
+	reasonCanaryPromoted = "CanaryPromoted"
+	reasonCanaryAborted  = "CanaryAborted"
+)
+
+// greetingContainerName is the name of the container running the greeting server.
+const greetingContainerName = "greeting"
+
+// canaryName returns the name of the canary deployment.
+func (o *Operator) canaryName() string {
+	return o.resourceName + "-" + trackCanary
+}
+
+// isCanary tells whether the object is the canary deployment of this instance.
+func (o *Operator) isCanary(kind string, object meta.Object) bool {
+	return kind == "deployment" && object.GetName() == o.canaryName() &&
+		object.GetLabels()[trackLabel] == trackCanary && object.GetLabels()[instanceLabel] == o.resourceName
+}
+
+// canaryReplicas returns the replicas receiving the weight, in percent, of the traffic next to the stable replicas.
+// A canary always runs at least one pod.
+func canaryReplicas(stable int32, weight int) int32 {
+	replicas := int32(math.Round(float64(stable) * float64(weight) / float64(100-weight)))
+	if replicas < 1 {
+		return 1
+	}
+	return replicas
+}
+
+// desiredCanary returns the canary deployment running the image next to the stable deployment.
+// Its pods carry the labels selected by the service, so that the traffic is split by pod count.
+func (o *Operator) desiredCanary(image string, weight int, replicas int32) *apps.Deployment {
+	deployment := o.desiredDeployment()
+	deployment.Name = o.canaryName()
+	deployment.Labels[trackLabel] = trackCanary
+	deployment.Annotations = map[string]string{canaryWeightAnnotation: strconv.Itoa(weight)}
+	deployment.Spec.Replicas = &replicas
+
+	// The selector differs from the stable one, so that the deployments do not fight over the pods.
+	deployment.Spec.Selector.MatchLabels[trackLabel] = trackCanary
+	template := &deployment.Spec.Template
+	template.Name = o.canaryName()
+	template.Labels[trackLabel] = trackCanary
+	delete(template.Annotations, imageAnnotation)
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == greetingContainerName {
+			template.Spec.Containers[i].Image = image
+		}
+	}
+
+	return deployment
+}
+
+// StartCanary creates or updates the canary deployment running the image, sized to receive the weight,
+// in percent, of the traffic of the service next to the stable deployment.
+func (o *Operator) StartCanary(ctx context.Context, image string, weight int) error {
+	if o.workload == WorkloadDaemonSet {
+		return fmt.Errorf("canary requires the %s workload", WorkloadDeployment)
+	}
+	if err := validateImage(image); err != nil {
+		return err
+	}
+	if weight < 1 || weight > 99 {
+		return fmt.Errorf("canary weight %d must be between 1 and 99", weight)
+	}
+
+	stable, err := o.getDeployment(ctx, o.resourceName)
+	if err != nil {
+		return err
+	}
+	if stable == nil {
+		return fmt.Errorf("stable deployment %s not found in namespace %s, deploy it first", o.resourceName, o.namespace)
+	}
+
+	var stableReplicas int32 = 1
+	if stable.Spec.Replicas != nil {
+		stableReplicas = *stable.Spec.Replicas
+	}
+	replicas := canaryReplicas(stableReplicas, weight)
+
+	logger := o.logger("deployment").WithFields(log.Fields{"name": o.canaryName(), "image": image, "weight": weight, "replicas": replicas})
+	logger.Info("Starting canary")
+
+	written, err := o.writeCanary(ctx, o.desiredCanary(image, weight, replicas))
+	if err != nil {
+		return fmt.Errorf("write canary deployment: %w", err)
+	}
+	o.logDryRun("deployment", written)
+
+	o.event(written, api.EventTypeNormal, reasonCanaryStarted,
+		fmt.Sprintf("Canary %s runs %s on %d replicas next to %d stable replicas, for %d%% of the traffic", o.canaryName(), image, replicas, stableReplicas, weight))
+	return nil
+}
+
+// writeCanary creates or replaces the canary deployment, replicas included.
+func (o *Operator) writeCanary(ctx context.Context, desired *apps.Deployment) (*apps.Deployment, error) {
+	if !o.legacyUpdate {
+		return o.applyDeployment(ctx, desired)
+	}
+
+	deploymentClient := o.client.AppsV1().Deployments(o.namespace)
+
+	var written *apps.Deployment
+	err := o.api.call(ctx, "deployment", "create", func(ctx context.Context) error {
+		var err error
+		written, err = deploymentClient.Create(ctx, desired, o.createOptions())
+		return err
+	})
+	if !kerror.IsAlreadyExists(err) {
+		return written, err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := o.getDeployment(ctx, desired.Name)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return kerror.NewNotFound(apps.Resource("deployments"), desired.Name)
+		}
+
+		updated := desired.DeepCopy()
+		updated.ResourceVersion = current.ResourceVersion
+		return o.api.call(ctx, "deployment", "update", func(ctx context.Context) error {
+			var err error
+			written, err = deploymentClient.Update(ctx, updated, o.updateOptions())
+			return err
+		})
+	})
+	return written, err
+}
+
+// PromoteCanary rolls the stable deployment to the image of the canary, then deletes the canary once the
+// stable deployment is rolled out. The image must then be given to the operator, otherwise its next
+// reconciliation rolls the stable deployment back.
+func (o *Operator) PromoteCanary(ctx context.Context) error {
+	canary, err := o.getDeployment(ctx, o.canaryName())
+	if err != nil {
+		return err
+	}
+	if canary == nil || !o.isCanary("deployment", canary) {
+		return fmt.Errorf("no canary found in namespace %s", o.namespace)
+	}
+
+	image := deploymentImage(canary)
+	if image == "" {
+		return fmt.Errorf("canary %s has no %s container", canary.Name, greetingContainerName)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{imageAnnotation: nil},
+				},
+				"spec": map[string]interface{}{
+					"containers": []map[string]interface{}{{"name": greetingContainerName, "image": image}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	logger := o.logger("deployment").WithField("image", image)
+	logger.Info("Promoting canary")
+
+	var stable *apps.Deployment
+	err = o.api.call(ctx, "deployment", "patch", func(ctx context.Context) error {
+		var err error
+		stable, err = o.client.AppsV1().Deployments(o.namespace).Patch(ctx, o.resourceName, types.StrategicMergePatchType, patch, o.patchOptions())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("patch stable deployment: %w", err)
+	}
+	o.logDryRun("deployment", stable)
+
+	// The canary keeps serving until the stable pods run its image, and nothing rolls out in a server dry-run.
+	if !o.serverDryRun {
+		if err = o.waitForRollout(ctx); err != nil {
+			return fmt.Errorf("stable deployment not rolled out, keeping the canary: %w", err)
+		}
+	}
+
+	if err = o.deleteCanary(ctx); err != nil {
+		return err
+	}
+
+	o.event(stable, api.EventTypeNormal, reasonCanaryPromoted, fmt.Sprintf("Promoted canary image %s to deployment %s", image, o.resourceName))
+	logger.Warning("Canary promoted, pass its image to the operator so that the next reconciliation keeps it")
+	return nil
+}
+
+// AbortCanary deletes the canary deployment, leaving the stable deployment untouched.
+func (o *Operator) AbortCanary(ctx context.Context) error {
+	canary, err := o.getDeployment(ctx, o.canaryName())
+	if err != nil {
+		return err
+	}
+	if canary == nil || !o.isCanary("deployment", canary) {
+		return fmt.Errorf("no canary found in namespace %s", o.namespace)
+	}
+
+	if err = o.deleteCanary(ctx); err != nil {
+		return err
+	}
+
+	o.event(canary, api.EventTypeNormal, reasonCanaryAborted, fmt.Sprintf("Aborted canary %s of image %s", canary.Name, deploymentImage(canary)))
+	return nil
+}
+
+func (o *Operator) deleteCanary(ctx context.Context) error {
+	return o.deleteObject(ctx, "deployment", o.canaryName(), func(ctx context.Context) error {
+		return o.client.AppsV1().Deployments(o.namespace).Delete(ctx, o.canaryName(), o.deleteOptions())
+	})
+}
+
+// getDeployment returns the named deployment of the namespace, or nil when missing.
+func (o *Operator) getDeployment(ctx context.Context, name string) (*apps.Deployment, error) {
+	var deployment *apps.Deployment
+	err := o.api.call(ctx, "deployment", "get", func(ctx context.Context) error {
+		var err error
+		deployment, err = o.client.AppsV1().Deployments(o.namespace).Get(ctx, name, meta.GetOptions{})
+		return err
+	})
+	if kerror.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get deployment %q: %w", name, err)
+	}
+	return deployment, nil
+}
+
+// deploymentImage returns the image of the greeting container of the deployment.
+func deploymentImage(deployment *apps.Deployment) string {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == greetingContainerName {
+			return container.Image
+		}
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// cleanupTimeout bounds the deletion of the created resources once the run is interrupted.
+const cleanupTimeout = 30 * time.Second
+
+// Exit codes of an interrupted run.
+const (
+	// exitInterruptedClean is used when every resource created by the run was deleted.
+	exitInterruptedClean = 2
+	// exitInterruptedDirty is used when the run may have left resources behind.
+	exitInterruptedDirty = 3
+)
+
+// InterruptedError is returned when the run is interrupted before the resources are all in place.
+type InterruptedError struct {
+	// Cleaned reports whether the resources created by the run were deleted.
+	Cleaned bool
+	Err     error
+}
+
+func (e *InterruptedError) Error() string {
+	if e.Cleaned {
+		return fmt.Sprintf("interrupted, created resources deleted: %v", e.Err)
+	}
+	return fmt.Sprintf("interrupted, resources may be left behind: %v", e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the process for the interruption.
+func (e *InterruptedError) ExitCode() int {
+	if e.Cleaned {
+		return exitInterruptedClean
+	}
+	return exitInterruptedDirty
+}
+
+// createdResource is a resource that may be created by the run.
+type createdResource struct {
+	kind string
+	name string
+	get  func(context.Context) error
+	del  func(context.Context) error
+}
+
+// trackCreation calls create, remembering the resource for the cleanup when it did not exist before.
+// Nothing is tracked unless the cleanup on interrupt is enabled, sparing the extra lookup.
+func (o *Operator) trackCreation(ctx context.Context, resource createdResource, create func(context.Context) error) error {
+	// Nothing is created by a server dry-run, there is nothing to clean up.
+	if !o.cleanupOnInterrupt || o.serverDryRun {
+		return create(ctx)
+	}
+
+	err := resource.get(ctx)
+	if err != nil && !kerror.IsNotFound(err) {
+		return fmt.Errorf("get %s: %w", resource.kind, err)
+	}
+	existed := err == nil
+
+	if err = create(ctx); err != nil {
+		return err
+	}
+
+	if !existed {
+		o.created = append(o.created, resource)
+	}
+	return nil
+}
+
+// interrupted deletes the resources created by the run when the cleanup is enabled, wrapping the cause of the interruption.
+func (o *Operator) interrupted(cause error) error {
+	if !o.cleanupOnInterrupt {
+		return &InterruptedError{Err: cause}
+	}
+
+	log.WithField("namespace", o.namespace).WithField("resources", len(o.created)).Warning("Interrupted, deleting the created resources")
+
+	// The run context is cancelled, the cleanup gets its own.
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	cleaned := true
+	for i := len(o.created) - 1; i >= 0; i-- {
+		resource := o.created[i]
+		if err := o.deleteObject(ctx, resource.kind, resource.name, resource.del); err != nil {
+			log.WithError(err).Error("Unable to delete created resource")
+			cleaned = false
+		}
+	}
+	o.created = nil
+
+	return &InterruptedError{Cleaned: cleaned, Err: cause}
+}
+
+// namespaceResource returns the namespace of the greeting server as a resource to clean up.
+func (o *Operator) namespaceResource() createdResource {
+	client := o.client.CoreV1().Namespaces()
+	return createdResource{
+		kind: "namespace",
+		name: o.namespace,
+		get: func(ctx context.Context) error {
+			_, err := client.Get(ctx, o.namespace, meta.GetOptions{})
+			return err
+		},
+		del: func(ctx context.Context) error {
+			return client.Delete(ctx, o.namespace, o.deleteOptions())
+		},
+	}
+}
+
+// deploymentResource returns the deployment of the greeting server as a resource to clean up.
+func (o *Operator) deploymentResource() createdResource {
+	client := o.client.AppsV1().Deployments(o.namespace)
+	return createdResource{
+		kind: "deployment",
+		name: o.resourceName,
+		get: func(ctx context.Context) error {
+			_, err := client.Get(ctx, o.resourceName, meta.GetOptions{})
+			return err
+		},
+		del: func(ctx context.Context) error {
+			return client.Delete(ctx, o.resourceName, o.deleteOptions())
+		},
+	}
+}
+
+// daemonSetResource returns the daemonset of the greeting server as a resource to clean up.
+func (o *Operator) daemonSetResource() createdResource {
+	client := o.client.AppsV1().DaemonSets(o.namespace)
+	return createdResource{
+		kind: "daemonset",
+		name: o.resourceName,
+		get: func(ctx context.Context) error {
+			_, err := client.Get(ctx, o.resourceName, meta.GetOptions{})
+			return err
+		},
+		del: func(ctx context.Context) error {
+			return client.Delete(ctx, o.resourceName, o.deleteOptions())
+		},
+	}
+}
+
+// serviceResource returns the service of the greeting server as a resource to clean up.
+func (o *Operator) serviceResource() createdResource {
+	client := o.client.CoreV1().Services(o.namespace)
+	return createdResource{
+		kind: "service",
+		name: o.resourceName,
+		get: func(ctx context.Context) error {
+			_, err := client.Get(ctx, o.resourceName, meta.GetOptions{})
+			return err
+		},
+		del: func(ctx context.Context) error {
+			return client.Delete(ctx, o.resourceName, o.deleteOptions())
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// configMapChecksumAnnotation holds the checksum of the mounted config maps on the pod template,
+// so that changing their content rolls the pods.
+const configMapChecksumAnnotation = "greeting.moutoum.dev/configmap-checksum"
+
+// ConfigMapMount is a ConfigMap mounted into the greeting server container.
+type ConfigMapMount struct {
+	// Name of the ConfigMap.
+	Name string
+	// Path where the ConfigMap is mounted.
+	Path string
+}
+
+// ParseConfigMapMounts parses the ConfigMap mounts formatted as name:/path, rejecting path collisions.
+func ParseConfigMapMounts(values []string) ([]ConfigMapMount, error) {
+	mounts := make([]ConfigMapMount, 0, len(values))
+	paths := make(map[string]string, len(values))
+	for _, value := range values {
+		name, mountPath, found := strings.Cut(value, ":")
+		if !found {
+			return nil, fmt.Errorf("configmap mount %q: expected name:/path", value)
+		}
+
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("configmap mount %q: invalid name: %s", value, strings.Join(errs, ", "))
+		}
+
+		if !path.IsAbs(mountPath) {
+			return nil, fmt.Errorf("configmap mount %q: path must be absolute", value)
+		}
+
+		mountPath = path.Clean(mountPath)
+		if other, ok := paths[mountPath]; ok {
+			return nil, fmt.Errorf("configmap mount %q: path already used by configmap %q", value, other)
+		}
+		paths[mountPath] = name
+
+		mounts = append(mounts, ConfigMapMount{Name: name, Path: mountPath})
+	}
+
+	return mounts, nil
+}
+
+// configMapVolumeName returns the name of the volume of the i-th mounted ConfigMap.
+func configMapVolumeName(i int) string {
+	return fmt.Sprintf("configmap-%d", i)
+}
+
+// configMapVolumes returns the volumes and the greeting server mounts of the mounted ConfigMaps.
+func (o *Operator) configMapVolumes() ([]api.Volume, []api.VolumeMount) {
+	var volumes []api.Volume
+	var mounts []api.VolumeMount
+	for i, m := range o.configMapMounts {
+		volumes = append(volumes, api.Volume{
+			Name: configMapVolumeName(i),
+			VolumeSource: api.VolumeSource{
+				ConfigMap: &api.ConfigMapVolumeSource{
+					LocalObjectReference: api.LocalObjectReference{Name: m.Name},
+				},
+			},
+		})
+		mounts = append(mounts, api.VolumeMount{
+			Name:      configMapVolumeName(i),
+			MountPath: m.Path,
+			ReadOnly:  true,
+		})
+	}
+
+	return volumes, mounts
+}
+
+// checkConfigMaps ensures every mounted ConfigMap exists and computes the checksum of their content.
+func (o *Operator) checkConfigMaps(ctx context.Context) error {
+	if len(o.configMapMounts) == 0 {
+		return nil
+	}
+
+	configMapClient := o.client.CoreV1().ConfigMaps(o.namespace)
+
+	hash := sha256.New()
+	for _, m := range o.configMapMounts {
+		var configMap *api.ConfigMap
+		err := o.api.call(ctx, "configmap", "get", func(ctx context.Context) error {
+			var err error
+			configMap, err = configMapClient.Get(ctx, m.Name, meta.GetOptions{})
+			return err
+		})
+		if err != nil {
+			if kerror.IsNotFound(err) {
+				return fmt.Errorf("configmap %q not found in namespace %q", m.Name, o.namespace)
+			}
+			return fmt.Errorf("get configmap: %w", err)
+		}
+
+		fmt.Fprintf(hash, "%s\n", m.Name)
+		writeSortedData(hash, configMap.Data)
+
+		binaryData := make(map[string]string, len(configMap.BinaryData))
+		for k, v := range configMap.BinaryData {
+			binaryData[k] = string(v)
+		}
+		writeSortedData(hash, binaryData)
+	}
+
+	o.configMapChecksum = hex.EncodeToString(hash.Sum(nil))
+	log.WithField("namespace", o.namespace).WithField("checksum", o.configMapChecksum).Info("Mounted configmaps found")
+
+	return nil
+}
+
+// writeSortedData writes the key/value pairs in a deterministic order.
+func writeSortedData(w io.Writer, data map[string]string) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s=%d:%s\n", k, len(data[k]), data[k])
+	}
+}
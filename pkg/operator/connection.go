@@ -0,0 +1,75 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ClusterConnection tells how to reach the API server.
+// The in-cluster configuration is used unless Server, Kubeconfig or Context is set, the kubeconfig outside
+// of a pod.
+type ClusterConnection struct {
+	// Kubeconfig is the kubeconfig file, the KUBECONFIG files or ~/.kube/config when empty.
+	Kubeconfig string
+	// Context is the kubeconfig context, its current context when empty.
+	Context string
+
+	// Server is the URL of the API server.
+	Server string
+	// Token authenticates the requests.
+	Token string
+	// TokenFile holds the token, re-read when it expires so that rotated tokens keep working.
+	TokenFile string
+	// CAFile holds the certificate authority of the API server.
+	CAFile string
+	// Insecure skips the verification of the API server certificate.
+	Insecure bool
+}
+
+// NewClusterConfig returns the configuration to connect to the API server given by the connection,
+// or to the current cluster.
+func NewClusterConfig(conn ClusterConnection) (*rest.Config, error) {
+	if conn.Server != "" {
+		return &rest.Config{
+			Host:            conn.Server,
+			BearerToken:     conn.Token,
+			BearerTokenFile: conn.TokenFile,
+			TLSClientConfig: rest.TLSClientConfig{
+				CAFile:   conn.CAFile,
+				Insecure: conn.Insecure,
+			},
+		}, nil
+	}
+
+	if conn.Kubeconfig == "" && conn.Context == "" {
+		cfg, err := rest.InClusterConfig()
+		if !errors.Is(err, rest.ErrNotInCluster) {
+			if err != nil {
+				return nil, fmt.Errorf("in cluster config: %w", err)
+			}
+			return cfg, nil
+		}
+	}
+
+	return kubeconfigClusterConfig(conn.Kubeconfig, conn.Context)
+}
+
+// kubeconfigClusterConfig loads the configuration of the kubeconfig context with the kubectl loading rules.
+func kubeconfigClusterConfig(kubeconfig, context string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if clientcmd.IsEmptyConfig(err) {
+		return nil, fmt.Errorf("not running in a pod and no kubeconfig found, pass --kubeconfig or --server: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig: %w", err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,400 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// GreetingController reconciles a greeting server for every Greeting resource.
+type GreetingController struct {
+	// defaults is the configuration of the instances, overridden by the Greeting specs.
+	defaults      *Config
+	namespace     string
+	resyncPeriod  time.Duration
+	workers       int
+	client        kubernetes.Interface
+	dynamicClient dynamic.Interface
+	queue         workqueue.RateLimitingInterface
+	greetings     cache.GenericLister
+	// namespaceSelector restricts the reconciled Greetings to the matching namespaces, nil for every namespace.
+	namespaceSelector labels.Selector
+	namespaces        corelisters.NamespaceLister
+	recorder          record.EventRecorder
+	api               apiPolicy
+}
+
+// NewGreetingController creates a GreetingController watching the Greeting resources of the namespace,
+// or of every namespace when empty.
+func NewGreetingController(defaults *Config, namespace string) (*GreetingController, error) {
+	cfg, err := NewClusterConfig(defaults.Connection)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeout = defaults.RequestTimeout
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new k8s client: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new k8s dynamic client: %w", err)
+	}
+
+	var namespaceSelector labels.Selector
+	if defaults.NamespaceSelector != "" {
+		if namespaceSelector, err = labels.Parse(defaults.NamespaceSelector); err != nil {
+			return nil, fmt.Errorf("parse namespace selector: %w", err)
+		}
+	}
+
+	_, recorder := newEventBroadcaster(client)
+
+	return &GreetingController{
+		namespaceSelector: namespaceSelector,
+		defaults:          defaults,
+		namespace:         namespace,
+		resyncPeriod:      defaults.ResyncPeriod,
+		workers:           defaults.MaxConcurrentReconciles,
+		client:            client,
+		dynamicClient:     dynamicClient,
+		recorder:          recorder,
+		api:               defaults.apiCallPolicy(),
+	}, nil
+}
+
+// Run reconciles the Greeting resources until the context is cancelled.
+func (c *GreetingController) Run(ctx context.Context) error {
+	c.queue = newQueue("greetings")
+	defer c.queue.ShutDown()
+
+	greetingFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.dynamicClient, c.resyncPeriod, c.namespace, nil)
+	greetingInformer := greetingFactory.ForResource(greetingResource)
+	greetingInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+	})
+	c.greetings = greetingInformer.Lister()
+
+	// Changes of the children, such as their status, are reported on their owner.
+	childFactory := informers.NewSharedInformerFactoryWithOptions(c.client, c.resyncPeriod,
+		informers.WithNamespace(c.namespace),
+		informers.WithTweakListOptions(func(opts *meta.ListOptions) {
+			opts.LabelSelector = managedSelector()
+		}),
+	)
+	childHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueOwner,
+		UpdateFunc: func(_, obj interface{}) { c.enqueueOwner(obj) },
+		DeleteFunc: c.enqueueOwner,
+	}
+	childFactory.Apps().V1().Deployments().Informer().AddEventHandler(childHandler)
+	childFactory.Apps().V1().DaemonSets().Informer().AddEventHandler(childHandler)
+	childFactory.Core().V1().Services().Informer().AddEventHandler(childHandler)
+
+	factories := []informers.SharedInformerFactory{childFactory}
+	if c.namespaceSelector != nil {
+		// Labeling a namespace in or out of the selection reconciles its Greetings again.
+		namespaceFactory := informers.NewSharedInformerFactoryWithOptions(c.client, c.resyncPeriod,
+			informers.WithTweakListOptions(func(opts *meta.ListOptions) {
+				opts.LabelSelector = c.namespaceSelector.String()
+			}),
+		)
+		namespaceInformer := namespaceFactory.Core().V1().Namespaces()
+		namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.enqueueNamespace,
+			UpdateFunc: func(_, obj interface{}) { c.enqueueNamespace(obj) },
+			DeleteFunc: c.enqueueNamespace,
+		})
+		c.namespaces = namespaceInformer.Lister()
+		factories = append(factories, namespaceFactory)
+	}
+
+	greetingFactory.Start(ctx.Done())
+	for _, f := range factories {
+		f.Start(ctx.Done())
+		defer f.Shutdown()
+	}
+
+	setCachesState(cachesSyncing)
+	defer setCachesState(cachesIdle)
+
+	for resource, synced := range greetingFactory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("sync %s informer cache: %w", resource, ctx.Err())
+		}
+	}
+	for _, f := range factories {
+		for informerType, synced := range f.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				return fmt.Errorf("sync %v informer cache: %w", informerType, ctx.Err())
+			}
+		}
+	}
+
+	setCachesState(cachesSynced)
+	log.WithField("namespace", c.namespace).WithField("selector", c.defaults.NamespaceSelector).Info("Watching greeting resources")
+
+	runWorkers(ctx, c.queue, c.workers, c.processNextItem)
+
+	log.WithField("namespace", c.namespace).Info("Stopped watching greeting resources")
+	return nil
+}
+
+func (c *GreetingController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.WithError(err).Warning("Unable to compute greeting key")
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *GreetingController) enqueueOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	object, ok := obj.(meta.Object)
+	if !ok {
+		return
+	}
+
+	for _, ref := range object.GetOwnerReferences() {
+		if ref.APIVersion == GreetingGroupVersion.String() && ref.Kind == "Greeting" {
+			c.queue.Add(object.GetNamespace() + "/" + ref.Name)
+		}
+	}
+}
+
+// enqueueNamespace enqueues the keys of the Greetings of the namespace.
+func (c *GreetingController) enqueueNamespace(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	namespace, ok := obj.(meta.Object)
+	if !ok {
+		return
+	}
+
+	greetings, err := c.greetings.ByNamespace(namespace.GetName()).List(labels.Everything())
+	if err != nil {
+		log.WithError(err).WithField("namespace", namespace.GetName()).Warning("Unable to list greetings")
+		return
+	}
+	for _, greeting := range greetings {
+		c.enqueue(greeting)
+	}
+}
+
+// selected tells whether the Greetings of the namespace are reconciled.
+func (c *GreetingController) selected(namespace string) (bool, error) {
+	if c.namespaceSelector == nil {
+		return true, nil
+	}
+
+	_, err := c.namespaces.Get(namespace)
+	if kerror.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// processNextItem reconciles the next key of the queue, and returns false once the queue is shut down.
+func (c *GreetingController) processNextItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	// The keys left in the queue at shutdown are dropped, the next run reconciles them again.
+	if ctx.Err() != nil {
+		c.queue.Forget(item)
+		return true
+	}
+
+	key := item.(string)
+	err := c.reconcile(ctx, key)
+	namespace, _, _ := cache.SplitMetaNamespaceKey(key)
+	observeReconcile(namespace, err)
+	if err != nil {
+		log.WithError(err).WithField("greeting", key).Warning("Reconciliation failed, retrying")
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+// reconcile creates or updates the greeting server of a Greeting resource, then reports its status.
+func (c *GreetingController) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	// The resources of a namespace leaving the selection are kept, they are only not reconciled anymore.
+	selected, err := c.selected(namespace)
+	if err != nil {
+		return err
+	}
+	if !selected {
+		log.WithField("greeting", key).Debug("Namespace not selected, skipping greeting")
+		return nil
+	}
+
+	obj, err := c.greetings.ByNamespace(namespace).Get(name)
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			// The children are garbage collected through their owner references.
+			return nil
+		}
+		return err
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected greeting type %T", obj)
+	}
+
+	var greeting Greeting
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &greeting); err != nil {
+		return fmt.Errorf("convert greeting: %w", err)
+	}
+
+	logger := log.WithField("greeting", key)
+
+	operator, err := NewOffline(c.instanceConfig(&greeting))
+	if err != nil {
+		// Retrying won't fix an invalid spec, the next update of the resource will.
+		logger.WithError(err).Error("Invalid greeting")
+		return nil
+	}
+	operator.client = c.client
+	operator.dynamicClient = c.dynamicClient
+	operator.recorder = c.recorder
+	operator.eventObject = u
+
+	logger.Info("Reconciling greeting")
+	if err = operator.reconcile(ctx); err != nil {
+		return err
+	}
+
+	status, err := operator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.updateStatus(ctx, u, greetingStatus(status))
+}
+
+// instanceConfig returns the configuration of the greeting server of a Greeting resource.
+func (c *GreetingController) instanceConfig(greeting *Greeting) *Config {
+	config := *c.defaults
+	config.Namespace = greeting.Namespace
+	config.ResourceName = greeting.Name
+
+	if greeting.Spec.Image != "" {
+		config.Image = greeting.Spec.Image
+	}
+	if greeting.Spec.Port != 0 {
+		config.Port = greeting.Spec.Port
+	}
+	if greeting.Spec.Replicas != nil {
+		config.Replicas = *greeting.Spec.Replicas
+		config.ManageReplicas = true
+	}
+	if greeting.Spec.Name != "" {
+		config.Name = greeting.Spec.Name
+		config.NameFrom = nil
+	}
+	if greeting.Spec.ServiceType != "" {
+		config.ServiceType = api.ServiceType(greeting.Spec.ServiceType)
+	}
+
+	controller := true
+	config.OwnerReferences = []meta.OwnerReference{{
+		APIVersion:         GreetingGroupVersion.String(),
+		Kind:               "Greeting",
+		Name:               greeting.Name,
+		UID:                greeting.UID,
+		Controller:         &controller,
+		BlockOwnerDeletion: &controller,
+	}}
+
+	return &config
+}
+
+// greetingStatus converts the status of the managed resources into the status of the Greeting resource.
+func greetingStatus(status *Status) GreetingStatus {
+	var greetingStatus GreetingStatus
+	if status.Workload != nil {
+		greetingStatus.ReadyReplicas = status.Workload.Ready
+	}
+
+	if status.Service != nil {
+		if len(status.Service.External) > 0 {
+			greetingStatus.Address = status.Service.External[0]
+		} else {
+			greetingStatus.Address = status.Service.ClusterIP
+		}
+	}
+
+	return greetingStatus
+}
+
+// updateStatus writes the status of the Greeting resource when it changed.
+func (c *GreetingController) updateStatus(ctx context.Context, u *unstructured.Unstructured, status GreetingStatus) error {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return fmt.Errorf("convert greeting status: %w", err)
+	}
+
+	current, _, _ := unstructured.NestedMap(u.Object, "status")
+	if fmt.Sprint(current) == fmt.Sprint(raw) {
+		return nil
+	}
+
+	updated := u.DeepCopy()
+	if err = unstructured.SetNestedMap(updated.Object, raw, "status"); err != nil {
+		return fmt.Errorf("set greeting status: %w", err)
+	}
+
+	opts := meta.UpdateOptions{}
+	if c.defaults.ServerDryRun {
+		opts.DryRun = []string{meta.DryRunAll}
+	}
+
+	api := c.api
+	api.namespace = u.GetNamespace()
+	err = api.call(ctx, "greeting", "update_status", func(ctx context.Context) error {
+		_, err := c.dynamicClient.Resource(greetingResource).Namespace(u.GetNamespace()).UpdateStatus(ctx, updated, opts)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("update greeting status: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package operator
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// greetingCRD returns the CustomResourceDefinition of the Greeting resource, its schema being generated from the Go types.
+func greetingCRD() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata": map[string]interface{}{
+			"name": greetingResource.Resource + "." + greetingResource.Group,
+		},
+		"spec": map[string]interface{}{
+			"group": greetingResource.Group,
+			"names": map[string]interface{}{
+				"kind":     "Greeting",
+				"listKind": "GreetingList",
+				"plural":   greetingResource.Resource,
+				"singular": "greeting",
+			},
+			"scope": "Namespaced",
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":    greetingResource.Version,
+					"served":  true,
+					"storage": true,
+					"schema": map[string]interface{}{
+						"openAPIV3Schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"spec":   openAPISchema(reflect.TypeOf(GreetingSpec{})),
+								"status": openAPISchema(reflect.TypeOf(GreetingStatus{})),
+							},
+						},
+					},
+					"subresources": map[string]interface{}{
+						"status": map[string]interface{}{},
+					},
+					"additionalPrinterColumns": []interface{}{
+						map[string]interface{}{"name": "Ready", "type": "integer", "jsonPath": ".status.readyReplicas"},
+						map[string]interface{}{"name": "Address", "type": "string", "jsonPath": ".status.address"},
+						map[string]interface{}{"name": "Age", "type": "date", "jsonPath": ".metadata.creationTimestamp"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// openAPISchema generates the structural schema of a Go type, fields being named after their JSON tag.
+func openAPISchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return openAPISchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": openAPISchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = openAPISchema(field.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+
+	panic(fmt.Sprintf("unsupported type %s in custom resource schema", t))
+}
+
+// WriteGreetingCRD writes the CustomResourceDefinition of the Greeting resource as YAML.
+func WriteGreetingCRD(w io.Writer) error {
+	raw, err := yaml.Marshal(greetingCRD())
+	if err != nil {
+		return fmt.Errorf("marshal crd: %w", err)
+	}
+
+	if _, err = w.Write(raw); err != nil {
+		return fmt.Errorf("write crd: %w", err)
+	}
+
+	return nil
+}
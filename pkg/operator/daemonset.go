@@ -0,0 +1,167 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsac "k8s.io/client-go/applyconfigurations/apps/v1"
+)
+
+// desiredDaemonSet returns the daemonset running the greeting server on every node.
+func (o *Operator) desiredDaemonSet() *apps.DaemonSet {
+	return &apps.DaemonSet{
+		TypeMeta: meta.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      o.resourceName,
+			Namespace: o.namespace,
+			Labels:    o.objectLabels(),
+
+			OwnerReferences: o.ownerReferences,
+		},
+		Spec: apps.DaemonSetSpec{
+			Selector: &meta.LabelSelector{MatchLabels: o.selectorLabels()},
+			Template: o.podTemplate(),
+
+			MinReadySeconds: o.minReadySeconds,
+		},
+	}
+}
+
+// createDaemonSet creates or updates the daemonset, recording an event when it changed.
+// An up to date daemonset is left untouched.
+func (o *Operator) createDaemonSet(ctx context.Context) error {
+	desired := o.desiredDaemonSet()
+
+	live, diff, err := o.liveDaemonSet(ctx, desired)
+	if err != nil {
+		return err
+	}
+	exists := live != nil
+
+	if exists {
+		if err = o.checkManaged("daemonset", live); err != nil {
+			return err
+		}
+		if len(diff) == 0 {
+			o.logger("daemonset").Info("DaemonSet up to date")
+			return nil
+		}
+		o.logger("daemonset").WithField("diff", diff).Info("DaemonSet changed")
+	}
+
+	written, err := o.writeDaemonSet(ctx, desired)
+
+	target := written
+	if target == nil && exists {
+		target = live
+	}
+	if target == nil {
+		target = desired
+	}
+	o.recordWrite(target, "daemonset", exists, diff, err)
+
+	return err
+}
+
+// liveDaemonSet returns the live daemonset, or nil when missing, with its differences from the desired one.
+func (o *Operator) liveDaemonSet(ctx context.Context, desired *apps.DaemonSet) (*apps.DaemonSet, []string, error) {
+	var live *apps.DaemonSet
+	err := o.api.call(ctx, "daemonset", "get", func(ctx context.Context) error {
+		var err error
+		live, err = o.client.AppsV1().DaemonSets(o.namespace).Get(ctx, desired.Name, meta.GetOptions{})
+		return err
+	})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("get daemonset: %w", err)
+	}
+
+	return live, daemonSetDiff(live, desired), nil
+}
+
+// writeDaemonSet creates or updates the daemonset, returning it when known.
+func (o *Operator) writeDaemonSet(ctx context.Context, greetingDaemonSet *apps.DaemonSet) (*apps.DaemonSet, error) {
+	if !o.legacyUpdate {
+		o.logger("daemonset").Info("Applying daemonset")
+		applied, err := o.applyDaemonSet(ctx, greetingDaemonSet)
+		if err != nil {
+			return nil, fmt.Errorf("apply daemonset: %w", err)
+		}
+
+		o.logger("daemonset").Info("DaemonSet applied")
+		o.logDryRun("daemonset", applied)
+		return applied, nil
+	}
+
+	daemonSetClient := o.client.AppsV1().DaemonSets(o.namespace)
+
+	o.logger("daemonset").Info("Creating daemonset")
+
+	var alreadyExists bool
+	var created *apps.DaemonSet
+	err := o.api.call(ctx, "daemonset", "create", func(ctx context.Context) error {
+		var err error
+		created, err = daemonSetClient.Create(ctx, greetingDaemonSet, o.createOptions())
+		return err
+	})
+	if err != nil {
+		if !kerror.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("create daemonset: %w", err)
+		} else {
+			alreadyExists = true
+		}
+	}
+
+	if alreadyExists {
+		o.logger("daemonset").Info("DaemonSet already exists, updating current")
+		if err = o.updateDaemonSet(ctx, greetingDaemonSet); err != nil {
+			return nil, fmt.Errorf("update daemonset: %w", err)
+		}
+	}
+
+	o.logger("daemonset").Info("DaemonSet created")
+	o.logDryRun("daemonset", created)
+	return created, nil
+}
+
+// applyDaemonSet applies the desired daemonset with server-side apply.
+func (o *Operator) applyDaemonSet(ctx context.Context, desired *apps.DaemonSet) (*apps.DaemonSet, error) {
+	spec := &appsac.DaemonSetSpecApplyConfiguration{}
+	if err := convertApplyConfiguration(desired.Spec, spec); err != nil {
+		return nil, err
+	}
+
+	daemonSet := appsac.DaemonSet(desired.Name, desired.Namespace).
+		WithLabels(desired.Labels).
+		WithAnnotations(desired.Annotations).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(desired.OwnerReferences)...).
+		WithSpec(spec)
+
+	var applied *apps.DaemonSet
+	err := o.api.call(ctx, "daemonset", "apply", func(ctx context.Context) error {
+		var err error
+		applied, err = o.client.AppsV1().DaemonSets(o.namespace).Apply(ctx, daemonSet, o.applyOptions())
+		return err
+	})
+	return applied, err
+}
+
+func (o *Operator) deleteDaemonSet(ctx context.Context) error {
+	err := o.api.call(ctx, "daemonset", "delete", func(ctx context.Context) error {
+		return o.client.AppsV1().DaemonSets(o.namespace).Delete(ctx, o.resourceName, o.deleteOptions())
+	})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("delete daemonset: %w", err)
+	}
+
+	o.logger("daemonset").Info("DaemonSet deleted")
+	return nil
+}
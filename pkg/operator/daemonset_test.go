@@ -0,0 +1,87 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// workloads returns whether the deployment and the daemonset of the operator exist.
+func workloads(t *testing.T, client *fake.Clientset) (deployment, daemonSet bool) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := client.AppsV1().Deployments("default").Get(ctx, "greeting", meta.GetOptions{})
+	if err != nil && !kerror.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	deployment = err == nil
+	_, err = client.AppsV1().DaemonSets("default").Get(ctx, "greeting", meta.GetOptions{})
+	if err != nil && !kerror.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	daemonSet = err == nil
+	return deployment, daemonSet
+}
+
+func TestReconcileSwitchesWorkload(t *testing.T) {
+	for _, mode := range writeModes {
+		t.Run(mode.name, func(t *testing.T) {
+			client := newFakeClient()
+			for _, workload := range []string{WorkloadDeployment, WorkloadDaemonSet, WorkloadDeployment} {
+				op := newTestOperator(t, client, append(mode.opts, WithWorkload(workload))...)
+				if err := op.reconcile(context.Background()); err != nil {
+					t.Fatalf("reconcile %s: %v", workload, err)
+				}
+
+				deployment, daemonSet := workloads(t, client)
+				if deployment != (workload == WorkloadDeployment) || daemonSet != (workload == WorkloadDaemonSet) {
+					t.Errorf("%s workload: deployment %t, daemonset %t, want only the %s", workload, deployment, daemonSet, workload)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileDaemonSet(t *testing.T) {
+	client := newFakeClient()
+	op := newTestOperator(t, client, WithWorkload(WorkloadDaemonSet), WithImage("greeting:1"))
+	if err := op.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	live, err := client.AppsV1().DaemonSets("default").Get(context.Background(), "greeting", meta.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live.Labels[managedByLabel] != managedByValue {
+		t.Errorf("labels = %v, want the managed labels", live.Labels)
+	}
+	containers := live.Spec.Template.Spec.Containers
+	if len(containers) != 1 || containers[0].Name != greetingContainerName || containers[0].Image != "greeting:1" {
+		t.Errorf("containers = %v, want the greeting server of greeting:1", containers)
+	}
+	// The service selects the pods of the daemonset the same way.
+	service, err := client.CoreV1().Services("default").Get(context.Background(), "greeting", meta.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range service.Spec.Selector {
+		if live.Spec.Template.Labels[key] != value {
+			t.Errorf("service selects %s=%s, the daemonset pods are labelled %v", key, value, live.Spec.Template.Labels)
+		}
+	}
+
+	// Reconciled again without changes, the daemonset is left untouched.
+	client.ClearActions()
+	if err = newTestOperator(t, client, WithWorkload(WorkloadDaemonSet), WithImage("greeting:1")).reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetResource().Resource == "daemonsets" && action.GetVerb() != "get" {
+			t.Errorf("unchanged daemonset written: %s", action.GetVerb())
+		}
+	}
+}
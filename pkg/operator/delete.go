@@ -0,0 +1,239 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DeleteOptions tune the deletion of the managed resources.
+type DeleteOptions struct {
+	// Namespace also deletes the namespace, only if it was created by the operator.
+	Namespace bool
+	// Wait blocks until the resources are fully deleted.
+	Wait bool
+}
+
+// Delete removes every resource managed by the operator for this instance in its namespace.
+// Resources already gone are ignored.
+func (o *Operator) Delete(ctx context.Context, opts DeleteOptions) error {
+	// Nothing is deleted by a server dry-run, there is nothing to wait for.
+	if o.serverDryRun {
+		opts.Wait = false
+	}
+
+	propagation := meta.DeletePropagationBackground
+	if opts.Wait {
+		propagation = meta.DeletePropagationForeground
+	}
+	deleteOpts := o.deleteOptions()
+	deleteOpts.PropagationPolicy = &propagation
+	listOpts := meta.ListOptions{LabelSelector: o.instanceSelector()}
+
+	routes, err := o.listRoutes(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list routes: %w", err)
+	}
+	for _, route := range routes {
+		name := route.GetName()
+		if err = o.deleteObject(ctx, "route", name, func(ctx context.Context) error {
+			return o.dynamicClient.Resource(routeResource).Namespace(o.namespace).Delete(ctx, name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	serviceMonitors, err := o.listServiceMonitors(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list servicemonitors: %w", err)
+	}
+	for _, serviceMonitor := range serviceMonitors {
+		name := serviceMonitor.GetName()
+		if err = o.deleteObject(ctx, "servicemonitor", name, func(ctx context.Context) error {
+			return o.dynamicClient.Resource(serviceMonitorResource).Namespace(o.namespace).Delete(ctx, name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	services, err := o.client.CoreV1().Services(o.namespace).List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list services: %w", err)
+	}
+	for _, service := range services.Items {
+		if err = o.deleteObject(ctx, "service", service.Name, func(ctx context.Context) error {
+			return o.client.CoreV1().Services(o.namespace).Delete(ctx, service.Name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	deployments, err := o.client.AppsV1().Deployments(o.namespace).List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list deployments: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		if err = o.deleteObject(ctx, "deployment", deployment.Name, func(ctx context.Context) error {
+			return o.client.AppsV1().Deployments(o.namespace).Delete(ctx, deployment.Name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	daemonSets, err := o.client.AppsV1().DaemonSets(o.namespace).List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list daemonsets: %w", err)
+	}
+	for _, daemonSet := range daemonSets.Items {
+		if err = o.deleteObject(ctx, "daemonset", daemonSet.Name, func(ctx context.Context) error {
+			return o.client.AppsV1().DaemonSets(o.namespace).Delete(ctx, daemonSet.Name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// The quota and the limit range go after the workloads they bound.
+	quotas, err := o.client.CoreV1().ResourceQuotas(o.namespace).List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list resourcequotas: %w", err)
+	}
+	for _, quota := range quotas.Items {
+		if err = o.deleteObject(ctx, "resourcequota", quota.Name, func(ctx context.Context) error {
+			return o.client.CoreV1().ResourceQuotas(o.namespace).Delete(ctx, quota.Name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	limitRanges, err := o.client.CoreV1().LimitRanges(o.namespace).List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list limitranges: %w", err)
+	}
+	for _, limitRange := range limitRanges.Items {
+		if err = o.deleteObject(ctx, "limitrange", limitRange.Name, func(ctx context.Context) error {
+			return o.client.CoreV1().LimitRanges(o.namespace).Delete(ctx, limitRange.Name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// The Secrets go last, the pods being deleted may still read them.
+	secrets, err := o.client.CoreV1().Secrets(o.namespace).List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("list secrets: %w", err)
+	}
+	for _, secret := range secrets.Items {
+		if err = o.deleteObject(ctx, "secret", secret.Name, func(ctx context.Context) error {
+			return o.client.CoreV1().Secrets(o.namespace).Delete(ctx, secret.Name, deleteOpts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if opts.Wait {
+		if err = o.waitForDeletion(ctx, listOpts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Namespace && len(o.peerInstances) > 0 {
+		log.WithField("namespace", o.namespace).Warning("Namespace kept, other instances are deployed in it")
+	} else if opts.Namespace {
+		if err = o.deleteNamespace(ctx, opts.Wait); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteObject deletes a single object, ignoring it when already gone.
+func (o *Operator) deleteObject(ctx context.Context, kind, name string, del func(context.Context) error) error {
+	logger := log.WithFields(log.Fields{"namespace": o.namespace, "kind": kind, "name": name})
+
+	if err := o.api.call(ctx, kind, "delete", del); err != nil {
+		if kerror.IsNotFound(err) {
+			logger.Info("Resource already deleted")
+			return nil
+		}
+		return fmt.Errorf("delete %s %q: %w", kind, name, err)
+	}
+
+	logger.Info("Resource deleted")
+	return nil
+}
+
+// waitForDeletion blocks until no managed resource is left in the namespace.
+func (o *Operator) waitForDeletion(ctx context.Context, listOpts meta.ListOptions) error {
+	log.WithField("namespace", o.namespace).Info("Waiting for the resources to be deleted")
+
+	err := wait.PollImmediateUntilWithContext(ctx, waitInterval, func(ctx context.Context) (bool, error) {
+		services, err := o.client.CoreV1().Services(o.namespace).List(ctx, listOpts)
+		if err != nil {
+			return false, fmt.Errorf("list services: %w", err)
+		}
+
+		deployments, err := o.client.AppsV1().Deployments(o.namespace).List(ctx, listOpts)
+		if err != nil {
+			return false, fmt.Errorf("list deployments: %w", err)
+		}
+
+		daemonSets, err := o.client.AppsV1().DaemonSets(o.namespace).List(ctx, listOpts)
+		if err != nil {
+			return false, fmt.Errorf("list daemonsets: %w", err)
+		}
+
+		return len(services.Items)+len(deployments.Items)+len(daemonSets.Items) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for deletion: %w", err)
+	}
+
+	return nil
+}
+
+// deleteNamespace deletes the namespace, refusing to do so when it was not created by the operator.
+func (o *Operator) deleteNamespace(ctx context.Context, waitDeletion bool) error {
+	namespaceClient := o.client.CoreV1().Namespaces()
+
+	namespace, err := namespaceClient.Get(ctx, o.namespace, meta.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			log.WithField("namespace", o.namespace).Info("Namespace already deleted")
+			return nil
+		}
+		return fmt.Errorf("get namespace: %w", err)
+	}
+
+	if namespace.Labels[managedByLabel] != managedByValue {
+		return fmt.Errorf("namespace %q is not managed by the operator, refusing to delete it", o.namespace)
+	}
+
+	if err = o.deleteObject(ctx, "namespace", o.namespace, func(ctx context.Context) error {
+		return namespaceClient.Delete(ctx, o.namespace, o.deleteOptions())
+	}); err != nil {
+		return err
+	}
+
+	if !waitDeletion {
+		return nil
+	}
+
+	log.WithField("namespace", o.namespace).Info("Waiting for the namespace to be deleted")
+	err = wait.PollImmediateUntilWithContext(ctx, waitInterval, func(ctx context.Context) (bool, error) {
+		_, err := namespaceClient.Get(ctx, o.namespace, meta.GetOptions{})
+		if kerror.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("wait for namespace deletion: %w", err)
+	}
+
+	return nil
+}
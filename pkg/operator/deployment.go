@@ -0,0 +1,220 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsac "k8s.io/client-go/applyconfigurations/apps/v1"
+)
+
+// desiredDeployment returns the deployment running the greeting server.
+func (o *Operator) desiredDeployment() *apps.Deployment {
+	replicas := int32(o.replicas)
+	deployment := &apps.Deployment{
+		TypeMeta: meta.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      o.resourceName,
+			Namespace: o.namespace,
+			Labels:    o.objectLabels(),
+
+			OwnerReferences: o.ownerReferences,
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &meta.LabelSelector{MatchLabels: o.selectorLabels()},
+			Template: o.podTemplate(),
+			Strategy: apps.DeploymentStrategy{Type: o.strategy},
+
+			MinReadySeconds: o.minReadySeconds,
+		},
+	}
+
+	if o.progressDeadlineSeconds > 0 {
+		deployment.Spec.ProgressDeadlineSeconds = &o.progressDeadlineSeconds
+	}
+
+	return deployment
+}
+
+// createDeployment creates or updates the deployment, recording an event when it changed.
+// An up to date deployment is left untouched.
+func (o *Operator) createDeployment(ctx context.Context) error {
+	desired := o.desiredDeployment()
+
+	live, diff, err := o.liveDeployment(ctx, desired)
+	if err != nil {
+		return err
+	}
+	exists := live != nil
+
+	if exists {
+		if err = o.checkManaged("deployment", live); err != nil {
+			return err
+		}
+		// Writing the deployment would un-pause it, the rollouts stay frozen until resumed.
+		if live.Spec.Paused {
+			o.logger("deployment").Warning("Deployment paused, reconciliation suspended")
+			return nil
+		}
+		if err = o.checkSelector(ctx, live, desired); errors.Is(err, errRecreated) {
+			// The deletion is not persisted by a server dry-run, the creation would fail on the live deployment.
+			if o.serverDryRun {
+				return nil
+			}
+			live, exists = nil, false
+		} else if err != nil {
+			return err
+		}
+	}
+	if exists {
+		if len(diff) == 0 {
+			o.logger("deployment").Info("Deployment up to date")
+			return nil
+		}
+		o.logger("deployment").WithField("diff", diff).Info("Deployment changed")
+		if err = o.clearRollingUpdate(ctx, live, desired); err != nil {
+			return err
+		}
+	}
+
+	written, err := o.writeDeployment(ctx, desired, live)
+
+	target := written
+	if target == nil && exists {
+		target = live
+	}
+	if target == nil {
+		target = desired
+	}
+	o.recordWrite(target, "deployment", exists, diff, err)
+
+	return err
+}
+
+// liveDeployment returns the live deployment, or nil when missing, with its differences from the desired one.
+func (o *Operator) liveDeployment(ctx context.Context, desired *apps.Deployment) (*apps.Deployment, []string, error) {
+	var live *apps.Deployment
+	err := o.api.call(ctx, "deployment", "get", func(ctx context.Context) error {
+		var err error
+		live, err = o.client.AppsV1().Deployments(o.namespace).Get(ctx, desired.Name, meta.GetOptions{})
+		return err
+	})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("get deployment: %w", err)
+	}
+
+	o.preserveReplicas(live, desired)
+	return live, deploymentDiff(live, desired), nil
+}
+
+// writeDeployment creates or updates the deployment, returning it when known. The live deployment is nil
+// when missing.
+func (o *Operator) writeDeployment(ctx context.Context, greetingDeployment, live *apps.Deployment) (*apps.Deployment, error) {
+	if !o.legacyUpdate {
+		o.logger("deployment").Info("Applying deployment")
+		applied, err := o.applyDeployment(ctx, o.withoutForeignReplicas(greetingDeployment, live))
+		if err != nil {
+			return nil, fmt.Errorf("apply deployment: %w", err)
+		}
+
+		o.logger("deployment").Info("Deployment applied")
+		o.logDryRun("deployment", applied)
+		return applied, nil
+	}
+
+	deploymentClient := o.client.AppsV1().Deployments(o.namespace)
+
+	o.logger("deployment").Info("Creating deployment")
+
+	var alreadyExists bool
+	var created *apps.Deployment
+	err := o.api.call(ctx, "deployment", "create", func(ctx context.Context) error {
+		var err error
+		created, err = deploymentClient.Create(ctx, greetingDeployment, o.createOptions())
+		return err
+	})
+	if err != nil {
+		if !kerror.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("create deployment: %w", err)
+		} else {
+			alreadyExists = true
+		}
+	}
+
+	if alreadyExists {
+		o.logger("deployment").Info("Deployment already exists, updating current")
+		if err = o.updateDeployment(ctx, greetingDeployment); err != nil {
+			return nil, fmt.Errorf("update deployment: %w", err)
+		}
+	}
+
+	o.logger("deployment").Info("Deployment created")
+	o.logDryRun("deployment", created)
+	return created, nil
+}
+
+// applyDeployment applies the desired deployment with server-side apply.
+func (o *Operator) applyDeployment(ctx context.Context, desired *apps.Deployment) (*apps.Deployment, error) {
+	spec := &appsac.DeploymentSpecApplyConfiguration{}
+	if err := convertApplyConfiguration(desired.Spec, spec); err != nil {
+		return nil, err
+	}
+
+	deployment := appsac.Deployment(desired.Name, desired.Namespace).
+		WithLabels(desired.Labels).
+		WithAnnotations(desired.Annotations).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(desired.OwnerReferences)...).
+		WithSpec(spec)
+
+	var applied *apps.Deployment
+	err := o.api.call(ctx, "deployment", "apply", func(ctx context.Context) error {
+		var err error
+		applied, err = o.client.AppsV1().Deployments(o.namespace).Apply(ctx, deployment, o.applyOptions())
+		return err
+	})
+	return applied, err
+}
+
+func (o *Operator) deleteDeployment(ctx context.Context) error {
+	err := o.api.call(ctx, "deployment", "delete", func(ctx context.Context) error {
+		return o.client.AppsV1().Deployments(o.namespace).Delete(ctx, o.resourceName, o.deleteOptions())
+	})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("delete deployment: %w", err)
+	}
+
+	o.logger("deployment").Info("Deployment deleted")
+	return nil
+}
+
+// withoutForeignReplicas leaves the replicas out of the applied deployment once scaled by an autoscaler or
+// a human, when they are not managed: applying them, forced, would take their ownership back and race the
+// autoscaler. They are still applied while the operator owns them alone, since leaving them out would reset
+// them to the default of 1.
+func (o *Operator) withoutForeignReplicas(desired, live *apps.Deployment) *apps.Deployment {
+	if o.manageReplicas || live == nil || ownsField(live, fieldManager, "f:spec", "f:replicas") {
+		return desired
+	}
+	applied := desired.DeepCopy()
+	applied.Spec.Replicas = nil
+	return applied
+}
+
+// preserveReplicas keeps the live replicas in the desired deployment when they are not managed by the operator,
+// so that the scaling done by an autoscaler or a human is not undone.
+func (o *Operator) preserveReplicas(live, desired *apps.Deployment) {
+	if !o.manageReplicas && live.Spec.Replicas != nil {
+		replicas := *live.Spec.Replicas
+		desired.Spec.Replicas = &replicas
+	}
+}
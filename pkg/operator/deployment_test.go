@@ -0,0 +1,142 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scaledDeployment returns the deployment of the operator scaled to the replicas by the manager, an update
+// taking the ownership of the replicas like the autoscalers do.
+func scaledDeployment(t *testing.T, replicas int32, scaledBy string) *apps.Deployment {
+	t.Helper()
+	deployment := newTestOperator(t, newFakeClient()).desiredDeployment()
+	deployment.Spec.Replicas = &replicas
+	deployment.ManagedFields = []meta.ManagedFieldsEntry{{
+		Manager:   fieldManager,
+		Operation: meta.ManagedFieldsOperationApply,
+		FieldsV1:  &meta.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{}}}`)},
+	}}
+	if scaledBy != "" {
+		deployment.ManagedFields = append(deployment.ManagedFields, meta.ManagedFieldsEntry{
+			Manager:   scaledBy,
+			Operation: meta.ManagedFieldsOperationUpdate,
+			FieldsV1:  &meta.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		})
+	}
+	return deployment
+}
+
+func appliedReplicas(t *testing.T, patch []byte) *int32 {
+	t.Helper()
+	var applied apps.Deployment
+	if err := json.Unmarshal(patch, &applied); err != nil {
+		t.Fatalf("unmarshal applied deployment: %v", err)
+	}
+	return applied.Spec.Replicas
+}
+
+func TestApplyLeavesForeignReplicasOut(t *testing.T) {
+	client := newFakeClient(scaledDeployment(t, 5, "kube-controller-manager"))
+	op := newTestOperator(t, client, WithImage("greeting:2"))
+	if err := op.install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	if replicas := appliedReplicas(t, appliedPatch(t, client.Actions(), "deployments")); replicas != nil {
+		t.Errorf("applied replicas = %d, want none once scaled by the autoscaler", *replicas)
+	}
+	live, err := client.AppsV1().Deployments("default").Get(context.Background(), "greeting", meta.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *live.Spec.Replicas != 5 || live.Spec.Template.Spec.Containers[0].Image != "greeting:2" {
+		t.Errorf("live deployment = %d replicas of %s, want 5 of greeting:2", *live.Spec.Replicas, live.Spec.Template.Spec.Containers[0].Image)
+	}
+}
+
+func TestApplyKeepsOwnedReplicas(t *testing.T) {
+	// Created by the operator, the replicas are owned by its applies alone: leaving them out would reset them.
+	client := newFakeClient()
+	if err := newTestOperator(t, client, WithReplicas(3)).install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	client.ClearActions()
+
+	if err := newTestOperator(t, client, WithImage("greeting:2")).install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if replicas := appliedReplicas(t, appliedPatch(t, client.Actions(), "deployments")); replicas == nil || *replicas != 3 {
+		t.Errorf("applied replicas = %v, want the live 3", replicas)
+	}
+}
+
+func TestApplyManagedReplicas(t *testing.T) {
+	client := newFakeClient(scaledDeployment(t, 5, "kube-controller-manager"))
+	op := newTestOperator(t, client, WithReplicas(2))
+	if err := op.install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	if replicas := appliedReplicas(t, appliedPatch(t, client.Actions(), "deployments")); replicas == nil || *replicas != 2 {
+		t.Errorf("applied replicas = %v, want the managed 2", replicas)
+	}
+}
+
+func TestLegacyUpdatePreservesForeignFields(t *testing.T) {
+	live := scaledDeployment(t, 4, "")
+	live.ManagedFields = nil
+	live.Labels["team"] = "greeters"
+	live.Annotations = map[string]string{"deployment.kubernetes.io/revision": "3"}
+	live.Spec.Template.Annotations = map[string]string{"kubectl.kubernetes.io/restartedAt": "2026-10-14T10:00:00Z"}
+	client := newFakeClient(live)
+
+	op := newTestOperator(t, client, WithLegacyUpdate(true), WithImage("greeting:2"))
+	if err := op.install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	updated, err := client.AppsV1().Deployments("default").Get(context.Background(), "greeting", meta.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := updated.Spec.Template.Spec.Containers[0].Image; image != "greeting:2" {
+		t.Errorf("image = %s, want greeting:2", image)
+	}
+	for _, field := range []struct{ name, got, want string }{
+		{"replicas", strconv.Itoa(int(*updated.Spec.Replicas)), "4"},
+		{"foreign label", updated.Labels["team"], "greeters"},
+		{"operator label", updated.Labels[managedByLabel], managedByValue},
+		{"foreign annotation", updated.Annotations["deployment.kubernetes.io/revision"], "3"},
+		{"template annotation", updated.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"], "2026-10-14T10:00:00Z"},
+	} {
+		if field.got != field.want {
+			t.Errorf("%s = %q, want %q", field.name, field.got, field.want)
+		}
+	}
+}
+
+func TestPreserveReplicas(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		manage bool
+		want   int32
+	}{
+		{name: "unmanaged", want: 5},
+		{name: "managed", manage: true, want: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			op := newTestOperator(t, newFakeClient())
+			op.manageReplicas = tc.manage
+			desired := op.desiredDeployment()
+			op.preserveReplicas(scaledDeployment(t, 5, "kube-controller-manager"), desired)
+			if *desired.Spec.Replicas != tc.want {
+				t.Errorf("replicas = %d, want %d", *desired.Spec.Replicas, tc.want)
+			}
+		})
+	}
+}
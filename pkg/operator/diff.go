@@ -0,0 +1,121 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Diff writes the changes the operator would make to the live resources, without writing anything.
+// It returns whether any resource would change.
+func (o *Operator) Diff(ctx context.Context, w io.Writer) (bool, error) {
+	if err := o.checkConfigMaps(ctx); err != nil {
+		return false, err
+	}
+
+	if err := o.loadName(ctx); err != nil {
+		return false, err
+	}
+
+	if err := o.resolveImage(ctx); err != nil {
+		return false, err
+	}
+
+	var changed bool
+	report := func(kind string, exists bool, diff []string) {
+		name := o.namespace + "/" + o.resourceName
+		switch {
+		case !exists:
+			fmt.Fprintf(w, "%s %s: would be created\n", kind, name)
+		case len(diff) == 0:
+			fmt.Fprintf(w, "%s %s: up to date\n", kind, name)
+			return
+		default:
+			fmt.Fprintf(w, "%s %s:\n", kind, name)
+			for _, line := range diff {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+		changed = true
+	}
+
+	err := o.api.call(ctx, "namespace", "get", func(ctx context.Context) error {
+		_, err := o.client.CoreV1().Namespaces().Get(ctx, o.namespace, meta.GetOptions{})
+		return err
+	})
+	if err != nil && !kerror.IsNotFound(err) {
+		return false, fmt.Errorf("get namespace: %w", err)
+	}
+	if err != nil {
+		fmt.Fprintf(w, "namespace %s: would be created\n", o.namespace)
+		changed = true
+	}
+
+	// With a server dry-run, the live resources are compared with what the API server would store,
+	// defaulted and mutated by the admission. Nothing can be applied in a missing namespace.
+	serverDryRun := o.serverDryRun && err == nil
+
+	// The workload of the other kind is deleted by the reconciliation.
+	switch o.workload {
+	case WorkloadDaemonSet:
+		if live, _, err := o.liveDeployment(ctx, o.desiredDeployment()); err != nil {
+			return false, err
+		} else if live != nil {
+			fmt.Fprintf(w, "deployment %s/%s: would be deleted\n", o.namespace, o.resourceName)
+			changed = true
+		}
+
+		desired := o.desiredDaemonSet()
+		if serverDryRun {
+			if desired, err = o.applyDaemonSet(ctx, desired); err != nil {
+				return false, fmt.Errorf("server dry-run daemonset: %w", err)
+			}
+		}
+
+		live, diff, err := o.liveDaemonSet(ctx, desired)
+		if err != nil {
+			return false, err
+		}
+		report("daemonset", live != nil, diff)
+	default:
+		if live, _, err := o.liveDaemonSet(ctx, o.desiredDaemonSet()); err != nil {
+			return false, err
+		} else if live != nil {
+			fmt.Fprintf(w, "daemonset %s/%s: would be deleted\n", o.namespace, o.resourceName)
+			changed = true
+		}
+
+		desired := o.desiredDeployment()
+		if serverDryRun {
+			if desired, err = o.applyDeployment(ctx, desired); err != nil {
+				return false, fmt.Errorf("server dry-run deployment: %w", err)
+			}
+		}
+
+		live, diff, err := o.liveDeployment(ctx, desired)
+		if err != nil {
+			return false, err
+		}
+		report("deployment", live != nil, diff)
+	}
+
+	if !o.hostPort {
+		desired := o.desiredService()
+		if serverDryRun {
+			if desired, err = o.applyService(ctx, desired); err != nil {
+				return false, fmt.Errorf("server dry-run service: %w", err)
+			}
+		}
+
+		live, diff, err := o.liveService(ctx, desired)
+		if err != nil {
+			return false, err
+		}
+		report("service", live != nil, diff)
+	}
+
+	return changed, nil
+}
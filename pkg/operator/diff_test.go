@@ -0,0 +1,151 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// assertNoWrites fails on any write request. The workload of the other kind is deleted blindly on every run,
+// which is no write as long as it is missing. The events are left out: the ones of a previous run are still
+// sent in the background.
+func assertNoWrites(t *testing.T, client *fake.Clientset) {
+	t.Helper()
+	for _, action := range client.Actions() {
+		if action.GetResource().Resource == "events" {
+			continue
+		}
+		switch action.GetVerb() {
+		case "create", "update", "patch":
+			t.Errorf("unchanged resources written: %s %s", action.GetVerb(), action.GetResource().Resource)
+		case "delete":
+			name := action.(ktesting.DeleteAction).GetName()
+			if _, err := client.Tracker().Get(action.GetResource(), action.GetNamespace(), name); !kerror.IsNotFound(err) {
+				t.Errorf("resource deleted: %s %s", action.GetResource().Resource, name)
+			}
+		}
+	}
+}
+
+func TestReinstallWritesNothing(t *testing.T) {
+	for _, mode := range writeModes {
+		for _, workload := range []string{WorkloadDeployment, WorkloadDaemonSet} {
+			t.Run(mode.name+"/"+workload, func(t *testing.T) {
+				opts := append(mode.opts, WithWorkload(workload), WithImage("greeting:1"))
+				client := newFakeClient()
+				if err := newTestOperator(t, client, opts...).install(context.Background()); err != nil {
+					t.Fatalf("first install: %v", err)
+				}
+				client.ClearActions()
+
+				op := newTestOperator(t, client, opts...)
+				if err := op.install(context.Background()); err != nil {
+					t.Fatalf("install: %v", err)
+				}
+				assertNoWrites(t, client)
+				if len(op.actions) > 0 {
+					t.Errorf("actions = %v, want every resource unchanged", op.actions)
+				}
+			})
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	client := newFakeClient()
+	if err := newTestOperator(t, client, WithImage("greeting:1")).install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantChanged bool
+		want        []string
+	}{
+		{
+			name: "up to date",
+			opts: []Option{WithImage("greeting:1")},
+			want: []string{"deployment default/greeting: up to date", "service default/greeting: up to date"},
+		},
+		{
+			name:        "image changed",
+			opts:        []Option{WithImage("greeting:2")},
+			wantChanged: true,
+			want:        []string{"deployment default/greeting:", "containers[greeting].image: greeting:1 -> greeting:2", "service default/greeting: up to date"},
+		},
+		{
+			name:        "workload changed",
+			opts:        []Option{WithImage("greeting:1"), WithWorkload(WorkloadDaemonSet)},
+			wantChanged: true,
+			want:        []string{"deployment default/greeting: would be deleted", "daemonset default/greeting: would be created"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client.ClearActions()
+			var out bytes.Buffer
+			changed, err := newTestOperator(t, client, test.opts...).Diff(context.Background(), &out)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+
+			if changed != test.wantChanged {
+				t.Errorf("changed = %t, want %t", changed, test.wantChanged)
+			}
+			for _, line := range test.want {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("diff output misses %q:\n%s", line, out.String())
+				}
+			}
+			assertNoWrites(t, client)
+		})
+	}
+}
+
+func TestDiffIgnoresServerDefaults(t *testing.T) {
+	op := newTestOperator(t, newFakeClient())
+
+	// The live deployment as stored by the API server: defaulted, and completed by other controllers.
+	deployment := op.desiredDeployment()
+	live := deployment.DeepCopy()
+	revisions := int32(10)
+	live.Spec.RevisionHistoryLimit = &revisions
+	live.Labels["team"] = "greeters"
+	live.Annotations = map[string]string{"deployment.kubernetes.io/revision": "3"}
+	live.OwnerReferences = append(live.OwnerReferences, meta.OwnerReference{Kind: "Application", Name: "greeting", UID: "1234"})
+	live.Spec.Template.Annotations = map[string]string{"kubectl.kubernetes.io/restartedAt": "2026-10-14T12:00:00Z"}
+	pod := &live.Spec.Template.Spec
+	pod.RestartPolicy = api.RestartPolicyAlways
+	pod.DNSPolicy = api.DNSClusterFirst
+	pod.SchedulerName = api.DefaultSchedulerName
+	container := &pod.Containers[0]
+	container.TerminationMessagePath = api.TerminationMessagePathDefault
+	container.TerminationMessagePolicy = api.TerminationMessageReadFile
+	if container.ImagePullPolicy == "" {
+		container.ImagePullPolicy = api.PullIfNotPresent
+	}
+	if probe := container.ReadinessProbe; probe != nil {
+		probe.PeriodSeconds, probe.SuccessThreshold, probe.FailureThreshold = 10, 1, 3
+	}
+	if diff := deploymentDiff(live, deployment); len(diff) > 0 {
+		t.Errorf("deployment diff = %v, want none", diff)
+	}
+
+	service := op.desiredService()
+	liveService := service.DeepCopy()
+	liveService.Spec.ClusterIP = "10.0.0.10"
+	liveService.Spec.SessionAffinity = api.ServiceAffinityNone
+	liveService.Spec.Ports[0].NodePort = 30080
+	if diff := serviceDiff(liveService, service); len(diff) > 0 {
+		t.Errorf("service diff = %v, want none", diff)
+	}
+}
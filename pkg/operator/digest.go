@@ -0,0 +1,150 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	log "github.com/sirupsen/logrus"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Modes of resolution of the image tag to a digest.
+const (
+	// ResolveDigestOff deploys the image as given.
+	ResolveDigestOff = "false"
+	// ResolveDigestOn pins the image to its digest, failing when the registry cannot resolve it.
+	ResolveDigestOn = "true"
+	// ResolveDigestBestEffort pins the image to its digest, deploying the tag when the registry cannot resolve it.
+	ResolveDigestBestEffort = "best-effort"
+)
+
+// imageAnnotation records the image as given on the pods running its resolved digest.
+const imageAnnotation = "greeting.moutoum.dev/image"
+
+// containerImage returns the image of the greeting container, pinned to its digest once resolved.
+func (o *Operator) containerImage() string {
+	if o.resolvedImage != "" {
+		return o.resolvedImage
+	}
+	return o.image
+}
+
+// resolveImage pins the image to the digest its tag currently points to, when enabled.
+func (o *Operator) resolveImage(ctx context.Context) error {
+	if o.resolveDigest == "" || o.resolveDigest == ResolveDigestOff {
+		return nil
+	}
+
+	resolved, err := o.lookupDigest(ctx)
+	if err != nil {
+		if o.resolveDigest == ResolveDigestBestEffort {
+			log.WithError(err).WithField("image", o.image).Warning("Unable to resolve the image digest, deploying the tag")
+			o.resolvedImage = ""
+			return nil
+		}
+		return fmt.Errorf("resolve image digest: %w", err)
+	}
+
+	if resolved != "" && resolved != o.resolvedImage {
+		log.WithField("image", o.image).WithField("resolved", resolved).Info("Resolved image digest")
+	}
+	o.resolvedImage = resolved
+	return nil
+}
+
+// lookupDigest queries the registry for the digest of the image tag, and returns the image pinned to it.
+// An image already given by digest is returned empty, it is deployed as is.
+func (o *Operator) lookupDigest(ctx context.Context) (string, error) {
+	ref, err := name.ParseReference(o.image)
+	if err != nil {
+		return "", fmt.Errorf("parse image %q: %w", o.image, err)
+	}
+	tag, ok := ref.(name.Tag)
+	if !ok {
+		return "", nil
+	}
+
+	opts := []remote.Option{remote.WithContext(ctx)}
+	if o.imagePullSecret != "" {
+		keychain, err := o.pullSecretKeychain(ctx)
+		if err != nil {
+			return "", err
+		}
+		opts = append(opts, remote.WithAuthFromKeychain(keychain))
+	}
+
+	var digest string
+	if desc, err := remote.Head(tag, opts...); err == nil {
+		digest = desc.Digest.String()
+	} else {
+		// Some registries do not answer HEAD requests on manifests.
+		desc, err := remote.Get(tag, opts...)
+		if err != nil {
+			return "", fmt.Errorf("get manifest of %s: %w", tag, err)
+		}
+		digest = desc.Digest.String()
+	}
+
+	// The repository is kept as written, only the tag is replaced.
+	return strings.TrimSuffix(o.image, ":"+tag.TagStr()) + "@" + digest, nil
+}
+
+// dockerConfig is the content of a kubernetes.io/dockerconfigjson secret.
+type dockerConfig struct {
+	Auths map[string]authn.AuthConfig `json:"auths"`
+}
+
+// dockerConfigKeychain authenticates to the registries with the credentials of a docker config, by registry host.
+type dockerConfigKeychain map[string]authn.AuthConfig
+
+func (k dockerConfigKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	if auth, ok := k[resource.RegistryStr()]; ok {
+		return authn.FromConfig(auth), nil
+	}
+	return authn.Anonymous, nil
+}
+
+// pullSecretKeychain returns the credentials of the image pull secret.
+func (o *Operator) pullSecretKeychain(ctx context.Context) (authn.Keychain, error) {
+	var secret *api.Secret
+	err := o.api.call(ctx, "secret", "get", func(ctx context.Context) error {
+		var err error
+		secret, err = o.client.CoreV1().Secrets(o.namespace).Get(ctx, o.imagePullSecret, meta.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get image pull secret: %w", err)
+	}
+
+	raw, ok := secret.Data[api.DockerConfigJsonKey]
+	if !ok {
+		return nil, fmt.Errorf("image pull secret %q has no %s key", o.imagePullSecret, api.DockerConfigJsonKey)
+	}
+
+	var config dockerConfig
+	if err = json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("parse image pull secret %q: %w", o.imagePullSecret, err)
+	}
+
+	keychain := make(dockerConfigKeychain, len(config.Auths))
+	for key, auth := range config.Auths {
+		keychain[registryHost(key)] = auth
+	}
+	return keychain, nil
+}
+
+// registryHost returns the registry host of a docker config key, which may be written as a URL.
+func registryHost(key string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, _, _ := strings.Cut(key, "/")
+	if host == "docker.io" {
+		return name.DefaultRegistry
+	}
+	return host
+}
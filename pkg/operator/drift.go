@@ -0,0 +1,283 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The diff functions compare the fields managed by the operator only. Fields defaulted by the
+// API server or owned by other controllers are ignored, so that an untouched live object has no diff.
+
+// deploymentDiff returns the differences between the live and the desired deployments.
+func deploymentDiff(live, desired *apps.Deployment) []string {
+	var diff []string
+	diff = append(diff, objectMetaDiff(&live.ObjectMeta, &desired.ObjectMeta)...)
+	diff = append(diff, valueDiff("replicas", int32Value(live.Spec.Replicas), int32Value(desired.Spec.Replicas))...)
+	diff = append(diff, valueDiff("selector", meta.FormatLabelSelector(live.Spec.Selector), meta.FormatLabelSelector(desired.Spec.Selector))...)
+	diff = append(diff, valueDiff("strategy", live.Spec.Strategy.Type, desired.Spec.Strategy.Type)...)
+	diff = append(diff, valueDiff("minReadySeconds", live.Spec.MinReadySeconds, desired.Spec.MinReadySeconds)...)
+	if desired.Spec.ProgressDeadlineSeconds != nil {
+		diff = append(diff, valueDiff("progressDeadlineSeconds", int32Value(live.Spec.ProgressDeadlineSeconds), *desired.Spec.ProgressDeadlineSeconds)...)
+	}
+	diff = append(diff, podTemplateDiff(&live.Spec.Template, &desired.Spec.Template)...)
+	return diff
+}
+
+// daemonSetDiff returns the differences between the live and the desired daemonsets.
+func daemonSetDiff(live, desired *apps.DaemonSet) []string {
+	var diff []string
+	diff = append(diff, objectMetaDiff(&live.ObjectMeta, &desired.ObjectMeta)...)
+	diff = append(diff, valueDiff("minReadySeconds", live.Spec.MinReadySeconds, desired.Spec.MinReadySeconds)...)
+	diff = append(diff, podTemplateDiff(&live.Spec.Template, &desired.Spec.Template)...)
+	return diff
+}
+
+// serviceDiff returns the differences between the live and the desired services.
+func serviceDiff(live, desired *api.Service) []string {
+	var diff []string
+	diff = append(diff, objectMetaDiff(&live.ObjectMeta, &desired.ObjectMeta)...)
+	diff = append(diff, valueDiff("type", live.Spec.Type, desired.Spec.Type)...)
+	diff = append(diff, valueDiff("selector", live.Spec.Selector, desired.Spec.Selector)...)
+
+	livePorts := make(map[string]api.ServicePort, len(live.Spec.Ports))
+	for _, port := range live.Spec.Ports {
+		livePorts[port.Name] = port
+	}
+	for _, port := range desired.Spec.Ports {
+		livePort, ok := livePorts[port.Name]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("ports[%s]: missing", port.Name))
+			continue
+		}
+		delete(livePorts, port.Name)
+
+		field := fmt.Sprintf("ports[%s]", port.Name)
+		diff = append(diff, valueDiff(field+".port", livePort.Port, port.Port)...)
+		diff = append(diff, valueDiff(field+".protocol", livePort.Protocol, port.Protocol)...)
+		diff = append(diff, valueDiff(field+".targetPort", livePort.TargetPort.String(), targetPortValue(port))...)
+		if port.NodePort != 0 {
+			diff = append(diff, valueDiff(field+".nodePort", livePort.NodePort, port.NodePort)...)
+		}
+	}
+	for name := range livePorts {
+		diff = append(diff, fmt.Sprintf("ports[%s]: unexpected", name))
+	}
+
+	return diff
+}
+
+// objectMetaDiff returns the differences between the metadata set by the operator.
+func objectMetaDiff(live, desired *meta.ObjectMeta) []string {
+	var diff []string
+	diff = append(diff, mapDiff("labels", live.Labels, desired.Labels)...)
+	diff = append(diff, mapDiff("annotations", live.Annotations, desired.Annotations)...)
+
+	// Other owners, such as the ones set by users, are kept.
+	owners := make(map[types.UID]bool, len(live.OwnerReferences))
+	for _, ref := range live.OwnerReferences {
+		owners[ref.UID] = true
+	}
+	for _, ref := range desired.OwnerReferences {
+		if !owners[ref.UID] {
+			diff = append(diff, fmt.Sprintf("ownerReferences[%s/%s]: missing", ref.Kind, ref.Name))
+		}
+	}
+
+	return diff
+}
+
+// targetPortValue returns the target port of the service port, defaulted to the port as the API server does.
+func targetPortValue(port api.ServicePort) string {
+	if port.TargetPort.String() == "0" {
+		return fmt.Sprint(port.Port)
+	}
+	return port.TargetPort.String()
+}
+
+// podTemplateDiff returns the differences between the live and the desired pod templates.
+func podTemplateDiff(live, desired *api.PodTemplateSpec) []string {
+	var diff []string
+	diff = append(diff, mapDiff("template.labels", live.Labels, desired.Labels)...)
+	diff = append(diff, mapDiff("template.annotations", live.Annotations, desired.Annotations)...)
+	diff = append(diff, containersDiff("initContainers", live.Spec.InitContainers, desired.Spec.InitContainers)...)
+	diff = append(diff, containersDiff("containers", live.Spec.Containers, desired.Spec.Containers)...)
+	diff = append(diff, valueDiff("volumes", volumesSummary(live.Spec.Volumes), volumesSummary(desired.Spec.Volumes))...)
+	diff = append(diff, valueDiff("topologySpreadConstraints", topologySummary(live.Spec.TopologySpreadConstraints), topologySummary(desired.Spec.TopologySpreadConstraints))...)
+	diff = append(diff, valueDiff("terminationGracePeriodSeconds", int64Value(live.Spec.TerminationGracePeriodSeconds), int64Value(desired.Spec.TerminationGracePeriodSeconds))...)
+	return diff
+}
+
+// containersDiff compares the containers by name, in order.
+func containersDiff(field string, live, desired []api.Container) []string {
+	var diff []string
+	diff = append(diff, valueDiff(field, containerNames(live), containerNames(desired))...)
+	if len(diff) > 0 {
+		return diff
+	}
+
+	for i := range desired {
+		l, d := &live[i], &desired[i]
+		prefix := fmt.Sprintf("%s[%s].", field, d.Name)
+		diff = append(diff, valueDiff(prefix+"image", l.Image, d.Image)...)
+		diff = append(diff, valueDiff(prefix+"command", l.Command, d.Command)...)
+		diff = append(diff, valueDiff(prefix+"args", l.Args, d.Args)...)
+		diff = append(diff, valueDiff(prefix+"env", envSummary(l.Env), envSummary(d.Env))...)
+		diff = append(diff, valueDiff(prefix+"ports", portsSummary(l.Ports), portsSummary(d.Ports))...)
+		diff = append(diff, valueDiff(prefix+"volumeMounts", mountsSummary(l.VolumeMounts), mountsSummary(d.VolumeMounts))...)
+		diff = append(diff, valueDiff(prefix+"livenessProbe", probeSummary(l.LivenessProbe), probeSummary(d.LivenessProbe))...)
+		diff = append(diff, valueDiff(prefix+"readinessProbe", probeSummary(l.ReadinessProbe), probeSummary(d.ReadinessProbe))...)
+		if d.ImagePullPolicy != "" {
+			diff = append(diff, valueDiff(prefix+"imagePullPolicy", l.ImagePullPolicy, d.ImagePullPolicy)...)
+		}
+	}
+
+	return diff
+}
+
+// valueDiff returns a single difference line when the values are not semantically equal.
+func valueDiff(field string, live, desired interface{}) []string {
+	if equality.Semantic.DeepEqual(live, desired) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %v -> %v", field, live, desired)}
+}
+
+// mapDiff compares the desired entries only, other entries being owned by someone else.
+func mapDiff(field string, live, desired map[string]string) []string {
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var diff []string
+	for _, k := range keys {
+		if v, ok := live[k]; !ok {
+			diff = append(diff, fmt.Sprintf("%s[%s]: <none> -> %s", field, k, desired[k]))
+		} else if v != desired[k] {
+			diff = append(diff, fmt.Sprintf("%s[%s]: %s -> %s", field, k, v, desired[k]))
+		}
+	}
+
+	return diff
+}
+
+func int32Value(v *int32) int32 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func int64Value(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func containerNames(containers []api.Container) []string {
+	names := make([]string, 0, len(containers))
+	for _, c := range containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func envSummary(env []api.EnvVar) []string {
+	summary := make([]string, 0, len(env))
+	for _, e := range env {
+		if e.ValueFrom != nil {
+			summary = append(summary, fmt.Sprintf("%s=<from %s>", e.Name, envSourceSummary(e.ValueFrom)))
+			continue
+		}
+		summary = append(summary, e.Name+"="+e.Value)
+	}
+	return summary
+}
+
+func envSourceSummary(source *api.EnvVarSource) string {
+	switch {
+	case source.SecretKeyRef != nil:
+		return "secret " + source.SecretKeyRef.Name + "/" + source.SecretKeyRef.Key
+	case source.ConfigMapKeyRef != nil:
+		return "configmap " + source.ConfigMapKeyRef.Name + "/" + source.ConfigMapKeyRef.Key
+	case source.FieldRef != nil:
+		return "field " + source.FieldRef.FieldPath
+	default:
+		return "source"
+	}
+}
+
+func portsSummary(ports []api.ContainerPort) []string {
+	summary := make([]string, 0, len(ports))
+	for _, p := range ports {
+		s := fmt.Sprintf("%s:%d/%s", p.Name, p.ContainerPort, p.Protocol)
+		if p.HostPort != 0 {
+			s += fmt.Sprintf(" host %d", p.HostPort)
+		}
+		summary = append(summary, s)
+	}
+	return summary
+}
+
+func mountsSummary(mounts []api.VolumeMount) []string {
+	summary := make([]string, 0, len(mounts))
+	for _, m := range mounts {
+		summary = append(summary, fmt.Sprintf("%s:%s ro=%t", m.Name, m.MountPath, m.ReadOnly))
+	}
+	return summary
+}
+
+func volumesSummary(volumes []api.Volume) []string {
+	summary := make([]string, 0, len(volumes))
+	for _, v := range volumes {
+		switch {
+		case v.ConfigMap != nil:
+			summary = append(summary, v.Name+"=configmap "+v.ConfigMap.Name)
+		case v.Secret != nil:
+			summary = append(summary, v.Name+"=secret "+v.Secret.SecretName)
+		case v.EmptyDir != nil:
+			summary = append(summary, v.Name+"=emptyDir")
+		default:
+			summary = append(summary, v.Name)
+		}
+	}
+	return summary
+}
+
+func topologySummary(constraints []api.TopologySpreadConstraint) []string {
+	summary := make([]string, 0, len(constraints))
+	for _, c := range constraints {
+		summary = append(summary, fmt.Sprintf("%s=%d:%s", c.TopologyKey, c.MaxSkew, c.WhenUnsatisfiable))
+	}
+	return summary
+}
+
+// probeSummary describes the fields of a probe set by the operator.
+func probeSummary(probe *api.Probe) string {
+	if probe == nil {
+		return "<none>"
+	}
+
+	var handler string
+	switch {
+	case probe.HTTPGet != nil:
+		handler = fmt.Sprintf("http %s:%s", probe.HTTPGet.Path, probe.HTTPGet.Port.String())
+	case probe.TCPSocket != nil:
+		handler = fmt.Sprintf("tcp %s", probe.TCPSocket.Port.String())
+	case probe.Exec != nil:
+		handler = fmt.Sprintf("exec %s", strings.Join(probe.Exec.Command, " "))
+	case probe.GRPC != nil:
+		handler = fmt.Sprintf("grpc %d", probe.GRPC.Port)
+	}
+
+	return fmt.Sprintf("%s timeout=%ds delay=%ds", handler, probe.TimeoutSeconds, probe.InitialDelaySeconds)
+}
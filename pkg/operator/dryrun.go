@@ -0,0 +1,50 @@
+package operator
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// dryRun returns the dry-run directive of every write request, none unless the server dry-run is enabled.
+// The API server then runs the defaulting and the admission of the request without persisting anything.
+func (o *Operator) dryRun() []string {
+	if !o.serverDryRun {
+		return nil
+	}
+	return []string{meta.DryRunAll}
+}
+
+// createOptions returns the options of every create request.
+func (o *Operator) createOptions() meta.CreateOptions {
+	return meta.CreateOptions{DryRun: o.dryRun()}
+}
+
+// updateOptions returns the options of every update request.
+func (o *Operator) updateOptions() meta.UpdateOptions {
+	return meta.UpdateOptions{DryRun: o.dryRun()}
+}
+
+// patchOptions returns the options of every patch request.
+func (o *Operator) patchOptions() meta.PatchOptions {
+	return meta.PatchOptions{DryRun: o.dryRun()}
+}
+
+// deleteOptions returns the options of every delete request.
+func (o *Operator) deleteOptions() meta.DeleteOptions {
+	return meta.DeleteOptions{DryRun: o.dryRun()}
+}
+
+// logDryRun logs the object returned by a server dry-run write, as the server would have stored it.
+func (o *Operator) logDryRun(kind string, object interface{}) {
+	if !o.serverDryRun || object == nil {
+		return
+	}
+
+	raw, err := yaml.Marshal(object)
+	if err != nil {
+		o.logger(kind).WithError(err).Warning("Unable to marshal the server dry-run result")
+		return
+	}
+
+	o.logger(kind).WithField("object", string(raw)).Info("Server dry-run accepted")
+}
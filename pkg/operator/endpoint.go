@@ -0,0 +1,138 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Output formats of the run.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// Endpoints are the addresses where the greeting server can be reached.
+type Endpoints struct {
+	// Namespace of the service.
+	Namespace string `json:"namespace"`
+	// Name of the service.
+	Name string `json:"name"`
+	// ServiceType is the type of the service.
+	ServiceType string `json:"serviceType"`
+	// URLs of the greet endpoint, empty while the load balancer address is pending.
+	URLs []string `json:"urls"`
+}
+
+// waitForAddress blocks until the load balancer service has been given an address, or the wait timeout expires.
+func (o *Operator) waitForAddress(ctx context.Context) error {
+	waitCtx, cancel := context.WithTimeout(ctx, o.waitTimeout)
+	defer cancel()
+
+	return o.waitFor(waitCtx, "service", o.serviceReachable)
+}
+
+// endpoints resolves the URLs of the greeting server from its service:
+// the load balancer addresses, the node addresses for a node port, the cluster DNS name otherwise.
+func (o *Operator) endpoints(ctx context.Context) (*Endpoints, error) {
+	service, err := o.client.CoreV1().Services(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get service: %w", err)
+	}
+
+	endpoints := &Endpoints{
+		Namespace:   service.Namespace,
+		Name:        service.Name,
+		ServiceType: string(service.Spec.Type),
+		URLs:        []string{},
+	}
+	if len(service.Spec.Ports) == 0 {
+		return endpoints, nil
+	}
+	port := service.Spec.Ports[0]
+
+	switch service.Spec.Type {
+	case api.ServiceTypeLoadBalancer:
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			addr := ingress.IP
+			if addr == "" {
+				addr = ingress.Hostname
+			}
+			if addr != "" {
+				endpoints.URLs = append(endpoints.URLs, greetURL(addr, port.Port))
+			}
+		}
+	case api.ServiceTypeNodePort:
+		nodes, err := o.client.CoreV1().Nodes().List(ctx, meta.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("list nodes: %w", err)
+		}
+		for _, node := range nodes.Items {
+			if addr := nodeAddress(&node); addr != "" {
+				endpoints.URLs = append(endpoints.URLs, greetURL(addr, port.NodePort))
+			}
+		}
+	default:
+		endpoints.URLs = append(endpoints.URLs, greetURL(fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace), port.Port))
+	}
+
+	return endpoints, nil
+}
+
+// nodeAddress returns the external IP of the node, falling back to its internal IP.
+func nodeAddress(node *api.Node) string {
+	var internal string
+	for _, addr := range node.Status.Addresses {
+		switch addr.Type {
+		case api.NodeExternalIP:
+			return addr.Address
+		case api.NodeInternalIP:
+			if internal == "" {
+				internal = addr.Address
+			}
+		}
+	}
+	return internal
+}
+
+// greetURL returns the URL of the greet endpoint served at the address and port.
+func greetURL(addr string, port int32) string {
+	return "http://" + net.JoinHostPort(addr, strconv.Itoa(int(port))) + "/greet"
+}
+
+// serviceEndpoints resolves and logs the URLs of the greeting server.
+func (o *Operator) serviceEndpoints(ctx context.Context) (*Endpoints, error) {
+	endpoints, err := o.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(endpoints.URLs) == 0 && endpoints.ServiceType == string(api.ServiceTypeLoadBalancer) {
+		o.logger("service").Warning("Load balancer address not assigned yet, use --wait-for-ip to wait for it")
+	}
+	for _, url := range endpoints.URLs {
+		o.logger("service").WithField("url", url).Info("Greeting server endpoint")
+	}
+	return endpoints, nil
+}
+
+// printEndpoints writes the URLs of the greeting server to w, one per line.
+func (o *Operator) printEndpoints(ctx context.Context, w io.Writer) error {
+	endpoints, err := o.serviceEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, url := range endpoints.URLs {
+		if _, err = fmt.Fprintln(w, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcore "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the events recorded by the operator.
+const (
+	reasonCreated            = "Created"
+	reasonUpdated            = "Updated"
+	reasonUpdateFailed       = "UpdateFailed"
+	reasonRolloutTimedOut    = "RolloutTimedOut"
+	reasonVerificationFailed = "VerificationFailed"
+	reasonRolledBack         = "RolledBack"
+	reasonPaused             = "Paused"
+	reasonResumed            = "Resumed"
+)
+
+// maxEventMessage is the length above which the event messages are truncated.
+const maxEventMessage = 1024
+
+// newEventBroadcaster returns a broadcaster sending the recorded events to the API server.
+// Its correlator aggregates and rate limits repeated events.
+func newEventBroadcaster(client kubernetes.Interface) (record.EventBroadcaster, record.EventRecorder) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcore.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, api.EventSource{Component: managedByValue})
+
+	return broadcaster, recorder
+}
+
+// event records an event on the object, or on the Greeting resource owning it in controller mode.
+func (o *Operator) event(object runtime.Object, eventType, reason, message string) {
+	// Events are persisted, a server dry-run records none.
+	if o.recorder == nil || o.serverDryRun {
+		return
+	}
+
+	if o.eventObject != nil {
+		object = o.eventObject
+	}
+
+	if len(message) > maxEventMessage {
+		message = message[:maxEventMessage-3] + "..."
+	}
+
+	o.recorder.Event(object, eventType, reason, message)
+}
+
+// recordWrite records the outcome of the creation or update of a resource, only when something changed.
+func (o *Operator) recordWrite(object runtime.Object, kind string, existed bool, diff []string, err error) {
+	switch {
+	case err != nil:
+		o.event(object, api.EventTypeWarning, reasonUpdateFailed, fmt.Sprintf("Unable to write %s %s: %v", kind, o.resourceName, err))
+	case !existed:
+		o.recordAction(kind, ActionCreated)
+		o.event(object, api.EventTypeNormal, reasonCreated, fmt.Sprintf("Created %s %s", kind, o.resourceName))
+	case len(diff) > 0:
+		o.recordAction(kind, ActionUpdated)
+		o.event(object, api.EventTypeNormal, reasonUpdated, fmt.Sprintf("Updated %s %s: %s", kind, o.resourceName, strings.Join(diff, "; ")))
+	}
+}
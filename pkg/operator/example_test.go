@@ -0,0 +1,89 @@
+package operator_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"edb-challenge/pkg/operator"
+)
+
+func ExampleNew() {
+	// A real cluster is reached with the clientset of operator.NewClusterConfig instead.
+	client := fake.NewSimpleClientset()
+
+	op, err := operator.New(client,
+		operator.WithNamespace("greeting"),
+		operator.WithImage("greeting:1.2.0"),
+		operator.WithReplicas(3),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer op.Close()
+
+	// Diff only reads the cluster, Start would install the greeting server.
+	if _, err = op.Diff(context.Background(), os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// namespace greeting: would be created
+	// deployment greeting/greeting: would be created
+	// service greeting/greeting: would be created
+}
+
+func ExampleNewConfig() {
+	config, err := operator.NewConfig(
+		operator.WithImage("registry.example.com/greeting:1.2.0"),
+		operator.WithServiceType(api.ServiceTypeClusterIP),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(config.Image, config.ServiceType, config.Namespace)
+	// Output: registry.example.com/greeting:1.2.0 ClusterIP default
+}
+
+func ExampleNewConfig_invalid() {
+	// Every invalid option is reported at once.
+	_, err := operator.NewConfig(
+		operator.WithImage("Greeting:latest"),
+		operator.WithPort(0),
+	)
+	fmt.Println(err)
+	// Output:
+	// invalid image reference "Greeting:latest"
+	// port 0 must be between 1 and 65535
+}
+
+func ExampleWithWorkload() {
+	config, err := operator.NewConfig(
+		operator.WithWorkload(operator.WorkloadDaemonSet),
+		operator.WithHostPort(true),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// The options check their own value, Validate the configuration as a whole.
+	fmt.Println(config.Workload, config.Validate())
+	// Output: daemonset <nil>
+}
+
+func ExampleWithProbe() {
+	config, err := operator.NewConfig(operator.WithProbe(operator.ProbeExec, "/bin/greeting-server", "--check"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(config.ProbeType, config.ProbeExecCommand)
+	// Output: exec [/bin/greeting-server --check]
+}
@@ -0,0 +1,269 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// fieldDefault is a value set by the API server when the field is omitted.
+type fieldDefault struct {
+	path  []string
+	value interface{}
+}
+
+// serverFields are populated by the API server and meaningless in a manifest.
+var serverFields = [][]string{
+	{"status"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "selfLink"},
+	{"metadata", "annotations", revisionAnnotation},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "labels", "kubernetes.io/metadata.name"},
+	{"spec", "finalizers"},
+	{"spec", "clusterIP"},
+	{"spec", "clusterIPs"},
+	{"spec", "ipFamilies"},
+	{"spec", "ipFamilyPolicy"},
+	{"spec", "healthCheckNodePort"},
+	{"spec", "template", "metadata", "creationTimestamp"},
+}
+
+// workloadDefaults are the values defaulted by the API server in deployments and daemonsets.
+var workloadDefaults = []fieldDefault{
+	{[]string{"spec", "revisionHistoryLimit"}, int64(10)},
+	{[]string{"spec", "progressDeadlineSeconds"}, int64(defaultProgressDeadlineSeconds)},
+	{[]string{"spec", "strategy"}, map[string]interface{}{
+		"type":          "RollingUpdate",
+		"rollingUpdate": map[string]interface{}{"maxSurge": "25%", "maxUnavailable": "25%"},
+	}},
+	{[]string{"spec", "updateStrategy"}, map[string]interface{}{
+		"type":          "RollingUpdate",
+		"rollingUpdate": map[string]interface{}{"maxSurge": int64(0), "maxUnavailable": int64(1)},
+	}},
+	{[]string{"spec", "template", "spec", "dnsPolicy"}, "ClusterFirst"},
+	{[]string{"spec", "template", "spec", "restartPolicy"}, "Always"},
+	{[]string{"spec", "template", "spec", "schedulerName"}, "default-scheduler"},
+	{[]string{"spec", "template", "spec", "securityContext"}, map[string]interface{}{}},
+	{[]string{"spec", "template", "spec", "terminationGracePeriodSeconds"}, int64(30)},
+}
+
+// containerDefaults are the values defaulted by the API server in every container.
+var containerDefaults = []fieldDefault{
+	{[]string{"terminationMessagePath"}, "/dev/termination-log"},
+	{[]string{"terminationMessagePolicy"}, "File"},
+	{[]string{"resources"}, map[string]interface{}{}},
+	{[]string{"livenessProbe", "timeoutSeconds"}, int64(1)},
+	{[]string{"livenessProbe", "periodSeconds"}, int64(10)},
+	{[]string{"livenessProbe", "successThreshold"}, int64(1)},
+	{[]string{"livenessProbe", "failureThreshold"}, int64(3)},
+	{[]string{"livenessProbe", "httpGet", "scheme"}, "HTTP"},
+	{[]string{"readinessProbe", "timeoutSeconds"}, int64(1)},
+	{[]string{"readinessProbe", "periodSeconds"}, int64(10)},
+	{[]string{"readinessProbe", "successThreshold"}, int64(1)},
+	{[]string{"readinessProbe", "failureThreshold"}, int64(3)},
+	{[]string{"readinessProbe", "httpGet", "scheme"}, "HTTP"},
+}
+
+// serviceDefaults are the values defaulted by the API server in services.
+var serviceDefaults = []fieldDefault{
+	{[]string{"spec", "sessionAffinity"}, "None"},
+	{[]string{"spec", "internalTrafficPolicy"}, "Cluster"},
+	{[]string{"spec", "externalTrafficPolicy"}, "Cluster"},
+	{[]string{"spec", "allocateLoadBalancerNodePorts"}, true},
+}
+
+// Export writes the live managed resources of the instance to dir as clean YAML files, one per object,
+// and returns the names of the files written. With kustomize, a kustomization.yaml listing them is also written.
+func (o *Operator) Export(ctx context.Context, dir string, kustomize bool) ([]string, error) {
+	objects, err := o.liveObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	var files []string
+	for _, object := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			return nil, fmt.Errorf("convert object: %w", err)
+		}
+		cleanObject(content)
+
+		u := unstructured.Unstructured{Object: content}
+		file := fmt.Sprintf("%s-%s.yaml", strings.ToLower(u.GetKind()), u.GetName())
+		if err = writeYAML(filepath.Join(dir, file), content); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if kustomize {
+		kustomization := map[string]interface{}{
+			"apiVersion": "kustomize.config.k8s.io/v1beta1",
+			"kind":       "Kustomization",
+			"resources":  files,
+		}
+		if err = writeYAML(filepath.Join(dir, "kustomization.yaml"), kustomization); err != nil {
+			return nil, err
+		}
+		files = append(files, "kustomization.yaml")
+	}
+
+	return files, nil
+}
+
+// liveObjects returns the live managed namespace, workload and service, in creation order.
+// Missing objects are skipped, and so is a namespace not created by the operator.
+func (o *Operator) liveObjects(ctx context.Context) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	add := func(kind string, desired runtime.Object, get func() (runtime.Object, error)) error {
+		object, err := get()
+		if err != nil {
+			if kerror.IsNotFound(err) {
+				o.logger(kind).Warning("Resource not found, not exported")
+				return nil
+			}
+			return fmt.Errorf("get %s: %w", kind, err)
+		}
+
+		object.GetObjectKind().SetGroupVersionKind(desired.GetObjectKind().GroupVersionKind())
+		objects = append(objects, object)
+		return nil
+	}
+
+	namespace, err := o.client.CoreV1().Namespaces().Get(ctx, o.namespace, meta.GetOptions{})
+	switch {
+	case err != nil && !kerror.IsNotFound(err):
+		return nil, fmt.Errorf("get namespace: %w", err)
+	case err == nil && namespace.Labels[managedByLabel] == managedByValue:
+		namespace.GetObjectKind().SetGroupVersionKind(o.desiredNamespace().GroupVersionKind())
+		objects = append(objects, namespace)
+	default:
+		log.WithField("namespace", o.namespace).Info("Namespace not managed by the operator, not exported")
+	}
+
+	if o.workload == WorkloadDaemonSet {
+		err = add("daemonset", o.desiredDaemonSet(), func() (runtime.Object, error) {
+			return o.client.AppsV1().DaemonSets(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+		})
+	} else {
+		err = add("deployment", o.desiredDeployment(), func() (runtime.Object, error) {
+			return o.client.AppsV1().Deployments(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.hostPort {
+		err = add("service", o.desiredService(), func() (runtime.Object, error) {
+			return o.client.CoreV1().Services(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return objects, nil
+}
+
+// cleanObject strips the fields populated by the API server and the defaulted values matching their default,
+// so that the object can be applied to another cluster.
+func cleanObject(object map[string]interface{}) {
+	for _, path := range serverFields {
+		unstructured.RemoveNestedField(object, path...)
+	}
+
+	removeDefaults(object, workloadDefaults)
+	removeDefaults(object, serviceDefaults)
+
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, found, _ := unstructured.NestedSlice(object, "spec", "template", "spec", field)
+		if !found {
+			continue
+		}
+		for _, container := range containers {
+			if container, ok := container.(map[string]interface{}); ok {
+				removeDefaults(container, containerDefaults)
+			}
+		}
+		_ = unstructured.SetNestedSlice(object, containers, "spec", "template", "spec", field)
+	}
+
+	// Node ports are allocated by the cluster.
+	if ports, found, _ := unstructured.NestedSlice(object, "spec", "ports"); found {
+		for _, port := range ports {
+			if port, ok := port.(map[string]interface{}); ok {
+				delete(port, "nodePort")
+			}
+		}
+		_ = unstructured.SetNestedSlice(object, ports, "spec", "ports")
+	}
+
+	removeEmpty(object)
+}
+
+// removeDefaults removes the fields equal to their default value.
+func removeDefaults(object map[string]interface{}, defaults []fieldDefault) {
+	for _, d := range defaults {
+		value, found, err := unstructured.NestedFieldNoCopy(object, d.path...)
+		if err == nil && found && reflect.DeepEqual(value, d.value) {
+			unstructured.RemoveNestedField(object, d.path...)
+		}
+	}
+}
+
+// removeEmpty removes the null fields, and the maps left empty by the stripping, except meaningful ones like emptyDir.
+func removeEmpty(object map[string]interface{}) {
+	for key, value := range object {
+		switch value := value.(type) {
+		case nil:
+			delete(object, key)
+		case map[string]interface{}:
+			if len(value) == 0 {
+				continue
+			}
+			removeEmpty(value)
+			if len(value) == 0 {
+				delete(object, key)
+			}
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					removeEmpty(item)
+				}
+			}
+		}
+	}
+}
+
+// writeYAML writes the value as a YAML file.
+func writeYAML(path string, value interface{}) error {
+	raw, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", filepath.Base(path), err)
+	}
+
+	if err = os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
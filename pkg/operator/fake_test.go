@@ -0,0 +1,135 @@
+package operator
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// newFakeClient returns a fake clientset holding the objects. The fake tracker does not support server-side
+// apply, its requests are served as JSON merge patches owned by the operator field manager.
+func newFakeClient(objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("patch", "*", applyReactor(client.Tracker()))
+	return client
+}
+
+// newTestOperator returns an operator of the fake client configured by the options on top of DefaultConfig.
+func newTestOperator(t *testing.T, client *fake.Clientset, opts ...Option) *Operator {
+	t.Helper()
+	config, err := NewConfig(opts...)
+	if err != nil {
+		t.Fatalf("NewConfig: %v", err)
+	}
+	op, err := NewWithClient(client, config)
+	if err != nil {
+		t.Fatalf("NewWithClient: %v", err)
+	}
+	t.Cleanup(op.Close)
+	return op
+}
+
+// armedReactor reacts only once armed, so that it is prepended before any operator runs: the operators record
+// their events with the client from goroutines of their own, which a later PrependReactor would race with.
+func armedReactor(armed *atomic.Bool, reaction ktesting.ReactionFunc) ktesting.ReactionFunc {
+	return func(action ktesting.Action) (bool, runtime.Object, error) {
+		if !armed.Load() {
+			return false, nil, nil
+		}
+		return reaction(action)
+	}
+}
+
+func applyReactor(tracker ktesting.ObjectTracker) ktesting.ReactionFunc {
+	return func(action ktesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(ktesting.PatchAction)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		resource, namespace, name := patch.GetResource(), patch.GetNamespace(), patch.GetName()
+		fields, err := appliedFields(patch.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+
+		live, err := tracker.Get(resource, namespace, name)
+		if kerror.IsNotFound(err) {
+			created, err := decodeApplied(patch.GetPatch(), fields)
+			if err != nil {
+				return true, nil, err
+			}
+			return true, created, tracker.Create(resource, created, namespace)
+		}
+		if err != nil {
+			return true, nil, err
+		}
+
+		current, err := json.Marshal(live)
+		if err != nil {
+			return true, nil, err
+		}
+		merged, err := jsonpatch.MergePatch(current, patch.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+		updated, err := decodeApplied(merged, fields)
+		if err != nil {
+			return true, nil, err
+		}
+		return true, updated, tracker.Update(resource, updated, namespace)
+	}
+}
+
+// decodeApplied decodes the applied object, owning the applied fields.
+func decodeApplied(raw []byte, fields []byte) (runtime.Object, error) {
+	object, err := runtime.Decode(scheme.Codecs.UniversalDeserializer(), raw)
+	if err != nil {
+		return nil, err
+	}
+	accessor := object.(meta.Object)
+	managed := []meta.ManagedFieldsEntry{{
+		Manager:   fieldManager,
+		Operation: meta.ManagedFieldsOperationApply,
+		FieldsV1:  &meta.FieldsV1{Raw: fields},
+	}}
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != meta.ManagedFieldsOperationApply {
+			managed = append(managed, entry)
+		}
+	}
+	accessor.SetManagedFields(managed)
+	return object, nil
+}
+
+// appliedFields returns the fields set by the apply request in the format of the managed fields, the lists
+// being owned as a whole.
+func appliedFields(raw []byte) ([]byte, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, err
+	}
+	delete(object, "apiVersion")
+	delete(object, "kind")
+	return json.Marshal(fieldSet(object))
+}
+
+func fieldSet(object map[string]interface{}) map[string]interface{} {
+	set := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		if nested, ok := value.(map[string]interface{}); ok {
+			set["f:"+key] = fieldSet(nested)
+		} else {
+			set["f:"+key] = map[string]interface{}{}
+		}
+	}
+	return set
+}
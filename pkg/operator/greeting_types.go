@@ -0,0 +1,45 @@
+package operator
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GreetingGroupVersion is the API group and version of the Greeting custom resource.
+var GreetingGroupVersion = schema.GroupVersion{Group: "moutoum.dev", Version: "v1alpha1"}
+
+// greetingResource identifies the Greeting custom resource for the dynamic client.
+var greetingResource = GreetingGroupVersion.WithResource("greetings")
+
+// Greeting describes a greeting server instance reconciled by the operator.
+type Greeting struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the desired greeting server.
+	Spec GreetingSpec `json:"spec"`
+	// Status is the observed state of the greeting server.
+	Status GreetingStatus `json:"status,omitempty"`
+}
+
+// GreetingSpec mirrors the Config, empty fields defaulting to the operator flags.
+type GreetingSpec struct {
+	// Image to use to create the greeting server.
+	Image string `json:"image,omitempty"`
+	// Port on which the greeting server is reachable.
+	Port int `json:"port,omitempty"`
+	// Replicas of the greeting server.
+	Replicas *uint `json:"replicas,omitempty"`
+	// Name of the greeting server.
+	Name string `json:"name,omitempty"`
+	// ServiceType is the type of the service exposing the greeting server.
+	ServiceType string `json:"serviceType,omitempty"`
+}
+
+// GreetingStatus is the observed state of a greeting server instance.
+type GreetingStatus struct {
+	// ReadyReplicas is the number of ready greeting server pods.
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// Address is where the service can be reached, its external address when available.
+	Address string `json:"address,omitempty"`
+}
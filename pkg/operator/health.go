@@ -0,0 +1,107 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// apiCheckInterval is how long the result of the API server check is reused by the readiness probe.
+const apiCheckInterval = 10 * time.Second
+
+// States of the informer caches reported to the readiness probe.
+const (
+	// cachesIdle is the state when no informer runs, such as before watching or while following the leader.
+	cachesIdle int32 = iota
+	cachesSyncing
+	cachesSynced
+)
+
+// cachesState holds the state of the informer caches of the running watch or controller.
+var cachesState atomic.Int32
+
+// setCachesState reports the state of the informer caches to the readiness probe.
+func setCachesState(state int32) {
+	cachesState.Store(state)
+}
+
+// HealthServer serves the liveness and readiness probes of the operator.
+type HealthServer struct {
+	addr   string
+	client kubernetes.Interface
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	apiErr    error
+}
+
+// NewHealthServer creates a HealthServer checking the API server reachability.
+func NewHealthServer(addr string, conn ClusterConnection) (*HealthServer, error) {
+	cfg, err := NewClusterConfig(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new k8s client: %w", err)
+	}
+
+	return &HealthServer{addr: addr, client: client}, nil
+}
+
+// Run serves /healthz and /readyz until the context is cancelled.
+func (s *HealthServer) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(rw http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(rw, "ok")
+	})
+	mux.HandleFunc("/readyz", s.handleReady)
+
+	server := &http.Server{Addr: s.addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	log.WithField("addr", s.addr).Info("Serving health probes")
+	if err := serveUntilDone(ctx, server, server.ListenAndServe); err != nil {
+		return fmt.Errorf("serve health probes: %w", err)
+	}
+
+	return nil
+}
+
+func (s *HealthServer) handleReady(rw http.ResponseWriter, req *http.Request) {
+	if err := s.checkAPIServer(req.Context()); err != nil {
+		http.Error(rw, fmt.Sprintf("api server unreachable: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	if cachesState.Load() == cachesSyncing {
+		http.Error(rw, "informer caches not synced", http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(rw, "ok")
+}
+
+// checkAPIServer asks the API server its version, reusing the last result for apiCheckInterval.
+func (s *HealthServer) checkAPIServer(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.checkedAt.IsZero() && time.Since(s.checkedAt) < apiCheckInterval {
+		return s.apiErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, s.apiErr = s.client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	s.checkedAt = time.Now()
+
+	return s.apiErr
+}
@@ -0,0 +1,94 @@
+package operator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// sharedVolumeName is the name of the emptyDir volume shared between the init containers and the greeting server.
+const sharedVolumeName = "shared"
+
+// initContainerRefRegexp splits an image reference from an optional command.
+// The tag is optional, so a single word following the image is read as a tag rather than a command.
+var initContainerRefRegexp = regexp.MustCompile(`^((?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9._/-]+(?::[\w][\w.-]{0,127})?(?:@sha256:[a-f0-9]{64})?)(?::(.+))?$`)
+
+// InitContainer is a container run to completion before the greeting server starts.
+type InitContainer struct {
+	// Name of the container.
+	Name string
+	// Image of the container.
+	Image string
+	// Command overrides the image entrypoint when not empty.
+	Command []string
+}
+
+// ParseInitContainer parses an init container definition formatted as name=image[:command].
+// The command is split on white spaces, no shell quoting is interpreted.
+func ParseInitContainer(value string) (InitContainer, error) {
+	name, ref, found := strings.Cut(value, "=")
+	if !found {
+		return InitContainer{}, fmt.Errorf("init container %q: expected name=image[:command]", value)
+	}
+
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return InitContainer{}, fmt.Errorf("init container %q: invalid name: %s", value, strings.Join(errs, ", "))
+	}
+	// The container names are unique across the init and the regular containers of a pod.
+	if name == greetingContainerName {
+		return InitContainer{}, fmt.Errorf("init container %q: name is reserved for the greeting server", value)
+	}
+
+	matches := initContainerRefRegexp.FindStringSubmatch(ref)
+	if matches == nil {
+		return InitContainer{}, fmt.Errorf("init container %q: invalid image", value)
+	}
+
+	return InitContainer{
+		Name:    name,
+		Image:   matches[1],
+		Command: strings.Fields(matches[2]),
+	}, nil
+}
+
+// ParseInitContainers parses every init container definition, keeping their order.
+func ParseInitContainers(values []string) ([]InitContainer, error) {
+	containers := make([]InitContainer, 0, len(values))
+	names := make(map[string]bool, len(values))
+	for _, value := range values {
+		container, err := ParseInitContainer(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if names[container.Name] {
+			return nil, fmt.Errorf("init container %q: duplicated name", container.Name)
+		}
+		names[container.Name] = true
+
+		containers = append(containers, container)
+	}
+
+	return containers, nil
+}
+
+// initContainers returns the init containers of the pod, all mounting the shared volume.
+func (o *Operator) initContainers() []api.Container {
+	var containers []api.Container
+	for _, c := range o.initContainerSpecs {
+		containers = append(containers, api.Container{
+			Name:    c.Name,
+			Image:   c.Image,
+			Command: c.Command,
+			VolumeMounts: []api.VolumeMount{{
+				Name:      sharedVolumeName,
+				MountPath: o.sharedVolumePath,
+			}},
+		})
+	}
+
+	return containers
+}
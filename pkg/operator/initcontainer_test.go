@@ -0,0 +1,46 @@
+package operator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseInitContainer(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  InitContainer
+		err   string
+	}{
+		{value: "setup=busybox", want: InitContainer{Name: "setup", Image: "busybox", Command: []string{}}},
+		{value: "setup=busybox:1.36", want: InitContainer{Name: "setup", Image: "busybox:1.36", Command: []string{}}},
+		{value: "setup=busybox:1.36:sh -c true", want: InitContainer{Name: "setup", Image: "busybox:1.36", Command: []string{"sh", "-c", "true"}}},
+		{value: "busybox", err: "expected name=image"},
+		{value: "Setup=busybox", err: "invalid name"},
+		{value: "greeting=busybox", err: "reserved for the greeting server"},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseInitContainer(tc.value)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("ParseInitContainer(%q) error = %v, want %q", tc.value, err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseInitContainer(%q): %v", tc.value, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseInitContainer(%q) = %+v, want %+v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateReservedInitContainerName(t *testing.T) {
+	config := DefaultConfig()
+	config.InitContainers = []InitContainer{{Name: greetingContainerName, Image: "busybox"}}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "reserved for the greeting server") {
+		t.Fatalf("Validate() = %v, want the reserved name refused", err)
+	}
+}
@@ -0,0 +1,194 @@
+package operator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// InstancesKey is the key of the configuration file listing the instances.
+const InstancesKey = "instances"
+
+// Instance is one of several greeting servers deployed from the configuration file.
+// Empty fields keep the value of the flags.
+type Instance struct {
+	// Instance names the resources of the greeting server.
+	Instance string `json:"instance"`
+	// Namespace of the resources.
+	Namespace string `json:"namespace,omitempty"`
+	// Image of the greeting server.
+	Image string `json:"image,omitempty"`
+	// Port exposed by the service.
+	Port int `json:"port,omitempty"`
+	// Replicas of the greeting server.
+	Replicas *uint `json:"replicas,omitempty"`
+	// Name greeted by the server.
+	Name string `json:"name,omitempty"`
+}
+
+// ParseInstances decodes the instances listed in the configuration file, rejecting unknown keys.
+func ParseInstances(value interface{}) ([]Instance, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	var instances []Instance
+	if err = decoder.Decode(&instances); err != nil {
+		return nil, err
+	}
+
+	for i, instance := range instances {
+		if instance.Instance == "" {
+			return nil, fmt.Errorf("instance #%d has no name", i+1)
+		}
+	}
+
+	return instances, nil
+}
+
+// InstanceConfigs returns the configuration of every greeting server to deploy,
+// the configuration itself unless instances or several namespaces are listed.
+// The instances which do not name their namespace are deployed to every namespace.
+func (c *Config) InstanceConfigs() ([]*Config, error) {
+	if len(c.Instances) == 0 && len(c.Namespaces) == 0 {
+		return []*Config{c}, nil
+	}
+
+	// Without instances, the flags describe a single one.
+	instances := c.Instances
+	if len(instances) == 0 {
+		instances = []Instance{{Instance: c.ResourceName}}
+	}
+	namespaces := c.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{c.Namespace}
+	}
+
+	var configs []*Config
+	seen := make(map[string]bool, len(instances)*len(namespaces))
+	for _, instance := range instances {
+		instanceNamespaces := namespaces
+		if instance.Namespace != "" {
+			instanceNamespaces = []string{instance.Namespace}
+		}
+
+		for _, namespace := range instanceNamespaces {
+			config := c.instanceConfig(instance, namespace)
+
+			key := config.Namespace + "/" + config.ResourceName
+			if seen[key] {
+				return nil, fmt.Errorf("instance %s is listed twice", key)
+			}
+			seen[key] = true
+
+			configs = append(configs, config)
+		}
+	}
+
+	// The instances sharing a namespace must not prune each other.
+	for _, config := range configs {
+		for _, other := range configs {
+			if other != config && other.Namespace == config.Namespace {
+				config.PeerInstances = append(config.PeerInstances, other.ResourceName)
+			}
+		}
+	}
+
+	return configs, nil
+}
+
+// instanceConfig returns the configuration of the instance in the namespace.
+func (c *Config) instanceConfig(instance Instance, namespace string) *Config {
+	config := *c
+	config.Instances = nil
+	config.Namespaces = nil
+	config.Namespace = namespace
+	config.ResourceName = instance.Instance
+
+	if instance.Image != "" {
+		config.Image = instance.Image
+	}
+	if instance.Port != 0 {
+		config.Port = instance.Port
+	}
+	if instance.Replicas != nil {
+		config.Replicas = *instance.Replicas
+		config.ManageReplicas = true
+	}
+	if instance.Name != "" {
+		config.Name = instance.Name
+		config.NameFrom = nil
+	}
+
+	return &config
+}
+
+// FilterInstances keeps the configurations of the named instances, every one of them when no name is given.
+func FilterInstances(configs []*Config, names []string) ([]*Config, error) {
+	if len(names) == 0 {
+		return configs, nil
+	}
+
+	var filtered []*Config
+	for _, name := range names {
+		var found bool
+		for _, config := range configs {
+			if config.ResourceName == name {
+				filtered = append(filtered, config)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown instance %q", name)
+		}
+	}
+
+	return filtered, nil
+}
+
+// ForEachInstance calls fn for every instance, one after the other or concurrently.
+// A failing instance does not stop the others, the failures are all reported at the end.
+func ForEachInstance(configs []*Config, concurrent bool, fn func(*Config) error) error {
+	if len(configs) == 1 {
+		return fn(configs[0])
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	call := func(config *Config) {
+		if err := fn(config); err != nil {
+			log.WithError(err).WithField("namespace", config.Namespace).WithField("instance", config.ResourceName).Error("Instance failed")
+
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("instance %s/%s: %w", config.Namespace, config.ResourceName, err))
+			mu.Unlock()
+		}
+	}
+
+	if !concurrent {
+		for _, config := range configs {
+			call(config)
+		}
+		return errors.Join(errs...)
+	}
+
+	var wg sync.WaitGroup
+	for _, config := range configs {
+		wg.Add(1)
+		go func(config *Config) {
+			defer wg.Done()
+			call(config)
+		}(config)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package operator
+
+import (
+	"fmt"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// managedByLabel marks the resources created by the operator.
+	managedByLabel = "app.kubernetes.io/managed-by"
+	// managedByValue is the value of the managed-by label set by the operator.
+	managedByValue = "greeting-operator"
+	// instanceLabel tells which greeting server a managed resource belongs to.
+	instanceLabel = "greeting.moutoum.dev/instance"
+)
+
+// objectLabels returns the labels stamped on every resource created by the operator.
+func (o *Operator) objectLabels() map[string]string {
+	labels := o.selectorLabels()
+	labels[managedByLabel] = managedByValue
+	labels[instanceLabel] = o.resourceName
+	return labels
+}
+
+// managedSelector returns the label selector matching every resource created by the operator.
+func managedSelector() string {
+	return managedByLabel + "=" + managedByValue
+}
+
+// instanceSelector returns the label selector matching the resources created by the operator for this instance.
+func (o *Operator) instanceSelector() string {
+	return managedSelector() + "," + instanceLabel + "=" + o.resourceName
+}
+
+// checkManaged refuses to overwrite a live object the operator did not create, unless adopting it,
+// in which case the desired labels written along the update mark it as managed.
+func (o *Operator) checkManaged(kind string, live meta.Object) error {
+	if live.GetLabels()[managedByLabel] == managedByValue {
+		return nil
+	}
+
+	if !o.adopt {
+		return fmt.Errorf("%s %s/%s already exists and is not managed by the operator (no %s=%s label), pass --adopt to take it over",
+			kind, live.GetNamespace(), live.GetName(), managedByLabel, managedByValue)
+	}
+
+	o.logger(kind).Warning("Adopting resource not created by the operator")
+	return nil
+}
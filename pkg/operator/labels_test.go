@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckManaged(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		adopt   bool
+		wantErr bool
+	}{
+		{name: "already labelled", labels: map[string]string{managedByLabel: managedByValue}},
+		{name: "refused", labels: map[string]string{"app": "greeting"}, wantErr: true},
+		{name: "other manager refused", labels: map[string]string{managedByLabel: "helm"}, wantErr: true},
+		{name: "adopted", labels: map[string]string{"app": "greeting"}, adopt: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op := newTestOperator(t, newFakeClient(), WithAdopt(test.adopt))
+			live := &api.Service{ObjectMeta: meta.ObjectMeta{Name: "greeting", Namespace: "default", Labels: test.labels}}
+
+			err := op.checkManaged("service", live)
+			if test.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "--adopt") {
+					t.Errorf("checkManaged error = %v, want a refusal suggesting --adopt", err)
+				}
+			} else if err != nil {
+				t.Errorf("checkManaged: %v", err)
+			}
+		})
+	}
+}
+
+// unmanagedDeployment returns a deployment named like the one of the operator but created by someone else.
+func unmanagedDeployment() *apps.Deployment {
+	return &apps.Deployment{
+		ObjectMeta: meta.ObjectMeta{Name: "greeting", Namespace: "default", Labels: map[string]string{"app": "greeting"}},
+		Spec: apps.DeploymentSpec{
+			Selector: &meta.LabelSelector{MatchLabels: map[string]string{"app": "greeting"}},
+			Template: api.PodTemplateSpec{Spec: api.PodSpec{Containers: []api.Container{{Name: greetingContainerName, Image: "other"}}}},
+		},
+	}
+}
+
+func TestInstallRefusesUnmanaged(t *testing.T) {
+	for _, mode := range writeModes {
+		t.Run(mode.name, func(t *testing.T) {
+			client := newFakeClient(unmanagedDeployment())
+			err := newTestOperator(t, client, mode.opts...).install(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "not managed by the operator") {
+				t.Fatalf("install error = %v, want the unmanaged deployment refused", err)
+			}
+
+			for _, action := range client.Actions() {
+				if action.GetResource().Resource == "deployments" && action.GetVerb() != "get" {
+					t.Errorf("unmanaged deployment written: %s", action.GetVerb())
+				}
+			}
+		})
+	}
+}
+
+func TestInstallAdoptsUnmanaged(t *testing.T) {
+	for _, mode := range writeModes {
+		t.Run(mode.name, func(t *testing.T) {
+			client := newFakeClient(unmanagedDeployment())
+			if err := newTestOperator(t, client, append(mode.opts, WithAdopt(true))...).install(context.Background()); err != nil {
+				t.Fatalf("install: %v", err)
+			}
+
+			live, err := client.AppsV1().Deployments("default").Get(context.Background(), "greeting", meta.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if live.Labels[managedByLabel] != managedByValue {
+				t.Errorf("labels = %v, want the adopted deployment marked as managed", live.Labels)
+			}
+			if image := deploymentImage(live); image != DefaultConfig().Image {
+				t.Errorf("image = %q, want the desired %q", image, DefaultConfig().Image)
+			}
+
+			// Once labelled, the next run no longer needs --adopt.
+			if err = newTestOperator(t, client, mode.opts...).install(context.Background()); err != nil {
+				t.Errorf("install without --adopt after the adoption: %v", err)
+			}
+		})
+	}
+}
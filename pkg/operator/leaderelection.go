@@ -0,0 +1,124 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// errLeadershipLost is returned when the leader lease is lost and candidacy is not re-entered.
+var errLeadershipLost = errors.New("leadership lost")
+
+// LeaderElectionConfig is the configuration of the Lease based leader election.
+type LeaderElectionConfig struct {
+	// Namespace of the Lease.
+	Namespace string
+	// Name of the Lease.
+	Name string
+	// Identity of this candidate, unique among the operator replicas.
+	Identity string
+	// LeaseDuration is how long followers wait before taking over a lease that is not renewed.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries renewing its lease before giving up.
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval between two attempts to acquire or renew the lease.
+	RetryPeriod time.Duration
+	// Reelect re-enters candidacy after losing leadership instead of returning errLeadershipLost.
+	Reelect bool
+	// Connection tells how to reach the API server holding the Lease.
+	Connection ClusterConnection
+}
+
+// RunLeaderElected calls run once the lease is acquired, cancelling its context as soon as the lease is lost.
+func RunLeaderElected(ctx context.Context, config LeaderElectionConfig, run func(context.Context) error) error {
+	cfg, err := NewClusterConfig(config.Connection)
+	if err != nil {
+		return err
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("new k8s client: %w", err)
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  meta.ObjectMeta{Namespace: config.Namespace, Name: config.Name},
+		Client:     client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: config.Identity},
+	}
+
+	logger := log.WithFields(log.Fields{
+		"lease":    config.Namespace + "/" + config.Name,
+		"identity": config.Identity,
+	})
+
+	for {
+		var leading, completed atomic.Bool
+		result := make(chan error, 1)
+		electionCtx, cancel := context.WithCancel(ctx)
+
+		elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   config.LeaseDuration,
+			RenewDeadline:   config.RenewDeadline,
+			RetryPeriod:     config.RetryPeriod,
+			ReleaseOnCancel: true,
+			Name:            config.Name,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					leading.Store(true)
+					logger.Info("Started leading")
+					err := run(ctx)
+					// The run may end on its own, the election is then pointless.
+					completed.Store(ctx.Err() == nil)
+					cancel()
+					result <- err
+				},
+				OnStoppedLeading: func() {
+					if leading.Load() {
+						logger.Warning("Stopped leading")
+					}
+				},
+				OnNewLeader: func(identity string) {
+					if identity != config.Identity {
+						logger.WithField("leader", identity).Info("Following the elected leader")
+					}
+				},
+			},
+		})
+		if err != nil {
+			cancel()
+			return fmt.Errorf("new leader elector: %w", err)
+		}
+
+		logger.Info("Waiting for leadership")
+		elector.Run(electionCtx)
+		cancel()
+
+		// The elector only returns without holding the lease when the context is cancelled.
+		if !leading.Load() {
+			return nil
+		}
+
+		err = <-result
+		if completed.Load() || ctx.Err() != nil {
+			return err
+		}
+		if err != nil {
+			logger.WithError(err).Warning("Reconciliation interrupted by the loss of leadership")
+		}
+
+		if !config.Reelect {
+			return errLeadershipLost
+		}
+		logger.Info("Re-entering leader candidacy")
+	}
+}
@@ -0,0 +1,14 @@
+package operator
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// logger returns the logger of a resource of the given kind, carrying its namespace and name.
+func (o *Operator) logger(kind string) *log.Entry {
+	return log.WithFields(log.Fields{
+		"namespace": o.namespace,
+		"kind":      kind,
+		"name":      o.resourceName,
+	})
+}
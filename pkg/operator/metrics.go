@@ -0,0 +1,102 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// metricsNamespace prefixes the name of every operator metric.
+const metricsNamespace = "greeting_operator"
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	operationsTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "operations_total",
+		Help:      "Number of operations on the managed resources, by namespace, resource kind, operation and result.",
+	}, []string{"namespace", "kind", "operation", "result"})
+
+	apiDuration = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of the Kubernetes API calls, by namespace, resource kind and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"namespace", "kind", "operation"})
+
+	lastReconcile = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_reconcile_timestamp_seconds",
+		Help:      "Unix time of the last reconciliation, by namespace.",
+	}, []string{"namespace"})
+
+	reconcileErrors = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_errors_total",
+		Help:      "Number of failed reconciliations, by namespace.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// instrument calls the Kubernetes API through call, recording its latency and result for the namespace and the resource kind.
+func instrument(namespace, kind, operation string, call func() error) error {
+	start := time.Now()
+	err := call()
+	apiDuration.WithLabelValues(namespace, kind, operation).Observe(time.Since(start).Seconds())
+	operationsTotal.WithLabelValues(namespace, kind, operation, resultLabel(err)).Inc()
+	return err
+}
+
+// resultLabel classifies the error of an API call, keeping the expected failures apart.
+func resultLabel(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case kerror.IsAlreadyExists(err):
+		return "already_exists"
+	case kerror.IsNotFound(err):
+		return "not_found"
+	case kerror.IsConflict(err):
+		return "conflict"
+	default:
+		return "error"
+	}
+}
+
+// observeReconcile records the completion of a reconciliation in the namespace.
+func observeReconcile(namespace string, err error) {
+	lastReconcile.WithLabelValues(namespace).SetToCurrentTime()
+	if err != nil {
+		reconcileErrors.WithLabelValues(namespace).Inc()
+	}
+}
+
+// ServeMetrics exposes the metrics on /metrics until the context is cancelled.
+func ServeMetrics(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	log.WithField("addr", addr).Info("Serving metrics")
+	if err := serveUntilDone(ctx, server, server.ListenAndServe); err != nil {
+		return fmt.Errorf("serve metrics: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// NameSource is the key of a ConfigMap holding the greeting name.
+type NameSource struct {
+	// Namespace of the ConfigMap.
+	Namespace string
+	// Name of the ConfigMap.
+	Name string
+	// Key holding the greeting name.
+	Key string
+}
+
+// ParseNameSource parses a ConfigMap key formatted as namespace/name:key.
+func ParseNameSource(value string) (NameSource, error) {
+	ref, key, found := strings.Cut(value, ":")
+	if !found {
+		return NameSource{}, fmt.Errorf("name source %q: expected namespace/name:key", value)
+	}
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found {
+		return NameSource{}, fmt.Errorf("name source %q: expected namespace/name:key", value)
+	}
+
+	source := NameSource{Namespace: namespace, Name: name, Key: key}
+	if err := source.validate(); err != nil {
+		return NameSource{}, fmt.Errorf("name source %q: %w", value, err)
+	}
+	return source, nil
+}
+
+func (s NameSource) validate() error {
+	if err := validateDNSLabel("namespace", s.Namespace); err != nil {
+		return err
+	}
+	if msgs := validation.IsDNS1123Subdomain(s.Name); len(msgs) > 0 {
+		return fmt.Errorf("invalid configmap name %q: %s", s.Name, strings.Join(msgs, ", "))
+	}
+	if msgs := validation.IsConfigMapKey(s.Key); len(msgs) > 0 {
+		return fmt.Errorf("invalid configmap key %q: %s", s.Key, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+func (s NameSource) String() string {
+	return s.Namespace + "/" + s.Name + ":" + s.Key
+}
+
+// loadName reads the greeting name from its source ConfigMap, when any.
+// The current name is kept when the ConfigMap or its key is missing.
+func (o *Operator) loadName(ctx context.Context) error {
+	if o.nameSource == nil {
+		return nil
+	}
+
+	var configMap *api.ConfigMap
+	err := o.api.call(ctx, "configmap", "get", func(ctx context.Context) error {
+		var err error
+		configMap, err = o.client.CoreV1().ConfigMaps(o.nameSource.Namespace).Get(ctx, o.nameSource.Name, meta.GetOptions{})
+		return err
+	})
+	if kerror.IsNotFound(err) {
+		log.WithField("source", o.nameSource.String()).WithField("name", o.name).Error("Name source configmap not found, keeping the current name")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get name source configmap: %w", err)
+	}
+
+	o.updateName(configMap)
+	return nil
+}
+
+// updateName takes the greeting name from the source ConfigMap, and returns whether it changed.
+// A missing or empty key keeps the last known name rather than blanking it.
+func (o *Operator) updateName(configMap *api.ConfigMap) bool {
+	logger := log.WithField("source", o.nameSource.String())
+
+	name := configMap.Data[o.nameSource.Key]
+	if name == "" {
+		logger.WithField("name", o.name).Error("Name source key missing or empty, keeping the last known name")
+		return false
+	}
+	if name == o.name {
+		return false
+	}
+
+	logger.WithField("previous", o.name).WithField("name", name).Info("Greeting name changed")
+	o.name = name
+	return true
+}
@@ -0,0 +1,690 @@
+// Package operator deploys and reconciles greeting servers on Kubernetes. The greeting-operator
+// command is a thin command line wrapper around it.
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+// Config is the configration required to create the Operator.
+type Config struct {
+	// Image to use to create the greeting server.
+	Image string
+	// ImagePullSecret is the docker config secret of the namespace pulling the image, also used to resolve its digest.
+	ImagePullSecret string
+	// ResolveDigest pins the image to the digest of its tag at deploy time, one of the ResolveDigest modes.
+	ResolveDigest string
+	// Port on which the greeting server is reachable.
+	Port int
+	// GRPCPort is the port of the gRPC services of the greeting server, on its container and its service,
+	// disabled when 0.
+	GRPCPort int
+	// Namespace is which the resources are created.
+	Namespace string
+	// Namespaces deploys the greeting server to each of the namespaces rather than to Namespace alone.
+	Namespaces []string
+	// NamespaceSelector restricts the Greeting resources reconciled by the controller to the namespaces matching the label selector.
+	NamespaceSelector string
+	// CreateNamespace creates the namespace when it does not exist.
+	CreateNamespace bool
+	// Number of greeting server replicas.
+	Replicas uint
+	// ManageReplicas overwrites the live replicas with Replicas, otherwise they are only set on creation.
+	ManageReplicas bool
+	// MaxReplicas is the maximum number of replicas, 0 for no maximum.
+	MaxReplicas uint
+	// Name of the greeting server.
+	Name string
+	// NameFrom is the ConfigMap key holding the name, overriding Name once read.
+	NameFrom *NameSource
+	// GreetingTemplate is the text/template of the greeting message, the server default when empty.
+	GreetingTemplate string
+	// ShowHost makes the greeting server tell its pod name and version in the greeting message.
+	ShowHost bool
+	// AuthToken is the bearer token required by the greeting server on /greet, kept in a Secret, open when empty.
+	AuthToken string
+	// Workload is the kind of workload running the greeting server.
+	Workload string
+	// HostPort exposes the daemonset pods on their node instead of creating a service.
+	HostPort bool
+	// ResourceName is the name of the created resources.
+	ResourceName string
+	// ServiceType is the type of the service exposing the greeting server.
+	ServiceType api.ServiceType
+	// OwnerReferences are set on the created resources, except the namespace.
+	OwnerReferences []meta.OwnerReference
+	// InitContainers run in order before the greeting server starts.
+	InitContainers []InitContainer
+	// SharedVolumePath is where the volume shared with the init containers is mounted.
+	SharedVolumePath string
+	// Sidecars run next to the greeting server in every pod.
+	Sidecars []Sidecar
+	// ConfigMapMounts are the ConfigMaps mounted into the greeting server.
+	ConfigMapMounts []ConfigMapMount
+	// TopologySpreads spread the greeting server pods across topology domains.
+	TopologySpreads []TopologySpread
+	// ContainerCommand replaces the entrypoint of the greeting server image, kept when empty.
+	ContainerCommand []string
+	// ContainerArgs are passed to the command of the greeting server container, the image ones being kept when empty.
+	ContainerArgs []string
+	// NamespaceQuota is the hard limits of the ResourceQuota of the namespace, none when empty.
+	NamespaceQuota api.ResourceList
+	// DefaultContainerLimits are given by the LimitRange of the namespace to the containers without limits,
+	// none when empty.
+	DefaultContainerLimits api.ResourceList
+	// ManageNamespacePolicies manages the ResourceQuota and the LimitRange of namespaces not created by the
+	// operator too.
+	ManageNamespacePolicies bool
+	// Strategy replaces the pods of the deployment on update, all at once with Recreate.
+	Strategy apps.DeploymentStrategyType
+	// AllowRecreate deletes and creates anew the deployments whose immutable selector changes.
+	AllowRecreate bool
+	// ProbeType is the type of the liveness and readiness probes of the greeting server.
+	ProbeType string
+	// ProbeExecCommand is run by the probes of type ProbeExec, without shell.
+	ProbeExecCommand []string
+	// ReadinessPath is the path of the readiness probe of the greeting server, no probe when empty.
+	ReadinessPath string
+	// MinReadySeconds a new pod must be ready before being considered available.
+	MinReadySeconds int32
+	// ProgressDeadlineSeconds after which a stalled rollout is failed, 0 keeps the Kubernetes default.
+	ProgressDeadlineSeconds int32
+	// ShutdownDelay the greeting server keeps serving once terminated, failing its readiness probe while the
+	// endpoints are updated, before draining its requests.
+	ShutdownDelay time.Duration
+	// TerminationGracePeriodSeconds given to the pods to shut down, 0 covering the shutdown delay and the drain.
+	TerminationGracePeriodSeconds int64
+	// LegacyUpdate creates and updates resources instead of using server-side apply.
+	LegacyUpdate bool
+	// ServerDryRun sends every write as a server-side dry-run, nothing is persisted.
+	ServerDryRun bool
+	// Adopt takes over the existing resources not created by the operator.
+	Adopt bool
+	// Wait for the greeting server to be rolled out and reachable.
+	Wait bool
+	// WaitTimeout is the maximum duration of the wait.
+	WaitTimeout time.Duration
+	// Expose tells how the greeting service is exposed outside of the cluster, besides its type.
+	Expose string
+	// RouteHost is the host of the route, generated by the router when empty.
+	RouteHost string
+	// RouteTLSEdge terminates TLS at the router.
+	RouteTLSEdge bool
+	// ServiceMonitor creates a Prometheus Operator ServiceMonitor scraping the greeting server.
+	ServiceMonitor bool
+	// MetricsPath is the path of the greeting server metrics.
+	MetricsPath string
+	// ScrapeInterval is the interval between two scrapes.
+	ScrapeInterval time.Duration
+	// RollbackOnFailure rolls the deployment back to its previous revision when the rollout fails.
+	RollbackOnFailure bool
+	// CrashLoopRestarts of a crash looping pod after which the rollout is failed, with RollbackOnFailure.
+	CrashLoopRestarts int32
+	// WaitForIP waits for the load balancer service address, even without Wait.
+	WaitForIP bool
+	// Output is the format of what is printed once installed: the greeting server URLs as text, or the
+	// Summary as json or yaml.
+	Output string
+	// Verify checks that the greeting server answers as expected once installed.
+	Verify bool
+	// VerifyTimeout is the maximum duration of the verification.
+	VerifyTimeout time.Duration
+	// Watch keeps the operator running to restore the resources when they drift or disappear.
+	Watch bool
+	// ResyncPeriod is the interval of the full reconciliation in watch mode.
+	ResyncPeriod time.Duration
+	// MaxConcurrentReconciles is the number of keys reconciled at once in watch mode.
+	MaxConcurrentReconciles int
+	// CleanupOnInterrupt deletes the resources created by the run when it is interrupted before completion.
+	CleanupOnInterrupt bool
+	// Prune deletes the managed resources of the namespace that are not desired anymore.
+	Prune bool
+	// PruneDryRun prints the resources that would be pruned instead of deleting them.
+	PruneDryRun bool
+	// Replace deletes the resources of the other instances of the namespace once the new ones are healthy.
+	Replace bool
+	// RetryMaxAttempts is the maximum number of calls to the API when they fail with a transient error.
+	RetryMaxAttempts int
+	// RetryMaxDuration is the maximum duration spent retrying a call to the API, 0 for no limit.
+	RetryMaxDuration time.Duration
+	// RequestTimeout is the maximum duration of a single API call, 0 for no limit.
+	RequestTimeout time.Duration
+	// Deadline is the maximum duration of the creation of the resources in standalone mode, 0 for no limit.
+	Deadline time.Duration
+	// Connection tells how to reach the API server.
+	Connection ClusterConnection
+	// Instances are the greeting servers to deploy in standalone mode, each overriding some of the fields above.
+	Instances []Instance
+	// PeerInstances are the other instances deployed in the same namespace, never pruned.
+	PeerInstances []string
+
+	// dynamicClient is the dynamic client set by WithDynamicClient, used by NewWithClient.
+	dynamicClient dynamic.Interface
+}
+
+// apiCallPolicy returns the timeout and the retries of the API calls.
+func (c *Config) apiCallPolicy() apiPolicy {
+	return apiPolicy{
+		maxAttempts: c.RetryMaxAttempts,
+		maxDuration: c.RetryMaxDuration,
+		timeout:     c.RequestTimeout,
+		namespace:   c.Namespace,
+	}
+}
+
+// DefaultResourceName is the name of the resources created in standalone mode.
+const DefaultResourceName = "greeting"
+
+// defaultProgressDeadlineSeconds is the progress deadline applied by Kubernetes when none is set.
+const defaultProgressDeadlineSeconds = 600
+
+// greetingShutdownTimeout is the time the greeting server gives to its in-flight requests on termination,
+// the default of its --shutdown-timeout flag.
+const greetingShutdownTimeout = 20 * time.Second
+
+// terminationGraceMargin is added to the shutdown of the greeting server in the default grace period
+// of its pods, for the process to exit.
+const terminationGraceMargin = 5 * time.Second
+
+const (
+	// WorkloadDeployment runs the greeting server as a Deployment of N replicas.
+	WorkloadDeployment = "deployment"
+	// WorkloadDaemonSet runs one greeting server pod per node.
+	WorkloadDaemonSet = "daemonset"
+)
+
+// Operator exposes a greeting server on kubernetes.
+type Operator struct {
+	image     string
+	port      int
+	grpcPort  int
+	namespace string
+	replicas  uint
+	name      string
+	workload  string
+	hostPort  bool
+	client    kubernetes.Interface
+
+	dynamicClient dynamic.Interface
+
+	namespaceCreation bool
+	manageReplicas    bool
+	greetingTemplate  string
+	showHost          bool
+	authToken         string
+	nameSource        *NameSource
+
+	imagePullSecret string
+	resolveDigest   string
+	// resolvedImage is the image pinned to its digest, empty until resolved.
+	resolvedImage string
+
+	resourceName    string
+	serviceType     api.ServiceType
+	ownerReferences []meta.OwnerReference
+
+	initContainerSpecs []InitContainer
+	sharedVolumePath   string
+	sidecars           []Sidecar
+	configMapMounts    []ConfigMapMount
+	configMapChecksum  string
+	topologySpreads    []TopologySpread
+
+	containerCommand        []string
+	containerArgs           []string
+	namespaceQuota          api.ResourceList
+	defaultContainerLimits  api.ResourceList
+	manageNamespacePolicies bool
+	strategy                apps.DeploymentStrategyType
+	allowRecreate           bool
+	probeType               string
+	probeExecCommand        []string
+	readinessPath           string
+	minReadySeconds         int32
+	progressDeadlineSeconds int32
+	shutdownDelay           time.Duration
+	terminationGracePeriod  int64
+	legacyUpdate            bool
+	serverDryRun            bool
+	adopt                   bool
+	wait                    bool
+	waitTimeout             time.Duration
+	expose                  string
+	routeHost               string
+	routeTLSEdge            bool
+	serviceMonitor          bool
+	metricsPath             string
+	scrapeInterval          time.Duration
+	rollbackOnFailure       bool
+	crashLoopRestarts       int32
+	waitForIP               bool
+	output                  string
+	verify                  bool
+	verifyTimeout           time.Duration
+	watch                   bool
+	resyncPeriod            time.Duration
+	maxConcurrentReconciles int
+	cleanupOnInterrupt      bool
+	prune                   bool
+	pruneDryRun             bool
+	replace                 bool
+	api                     apiPolicy
+	deadline                time.Duration
+	peerInstances           []string
+
+	// created are the resources created by the current run, deleted when interrupted.
+	created []createdResource
+	// actions are what the run did to the resources, by kind, for the summary.
+	actionsMu sync.Mutex
+	actions   map[string]Action
+
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	// eventObject receives the events instead of the managed resources when set.
+	eventObject runtime.Object
+}
+
+// NewForConfig creates an Operator linked to the cluster of the configured connection.
+func NewForConfig(config *Config) (*Operator, error) {
+	op, err := NewOffline(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := NewClusterConfig(config.Connection)
+	if err != nil {
+		return nil, err
+	}
+	// The client gives up on its own along the context of the call.
+	cfg.Timeout = config.RequestTimeout
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new k8s client: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new k8s dynamic client: %w", err)
+	}
+
+	op.setClients(client, dynamicClient)
+	return op, nil
+}
+
+// NewWithClient creates an Operator using the given client, such as a fake clientset, instead of
+// connecting to the cluster itself. Without a dynamic client, set with WithDynamicClient, the routes
+// and the service monitors are not supported.
+func NewWithClient(client kubernetes.Interface, config *Config) (*Operator, error) {
+	op, err := NewOffline(config)
+	if err != nil {
+		return nil, err
+	}
+
+	op.setClients(client, config.dynamicClient)
+	return op, nil
+}
+
+// errNoDynamicClient is returned when writing an unstructured resource without a dynamic client.
+var errNoDynamicClient = errors.New("no dynamic client, see NewWithClient")
+
+// setClients sets the clients of the operator and starts sending its events.
+func (o *Operator) setClients(client kubernetes.Interface, dynamicClient dynamic.Interface) {
+	o.client = client
+	o.dynamicClient = dynamicClient
+	o.broadcaster, o.recorder = newEventBroadcaster(client)
+}
+
+// Close stops sending the recorded events.
+func (o *Operator) Close() {
+	if o.broadcaster != nil {
+		o.broadcaster.Shutdown()
+	}
+}
+
+// NewOffline creates an Operator from a valid configuration, without any client.
+// It can only render the resources, e.g. for a dry-run.
+func NewOffline(config *Config) (*Operator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	op := Operator{
+		image:     config.Image,
+		port:      config.Port,
+		grpcPort:  config.GRPCPort,
+		namespace: config.Namespace,
+		replicas:  config.Replicas,
+		name:      config.Name,
+		workload:  config.Workload,
+		hostPort:  config.HostPort,
+
+		namespaceCreation: config.CreateNamespace,
+		manageReplicas:    config.ManageReplicas,
+		nameSource:        config.NameFrom,
+		greetingTemplate:  config.GreetingTemplate,
+		showHost:          config.ShowHost,
+		authToken:         config.AuthToken,
+
+		imagePullSecret: config.ImagePullSecret,
+		resolveDigest:   config.ResolveDigest,
+
+		resourceName:    config.ResourceName,
+		serviceType:     config.ServiceType,
+		ownerReferences: config.OwnerReferences,
+
+		initContainerSpecs: config.InitContainers,
+		sharedVolumePath:   config.SharedVolumePath,
+		sidecars:           config.Sidecars,
+		configMapMounts:    config.ConfigMapMounts,
+		topologySpreads:    config.TopologySpreads,
+
+		containerCommand:        config.ContainerCommand,
+		containerArgs:           config.ContainerArgs,
+		namespaceQuota:          config.NamespaceQuota,
+		defaultContainerLimits:  config.DefaultContainerLimits,
+		manageNamespacePolicies: config.ManageNamespacePolicies,
+		strategy:                config.Strategy,
+		allowRecreate:           config.AllowRecreate,
+		probeType:               config.ProbeType,
+		probeExecCommand:        config.ProbeExecCommand,
+		readinessPath:           config.ReadinessPath,
+		minReadySeconds:         config.MinReadySeconds,
+		progressDeadlineSeconds: config.ProgressDeadlineSeconds,
+		shutdownDelay:           config.ShutdownDelay,
+		terminationGracePeriod:  config.TerminationGracePeriodSeconds,
+		legacyUpdate:            config.LegacyUpdate,
+		serverDryRun:            config.ServerDryRun,
+		adopt:                   config.Adopt,
+		wait:                    config.Wait,
+		waitTimeout:             config.WaitTimeout,
+		expose:                  config.Expose,
+		routeHost:               config.RouteHost,
+		routeTLSEdge:            config.RouteTLSEdge,
+		serviceMonitor:          config.ServiceMonitor,
+		metricsPath:             config.MetricsPath,
+		scrapeInterval:          config.ScrapeInterval,
+		rollbackOnFailure:       config.RollbackOnFailure,
+		crashLoopRestarts:       config.CrashLoopRestarts,
+		waitForIP:               config.WaitForIP,
+		output:                  config.Output,
+		verify:                  config.Verify,
+		verifyTimeout:           config.VerifyTimeout,
+		watch:                   config.Watch,
+		resyncPeriod:            config.ResyncPeriod,
+		maxConcurrentReconciles: config.MaxConcurrentReconciles,
+		cleanupOnInterrupt:      config.CleanupOnInterrupt,
+		prune:                   config.Prune,
+		pruneDryRun:             config.PruneDryRun,
+		replace:                 config.Replace,
+		api:                     config.apiCallPolicy(),
+		deadline:                config.Deadline,
+		peerInstances:           config.PeerInstances,
+	}
+
+	return &op, nil
+}
+
+// Start creates the k8s resources exposing a greeting server.
+// When the context is cancelled before they are all in place, an InterruptedError is returned.
+func (o *Operator) Start(ctx context.Context) error {
+	if err := o.install(ctx); err != nil {
+		if ctx.Err() != nil {
+			return o.interrupted(err)
+		}
+		return err
+	}
+
+	switch {
+	case o.output != OutputText:
+		if err := o.printSummary(ctx, os.Stdout); err != nil {
+			return fmt.Errorf("print summary: %w", err)
+		}
+	case !o.hostPort && !o.serverDryRun:
+		if err := o.printEndpoints(ctx, os.Stdout); err != nil {
+			return fmt.Errorf("print endpoints: %w", err)
+		}
+	}
+
+	if o.watch {
+		return o.Watch(ctx)
+	}
+
+	return nil
+}
+
+// install creates the namespace and the resources of the greeting server, waiting for them when requested.
+// The whole installation is bounded by the deadline, if any.
+func (o *Operator) install(ctx context.Context) error {
+	installCtx := ctx
+	if o.deadline > 0 {
+		var cancel context.CancelFunc
+		installCtx, cancel = context.WithTimeout(ctx, o.deadline)
+		defer cancel()
+	}
+
+	phase, err := o.installPhases(installCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(installCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("deadline of %s exceeded while %s: %w", o.deadline, phase, err)
+	}
+
+	return err
+}
+
+// installPhases runs the phases of the installation, returning the name of the last one started.
+func (o *Operator) installPhases(ctx context.Context) (string, error) {
+	if !o.namespaceCreation {
+		if err := o.checkNamespace(ctx); err != nil {
+			return "checking the namespace", err
+		}
+	} else if err := o.trackCreation(ctx, o.namespaceResource(), o.createNamespace); err != nil {
+		return "creating the namespace", err
+	}
+
+	// The namespaced resources can only be dry-run in an existing namespace.
+	if o.serverDryRun {
+		if exists, err := o.namespaceExists(ctx); err == nil && !exists {
+			log.WithField("namespace", o.namespace).Warning("Namespace does not exist, skipping the server dry-run of its resources")
+			return "", nil
+		}
+	}
+
+	err := o.reconcile(ctx)
+	observeReconcile(o.namespace, err)
+	if err != nil {
+		return "reconciling the resources", err
+	}
+
+	if o.prune || o.pruneDryRun {
+		if err := o.pruneOrphans(ctx, o.pruneDryRun, o.humanOutput()); err != nil {
+			return "pruning", fmt.Errorf("prune: %w", err)
+		}
+	}
+
+	if o.wait {
+		if err := o.waitForRollout(ctx); err != nil {
+			if o.rollbackOnFailure && o.workload == WorkloadDeployment && errors.Is(err, errRolloutFailed) {
+				return "rolling back", o.rollbackDeployment(ctx, err)
+			}
+			return "waiting for the rollout", err
+		}
+	}
+
+	if o.waitForIP && !o.wait && !o.hostPort && o.serviceType == api.ServiceTypeLoadBalancer {
+		if err := o.waitForAddress(ctx); err != nil {
+			return "waiting for the load balancer address", err
+		}
+	}
+
+	if o.verify {
+		if err := o.verifyServer(ctx); err != nil {
+			return "verifying the greeting server", err
+		}
+	}
+
+	if err := o.replaceStale(ctx); err != nil {
+		return "replacing the previous instances", fmt.Errorf("replace: %w", err)
+	}
+
+	return "", nil
+}
+
+// reconcile creates or updates the resources of the greeting server in its namespace.
+func (o *Operator) reconcile(ctx context.Context) error {
+	if err := o.checkConfigMaps(ctx); err != nil {
+		return err
+	}
+
+	if err := o.loadName(ctx); err != nil {
+		return err
+	}
+
+	if err := o.resolveImage(ctx); err != nil {
+		return err
+	}
+
+	// The limit range only defaults the limits of the pods created after it.
+	if err := o.createNamespacePolicies(ctx); err != nil {
+		return err
+	}
+
+	// The pods read the token from the Secret, which must exist before them.
+	if err := o.createAuthSecret(ctx); err != nil {
+		return err
+	}
+
+	switch o.workload {
+	case WorkloadDaemonSet:
+		if err := o.deleteDeployment(ctx); err != nil {
+			return err
+		}
+
+		if err := o.trackCreation(ctx, o.daemonSetResource(), o.createDaemonSet); err != nil {
+			return err
+		}
+	default:
+		if err := o.deleteDaemonSet(ctx); err != nil {
+			return err
+		}
+
+		if err := o.trackCreation(ctx, o.deploymentResource(), o.createDeployment); err != nil {
+			return err
+		}
+	}
+
+	if o.hostPort {
+		o.logger("service").Info("Host port enabled, skipping service")
+	} else if err := o.trackCreation(ctx, o.serviceResource(), o.createService); err != nil {
+		return err
+	}
+
+	if o.serviceMonitor && !o.hostPort {
+		if err := o.createServiceMonitor(ctx); err != nil {
+			return err
+		}
+	}
+
+	if o.expose == ExposeRoute {
+		if err := o.createRoute(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// desiredNamespace returns the namespace holding the greeting resources.
+func (o *Operator) desiredNamespace() *api.Namespace {
+	return &api.Namespace{
+		TypeMeta: meta.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:   o.namespace,
+			Labels: map[string]string{managedByLabel: managedByValue},
+		},
+	}
+}
+
+func (o *Operator) createNamespace(ctx context.Context) error {
+	logger := log.WithField("namespace", o.namespace)
+
+	// Looking the namespace up first spares the creation, which may be forbidden, when it already exists.
+	exists, getErr := o.namespaceExists(ctx)
+	if getErr != nil && !kerror.IsForbidden(getErr) {
+		return fmt.Errorf("get namespace: %w", getErr)
+	}
+	if exists {
+		logger.Info("Namespace already exists")
+		return nil
+	}
+
+	logger.Info("Creating namespace")
+
+	err := o.api.call(ctx, "namespace", "create", func(ctx context.Context) error {
+		created, err := o.client.CoreV1().Namespaces().Create(ctx, o.desiredNamespace(), o.createOptions())
+		if err == nil {
+			o.logDryRun("namespace", created)
+		}
+		return err
+	})
+	switch {
+	case kerror.IsAlreadyExists(err):
+		logger.Info("Namespace already exists")
+		return nil
+	case getErr != nil && kerror.IsForbidden(err):
+		logger.WithError(err).Warning("Not allowed to look the namespace up nor to create it, assuming it exists")
+		return nil
+	case err != nil:
+		return fmt.Errorf("create namespace: %w", err)
+	}
+
+	logger.Info("Namespace created")
+	o.recordAction("namespace", ActionCreated)
+
+	return nil
+}
+
+// checkNamespace ensures the namespace exists when the operator is not allowed to create it.
+// A namespace that cannot be looked up is assumed to exist.
+func (o *Operator) checkNamespace(ctx context.Context) error {
+	exists, err := o.namespaceExists(ctx)
+	if err != nil {
+		if kerror.IsForbidden(err) {
+			log.WithField("namespace", o.namespace).WithError(err).Warning("Not allowed to look the namespace up, assuming it exists")
+			return nil
+		}
+		return fmt.Errorf("get namespace: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("namespace %s not found, create it or pass --create-namespace", o.namespace)
+	}
+
+	return nil
+}
+
+// namespaceExists tells whether the namespace of the greeting server exists.
+func (o *Operator) namespaceExists(ctx context.Context) (bool, error) {
+	err := o.api.call(ctx, "namespace", "get", func(ctx context.Context) error {
+		_, err := o.client.CoreV1().Namespaces().Get(ctx, o.namespace, meta.GetOptions{})
+		return err
+	})
+	if kerror.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,180 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// writeModes are the two ways the operator writes the resources: server-side apply, and the create then update
+// of --legacy-update.
+var writeModes = []struct {
+	name string
+	opts []Option
+}{
+	{"apply", nil},
+	{"legacy update", []Option{WithLegacyUpdate(true)}},
+}
+
+func getResources(t *testing.T, op *Operator) (*api.Namespace, *apps.Deployment, *api.Service) {
+	t.Helper()
+	ctx := context.Background()
+	namespace, err := op.client.CoreV1().Namespaces().Get(ctx, "default", meta.GetOptions{})
+	if err != nil {
+		t.Fatalf("get namespace: %v", err)
+	}
+	deployment, err := op.client.AppsV1().Deployments("default").Get(ctx, "greeting", meta.GetOptions{})
+	if err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	service, err := op.client.CoreV1().Services("default").Get(ctx, "greeting", meta.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	return namespace, deployment, service
+}
+
+func TestInstallCreatesResources(t *testing.T) {
+	for _, mode := range writeModes {
+		t.Run(mode.name, func(t *testing.T) {
+			op := newTestOperator(t, newFakeClient(), append(mode.opts, WithImage("greeting:1"), WithReplicas(2))...)
+			if err := op.install(context.Background()); err != nil {
+				t.Fatalf("install: %v", err)
+			}
+
+			namespace, deployment, service := getResources(t, op)
+			if namespace.Labels[managedByLabel] != managedByValue {
+				t.Errorf("namespace labels = %v, want %s=%s", namespace.Labels, managedByLabel, managedByValue)
+			}
+
+			if deployment.Labels[managedByLabel] != managedByValue || deployment.Labels[instanceLabel] != "greeting" {
+				t.Errorf("deployment labels = %v, want the managed instance labels", deployment.Labels)
+			}
+			if replicas := deployment.Spec.Replicas; replicas == nil || *replicas != 2 {
+				t.Errorf("deployment replicas = %v, want 2", replicas)
+			}
+			if image := deploymentImage(deployment); image != "greeting:1" {
+				t.Errorf("deployment image = %q, want greeting:1", image)
+			}
+
+			if service.Spec.Type != api.ServiceTypeLoadBalancer {
+				t.Errorf("service type = %s, want %s", service.Spec.Type, api.ServiceTypeLoadBalancer)
+			}
+			if len(service.Spec.Ports) != 1 || service.Spec.Ports[0].TargetPort != intstr.FromString("http") {
+				t.Errorf("service ports = %v, want the http port", service.Spec.Ports)
+			}
+			for key, value := range deployment.Spec.Template.Labels {
+				if selected, ok := service.Spec.Selector[key]; ok && selected != value {
+					t.Errorf("service selects %s=%s, the pods are labelled %s", key, selected, value)
+				}
+			}
+
+			for _, kind := range []string{"namespace", "deployment", "service"} {
+				if action := op.actions[kind]; action != ActionCreated {
+					t.Errorf("%s action = %q, want %q", kind, action, ActionCreated)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallUpdatesResources(t *testing.T) {
+	for _, mode := range writeModes {
+		t.Run(mode.name, func(t *testing.T) {
+			client := newFakeClient()
+			if err := newTestOperator(t, client, append(mode.opts, WithImage("greeting:1"))...).install(context.Background()); err != nil {
+				t.Fatalf("first install: %v", err)
+			}
+			// The cluster allocates the address of the service.
+			live, err := client.CoreV1().Services("default").Get(context.Background(), "greeting", meta.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			live.Spec.ClusterIP, live.Spec.ClusterIPs = "10.0.0.10", []string{"10.0.0.10"}
+			if _, err = client.CoreV1().Services("default").Update(context.Background(), live, meta.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			op := newTestOperator(t, client, append(mode.opts, WithImage("greeting:2"), WithPort(9090))...)
+			if err = op.install(context.Background()); err != nil {
+				t.Fatalf("install: %v", err)
+			}
+
+			_, deployment, service := getResources(t, op)
+			if image := deploymentImage(deployment); image != "greeting:2" {
+				t.Errorf("deployment image = %q, want greeting:2", image)
+			}
+			if len(service.Spec.Ports) != 1 || service.Spec.Ports[0].Port != 9090 {
+				t.Errorf("service ports = %v, want the port 9090", service.Spec.Ports)
+			}
+			if service.Spec.ClusterIP != "10.0.0.10" {
+				t.Errorf("service cluster IP = %q, want the allocated 10.0.0.10", service.Spec.ClusterIP)
+			}
+
+			want := map[string]Action{"namespace": "", "deployment": ActionUpdated, "service": ActionUpdated}
+			for kind, action := range want {
+				if op.actions[kind] != action {
+					t.Errorf("%s action = %q, want %q", kind, op.actions[kind], action)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallErrors(t *testing.T) {
+	forbidden := func(resource string) ktesting.ReactionFunc {
+		return func(ktesting.Action) (bool, runtime.Object, error) {
+			return true, nil, kerror.NewForbidden(api.Resource(resource), "greeting", errors.New("denied"))
+		}
+	}
+
+	tests := []struct {
+		name           string
+		verb, resource string
+		legacy         bool
+		// existing installs the resources first, so that they are updated.
+		existing bool
+		want     string
+	}{
+		{name: "namespace create", verb: "create", resource: "namespaces", want: "create namespace"},
+		{name: "deployment get", verb: "get", resource: "deployments", want: "get deployment"},
+		{name: "deployment apply", verb: "patch", resource: "deployments", want: "apply deployment"},
+		{name: "deployment create", verb: "create", resource: "deployments", legacy: true, want: "create deployment"},
+		{name: "service get", verb: "get", resource: "services", want: "get service"},
+		{name: "service apply", verb: "patch", resource: "services", want: "apply service"},
+		{name: "service create", verb: "create", resource: "services", legacy: true, want: "create service"},
+		{name: "deployment update", verb: "update", resource: "deployments", legacy: true, existing: true, want: "update deployment"},
+		{name: "service update", verb: "update", resource: "services", legacy: true, existing: true, want: "update service"},
+		{name: "deployment reapply", verb: "patch", resource: "deployments", existing: true, want: "apply deployment"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var armed atomic.Bool
+			client := newFakeClient()
+			client.PrependReactor(test.verb, test.resource, armedReactor(&armed, forbidden(test.resource)))
+			if test.existing {
+				if err := newTestOperator(t, client, WithLegacyUpdate(test.legacy)).install(context.Background()); err != nil {
+					t.Fatalf("first install: %v", err)
+				}
+			}
+			armed.Store(true)
+			op := newTestOperator(t, client, WithLegacyUpdate(test.legacy), WithImage("greeting:2"), WithPort(9090))
+
+			err := op.install(context.Background())
+			if err == nil || !strings.Contains(err.Error(), test.want) || !kerror.IsForbidden(err) {
+				t.Errorf("install error = %v, want a forbidden %q error", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,711 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Option sets a part of the configuration of the operator, rejecting invalid values.
+type Option func(*Config) error
+
+// DefaultConfig returns the configuration used when no option is given, the defaults of the command line flags.
+func DefaultConfig() *Config {
+	return &Config{
+		Image:                   "greeting:latest",
+		Port:                    80,
+		Namespace:               api.NamespaceDefault,
+		CreateNamespace:         true,
+		Replicas:                1,
+		MaxReplicas:             100,
+		Name:                    "anonymous",
+		ShowHost:                true,
+		Workload:                WorkloadDeployment,
+		ResourceName:            DefaultResourceName,
+		ServiceType:             api.ServiceTypeLoadBalancer,
+		SharedVolumePath:        "/cache",
+		Strategy:                apps.RollingUpdateDeploymentStrategyType,
+		ProbeType:               ProbeHTTP,
+		ReadinessPath:           "/ready",
+		ShutdownDelay:           5 * time.Second,
+		WaitTimeout:             5 * time.Minute,
+		Expose:                  ExposeNone,
+		MetricsPath:             "/metrics",
+		ScrapeInterval:          30 * time.Second,
+		CrashLoopRestarts:       3,
+		ResolveDigest:           ResolveDigestOff,
+		Output:                  OutputText,
+		VerifyTimeout:           time.Minute,
+		ResyncPeriod:            10 * time.Minute,
+		MaxConcurrentReconciles: 1,
+		RetryMaxAttempts:        5,
+		RetryMaxDuration:        time.Minute,
+	}
+}
+
+// NewConfig returns the default configuration modified by the options, in order.
+// The invalid options are all reported at once.
+func NewConfig(opts ...Option) (*Config, error) {
+	config := DefaultConfig()
+
+	var errs []error
+	for _, opt := range opts {
+		errs = append(errs, opt(config))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// New creates an Operator using the given client, configured by the options on top of DefaultConfig.
+func New(client kubernetes.Interface, opts ...Option) (*Operator, error) {
+	config, err := NewConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithClient(client, config)
+}
+
+// WithImage sets the image of the greeting server.
+func WithImage(image string) Option {
+	return func(c *Config) error {
+		if err := validateImage(image); err != nil {
+			return err
+		}
+		c.Image = image
+		return nil
+	}
+}
+
+// WithImagePullSecret sets the docker config secret pulling the image, also used to resolve its digest.
+func WithImagePullSecret(secret string) Option {
+	return func(c *Config) error {
+		if err := validateImagePullSecret(secret); err != nil {
+			return err
+		}
+		c.ImagePullSecret = secret
+		return nil
+	}
+}
+
+// WithResolveDigest pins the image to the digest of its tag at deploy time, in one of the ResolveDigest modes.
+func WithResolveDigest(mode string) Option {
+	return func(c *Config) error {
+		if err := validateResolveDigest(mode); err != nil {
+			return err
+		}
+		c.ResolveDigest = mode
+		return nil
+	}
+}
+
+// WithPort sets the port on which the greeting server is reachable.
+func WithPort(port int) Option {
+	return func(c *Config) error {
+		if err := validatePort(port); err != nil {
+			return err
+		}
+		c.Port = port
+		return nil
+	}
+}
+
+// WithGRPCPort sets the port of the gRPC services of the greeting server, 0 disabling them.
+func WithGRPCPort(port int) Option {
+	return func(c *Config) error {
+		if port == 0 {
+			c.GRPCPort = 0
+			return nil
+		}
+		if err := validatePort(port); err != nil {
+			return fmt.Errorf("gRPC %w", err)
+		}
+		if port == greetingHTTPPort {
+			return fmt.Errorf("gRPC port %d is the HTTP port of the greeting server", port)
+		}
+		c.GRPCPort = port
+		return nil
+	}
+}
+
+// WithNamespace sets the namespace of the resources.
+func WithNamespace(namespace string) Option {
+	return func(c *Config) error {
+		if err := validateDNSLabel("namespace", namespace); err != nil {
+			return err
+		}
+		c.Namespace = namespace
+		c.Namespaces = nil
+		return nil
+	}
+}
+
+// WithNamespaces deploys the greeting server to each of the namespaces, the first one when a single resource is handled.
+func WithNamespaces(namespaces ...string) Option {
+	return func(c *Config) error {
+		if len(namespaces) == 0 {
+			return errors.New("at least one namespace is required")
+		}
+
+		var errs []error
+		seen := make(map[string]bool, len(namespaces))
+		for _, namespace := range namespaces {
+			if err := validateDNSLabel("namespace", namespace); err != nil {
+				errs = append(errs, err)
+			} else if seen[namespace] {
+				errs = append(errs, fmt.Errorf("namespace %q is listed twice", namespace))
+			}
+			seen[namespace] = true
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+
+		c.Namespace = namespaces[0]
+		c.Namespaces = nil
+		if len(namespaces) > 1 {
+			c.Namespaces = namespaces
+		}
+		return nil
+	}
+}
+
+// WithNamespaceSelector restricts the Greeting resources reconciled by the controller to the namespaces matching the label selector.
+func WithNamespaceSelector(selector string) Option {
+	return func(c *Config) error {
+		if _, err := labels.Parse(selector); err != nil {
+			return fmt.Errorf("invalid namespace selector %q: %w", selector, err)
+		}
+		c.NamespaceSelector = selector
+		return nil
+	}
+}
+
+// WithCreateNamespace tells whether the namespace is created when missing, otherwise it must exist.
+func WithCreateNamespace(create bool) Option {
+	return func(c *Config) error {
+		c.CreateNamespace = create
+		return nil
+	}
+}
+
+// WithReplicas sets the number of replicas of the deployment. The replicas are then managed by the
+// operator, overwriting the live ones. They are ignored by the daemonset workload.
+func WithReplicas(replicas uint) Option {
+	return func(c *Config) error {
+		c.Replicas = replicas
+		c.ManageReplicas = true
+		return nil
+	}
+}
+
+// WithMaxReplicas sets the maximum number of replicas, 0 for no maximum.
+func WithMaxReplicas(maxReplicas uint) Option {
+	return func(c *Config) error {
+		c.MaxReplicas = maxReplicas
+		return nil
+	}
+}
+
+// WithName sets the name greeted by the greeting server.
+func WithName(name string) Option {
+	return func(c *Config) error {
+		c.Name = name
+		return nil
+	}
+}
+
+// WithGreetingTemplate sets the text/template of the greeting message, checked before being deployed.
+func WithGreetingTemplate(greetingTemplate string) Option {
+	return func(c *Config) error {
+		if _, err := template.New("greeting").Parse(greetingTemplate); err != nil {
+			return fmt.Errorf("greeting template: %w", err)
+		}
+		c.GreetingTemplate = greetingTemplate
+		return nil
+	}
+}
+
+// WithShowHost makes the greeting server tell its pod name and version in the greeting message.
+func WithShowHost(showHost bool) Option {
+	return func(c *Config) error {
+		c.ShowHost = showHost
+		return nil
+	}
+}
+
+// WithAuthToken sets the bearer token required by the greeting server on /greet.
+func WithAuthToken(token string) Option {
+	return func(c *Config) error {
+		if strings.TrimSpace(token) != token {
+			return errors.New("auth token must not start or end with spaces")
+		}
+		c.AuthToken = token
+		return nil
+	}
+}
+
+// WithNameFrom reads the name greeted by the greeting server from a ConfigMap key, followed in watch mode.
+func WithNameFrom(source NameSource) Option {
+	return func(c *Config) error {
+		if err := source.validate(); err != nil {
+			return fmt.Errorf("name source: %w", err)
+		}
+		c.NameFrom = &source
+		return nil
+	}
+}
+
+// WithWorkload sets the kind of workload running the greeting server.
+func WithWorkload(workload string) Option {
+	return func(c *Config) error {
+		if err := validateWorkload(workload); err != nil {
+			return err
+		}
+		c.Workload = workload
+		return nil
+	}
+}
+
+// WithHostPort exposes the daemonset pods on their node instead of creating a service.
+func WithHostPort(hostPort bool) Option {
+	return func(c *Config) error {
+		c.HostPort = hostPort
+		return nil
+	}
+}
+
+// WithResourceName sets the name of the created resources.
+func WithResourceName(name string) Option {
+	return func(c *Config) error {
+		if err := validateDNSLabel("name", name); err != nil {
+			return err
+		}
+		c.ResourceName = name
+		return nil
+	}
+}
+
+// WithServiceType sets the type of the service exposing the greeting server.
+func WithServiceType(serviceType api.ServiceType) Option {
+	return func(c *Config) error {
+		if err := validateServiceType(serviceType); err != nil {
+			return err
+		}
+		c.ServiceType = serviceType
+		return nil
+	}
+}
+
+// WithOwnerReferences sets the owners of the created resources, except the namespace.
+func WithOwnerReferences(refs ...meta.OwnerReference) Option {
+	return func(c *Config) error {
+		c.OwnerReferences = refs
+		return nil
+	}
+}
+
+// WithInitContainers sets the containers run in order before the greeting server starts.
+func WithInitContainers(initContainers ...InitContainer) Option {
+	return func(c *Config) error {
+		c.InitContainers = initContainers
+		return nil
+	}
+}
+
+// WithSharedVolumePath sets where the volume shared with the init containers is mounted.
+func WithSharedVolumePath(path string) Option {
+	return func(c *Config) error {
+		c.SharedVolumePath = path
+		return nil
+	}
+}
+
+// WithSidecars sets the containers running next to the greeting server.
+func WithSidecars(sidecars ...Sidecar) Option {
+	return func(c *Config) error {
+		c.Sidecars = sidecars
+		return nil
+	}
+}
+
+// WithConfigMapMounts sets the ConfigMaps mounted into the greeting server.
+func WithConfigMapMounts(mounts ...ConfigMapMount) Option {
+	return func(c *Config) error {
+		c.ConfigMapMounts = mounts
+		return nil
+	}
+}
+
+// WithTopologySpreads sets how the greeting server pods are spread across topology domains.
+func WithTopologySpreads(spreads ...TopologySpread) Option {
+	return func(c *Config) error {
+		c.TopologySpreads = spreads
+		return nil
+	}
+}
+
+// WithContainerCommand replaces the entrypoint of the greeting server image, such as a wrapper one, by the
+// command given as is, without shell. No command keeps the entrypoint of the image.
+func WithContainerCommand(command ...string) Option {
+	return func(c *Config) error {
+		if len(command) > 0 && command[0] == "" {
+			return errors.New("container command: empty executable")
+		}
+		c.ContainerCommand = command
+		return nil
+	}
+}
+
+// WithContainerArgs sets the arguments of the greeting server container, each given as is, without shell.
+// No arguments keep the ones of the image.
+func WithContainerArgs(args ...string) Option {
+	return func(c *Config) error {
+		c.ContainerArgs = args
+		return nil
+	}
+}
+
+// WithNamespacePolicies sets the hard limits of the ResourceQuota of the namespace, such as the ones returned
+// by NamespaceQuota, and the default limits the LimitRange of the namespace gives to the containers without
+// limits. They are only managed in the namespaces created by the operator, unless managed anyway.
+func WithNamespacePolicies(quota, defaultContainerLimits api.ResourceList, manageAnyway bool) Option {
+	return func(c *Config) error {
+		c.NamespaceQuota = quota
+		c.DefaultContainerLimits = defaultContainerLimits
+		c.ManageNamespacePolicies = manageAnyway
+		return nil
+	}
+}
+
+// WithStrategy sets how the pods of the deployment are replaced on update: RollingUpdate, the default, runs
+// the old and the new versions side by side while rolling out, Recreate deletes the old pods first.
+func WithStrategy(strategy apps.DeploymentStrategyType) Option {
+	return func(c *Config) error {
+		if strategy != apps.RollingUpdateDeploymentStrategyType && strategy != apps.RecreateDeploymentStrategyType {
+			return fmt.Errorf("unknown deployment strategy %q, expected %s or %s", strategy, apps.RollingUpdateDeploymentStrategyType, apps.RecreateDeploymentStrategyType)
+		}
+		c.Strategy = strategy
+		return nil
+	}
+}
+
+// WithAllowRecreate deletes the deployment, pods included, and creates it anew when its immutable selector
+// changes, rather than failing.
+func WithAllowRecreate(allow bool) Option {
+	return func(c *Config) error {
+		c.AllowRecreate = allow
+		return nil
+	}
+}
+
+// WithProbe sets the type of the liveness and readiness probes of the greeting server, and the command the
+// exec probes run, given as is, without shell.
+func WithProbe(probeType string, execCommand ...string) Option {
+	return func(c *Config) error {
+		if err := validateProbeType(probeType); err != nil {
+			return err
+		}
+		c.ProbeType = probeType
+		c.ProbeExecCommand = execCommand
+		return nil
+	}
+}
+
+// WithReadinessPath sets the path of the readiness probe of the greeting server, an empty path disables the probe.
+func WithReadinessPath(path string) Option {
+	return func(c *Config) error {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("readiness path %q must start with /", path)
+		}
+		c.ReadinessPath = path
+		return nil
+	}
+}
+
+// WithMinReadySeconds sets how long a new pod must be ready before being considered available.
+func WithMinReadySeconds(seconds int32) Option {
+	return func(c *Config) error {
+		if seconds < 0 {
+			return errors.New("min ready seconds must be positive")
+		}
+		c.MinReadySeconds = seconds
+		return nil
+	}
+}
+
+// WithProgressDeadlineSeconds sets the deadline after which a stalled rollout is failed,
+// 0 keeps the Kubernetes default.
+func WithProgressDeadlineSeconds(seconds int32) Option {
+	return func(c *Config) error {
+		if seconds < 0 {
+			return errors.New("progress deadline seconds must be positive")
+		}
+		c.ProgressDeadlineSeconds = seconds
+		return nil
+	}
+}
+
+// WithShutdownDelay sets the time the greeting server keeps serving once terminated, before draining.
+func WithShutdownDelay(delay time.Duration) Option {
+	return func(c *Config) error {
+		if delay < 0 {
+			return errors.New("shutdown delay must be positive")
+		}
+		c.ShutdownDelay = delay
+		return nil
+	}
+}
+
+// WithTerminationGracePeriodSeconds sets the time given to the pods to shut down, 0 covering the shutdown
+// delay and the drain of the greeting server.
+func WithTerminationGracePeriodSeconds(seconds int64) Option {
+	return func(c *Config) error {
+		if seconds < 0 {
+			return errors.New("termination grace period seconds must be positive")
+		}
+		c.TerminationGracePeriodSeconds = seconds
+		return nil
+	}
+}
+
+// WithLegacyUpdate creates and updates the resources instead of using server-side apply.
+func WithLegacyUpdate(legacy bool) Option {
+	return func(c *Config) error {
+		c.LegacyUpdate = legacy
+		return nil
+	}
+}
+
+// WithServerDryRun sends every write as a server-side dry-run.
+func WithServerDryRun(dryRun bool) Option {
+	return func(c *Config) error {
+		c.ServerDryRun = dryRun
+		return nil
+	}
+}
+
+// WithAdopt takes over the existing resources not created by the operator.
+func WithAdopt(adopt bool) Option {
+	return func(c *Config) error {
+		c.Adopt = adopt
+		return nil
+	}
+}
+
+// WithWait waits for the rollout before returning.
+func WithWait(wait bool) Option {
+	return func(c *Config) error {
+		c.Wait = wait
+		return nil
+	}
+}
+
+// WithWaitTimeout bounds the wait for the rollout and for the load balancer address.
+func WithWaitTimeout(timeout time.Duration) Option {
+	return func(c *Config) error {
+		if timeout <= 0 {
+			return errors.New("wait timeout must be positive")
+		}
+		c.WaitTimeout = timeout
+		return nil
+	}
+}
+
+// WithExpose sets how the greeting server is exposed besides its service.
+func WithExpose(expose string) Option {
+	return func(c *Config) error {
+		if expose != ExposeNone && expose != ExposeRoute {
+			return fmt.Errorf("unknown expose mode %q", expose)
+		}
+		c.Expose = expose
+		return nil
+	}
+}
+
+// WithRoute sets the host of the route, generated by the router when empty, and whether TLS is
+// terminated at the router. The route is only created when exposed with ExposeRoute.
+func WithRoute(host string, tlsEdge bool) Option {
+	return func(c *Config) error {
+		if msgs := validation.IsDNS1123Subdomain(host); host != "" && len(msgs) > 0 {
+			return fmt.Errorf("invalid route host %q: %s", host, strings.Join(msgs, ", "))
+		}
+		c.RouteHost = host
+		c.RouteTLSEdge = tlsEdge
+		return nil
+	}
+}
+
+// WithServiceMonitor creates a Prometheus ServiceMonitor scraping the path at the interval.
+func WithServiceMonitor(enabled bool, path string, interval time.Duration) Option {
+	return func(c *Config) error {
+		if enabled && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("metrics path %q must start with /", path)
+		}
+		if enabled && interval <= 0 {
+			return errors.New("scrape interval must be positive")
+		}
+		c.ServiceMonitor = enabled
+		c.MetricsPath = path
+		c.ScrapeInterval = interval
+		return nil
+	}
+}
+
+// WithRollbackOnFailure rolls the deployment back when its rollout fails, or when a pod restarts
+// at least crashLoopRestarts times.
+func WithRollbackOnFailure(rollback bool, crashLoopRestarts int32) Option {
+	return func(c *Config) error {
+		if rollback && crashLoopRestarts < 1 {
+			return fmt.Errorf("crash loop restarts (%d) must be at least 1", crashLoopRestarts)
+		}
+		c.RollbackOnFailure = rollback
+		c.CrashLoopRestarts = crashLoopRestarts
+		return nil
+	}
+}
+
+// WithWaitForIP waits for the address of the load balancer.
+func WithWaitForIP(waitForIP bool) Option {
+	return func(c *Config) error {
+		c.WaitForIP = waitForIP
+		return nil
+	}
+}
+
+// WithOutput sets the format of what is printed once installed: text, json or yaml.
+func WithOutput(output string) Option {
+	return func(c *Config) error {
+		if output != OutputText && output != OutputJSON && output != OutputYAML {
+			return fmt.Errorf("unknown output format %q", output)
+		}
+		c.Output = output
+		return nil
+	}
+}
+
+// WithVerify checks that the greeting server answers, for at most the timeout.
+func WithVerify(verify bool, timeout time.Duration) Option {
+	return func(c *Config) error {
+		if verify && timeout <= 0 {
+			return errors.New("verify timeout must be positive")
+		}
+		c.Verify = verify
+		c.VerifyTimeout = timeout
+		return nil
+	}
+}
+
+// WithWatch keeps reconciling the resources, fully every resync period.
+func WithWatch(watch bool, resyncPeriod time.Duration) Option {
+	return func(c *Config) error {
+		if resyncPeriod < 0 {
+			return errors.New("resync period must be positive")
+		}
+		c.Watch = watch
+		c.ResyncPeriod = resyncPeriod
+		return nil
+	}
+}
+
+// WithMaxConcurrentReconciles sets the number of keys reconciled at once in watch mode.
+func WithMaxConcurrentReconciles(workers int) Option {
+	return func(c *Config) error {
+		if workers < 1 {
+			return errors.New("max concurrent reconciles must be at least 1")
+		}
+		c.MaxConcurrentReconciles = workers
+		return nil
+	}
+}
+
+// WithCleanupOnInterrupt deletes the resources created by an interrupted run.
+func WithCleanupOnInterrupt(cleanup bool) Option {
+	return func(c *Config) error {
+		c.CleanupOnInterrupt = cleanup
+		return nil
+	}
+}
+
+// WithPrune deletes the managed resources which are not desired anymore, or only lists them with dryRun.
+func WithPrune(prune, dryRun bool) Option {
+	return func(c *Config) error {
+		c.Prune = prune
+		c.PruneDryRun = dryRun
+		return nil
+	}
+}
+
+// WithReplace deletes the resources of the other instances of the namespace once the new ones are healthy,
+// otherwise they are only reported.
+func WithReplace(replace bool) Option {
+	return func(c *Config) error {
+		c.Replace = replace
+		return nil
+	}
+}
+
+// WithRetry sets the attempts and the total duration of the retried API calls.
+func WithRetry(maxAttempts int, maxDuration time.Duration) Option {
+	return func(c *Config) error {
+		if maxAttempts < 1 {
+			return fmt.Errorf("retry max attempts (%d) must be at least 1", maxAttempts)
+		}
+		c.RetryMaxAttempts = maxAttempts
+		c.RetryMaxDuration = maxDuration
+		return nil
+	}
+}
+
+// WithRequestTimeout bounds every API call, 0 for no timeout.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *Config) error {
+		c.RequestTimeout = timeout
+		return nil
+	}
+}
+
+// WithDeadline bounds the whole installation, 0 for no deadline.
+func WithDeadline(deadline time.Duration) Option {
+	return func(c *Config) error {
+		c.Deadline = deadline
+		return nil
+	}
+}
+
+// WithConnection sets how NewForConfig reaches the API server.
+func WithConnection(conn ClusterConnection) Option {
+	return func(c *Config) error {
+		c.Connection = conn
+		return nil
+	}
+}
+
+// WithInstances sets the greeting servers to deploy in standalone mode.
+func WithInstances(instances ...Instance) Option {
+	return func(c *Config) error {
+		c.Instances = instances
+		return nil
+	}
+}
+
+// WithDynamicClient sets the client of the routes and the service monitors, used by NewWithClient.
+func WithDynamicClient(client dynamic.Interface) Option {
+	return func(c *Config) error {
+		c.dynamicClient = client
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SetPaused pauses or resumes the rollouts of the managed deployments of the instance.
+// Nothing is deleted nor scaled, a paused deployment keeps serving its current pods.
+func (o *Operator) SetPaused(ctx context.Context, paused bool) error {
+	deployments, err := o.managedDeployments(ctx)
+	if err != nil {
+		return err
+	}
+
+	reason, action := reasonResumed, "Resumed"
+	if paused {
+		reason, action = reasonPaused, "Paused"
+	}
+
+	for i := range deployments {
+		deployment := &deployments[i]
+		logger := o.logger("deployment").WithField("name", deployment.Name)
+
+		if deployment.Spec.Paused == paused {
+			logger.WithField("paused", paused).Info("Deployment already in the requested state")
+			continue
+		}
+
+		deployment, err = o.patchDeployment(ctx, deployment.Name, []byte(fmt.Sprintf(`{"spec":{"paused":%t}}`, paused)))
+		if err != nil {
+			return err
+		}
+
+		logger.Info(action + " deployment rollouts")
+		o.event(deployment, api.EventTypeNormal, reason, fmt.Sprintf("%s rollouts of deployment %s", action, deployment.Name))
+	}
+
+	return nil
+}
+
+// managedDeployments lists the deployments of the instance, found by their labels.
+func (o *Operator) managedDeployments(ctx context.Context) ([]apps.Deployment, error) {
+	var deployments *apps.DeploymentList
+	err := o.api.call(ctx, "deployment", "list", func(ctx context.Context) error {
+		var err error
+		deployments, err = o.client.AppsV1().Deployments(o.namespace).List(ctx, meta.ListOptions{LabelSelector: o.instanceSelector()})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list deployments: %w", err)
+	}
+
+	if len(deployments.Items) == 0 {
+		return nil, fmt.Errorf("no managed deployment found in namespace %s", o.namespace)
+	}
+	return deployments.Items, nil
+}
+
+// patchDeployment applies a merge patch to the named deployment, and returns the patched deployment.
+func (o *Operator) patchDeployment(ctx context.Context, name string, patch []byte) (*apps.Deployment, error) {
+	var deployment *apps.Deployment
+	err := o.api.call(ctx, "deployment", "patch", func(ctx context.Context) error {
+		var err error
+		deployment, err = o.client.AppsV1().Deployments(o.namespace).Patch(ctx, name, types.MergePatchType, patch, o.patchOptions())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("patch deployment %q: %w", name, err)
+	}
+	return deployment, nil
+}
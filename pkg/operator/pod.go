@@ -0,0 +1,162 @@
+package operator
+
+import (
+	"math"
+	"strconv"
+
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// greetingHTTPPort is the port the greeting server listens to for HTTP in its container.
+const greetingHTTPPort = 80
+
+// podTemplate returns the pod template shared by every kind of workload.
+func (o *Operator) podTemplate() api.PodTemplateSpec {
+	var volumes []api.Volume
+	if len(o.initContainerSpecs) > 0 {
+		volumes = append(volumes, api.Volume{
+			Name:         sharedVolumeName,
+			VolumeSource: api.VolumeSource{EmptyDir: &api.EmptyDirVolumeSource{}},
+		})
+	}
+
+	configMapVolumes, _ := o.configMapVolumes()
+	volumes = append(volumes, configMapVolumes...)
+
+	annotations := map[string]string{}
+	if o.configMapChecksum != "" {
+		annotations[configMapChecksumAnnotation] = o.configMapChecksum
+	}
+	if o.resolvedImage != "" {
+		annotations[imageAnnotation] = o.image
+	}
+	if checksum := o.authChecksum(); checksum != "" {
+		annotations[authChecksumAnnotation] = checksum
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	gracePeriod := o.terminationGracePeriodSeconds()
+	var imagePullSecrets []api.LocalObjectReference
+	if o.imagePullSecret != "" {
+		imagePullSecrets = []api.LocalObjectReference{{Name: o.imagePullSecret}}
+	}
+
+	return api.PodTemplateSpec{
+		ObjectMeta: meta.ObjectMeta{
+			Name:        o.resourceName,
+			Labels:      o.selectorLabels(),
+			Annotations: annotations,
+		},
+		Spec: api.PodSpec{
+			InitContainers: o.initContainers(),
+			Containers:     append([]api.Container{o.greetingContainer()}, o.sidecarContainers()...),
+			Volumes:        volumes,
+			RestartPolicy:  api.RestartPolicyAlways,
+
+			TerminationGracePeriodSeconds: &gracePeriod,
+
+			ImagePullSecrets: imagePullSecrets,
+
+			TopologySpreadConstraints: o.topologySpreadConstraints(),
+		},
+	}
+}
+
+// terminationGracePeriodSeconds returns the grace period of the pods, by default the shutdown delay and the
+// drain of the greeting server with a margin: 30 seconds, the Kubernetes default, for the default delay.
+// The greeting server delays its shutdown itself, so that the pods need no preStop hook.
+func (o *Operator) terminationGracePeriodSeconds() int64 {
+	if o.terminationGracePeriod > 0 {
+		return o.terminationGracePeriod
+	}
+	grace := o.shutdownDelay + greetingShutdownTimeout + terminationGraceMargin
+	return int64(math.Ceil(grace.Seconds()))
+}
+
+// selectorLabels returns the labels selecting the greeting server pods.
+func (o *Operator) selectorLabels() map[string]string {
+	return map[string]string{"app": o.resourceName}
+}
+
+// greetingContainer returns the container running the greeting server.
+func (o *Operator) greetingContainer() api.Container {
+	port := api.ContainerPort{
+		Name:          "http",
+		Protocol:      api.ProtocolTCP,
+		ContainerPort: greetingHTTPPort,
+	}
+	ports := []api.ContainerPort{port}
+	if o.grpcPort != 0 {
+		ports = append(ports, api.ContainerPort{
+			Name:          "grpc",
+			Protocol:      api.ProtocolTCP,
+			ContainerPort: int32(o.grpcPort),
+		})
+	}
+
+	if o.hostPort {
+		for i := range ports {
+			ports[i].HostPort = ports[i].ContainerPort
+		}
+	}
+
+	var volumeMounts []api.VolumeMount
+	if len(o.initContainerSpecs) > 0 {
+		volumeMounts = append(volumeMounts, api.VolumeMount{
+			Name:      sharedVolumeName,
+			MountPath: o.sharedVolumePath,
+		})
+	}
+
+	_, configMapMounts := o.configMapVolumes()
+	volumeMounts = append(volumeMounts, configMapMounts...)
+
+	return api.Container{
+		Name:            greetingContainerName,
+		Image:           o.containerImage(),
+		Command:         o.containerCommand,
+		Args:            o.containerArgs,
+		Ports:           ports,
+		VolumeMounts:    volumeMounts,
+		Env:             o.greetingEnv(),
+		LivenessProbe:   o.livenessProbe(),
+		ReadinessProbe:  o.readinessProbe(),
+		ImagePullPolicy: api.PullNever,
+	}
+}
+
+// readinessProbe returns the probe taking the pods out of the service while the greeting server shuts down,
+// of the same type as the liveness probe.
+func (o *Operator) readinessProbe() *api.Probe {
+	if o.readinessPath == "" {
+		return nil
+	}
+	return o.probe(o.readinessPath)
+}
+
+// greetingEnv returns the environment configuring the greeting server.
+func (o *Operator) greetingEnv() []api.EnvVar {
+	env := []api.EnvVar{{
+		Name:  "NAME",
+		Value: o.name,
+	}}
+	if o.greetingTemplate != "" {
+		env = append(env, api.EnvVar{Name: "GREETING_TEMPLATE", Value: o.greetingTemplate})
+	}
+	if o.showHost {
+		env = append(env, api.EnvVar{Name: "SHOW_HOST", Value: "true"})
+	}
+	if o.authToken != "" {
+		env = append(env, o.authTokenEnv())
+	}
+	if o.grpcPort != 0 {
+		env = append(env, api.EnvVar{Name: "GRPC_BIND", Value: ":" + strconv.Itoa(o.grpcPort)})
+	}
+	if o.shutdownDelay > 0 {
+		env = append(env, api.EnvVar{Name: "SHUTDOWN_DELAY", Value: o.shutdownDelay.String()})
+	}
+	return env
+}
@@ -0,0 +1,51 @@
+package operator
+
+import (
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Types of the probes of the greeting server.
+const (
+	// ProbeHTTP gets a path of the HTTP port, /health for the liveness probe.
+	ProbeHTTP = "http"
+	// ProbeTCP opens a connection to the gRPC port when set, to the HTTP port otherwise, for the images
+	// serving no HTTP endpoint.
+	ProbeTCP = "tcp"
+	// ProbeExec runs a command in the container.
+	ProbeExec = "exec"
+	// ProbeNone sets no probe at all.
+	ProbeNone = "none"
+)
+
+// probeTimeoutSeconds is the time given to the probes of the greeting server to succeed.
+const probeTimeoutSeconds = 3
+
+// livenessProbe returns the probe restarting the greeting server once it stops answering.
+func (o *Operator) livenessProbe() *api.Probe {
+	return o.probe("/health")
+}
+
+// probe returns the probe of the configured type, checked by Validate, the path being the one the HTTP
+// probes get, nil with ProbeNone.
+func (o *Operator) probe(path string) *api.Probe {
+	var handler api.ProbeHandler
+	switch o.probeType {
+	case ProbeNone:
+		return nil
+	case ProbeTCP:
+		port := intstr.FromInt(greetingHTTPPort)
+		if o.grpcPort != 0 {
+			port = intstr.FromInt(o.grpcPort)
+		}
+		handler.TCPSocket = &api.TCPSocketAction{Port: port}
+	case ProbeExec:
+		handler.Exec = &api.ExecAction{Command: o.probeExecCommand}
+	case ProbeHTTP:
+		handler.HTTPGet = &api.HTTPGetAction{
+			Path: path,
+			Port: intstr.FromInt(greetingHTTPPort),
+		}
+	}
+	return &api.Probe{ProbeHandler: handler, TimeoutSeconds: probeTimeoutSeconds}
+}
@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		liveness  api.ProbeHandler
+		readiness api.ProbeHandler
+		none      bool
+	}{
+		{
+			name:      "http",
+			liveness:  api.ProbeHandler{HTTPGet: &api.HTTPGetAction{Path: "/health", Port: intstr.FromInt(greetingHTTPPort)}},
+			readiness: api.ProbeHandler{HTTPGet: &api.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(greetingHTTPPort)}},
+		},
+		{
+			name:      "tcp",
+			opts:      []Option{WithProbe(ProbeTCP)},
+			liveness:  api.ProbeHandler{TCPSocket: &api.TCPSocketAction{Port: intstr.FromInt(greetingHTTPPort)}},
+			readiness: api.ProbeHandler{TCPSocket: &api.TCPSocketAction{Port: intstr.FromInt(greetingHTTPPort)}},
+		},
+		{
+			name:      "tcp on the gRPC port",
+			opts:      []Option{WithProbe(ProbeTCP), WithGRPCPort(9000)},
+			liveness:  api.ProbeHandler{TCPSocket: &api.TCPSocketAction{Port: intstr.FromInt(9000)}},
+			readiness: api.ProbeHandler{TCPSocket: &api.TCPSocketAction{Port: intstr.FromInt(9000)}},
+		},
+		{
+			name:      "exec",
+			opts:      []Option{WithProbe(ProbeExec, "cat", "/tmp/healthy")},
+			liveness:  api.ProbeHandler{Exec: &api.ExecAction{Command: []string{"cat", "/tmp/healthy"}}},
+			readiness: api.ProbeHandler{Exec: &api.ExecAction{Command: []string{"cat", "/tmp/healthy"}}},
+		},
+		{
+			name: "none",
+			opts: []Option{WithProbe(ProbeNone)},
+			none: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			container := newTestOperator(t, newFakeClient(), test.opts...).greetingContainer()
+
+			for _, probe := range []struct {
+				kind    string
+				probe   *api.Probe
+				handler api.ProbeHandler
+			}{
+				{"liveness", container.LivenessProbe, test.liveness},
+				{"readiness", container.ReadinessProbe, test.readiness},
+			} {
+				if test.none {
+					if probe.probe != nil {
+						t.Errorf("%s probe = %+v, want none", probe.kind, probe.probe)
+					}
+					continue
+				}
+				if probe.probe == nil {
+					t.Fatalf("%s probe missing", probe.kind)
+				}
+				if !reflect.DeepEqual(probe.probe.ProbeHandler, probe.handler) {
+					t.Errorf("%s probe handler = %+v, want %+v", probe.kind, probe.probe.ProbeHandler, probe.handler)
+				}
+				if probe.probe.TimeoutSeconds != probeTimeoutSeconds {
+					t.Errorf("%s probe timeout = %ds, want %ds", probe.kind, probe.probe.TimeoutSeconds, probeTimeoutSeconds)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeWithoutReadinessPath(t *testing.T) {
+	container := newTestOperator(t, newFakeClient(), WithReadinessPath("")).greetingContainer()
+	if container.ReadinessProbe != nil {
+		t.Errorf("readiness probe = %+v, want none without a readiness path", container.ReadinessProbe)
+	}
+	if container.LivenessProbe == nil {
+		t.Error("liveness probe missing")
+	}
+}
+
+func TestNewRejectsExecProbeWithoutCommand(t *testing.T) {
+	_, err := New(newFakeClient(), WithProbe(ProbeExec))
+	if err == nil || !strings.Contains(err.Error(), "exec probes require a command") {
+		t.Errorf("New() = %v, want the exec probe without command refused", err)
+	}
+}
@@ -0,0 +1,222 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// prunable is a kind of namespaced resource created by the operator, listed when pruning.
+type prunable struct {
+	kind string
+	list func(context.Context, meta.ListOptions) ([]meta.Object, error)
+	del  func(context.Context, string) error
+}
+
+// prunables returns the kinds of resources considered when pruning.
+// Any new kind of namespaced resource must be listed here so that its orphans are pruned.
+func (o *Operator) prunables() []prunable {
+	deployments := o.client.AppsV1().Deployments(o.namespace)
+	daemonSets := o.client.AppsV1().DaemonSets(o.namespace)
+	services := o.client.CoreV1().Services(o.namespace)
+	secrets := o.client.CoreV1().Secrets(o.namespace)
+	quotas := o.client.CoreV1().ResourceQuotas(o.namespace)
+	limitRanges := o.client.CoreV1().LimitRanges(o.namespace)
+
+	return []prunable{
+		{
+			kind: "resourcequota",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				list, err := quotas.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return listObjects(list)
+			},
+			del: func(ctx context.Context, name string) error {
+				return quotas.Delete(ctx, name, o.deleteOptions())
+			},
+		},
+		{
+			kind: "limitrange",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				list, err := limitRanges.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return listObjects(list)
+			},
+			del: func(ctx context.Context, name string) error {
+				return limitRanges.Delete(ctx, name, o.deleteOptions())
+			},
+		},
+		{
+			kind: "secret",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				list, err := secrets.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return listObjects(list)
+			},
+			del: func(ctx context.Context, name string) error {
+				return secrets.Delete(ctx, name, o.deleteOptions())
+			},
+		},
+		{
+			kind: "deployment",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				list, err := deployments.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return listObjects(list)
+			},
+			del: func(ctx context.Context, name string) error {
+				return deployments.Delete(ctx, name, o.deleteOptions())
+			},
+		},
+		{
+			kind: "daemonset",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				list, err := daemonSets.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return listObjects(list)
+			},
+			del: func(ctx context.Context, name string) error {
+				return daemonSets.Delete(ctx, name, o.deleteOptions())
+			},
+		},
+		{
+			kind: "route",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				items, err := o.listRoutes(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return unstructuredObjects(items), nil
+			},
+			del: func(ctx context.Context, name string) error {
+				return o.dynamicClient.Resource(routeResource).Namespace(o.namespace).Delete(ctx, name, o.deleteOptions())
+			},
+		},
+		{
+			kind: "servicemonitor",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				items, err := o.listServiceMonitors(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return unstructuredObjects(items), nil
+			},
+			del: func(ctx context.Context, name string) error {
+				return o.dynamicClient.Resource(serviceMonitorResource).Namespace(o.namespace).Delete(ctx, name, o.deleteOptions())
+			},
+		},
+		{
+			kind: "service",
+			list: func(ctx context.Context, opts meta.ListOptions) ([]meta.Object, error) {
+				list, err := services.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				return listObjects(list)
+			},
+			del: func(ctx context.Context, name string) error {
+				return services.Delete(ctx, name, o.deleteOptions())
+			},
+		},
+	}
+}
+
+// unstructuredObjects returns the unstructured items as objects.
+func unstructuredObjects(items []unstructured.Unstructured) []meta.Object {
+	objects := make([]meta.Object, 0, len(items))
+	for i := range items {
+		objects = append(objects, &items[i])
+	}
+	return objects
+}
+
+// listObjects returns the items of a typed list.
+func listObjects(list runtime.Object) ([]meta.Object, error) {
+	items, err := apimeta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]meta.Object, 0, len(items))
+	for _, item := range items {
+		object, err := apimeta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// pruneOrphans deletes the resources carrying the managed-by label in the namespace that are not desired anymore.
+// Resources owned by a controller, such as the ones of a Greeting resource, the resources of the
+// other instances of the configuration file and the canary deployment are left alone.
+// With dryRun, the resources are written to w instead of being deleted.
+func (o *Operator) pruneOrphans(ctx context.Context, dryRun bool, w io.Writer) error {
+	desired := make(map[string]bool)
+	for _, object := range o.desiredObjects() {
+		accessor, err := apimeta.Accessor(object)
+		if err != nil {
+			return err
+		}
+		desired[strings.ToLower(object.GetObjectKind().GroupVersionKind().Kind)+"/"+accessor.GetName()] = true
+	}
+
+	listOpts := meta.ListOptions{LabelSelector: managedSelector()}
+	for _, p := range o.prunables() {
+		objects, err := p.list(ctx, listOpts)
+		if err != nil {
+			return fmt.Errorf("list %s: %w", p.kind, err)
+		}
+
+		for _, object := range objects {
+			name := object.GetName()
+			if desired[p.kind+"/"+name] || meta.GetControllerOf(object) != nil || o.isPeerInstance(object) || o.isCanary(p.kind, object) {
+				continue
+			}
+
+			if dryRun {
+				fmt.Fprintf(w, "%s %s/%s would be pruned\n", p.kind, o.namespace, name)
+				continue
+			}
+
+			log.WithFields(log.Fields{"namespace": o.namespace, "kind": p.kind, "name": name}).Info("Pruning orphan resource")
+			if err = o.deleteObject(ctx, p.kind, name, func(ctx context.Context) error {
+				return p.del(ctx, name)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isPeerInstance tells whether the object belongs to another instance of the configuration file.
+func (o *Operator) isPeerInstance(object meta.Object) bool {
+	instance := object.GetLabels()[instanceLabel]
+	for _, peer := range o.peerInstances {
+		if instance == peer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package operator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Metrics of the reconcile queues, by queue name.
+var (
+	queueDepth = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "depth",
+		Help:      "Number of keys waiting in the reconcile queue.",
+	}, []string{"name"})
+
+	queueAdds = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "adds_total",
+		Help:      "Number of keys added to the reconcile queue.",
+	}, []string{"name"})
+
+	queueLatency = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "queue_duration_seconds",
+		Help:      "Time a key waits in the reconcile queue before being reconciled.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 10, 6),
+	}, []string{"name"})
+
+	queueWorkDuration = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "work_duration_seconds",
+		Help:      "Duration of the reconciliation of a key.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 10, 6),
+	}, []string{"name"})
+
+	queueUnfinishedWork = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "unfinished_work_seconds",
+		Help:      "Time spent by the reconciliations in progress.",
+	}, []string{"name"})
+
+	queueLongestRunning = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "longest_running_processor_seconds",
+		Help:      "Duration of the longest reconciliation in progress.",
+	}, []string{"name"})
+
+	queueRetries = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "workqueue",
+		Name:      "retries_total",
+		Help:      "Number of keys requeued with backoff after a failed reconciliation.",
+	}, []string{"name"})
+)
+
+func init() {
+	workqueue.SetProvider(queueMetrics{})
+}
+
+// queueMetrics provides the metrics of the named reconcile queues.
+type queueMetrics struct{}
+
+func (queueMetrics) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return queueDepth.WithLabelValues(name)
+}
+
+func (queueMetrics) NewAddsMetric(name string) workqueue.CounterMetric {
+	return queueAdds.WithLabelValues(name)
+}
+
+func (queueMetrics) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return queueLatency.WithLabelValues(name)
+}
+
+func (queueMetrics) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return queueWorkDuration.WithLabelValues(name)
+}
+
+func (queueMetrics) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return queueUnfinishedWork.WithLabelValues(name)
+}
+
+func (queueMetrics) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return queueLongestRunning.WithLabelValues(name)
+}
+
+func (queueMetrics) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return queueRetries.WithLabelValues(name)
+}
+
+// newQueue creates a rate-limited reconcile queue reporting its metrics under the name.
+// The keys are deduplicated, and the failing ones are retried with an exponential backoff.
+func newQueue(name string) workqueue.RateLimitingInterface {
+	return workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name)
+}
+
+// runWorkers reconciles the keys of the queue with the workers until the context is cancelled.
+// The queue is then shut down, and the reconciliations in progress are awaited.
+func runWorkers(ctx context.Context, queue workqueue.RateLimitingInterface, workers int, processNextItem func(context.Context) bool) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for processNextItem(ctx) {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	queue.ShutDownWithDrain()
+	wg.Wait()
+}
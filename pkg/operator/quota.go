@@ -0,0 +1,264 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreac "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+// containerLimitResources are the resources the default container limits may set.
+var containerLimitResources = []api.ResourceName{api.ResourceCPU, api.ResourceMemory, api.ResourceEphemeralStorage}
+
+// NamespaceQuota returns the hard limits of the namespace quota, set on both the requests and the limits of
+// its pods, leaving out the empty quantities.
+func NamespaceQuota(cpu, memory string) (api.ResourceList, error) {
+	quota := api.ResourceList{}
+	var errs []error
+	for _, q := range []struct {
+		name     string
+		value    string
+		requests api.ResourceName
+		limits   api.ResourceName
+	}{
+		{"cpu", cpu, api.ResourceRequestsCPU, api.ResourceLimitsCPU},
+		{"memory", memory, api.ResourceRequestsMemory, api.ResourceLimitsMemory},
+	} {
+		if q.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s quota %q: %w", q.name, q.value, err))
+			continue
+		}
+		quota[q.requests] = quantity
+		quota[q.limits] = quantity
+	}
+	return quota, errors.Join(errs...)
+}
+
+// ParseContainerLimits parses the default container limits formatted as resource=quantity, the resource
+// being cpu, memory or ephemeral-storage.
+func ParseContainerLimits(values []string) (api.ResourceList, error) {
+	limits := api.ResourceList{}
+	for _, value := range values {
+		name, raw, found := strings.Cut(value, "=")
+		if !found || !isContainerLimitResource(api.ResourceName(name)) {
+			return nil, fmt.Errorf("default container limit %q: expected cpu, memory or ephemeral-storage=quantity", value)
+		}
+		quantity, err := resource.ParseQuantity(raw)
+		if err != nil {
+			return nil, fmt.Errorf("default container limit %q: %w", value, err)
+		}
+		limits[api.ResourceName(name)] = quantity
+	}
+	return limits, nil
+}
+
+func isContainerLimitResource(name api.ResourceName) bool {
+	for _, resource := range containerLimitResources {
+		if name == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// quotaName returns the name of the ResourceQuota of the namespace.
+func (o *Operator) quotaName() string {
+	return o.resourceName + "-quota"
+}
+
+// limitRangeName returns the name of the LimitRange of the namespace.
+func (o *Operator) limitRangeName() string {
+	return o.resourceName + "-limits"
+}
+
+// desiredResourceQuota returns the quota bounding the resources of the whole namespace, nil without quota.
+func (o *Operator) desiredResourceQuota() *api.ResourceQuota {
+	if len(o.namespaceQuota) == 0 {
+		return nil
+	}
+	return &api.ResourceQuota{
+		TypeMeta: meta.TypeMeta{APIVersion: "v1", Kind: "ResourceQuota"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      o.quotaName(),
+			Namespace: o.namespace,
+			Labels:    o.objectLabels(),
+
+			OwnerReferences: o.ownerReferences,
+		},
+		Spec: api.ResourceQuotaSpec{Hard: o.namespaceQuota},
+	}
+}
+
+// desiredLimitRange returns the limit range giving the containers without limits the default ones, nil
+// without default limits.
+func (o *Operator) desiredLimitRange() *api.LimitRange {
+	if len(o.defaultContainerLimits) == 0 {
+		return nil
+	}
+	return &api.LimitRange{
+		TypeMeta: meta.TypeMeta{APIVersion: "v1", Kind: "LimitRange"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      o.limitRangeName(),
+			Namespace: o.namespace,
+			Labels:    o.objectLabels(),
+
+			OwnerReferences: o.ownerReferences,
+		},
+		Spec: api.LimitRangeSpec{Limits: []api.LimitRangeItem{{
+			Type:    api.LimitTypeContainer,
+			Default: o.defaultContainerLimits,
+		}}},
+	}
+}
+
+// createNamespacePolicies applies the ResourceQuota and the LimitRange of the namespace with server-side
+// apply, whatever the update mode, before the pods they bound. They are only managed in the namespaces
+// created by the operator, unless told to manage them in any namespace, and deleted once not configured.
+func (o *Operator) createNamespacePolicies(ctx context.Context) error {
+	quota, limitRange := o.desiredResourceQuota(), o.desiredLimitRange()
+	if quota != nil || limitRange != nil {
+		managed, err := o.managesNamespacePolicies(ctx)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			o.logger("namespace").Info("Namespace not created by the operator, leaving its quota and limit range alone, pass --manage-namespace-policies to manage them")
+			return nil
+		}
+	}
+
+	if quota == nil {
+		if err := o.deleteNamespacePolicy(ctx, "resourcequota", o.quotaName(), o.getResourceQuota, o.client.CoreV1().ResourceQuotas(o.namespace).Delete); err != nil {
+			return err
+		}
+	} else if err := o.applyResourceQuota(ctx, quota); err != nil {
+		return err
+	}
+
+	if limitRange == nil {
+		return o.deleteNamespacePolicy(ctx, "limitrange", o.limitRangeName(), o.getLimitRange, o.client.CoreV1().LimitRanges(o.namespace).Delete)
+	}
+	return o.applyLimitRange(ctx, limitRange)
+}
+
+// managesNamespacePolicies tells whether the quota and the limit range of the namespace are managed: the
+// namespace carries the label of the ones created by the operator, or they are managed in any namespace.
+func (o *Operator) managesNamespacePolicies(ctx context.Context) (bool, error) {
+	if o.manageNamespacePolicies {
+		return true, nil
+	}
+
+	var namespace *api.Namespace
+	err := o.api.call(ctx, "namespace", "get", func(ctx context.Context) error {
+		var err error
+		namespace, err = o.client.CoreV1().Namespaces().Get(ctx, o.namespace, meta.GetOptions{})
+		return err
+	})
+	switch {
+	case kerror.IsForbidden(err):
+		o.logger("namespace").WithError(err).Warning("Not allowed to look the namespace up, assuming it was not created by the operator")
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("get namespace: %w", err)
+	}
+	return namespace.Labels[managedByLabel] == managedByValue, nil
+}
+
+func (o *Operator) applyResourceQuota(ctx context.Context, desired *api.ResourceQuota) error {
+	quota := coreac.ResourceQuota(desired.Name, desired.Namespace).
+		WithLabels(desired.Labels).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(desired.OwnerReferences)...).
+		WithSpec(coreac.ResourceQuotaSpec().WithHard(desired.Spec.Hard))
+
+	err := o.api.call(ctx, "resourcequota", "apply", func(ctx context.Context) error {
+		applied, err := o.client.CoreV1().ResourceQuotas(o.namespace).Apply(ctx, quota, o.applyOptions())
+		if err == nil {
+			o.logDryRun("resourcequota", applied)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply resource quota: %w", err)
+	}
+
+	o.logger("resourcequota").WithField("hard", resourceListSummary(desired.Spec.Hard)).Info("Resource quota applied")
+	o.recordAction("resourcequota", ActionApplied)
+	return nil
+}
+
+func (o *Operator) applyLimitRange(ctx context.Context, desired *api.LimitRange) error {
+	item := desired.Spec.Limits[0]
+	limitRange := coreac.LimitRange(desired.Name, desired.Namespace).
+		WithLabels(desired.Labels).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(desired.OwnerReferences)...).
+		WithSpec(coreac.LimitRangeSpec().WithLimits(coreac.LimitRangeItem().WithType(item.Type).WithDefault(item.Default)))
+
+	err := o.api.call(ctx, "limitrange", "apply", func(ctx context.Context) error {
+		applied, err := o.client.CoreV1().LimitRanges(o.namespace).Apply(ctx, limitRange, o.applyOptions())
+		if err == nil {
+			o.logDryRun("limitrange", applied)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply limit range: %w", err)
+	}
+
+	o.logger("limitrange").WithField("default", resourceListSummary(item.Default)).Info("Limit range applied")
+	o.recordAction("limitrange", ActionApplied)
+	return nil
+}
+
+func (o *Operator) getResourceQuota(ctx context.Context, name string) (meta.Object, error) {
+	return o.client.CoreV1().ResourceQuotas(o.namespace).Get(ctx, name, meta.GetOptions{})
+}
+
+func (o *Operator) getLimitRange(ctx context.Context, name string) (meta.Object, error) {
+	return o.client.CoreV1().LimitRanges(o.namespace).Get(ctx, name, meta.GetOptions{})
+}
+
+// deleteNamespacePolicy deletes the quota or the limit range left by a previous configuration, only when
+// created by the operator for this instance.
+func (o *Operator) deleteNamespacePolicy(ctx context.Context, kind, name string,
+	get func(context.Context, string) (meta.Object, error), del func(context.Context, string, meta.DeleteOptions) error) error {
+	var live meta.Object
+	err := o.api.call(ctx, kind, "get", func(ctx context.Context) error {
+		var err error
+		live, err = get(ctx, name)
+		return err
+	})
+	if kerror.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get %s: %w", kind, err)
+	}
+	if live.GetLabels()[managedByLabel] != managedByValue || live.GetLabels()[instanceLabel] != o.resourceName {
+		return nil
+	}
+
+	return o.deleteObject(ctx, kind, name, func(ctx context.Context) error {
+		return del(ctx, name, o.deleteOptions())
+	})
+}
+
+// resourceListSummary describes the quantities, sorted by resource.
+func resourceListSummary(list api.ResourceList) []string {
+	summary := make([]string, 0, len(list))
+	for name, quantity := range list {
+		summary = append(summary, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	sort.Strings(summary)
+	return summary
+}
@@ -0,0 +1,81 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// errRecreated tells that the deployment was deleted to be created anew.
+var errRecreated = errors.New("deployment recreated")
+
+// checkSelector compares the immutable selector of the live deployment with the desired one, which cannot be
+// written over it. With allow recreate, the live deployment is deleted and errRecreated returned, so that
+// the desired one is created in its place.
+func (o *Operator) checkSelector(ctx context.Context, live, desired *apps.Deployment) error {
+	if equality.Semantic.DeepEqual(live.Spec.Selector, desired.Spec.Selector) {
+		return nil
+	}
+
+	change := fmt.Sprintf("spec.selector is immutable and changes from %s to %s",
+		meta.FormatLabelSelector(live.Spec.Selector), meta.FormatLabelSelector(desired.Spec.Selector))
+	if !o.allowRecreate {
+		return fmt.Errorf("deployment %s/%s cannot be updated: %s, pass --allow-recreate to delete the deployment and its pods and create it anew",
+			live.Namespace, live.Name, change)
+	}
+
+	o.logger("deployment").WithField("change", change).Warning("Recreating deployment, its pods stop serving until the new ones are ready")
+	if err := o.recreateDeployment(ctx, live.Name); err != nil {
+		return err
+	}
+	return errRecreated
+}
+
+// recreateDeployment deletes the deployment in the foreground and waits for it to be gone, which happens once
+// its replicasets and their pods are deleted, within the wait timeout.
+func (o *Operator) recreateDeployment(ctx context.Context, name string) error {
+	propagation := meta.DeletePropagationForeground
+	deleteOpts := o.deleteOptions()
+	deleteOpts.PropagationPolicy = &propagation
+	if err := o.deleteObject(ctx, "deployment", name, func(ctx context.Context) error {
+		return o.client.AppsV1().Deployments(o.namespace).Delete(ctx, name, deleteOpts)
+	}); err != nil {
+		return err
+	}
+	// Nothing is deleted by a server dry-run, there is nothing to wait for.
+	if o.serverDryRun {
+		return nil
+	}
+
+	o.logger("deployment").Info("Waiting for the deployment and its pods to be deleted")
+	waitCtx, cancel := context.WithTimeout(ctx, o.waitTimeout)
+	defer cancel()
+	err := wait.PollImmediateUntilWithContext(waitCtx, waitInterval, func(ctx context.Context) (bool, error) {
+		_, err := o.client.AppsV1().Deployments(o.namespace).Get(ctx, name, meta.GetOptions{})
+		if kerror.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("wait for deployment %q deletion: %w", name, err)
+	}
+	return nil
+}
+
+// clearRollingUpdate drops the rolling update parameters of the live deployment switching to the Recreate
+// strategy, which refuses them. Server-side apply keeps the ones defaulted by the API server otherwise.
+func (o *Operator) clearRollingUpdate(ctx context.Context, live, desired *apps.Deployment) error {
+	if o.legacyUpdate || desired.Spec.Strategy.Type != apps.RecreateDeploymentStrategyType || live.Spec.Strategy.RollingUpdate == nil {
+		return nil
+	}
+	patch := fmt.Sprintf(`{"spec":{"strategy":{"type":%q,"rollingUpdate":null}}}`, apps.RecreateDeploymentStrategyType)
+	_, err := o.patchDeployment(ctx, live.Name, []byte(patch))
+	return err
+}
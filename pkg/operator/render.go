@@ -0,0 +1,78 @@
+package operator
+
+import (
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// desiredObjects returns every resource managed by the operator, in creation order.
+// Any new kind of resource must be listed here so that it is rendered along the others.
+func (o *Operator) desiredObjects() []runtime.Object {
+	var objects []runtime.Object
+	if o.namespaceCreation {
+		objects = append(objects, o.desiredNamespace())
+	}
+
+	if quota := o.desiredResourceQuota(); quota != nil {
+		objects = append(objects, quota)
+	}
+	if limitRange := o.desiredLimitRange(); limitRange != nil {
+		objects = append(objects, limitRange)
+	}
+
+	if o.authToken != "" {
+		objects = append(objects, o.desiredAuthSecret())
+	}
+
+	switch o.workload {
+	case WorkloadDaemonSet:
+		objects = append(objects, o.desiredDaemonSet())
+	default:
+		objects = append(objects, o.desiredDeployment())
+	}
+
+	if !o.hostPort {
+		objects = append(objects, o.desiredService())
+	}
+
+	if o.serviceMonitor && !o.hostPort {
+		objects = append(objects, o.desiredServiceMonitor())
+	}
+
+	if o.expose == ExposeRoute {
+		objects = append(objects, o.desiredRoute())
+	}
+
+	return objects
+}
+
+// Render writes the resources managed by the operator as a multi-documents YAML stream.
+// No request is sent to the cluster, hence the mounted ConfigMaps checksum is not computed.
+func (o *Operator) Render(w io.Writer) error {
+	if len(o.configMapMounts) > 0 {
+		log.Warning("Rendering without the mounted configmaps checksum")
+	}
+
+	for i, object := range o.desiredObjects() {
+		raw, err := yaml.Marshal(object)
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", object.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+
+		if i > 0 {
+			if _, err = io.WriteString(w, "---\n"); err != nil {
+				return fmt.Errorf("write separator: %w", err)
+			}
+		}
+
+		if _, err = w.Write(raw); err != nil {
+			return fmt.Errorf("write %s: %w", object.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+	}
+
+	return nil
+}
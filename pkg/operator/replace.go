@@ -0,0 +1,90 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// staleObject is a managed resource of a previous instance of the greeting server.
+type staleObject struct {
+	prunable
+	name     string
+	instance string
+}
+
+func (s staleObject) String() string {
+	return fmt.Sprintf("%s %s (instance %s)", s.kind, s.name, s.instance)
+}
+
+// staleObjects lists the managed resources of the namespace belonging to another instance, such as the
+// resources left behind by a renamed release. The resources of the other instances of the configuration
+// file and the ones owned by a controller are left alone.
+// They are returned exposure first, so that no traffic is sent to the workloads being deleted.
+func (o *Operator) staleObjects(ctx context.Context) ([]staleObject, error) {
+	prunables := o.prunables()
+
+	var stale []staleObject
+	listOpts := meta.ListOptions{LabelSelector: managedSelector()}
+	for i := len(prunables) - 1; i >= 0; i-- {
+		p := prunables[i]
+		objects, err := p.list(ctx, listOpts)
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", p.kind, err)
+		}
+
+		for _, object := range objects {
+			instance := object.GetLabels()[instanceLabel]
+			if instance == "" || instance == o.resourceName || meta.GetControllerOf(object) != nil || o.isPeerInstance(object) {
+				continue
+			}
+			stale = append(stale, staleObject{prunable: p, name: object.GetName(), instance: instance})
+		}
+	}
+
+	return stale, nil
+}
+
+// replaceStale deletes the resources of the previous instances once the new ones are healthy.
+// Without replace, the stale resources are only reported.
+func (o *Operator) replaceStale(ctx context.Context) error {
+	stale, err := o.staleObjects(ctx)
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	resources := make([]string, 0, len(stale))
+	for _, s := range stale {
+		resources = append(resources, s.String())
+	}
+	logger := log.WithField("namespace", o.namespace).WithField("resources", resources)
+
+	if !o.replace {
+		logger.Warning("Resources of other instances are still serving in the namespace, pass --replace to delete them")
+		return nil
+	}
+
+	// The rollout was already awaited when waiting, and there is nothing to await with a server dry-run.
+	if !o.wait && !o.serverDryRun {
+		if err = o.waitForRollout(ctx); err != nil {
+			return fmt.Errorf("new resources not healthy, keeping the previous ones: %w", err)
+		}
+	}
+
+	logger.Info("Replacing the resources of the previous instances")
+	for _, s := range stale {
+		s := s
+		if err = o.deleteObject(ctx, s.kind, s.name, func(ctx context.Context) error {
+			return s.del(ctx, s.name)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	api "k8s.io/api/core/v1"
+)
+
+// restartedAtAnnotation records on the pod template the time of the last restart, changing it rolls the pods.
+const restartedAtAnnotation = "greeting.moutoum.dev/restartedAt"
+
+// reasonRestarted is the reason of the event recorded on a restarted deployment.
+const reasonRestarted = "Restarted"
+
+// Restart rolls the pods of the managed deployments of the instance, as kubectl rollout restart does.
+// Paused deployments are refused, their pods would only roll once resumed.
+func (o *Operator) Restart(ctx context.Context, wait bool) error {
+	deployments, err := o.managedDeployments(ctx)
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments {
+		if deployment.Spec.Paused {
+			return fmt.Errorf("deployment %s is paused, resume it before restarting", deployment.Name)
+		}
+	}
+
+	restartedAt := time.Now().UTC().Format(time.RFC3339)
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{restartedAtAnnotation: restartedAt},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range deployments {
+		deployment, err := o.patchDeployment(ctx, deployments[i].Name, patch)
+		if err != nil {
+			return err
+		}
+		o.logDryRun("deployment", deployment)
+
+		o.logger("deployment").WithField("name", deployment.Name).WithField("restartedAt", restartedAt).Info("Restarted deployment")
+		o.event(deployment, api.EventTypeNormal, reasonRestarted, fmt.Sprintf("Restarted the pods of deployment %s", deployment.Name))
+	}
+
+	// Nothing rolls out in a server dry-run.
+	if wait && !o.serverDryRun {
+		return o.waitForRollout(ctx)
+	}
+	return nil
+}
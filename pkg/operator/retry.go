@@ -0,0 +1,142 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// retryInitialDelay is the delay before the first retry, doubled on every attempt.
+const retryInitialDelay = 500 * time.Millisecond
+
+// apiPolicy bounds the duration of the API calls and their retries when failing with a transient error.
+type apiPolicy struct {
+	// maxAttempts is the maximum number of calls, the first one included.
+	maxAttempts int
+	// maxDuration is the maximum duration spent retrying.
+	maxDuration time.Duration
+	// timeout is the maximum duration of a single call, 0 for no limit.
+	timeout time.Duration
+	// namespace labels the metrics of the calls.
+	namespace string
+}
+
+// call calls the Kubernetes API through fn, instrumented for the resource kind,
+// and retries with an exponential backoff as long as it fails with a transient error.
+// Every attempt gets its own timeout.
+func (p apiPolicy) call(ctx context.Context, kind, operation string, fn func(context.Context) error) error {
+	backoff := wait.Backoff{
+		Duration: retryInitialDelay,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    p.maxAttempts,
+		Cap:      p.maxDuration,
+	}
+
+	retryCtx := ctx
+	if p.maxDuration > 0 {
+		var cancel context.CancelFunc
+		retryCtx, cancel = context.WithTimeout(ctx, p.maxDuration)
+		defer cancel()
+	}
+
+	var attempt int
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(retryCtx, backoff, func() (bool, error) {
+		attempt++
+		lastErr = instrument(p.namespace, kind, operation, func() error { return p.callOnce(ctx, kind, operation, fn) })
+		if lastErr == nil {
+			return true, nil
+		}
+		if !isTransient(lastErr) || ctx.Err() != nil {
+			return false, lastErr
+		}
+		if attempt >= p.maxAttempts {
+			return false, lastErr
+		}
+
+		logger := log.WithError(lastErr).WithFields(log.Fields{
+			"kind":      kind,
+			"operation": operation,
+			"attempt":   attempt,
+		})
+
+		// The API server tells how long to wait when throttling.
+		if seconds, ok := kerror.SuggestsClientDelay(lastErr); ok && seconds > 0 {
+			logger.WithField("retryAfter", seconds).Warning("Transient API error, retrying")
+			select {
+			case <-retryCtx.Done():
+			case <-time.After(time.Duration(seconds) * time.Second):
+			}
+			return false, nil
+		}
+
+		logger.Warning("Transient API error, retrying")
+		return false, nil
+	})
+	// Running out of attempts or of time reports the error of the last call.
+	if err != nil && lastErr != nil && (errors.Is(err, wait.ErrWaitTimeout) || errors.Is(err, context.DeadlineExceeded)) {
+		return lastErr
+	}
+
+	return err
+}
+
+// callOnce calls fn within the call timeout, telling which call timed out.
+func (p apiPolicy) callOnce(ctx context.Context, kind, operation string, fn func(context.Context) error) error {
+	if p.timeout <= 0 {
+		return fn(ctx)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := fn(callCtx)
+	if err != nil && callCtx.Err() != nil && ctx.Err() == nil {
+		return &callTimeoutError{kind: kind, operation: operation, timeout: p.timeout, err: err}
+	}
+
+	return err
+}
+
+// callTimeoutError is returned when a single API call exceeds the request timeout.
+type callTimeoutError struct {
+	kind      string
+	operation string
+	timeout   time.Duration
+	err       error
+}
+
+func (e *callTimeoutError) Error() string {
+	return fmt.Sprintf("%s %s timed out after %s: %v", e.operation, e.kind, e.timeout, e.err)
+}
+
+func (e *callTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// isTransient tells whether the error is likely to go away when the call is retried.
+// Errors such as Forbidden or Invalid are not.
+func isTransient(err error) bool {
+	var timeoutErr *callTimeoutError
+	switch {
+	case errors.As(err, &timeoutErr):
+		// The API server may only be slow for a moment.
+		return true
+	case kerror.IsServerTimeout(err), kerror.IsTooManyRequests(err), kerror.IsServiceUnavailable(err):
+		return true
+	case utilnet.IsConnectionReset(err), utilnet.IsConnectionRefused(err), utilnet.IsProbableEOF(err):
+		return true
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+
+	return utilnet.IsTimeout(err) && !errors.Is(err, context.DeadlineExceeded)
+}
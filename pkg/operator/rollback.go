@@ -0,0 +1,147 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+// revisionAnnotation is set by the deployment controller on deployments and their replicasets.
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// crashLoopingPod returns the first greeting server pod crash looping past the restart threshold,
+// with its restarts, or an empty name when there is none.
+func (o *Operator) crashLoopingPod(ctx context.Context) (string, int32, error) {
+	selector := labels.SelectorFromSet(o.selectorLabels()).String()
+	pods, err := o.client.CoreV1().Pods(o.namespace).List(ctx, meta.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", 0, fmt.Errorf("list pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, container := range pod.Status.ContainerStatuses {
+			waiting := container.State.Waiting
+			if waiting != nil && waiting.Reason == "CrashLoopBackOff" && container.RestartCount >= o.crashLoopRestarts {
+				return pod.Name, container.RestartCount, nil
+			}
+		}
+	}
+
+	return "", 0, nil
+}
+
+// rollbackDeployment reverts the deployment pod template to the one of the previous revision
+// after a failed rollout, then waits for it to be available. The returned error always wraps the cause.
+func (o *Operator) rollbackDeployment(ctx context.Context, cause error) error {
+	logger := o.logger("deployment")
+	logger.WithError(cause).Warning("Rollout failed, rolling back")
+
+	revision, err := o.revertDeployment(ctx)
+	if err != nil {
+		return fmt.Errorf("%w\nrollback failed: %v", cause, err)
+	}
+
+	message := fmt.Sprintf("Rolled back deployment %s to revision %d", o.resourceName, revision)
+	logger.WithField("revision", revision).Warning(message)
+	o.event(o.rolloutObject(ctx), api.EventTypeWarning, reasonRolledBack, message)
+
+	waitCtx, cancel := context.WithTimeout(ctx, o.waitTimeout)
+	defer cancel()
+
+	if err = o.waitFor(waitCtx, "deployment", o.deploymentRolledOut); err != nil {
+		return fmt.Errorf("rolled back to revision %d, which is not available either: %v: %w", revision, err, cause)
+	}
+
+	return fmt.Errorf("rolled back to revision %d: %w", revision, cause)
+}
+
+// revertDeployment copies the pod template of the previous replicaset into the deployment,
+// returning the revision rolled back to.
+func (o *Operator) revertDeployment(ctx context.Context) (int64, error) {
+	deploymentClient := o.client.AppsV1().Deployments(o.namespace)
+
+	var revision int64
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var current *apps.Deployment
+		err := o.api.call(ctx, "deployment", "get", func(ctx context.Context) error {
+			var err error
+			current, err = deploymentClient.Get(ctx, o.resourceName, meta.GetOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		previous, err := o.previousReplicaSet(ctx, current)
+		if err != nil {
+			return err
+		}
+		revision, _ = strconv.ParseInt(previous.Annotations[revisionAnnotation], 10, 64)
+
+		updated := current.DeepCopy()
+		updated.Spec.Template = *previous.Spec.Template.DeepCopy()
+		delete(updated.Spec.Template.Labels, apps.DefaultDeploymentUniqueLabelKey)
+
+		return o.api.call(ctx, "deployment", "update", func(ctx context.Context) error {
+			_, err := deploymentClient.Update(ctx, updated, o.updateOptions())
+			return err
+		})
+	})
+
+	return revision, err
+}
+
+// previousReplicaSet returns the replicaset of the deployment with the highest revision below the current one.
+func (o *Operator) previousReplicaSet(ctx context.Context, deployment *apps.Deployment) (*apps.ReplicaSet, error) {
+	current, err := strconv.ParseInt(deployment.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("deployment revision %q: %w", deployment.Annotations[revisionAnnotation], err)
+	}
+
+	selector, err := meta.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("deployment selector: %w", err)
+	}
+
+	var replicaSets *apps.ReplicaSetList
+	err = o.api.call(ctx, "replicaset", "list", func(ctx context.Context) error {
+		var err error
+		replicaSets, err = o.client.AppsV1().ReplicaSets(o.namespace).List(ctx, meta.ListOptions{LabelSelector: selector.String()})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list replicasets: %w", err)
+	}
+
+	var previous *apps.ReplicaSet
+	var previousRevision int64
+	for i, replicaSet := range replicaSets.Items {
+		owner := meta.GetControllerOf(&replicaSet)
+		if owner == nil || owner.UID != deployment.UID {
+			continue
+		}
+
+		revision, err := strconv.ParseInt(replicaSet.Annotations[revisionAnnotation], 10, 64)
+		if err != nil || revision >= current {
+			continue
+		}
+		if previous == nil || revision > previousRevision {
+			previous, previousRevision = &replicaSets.Items[i], revision
+		}
+	}
+
+	if previous == nil {
+		return nil, errors.New("no previous revision to roll back to")
+	}
+	return previous, nil
+}
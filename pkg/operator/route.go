@@ -0,0 +1,158 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Ways of exposing the greeting service outside of the cluster, besides its own type.
+const (
+	// ExposeNone only relies on the service type.
+	ExposeNone = "none"
+	// ExposeRoute creates an OpenShift Route.
+	ExposeRoute = "route"
+)
+
+// routeResource identifies the OpenShift Route resource for the dynamic client.
+var routeResource = schema.GroupVersionResource{
+	Group:    "route.openshift.io",
+	Version:  "v1",
+	Resource: "routes",
+}
+
+// RouteStatus is the state of the route exposing the greeting server.
+type RouteStatus struct {
+	// Name of the route.
+	Name string `json:"name"`
+	// Host served by the route, generated by the router when not requested.
+	Host string `json:"host"`
+	// Admitted tells whether a router accepted the route.
+	Admitted bool `json:"admitted"`
+}
+
+// desiredRoute returns the route sending the traffic of its host to the greeting service.
+func (o *Operator) desiredRoute() *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"to": map[string]interface{}{
+			"kind": "Service",
+			"name": o.resourceName,
+		},
+		"port": map[string]interface{}{
+			"targetPort": "http",
+		},
+	}
+	if o.routeHost != "" {
+		spec["host"] = o.routeHost
+	}
+	if o.routeTLSEdge {
+		spec["tls"] = map[string]interface{}{
+			"termination":                   "edge",
+			"insecureEdgeTerminationPolicy": "Redirect",
+		}
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	route.SetAPIVersion(routeResource.GroupVersion().String())
+	route.SetKind("Route")
+	route.SetName(o.resourceName)
+	route.SetNamespace(o.namespace)
+	route.SetLabels(o.objectLabels())
+	route.SetOwnerReferences(o.ownerReferences)
+
+	return route
+}
+
+// createRoute applies the route with server-side apply, failing early on clusters without the Route API.
+func (o *Operator) createRoute(ctx context.Context) error {
+	if o.dynamicClient == nil {
+		return fmt.Errorf("apply route: %w", errNoDynamicClient)
+	}
+	if err := o.checkRouteAPI(); err != nil {
+		return err
+	}
+
+	desired := o.desiredRoute()
+	err := o.api.call(ctx, "route", "apply", func(ctx context.Context) error {
+		applied, err := o.dynamicClient.Resource(routeResource).Namespace(o.namespace).Apply(ctx, desired.GetName(), desired, o.applyOptions())
+		if err == nil {
+			o.logDryRun("route", applied.Object)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply route: %w", err)
+	}
+
+	o.logger("route").Info("Route applied")
+	o.recordAction("route", ActionApplied)
+	return nil
+}
+
+// checkRouteAPI looks the Route API up with the discovery client.
+func (o *Operator) checkRouteAPI() error {
+	_, err := o.client.Discovery().ServerResourcesForGroupVersion(routeResource.GroupVersion().String())
+	if kerror.IsNotFound(err) {
+		return fmt.Errorf("the %s API is not served by this cluster, exposing with a route requires OpenShift", routeResource.GroupVersion())
+	}
+	if err != nil {
+		return fmt.Errorf("discover the route API: %w", err)
+	}
+	return nil
+}
+
+// listRoutes lists the routes of the namespace, none when the Route API is not served or without a dynamic client.
+func (o *Operator) listRoutes(ctx context.Context, opts meta.ListOptions) ([]unstructured.Unstructured, error) {
+	if o.dynamicClient == nil {
+		return nil, nil
+	}
+	list, err := o.dynamicClient.Resource(routeResource).Namespace(o.namespace).List(ctx, opts)
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// routeStatus returns the state of the route, nil when missing.
+func (o *Operator) routeStatus(ctx context.Context) (*RouteStatus, error) {
+	if o.dynamicClient == nil {
+		return nil, nil
+	}
+	route, err := o.dynamicClient.Resource(routeResource).Namespace(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get route: %w", err)
+	}
+
+	status := &RouteStatus{Name: route.GetName()}
+	status.Host, _, _ = unstructured.NestedString(route.Object, "spec", "host")
+
+	ingresses, _, _ := unstructured.NestedSlice(route.Object, "status", "ingress")
+	for _, ingress := range ingresses {
+		ingress, ok := ingress.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if status.Host == "" {
+			status.Host, _, _ = unstructured.NestedString(ingress, "host")
+		}
+		conditions, _, _ := unstructured.NestedSlice(ingress, "conditions")
+		for _, condition := range conditions {
+			condition, ok := condition.(map[string]interface{})
+			if ok && condition["type"] == "Admitted" && condition["status"] == "True" {
+				status.Admitted = true
+			}
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,118 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreac "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+const (
+	// authTokenKey is the key of the auth Secret holding the bearer token.
+	authTokenKey = "token"
+
+	// authChecksumAnnotation holds the checksum of the auth token on the pod template, so that changing
+	// the token rolls the pods reading it from their environment.
+	authChecksumAnnotation = "greeting.moutoum.dev/auth-checksum"
+)
+
+// authSecretName returns the name of the Secret holding the bearer token of the greeting server.
+func (o *Operator) authSecretName() string {
+	return o.resourceName + "-auth"
+}
+
+// authChecksum returns the checksum of the auth token, empty without token.
+func (o *Operator) authChecksum() string {
+	if o.authToken == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(o.authToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// authTokenEnv returns the environment variable passing the bearer token from its Secret.
+func (o *Operator) authTokenEnv() api.EnvVar {
+	return api.EnvVar{
+		Name: "AUTH_TOKEN",
+		ValueFrom: &api.EnvVarSource{
+			SecretKeyRef: &api.SecretKeySelector{
+				LocalObjectReference: api.LocalObjectReference{Name: o.authSecretName()},
+				Key:                  authTokenKey,
+			},
+		},
+	}
+}
+
+// desiredAuthSecret returns the Secret holding the bearer token of the greeting server.
+func (o *Operator) desiredAuthSecret() *api.Secret {
+	return &api.Secret{
+		TypeMeta: meta.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      o.authSecretName(),
+			Namespace: o.namespace,
+			Labels:    o.objectLabels(),
+
+			OwnerReferences: o.ownerReferences,
+		},
+		Type: api.SecretTypeOpaque,
+		Data: map[string][]byte{authTokenKey: []byte(o.authToken)},
+	}
+}
+
+// createAuthSecret applies the auth Secret with server-side apply, whatever the update mode, or deletes it
+// once the token is removed. The Secret is never logged, even in dry-run.
+func (o *Operator) createAuthSecret(ctx context.Context) error {
+	if o.authToken == "" {
+		return o.deleteAuthSecret(ctx)
+	}
+
+	desired := o.desiredAuthSecret()
+	secret := coreac.Secret(desired.Name, desired.Namespace).
+		WithLabels(desired.Labels).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(desired.OwnerReferences)...).
+		WithType(desired.Type).
+		WithData(desired.Data)
+
+	err := o.api.call(ctx, "secret", "apply", func(ctx context.Context) error {
+		_, err := o.client.CoreV1().Secrets(o.namespace).Apply(ctx, secret, o.applyOptions())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply auth secret: %w", err)
+	}
+
+	o.logger("secret").WithField("name", desired.Name).Info("Auth secret applied")
+	o.recordAction("secret", ActionApplied)
+	return nil
+}
+
+// deleteAuthSecret deletes the auth Secret left by a previous token, only when created by the operator
+// for this instance.
+func (o *Operator) deleteAuthSecret(ctx context.Context) error {
+	secretClient := o.client.CoreV1().Secrets(o.namespace)
+
+	var live *api.Secret
+	err := o.api.call(ctx, "secret", "get", func(ctx context.Context) error {
+		var err error
+		live, err = secretClient.Get(ctx, o.authSecretName(), meta.GetOptions{})
+		return err
+	})
+	if kerror.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get auth secret: %w", err)
+	}
+	if live.Labels[managedByLabel] != managedByValue || live.Labels[instanceLabel] != o.resourceName {
+		return nil
+	}
+
+	return o.deleteObject(ctx, "secret", live.Name, func(ctx context.Context) error {
+		return secretClient.Delete(ctx, live.Name, o.deleteOptions())
+	})
+}
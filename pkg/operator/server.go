@@ -0,0 +1,31 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// serverShutdownTimeout bounds the time given to the in-flight requests when a server stops.
+const serverShutdownTimeout = 5 * time.Second
+
+// serveUntilDone runs serve, which starts the server, and gracefully shuts the server down once the context is cancelled.
+func serveUntilDone(ctx context.Context, server *http.Server, serve func() error) error {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.WithError(err).WithField("addr", server.Addr).Warning("Unable to shut down server")
+		}
+	}()
+
+	if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
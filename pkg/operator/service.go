@@ -0,0 +1,165 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	coreac "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+// desiredService returns the service exposing the greeting server, and its gRPC services when enabled.
+func (o *Operator) desiredService() *api.Service {
+	ports := []api.ServicePort{{
+		Name:       "http",
+		Protocol:   api.ProtocolTCP,
+		Port:       int32(o.port),
+		TargetPort: intstr.FromString("http"),
+	}}
+	if o.grpcPort != 0 {
+		ports = append(ports, api.ServicePort{
+			Name:       "grpc",
+			Protocol:   api.ProtocolTCP,
+			Port:       int32(o.grpcPort),
+			TargetPort: intstr.FromString("grpc"),
+		})
+	}
+
+	return &api.Service{
+		TypeMeta: meta.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      o.resourceName,
+			Namespace: o.namespace,
+			Labels:    o.objectLabels(),
+
+			OwnerReferences: o.ownerReferences,
+		},
+		Spec: api.ServiceSpec{
+			Selector: o.selectorLabels(),
+			Type:     o.serviceType,
+			Ports:    ports,
+		},
+	}
+}
+
+// createService creates or updates the service, recording an event when it changed.
+// An up to date service is left untouched.
+func (o *Operator) createService(ctx context.Context) error {
+	desired := o.desiredService()
+
+	live, diff, err := o.liveService(ctx, desired)
+	if err != nil {
+		return err
+	}
+	exists := live != nil
+
+	if exists {
+		if err = o.checkManaged("service", live); err != nil {
+			return err
+		}
+		if len(diff) == 0 {
+			o.logger("service").Info("Service up to date")
+			return nil
+		}
+		o.logger("service").WithField("diff", diff).Info("Service changed")
+	}
+
+	written, err := o.writeService(ctx, desired)
+
+	target := written
+	if target == nil && exists {
+		target = live
+	}
+	if target == nil {
+		target = desired
+	}
+	o.recordWrite(target, "service", exists, diff, err)
+
+	return err
+}
+
+// liveService returns the live service, or nil when missing, with its differences from the desired one.
+func (o *Operator) liveService(ctx context.Context, desired *api.Service) (*api.Service, []string, error) {
+	var live *api.Service
+	err := o.api.call(ctx, "service", "get", func(ctx context.Context) error {
+		var err error
+		live, err = o.client.CoreV1().Services(o.namespace).Get(ctx, desired.Name, meta.GetOptions{})
+		return err
+	})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("get service: %w", err)
+	}
+
+	return live, serviceDiff(live, desired), nil
+}
+
+// writeService creates or updates the service, returning it when known.
+func (o *Operator) writeService(ctx context.Context, service *api.Service) (*api.Service, error) {
+	if !o.legacyUpdate {
+		o.logger("service").Info("Applying service")
+		applied, err := o.applyService(ctx, service)
+		if err != nil {
+			return nil, fmt.Errorf("apply service: %w", err)
+		}
+
+		o.logger("service").Info("Service applied")
+		o.logDryRun("service", applied)
+		return applied, nil
+	}
+
+	serviceClient := o.client.CoreV1().Services(o.namespace)
+
+	var alreadyExists bool
+	var created *api.Service
+	err := o.api.call(ctx, "service", "create", func(ctx context.Context) error {
+		var err error
+		created, err = serviceClient.Create(ctx, service, o.createOptions())
+		return err
+	})
+	if err != nil {
+		if !kerror.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("create service: %w", err)
+		} else {
+			alreadyExists = true
+		}
+	}
+
+	if alreadyExists {
+		o.logger("service").Info("Service already exists, updating current")
+		if err = o.updateService(ctx, service); err != nil {
+			return nil, fmt.Errorf("update service: %w", err)
+		}
+	}
+
+	o.logger("service").Info("Service created")
+	o.logDryRun("service", created)
+	return created, nil
+}
+
+// applyService applies the desired service with server-side apply.
+func (o *Operator) applyService(ctx context.Context, desired *api.Service) (*api.Service, error) {
+	spec := &coreac.ServiceSpecApplyConfiguration{}
+	if err := convertApplyConfiguration(desired.Spec, spec); err != nil {
+		return nil, err
+	}
+
+	service := coreac.Service(desired.Name, desired.Namespace).
+		WithLabels(desired.Labels).
+		WithAnnotations(desired.Annotations).
+		WithOwnerReferences(ownerReferencesApplyConfiguration(desired.OwnerReferences)...).
+		WithSpec(spec)
+
+	var applied *api.Service
+	err := o.api.call(ctx, "service", "apply", func(ctx context.Context) error {
+		var err error
+		applied, err = o.client.CoreV1().Services(o.namespace).Apply(ctx, service, o.applyOptions())
+		return err
+	})
+	return applied, err
+}
@@ -0,0 +1,102 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// serviceMonitorResource identifies the ServiceMonitor resource of the Prometheus Operator for the dynamic client.
+// The resource is handled as unstructured, sparing a dependency on the Prometheus Operator API.
+var serviceMonitorResource = schema.GroupVersionResource{
+	Group:    "monitoring.coreos.com",
+	Version:  "v1",
+	Resource: "servicemonitors",
+}
+
+// desiredServiceMonitor returns the ServiceMonitor scraping the greeting server through its service.
+func (o *Operator) desiredServiceMonitor() *unstructured.Unstructured {
+	serviceMonitor := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": stringMap(o.objectLabels()),
+			},
+			"namespaceSelector": map[string]interface{}{
+				"matchNames": []interface{}{o.namespace},
+			},
+			"endpoints": []interface{}{map[string]interface{}{
+				"port":     "http",
+				"path":     o.metricsPath,
+				"interval": o.scrapeInterval.String(),
+			}},
+		},
+	}}
+
+	serviceMonitor.SetAPIVersion(serviceMonitorResource.GroupVersion().String())
+	serviceMonitor.SetKind("ServiceMonitor")
+	serviceMonitor.SetName(o.resourceName)
+	serviceMonitor.SetNamespace(o.namespace)
+	serviceMonitor.SetLabels(o.objectLabels())
+	serviceMonitor.SetOwnerReferences(o.ownerReferences)
+
+	return serviceMonitor
+}
+
+// createServiceMonitor applies the ServiceMonitor with server-side apply, whatever the update mode.
+// A cluster without the Prometheus Operator only gets a warning.
+func (o *Operator) createServiceMonitor(ctx context.Context) error {
+	if o.dynamicClient == nil {
+		return fmt.Errorf("apply servicemonitor: %w", errNoDynamicClient)
+	}
+
+	logger := o.logger("servicemonitor")
+	desired := o.desiredServiceMonitor()
+
+	err := o.api.call(ctx, "servicemonitor", "apply", func(ctx context.Context) error {
+		applied, err := o.dynamicClient.Resource(serviceMonitorResource).Namespace(o.namespace).Apply(ctx, desired.GetName(), desired, o.applyOptions())
+		if err == nil {
+			o.logDryRun("servicemonitor", applied.Object)
+		}
+		return err
+	})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			logger.Warning("ServiceMonitor resource not installed, skipping the service monitor")
+			return nil
+		}
+		return fmt.Errorf("apply servicemonitor: %w", err)
+	}
+
+	logger.Info("ServiceMonitor applied")
+	o.recordAction("servicemonitor", ActionApplied)
+	return nil
+}
+
+// listServiceMonitors lists the ServiceMonitors of the namespace, none when the resource is not installed
+// or without a dynamic client.
+func (o *Operator) listServiceMonitors(ctx context.Context, opts meta.ListOptions) ([]unstructured.Unstructured, error) {
+	if o.dynamicClient == nil {
+		return nil, nil
+	}
+	list, err := o.dynamicClient.Resource(serviceMonitorResource).Namespace(o.namespace).List(ctx, opts)
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// stringMap converts labels into an unstructured map.
+func stringMap(m map[string]string) map[string]interface{} {
+	converted := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		converted[k] = v
+	}
+	return converted
+}
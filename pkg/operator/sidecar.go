@@ -0,0 +1,110 @@
+package operator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Sidecar is an extra container running next to the greeting server.
+type Sidecar struct {
+	// Name of the container.
+	Name string
+	// Image of the container.
+	Image string
+	// Env holds the environment variables of the container.
+	Env []api.EnvVar
+	// Ports exposed by the container.
+	Ports []int32
+}
+
+// ParseSidecars parses the sidecar definitions formatted as name=image, then attaches to them
+// the environment variables formatted as name:KEY=VALUE and the ports formatted as name:port.
+func ParseSidecars(sidecars, envs, ports []string) ([]Sidecar, error) {
+	parsed := make([]Sidecar, 0, len(sidecars))
+	index := make(map[string]int, len(sidecars))
+	for _, value := range sidecars {
+		name, image, found := strings.Cut(value, "=")
+		if !found || image == "" {
+			return nil, fmt.Errorf("sidecar %q: expected name=image", value)
+		}
+
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("sidecar %q: invalid name: %s", value, strings.Join(errs, ", "))
+		}
+
+		if name == greetingContainerName {
+			return nil, fmt.Errorf("sidecar %q: name is reserved for the greeting server", value)
+		}
+
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("sidecar %q: duplicated name", name)
+		}
+
+		index[name] = len(parsed)
+		parsed = append(parsed, Sidecar{Name: name, Image: image})
+	}
+
+	for _, value := range envs {
+		name, env, found := strings.Cut(value, ":")
+		key, val, hasValue := strings.Cut(env, "=")
+		if !found || !hasValue || key == "" {
+			return nil, fmt.Errorf("sidecar env %q: expected name:KEY=VALUE", value)
+		}
+
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("sidecar env %q: unknown sidecar %q", value, name)
+		}
+
+		parsed[i].Env = append(parsed[i].Env, api.EnvVar{Name: key, Value: val})
+	}
+
+	for _, value := range ports {
+		name, rawPort, found := strings.Cut(value, ":")
+		if !found {
+			return nil, fmt.Errorf("sidecar port %q: expected name:port", value)
+		}
+
+		port, err := strconv.ParseInt(rawPort, 10, 32)
+		if err != nil || validation.IsValidPortNum(int(port)) != nil {
+			return nil, fmt.Errorf("sidecar port %q: invalid port", value)
+		}
+
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("sidecar port %q: unknown sidecar %q", value, name)
+		}
+
+		parsed[i].Ports = append(parsed[i].Ports, int32(port))
+	}
+
+	return parsed, nil
+}
+
+// sidecarContainers returns the sidecar containers of the pod.
+// Their ports are never exposed by the service, which only targets the greeting server.
+func (o *Operator) sidecarContainers() []api.Container {
+	var containers []api.Container
+	for _, s := range o.sidecars {
+		container := api.Container{
+			Name:  s.Name,
+			Image: s.Image,
+			Env:   s.Env,
+		}
+
+		for _, port := range s.Ports {
+			container.Ports = append(container.Ports, api.ContainerPort{
+				Protocol:      api.ProtocolTCP,
+				ContainerPort: port,
+			})
+		}
+
+		containers = append(containers, container)
+	}
+
+	return containers
+}
@@ -0,0 +1,377 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Status summarizes the deployed greeting stack.
+type Status struct {
+	// Workload is the state of the deployment or daemonset, nil when missing.
+	Workload *WorkloadStatus `json:"workload,omitempty"`
+	// Canary is the state of the canary deployment, nil when none runs.
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// Service is the state of the service, nil when missing.
+	Service *ServiceStatus `json:"service,omitempty"`
+	// Route is the state of the route, nil when missing or not requested.
+	Route *RouteStatus `json:"route,omitempty"`
+	// Pods are the greeting server pods.
+	Pods []PodStatus `json:"pods"`
+}
+
+// WorkloadStatus is the state of the workload running the greeting server.
+type WorkloadStatus struct {
+	// Kind is either Deployment or DaemonSet.
+	Kind string `json:"kind"`
+	// Name of the workload.
+	Name string `json:"name"`
+	// Desired number of pods.
+	Desired int32 `json:"desired"`
+	// Ready number of pods.
+	Ready int32 `json:"ready"`
+	// Updated number of pods, running the latest spec.
+	Updated int32 `json:"updated"`
+	// Available number of pods, ready for at least min ready seconds.
+	Available int32 `json:"available"`
+	// Paused tells whether the rollouts of the deployment are paused.
+	Paused bool `json:"paused,omitempty"`
+	// Conditions reported by the workload controller.
+	Conditions []ConditionStatus `json:"conditions,omitempty"`
+}
+
+// CanaryStatus is the state of the canary deployment trying a new image.
+type CanaryStatus struct {
+	WorkloadStatus
+	// Image run by the canary.
+	Image string `json:"image"`
+	// Weight is the percentage of the traffic requested for the canary.
+	Weight int `json:"weight"`
+}
+
+// ConditionStatus is a condition of the workload.
+type ConditionStatus struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ServiceStatus is the state of the service exposing the greeting server.
+type ServiceStatus struct {
+	// Name of the service.
+	Name string `json:"name"`
+	// Type of the service.
+	Type string `json:"type"`
+	// ClusterIP allocated to the service.
+	ClusterIP string `json:"clusterIP"`
+	// External IPs and hostnames of the service.
+	External []string `json:"external,omitempty"`
+	// NodePorts allocated to the service.
+	NodePorts []int32 `json:"nodePorts,omitempty"`
+}
+
+// PodStatus is the state of a greeting server pod.
+type PodStatus struct {
+	// Name of the pod.
+	Name string `json:"name"`
+	// Phase of the pod.
+	Phase string `json:"phase"`
+	// Ready tells whether the pod receives traffic.
+	Ready bool `json:"ready"`
+	// Restarts is the sum of the pod containers restarts.
+	Restarts int32 `json:"restarts"`
+	// Node running the pod.
+	Node string `json:"node"`
+	// Track is either stable or canary.
+	Track string `json:"track"`
+}
+
+// FullyAvailable tells whether the workload and the canary, when they exist, run all their desired pods on the latest spec.
+func (s *Status) FullyAvailable() bool {
+	if s.Canary != nil && !s.Canary.fullyAvailable() {
+		return false
+	}
+	if s.Workload == nil {
+		return true
+	}
+	return s.Workload.fullyAvailable()
+}
+
+func (s *WorkloadStatus) fullyAvailable() bool {
+	return s.Updated >= s.Desired && s.Available >= s.Desired
+}
+
+// Status fetches the state of the managed resources.
+func (o *Operator) Status(ctx context.Context) (*Status, error) {
+	status := &Status{Pods: []PodStatus{}}
+
+	var err error
+	switch o.workload {
+	case WorkloadDaemonSet:
+		status.Workload, err = o.daemonSetStatus(ctx)
+	default:
+		status.Workload, err = o.deploymentStatus(ctx)
+		if err == nil {
+			status.Canary, err = o.canaryStatus(ctx)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := o.client.CoreV1().Services(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil && !kerror.IsNotFound(err) {
+		return nil, fmt.Errorf("get service: %w", err)
+	}
+	if err == nil {
+		status.Service = newServiceStatus(service)
+	}
+
+	if o.expose == ExposeRoute {
+		if status.Route, err = o.routeStatus(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	selector := labels.SelectorFromSet(o.selectorLabels()).String()
+	pods, err := o.client.CoreV1().Pods(o.namespace).List(ctx, meta.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		status.Pods = append(status.Pods, newPodStatus(&pod))
+	}
+
+	return status, nil
+}
+
+func (o *Operator) deploymentStatus(ctx context.Context) (*WorkloadStatus, error) {
+	deployment, err := o.client.AppsV1().Deployments(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get deployment: %w", err)
+	}
+
+	return newDeploymentStatus(deployment), nil
+}
+
+func (o *Operator) canaryStatus(ctx context.Context) (*CanaryStatus, error) {
+	canary, err := o.client.AppsV1().Deployments(o.namespace).Get(ctx, o.canaryName(), meta.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get canary deployment: %w", err)
+	}
+	if !o.isCanary("deployment", canary) {
+		return nil, nil
+	}
+
+	weight, _ := strconv.Atoi(canary.Annotations[canaryWeightAnnotation])
+	return &CanaryStatus{
+		WorkloadStatus: *newDeploymentStatus(canary),
+		Image:          deploymentImage(canary),
+		Weight:         weight,
+	}, nil
+}
+
+func newDeploymentStatus(deployment *apps.Deployment) *WorkloadStatus {
+	var desired int32 = 1
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	status := &WorkloadStatus{
+		Kind:      "Deployment",
+		Name:      deployment.Name,
+		Desired:   desired,
+		Ready:     deployment.Status.ReadyReplicas,
+		Updated:   deployment.Status.UpdatedReplicas,
+		Available: deployment.Status.AvailableReplicas,
+		Paused:    deployment.Spec.Paused,
+	}
+
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		status.Updated = 0
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		status.Conditions = append(status.Conditions, ConditionStatus{
+			Type:    string(condition.Type),
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		})
+	}
+
+	return status
+}
+
+func (o *Operator) daemonSetStatus(ctx context.Context) (*WorkloadStatus, error) {
+	daemonSet, err := o.client.AppsV1().DaemonSets(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get daemonset: %w", err)
+	}
+
+	status := &WorkloadStatus{
+		Kind:      "DaemonSet",
+		Name:      daemonSet.Name,
+		Desired:   daemonSet.Status.DesiredNumberScheduled,
+		Ready:     daemonSet.Status.NumberReady,
+		Updated:   daemonSet.Status.UpdatedNumberScheduled,
+		Available: daemonSet.Status.NumberAvailable,
+	}
+
+	if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+		status.Updated = 0
+	}
+
+	for _, condition := range daemonSet.Status.Conditions {
+		status.Conditions = append(status.Conditions, ConditionStatus{
+			Type:    string(condition.Type),
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		})
+	}
+
+	return status, nil
+}
+
+func newServiceStatus(service *api.Service) *ServiceStatus {
+	status := &ServiceStatus{
+		Name:      service.Name,
+		Type:      string(service.Spec.Type),
+		ClusterIP: service.Spec.ClusterIP,
+	}
+
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			status.External = append(status.External, ingress.IP)
+		}
+		if ingress.Hostname != "" {
+			status.External = append(status.External, ingress.Hostname)
+		}
+	}
+	status.External = append(status.External, service.Spec.ExternalIPs...)
+
+	for _, port := range service.Spec.Ports {
+		if port.NodePort != 0 {
+			status.NodePorts = append(status.NodePorts, port.NodePort)
+		}
+	}
+
+	return status
+}
+
+func newPodStatus(pod *api.Pod) PodStatus {
+	status := PodStatus{
+		Name:  pod.Name,
+		Phase: string(pod.Status.Phase),
+		Node:  pod.Spec.NodeName,
+		Track: trackStable,
+	}
+	if pod.Labels[trackLabel] == trackCanary {
+		status.Track = trackCanary
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == api.PodReady {
+			status.Ready = condition.Status == api.ConditionTrue
+		}
+	}
+
+	for _, container := range pod.Status.ContainerStatuses {
+		status.Restarts += container.RestartCount
+	}
+
+	return status
+}
+
+// WriteJSON writes the status as an indented JSON document.
+func (s *Status) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteStatusesJSON writes the statuses of several instances as an indented JSON list.
+func WriteStatusesJSON(w io.Writer, statuses []*Status) error {
+	if statuses == nil {
+		statuses = []*Status{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(statuses)
+}
+
+// WriteTable writes the status as human readable tables.
+func (s *Status) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	if s.Workload == nil {
+		fmt.Fprintln(tw, "WORKLOAD\tnot found")
+	} else {
+		fmt.Fprintln(tw, "KIND\tNAME\tDESIRED\tREADY\tUPDATED\tAVAILABLE\tPAUSED")
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%t\n", s.Workload.Kind, s.Workload.Name, s.Workload.Desired, s.Workload.Ready, s.Workload.Updated, s.Workload.Available, s.Workload.Paused)
+		for _, c := range s.Workload.Conditions {
+			fmt.Fprintf(tw, "  %s=%s\t%s\t%s\n", c.Type, c.Status, c.Reason, c.Message)
+		}
+	}
+	fmt.Fprintln(tw)
+
+	if s.Canary != nil {
+		fmt.Fprintln(tw, "CANARY\tIMAGE\tWEIGHT\tDESIRED\tREADY\tUPDATED\tAVAILABLE")
+		fmt.Fprintf(tw, "%s\t%s\t%d%%\t%d\t%d\t%d\t%d\n", s.Canary.Name, s.Canary.Image, s.Canary.Weight, s.Canary.Desired, s.Canary.Ready, s.Canary.Updated, s.Canary.Available)
+		fmt.Fprintln(tw)
+	}
+
+	if s.Service == nil {
+		fmt.Fprintln(tw, "SERVICE\tnot found")
+	} else {
+		nodePorts := make([]string, 0, len(s.Service.NodePorts))
+		for _, port := range s.Service.NodePorts {
+			nodePorts = append(nodePorts, fmt.Sprint(port))
+		}
+		fmt.Fprintln(tw, "SERVICE\tTYPE\tCLUSTER-IP\tEXTERNAL\tNODE-PORTS")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Service.Name, s.Service.Type, s.Service.ClusterIP, orNone(strings.Join(s.Service.External, ",")), orNone(strings.Join(nodePorts, ",")))
+	}
+	fmt.Fprintln(tw)
+
+	if s.Route != nil {
+		fmt.Fprintln(tw, "ROUTE\tHOST\tADMITTED")
+		fmt.Fprintf(tw, "%s\t%s\t%t\n", s.Route.Name, orNone(s.Route.Host), s.Route.Admitted)
+		fmt.Fprintln(tw)
+	}
+
+	fmt.Fprintln(tw, "POD\tTRACK\tPHASE\tREADY\tRESTARTS\tNODE")
+	for _, pod := range s.Pods {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%d\t%s\n", pod.Name, pod.Track, pod.Phase, pod.Ready, pod.Restarts, orNone(pod.Node))
+	}
+
+	return tw.Flush()
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,184 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// SummaryAPIVersion is the version of the Summary schema, changed on incompatible changes only.
+const SummaryAPIVersion = "greeting-operator/v1"
+
+// Action is what the run did to a resource.
+type Action string
+
+const (
+	// ActionCreated is a resource missing before the run.
+	ActionCreated Action = "created"
+	// ActionUpdated is a resource which differed from the desired one.
+	ActionUpdated Action = "updated"
+	// ActionUnchanged is a resource already up to date.
+	ActionUnchanged Action = "unchanged"
+	// ActionApplied is a resource written with server-side apply on every run, whether it changed or not.
+	ActionApplied Action = "applied"
+)
+
+// Summary describes the resources managed by the run, printed with the json and yaml outputs.
+type Summary struct {
+	// APIVersion is SummaryAPIVersion.
+	APIVersion string `json:"apiVersion"`
+	// Resources are the managed resources, in their order of creation.
+	Resources []ResourceSummary `json:"resources"`
+	// Endpoints of the greeting server, missing with the host port or on a server dry-run.
+	Endpoints *Endpoints `json:"endpoints,omitempty"`
+}
+
+// ResourceSummary describes a managed resource.
+type ResourceSummary struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// UID is empty for the resources not persisted, on a server dry-run.
+	UID    string `json:"uid,omitempty"`
+	Action Action `json:"action"`
+	// ObservedGeneration is the generation of the workload observed by its controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ClusterIP is the address of the service in the cluster.
+	ClusterIP string `json:"clusterIP,omitempty"`
+}
+
+// recordAction remembers what the run did to the resource of the kind, unchanged by default.
+func (o *Operator) recordAction(kind string, action Action) {
+	o.actionsMu.Lock()
+	defer o.actionsMu.Unlock()
+	if o.actions == nil {
+		o.actions = map[string]Action{}
+	}
+	o.actions[kind] = action
+}
+
+// Summary looks the managed resources up once installed, with what the run did to them, and the endpoints
+// of the greeting server.
+func (o *Operator) Summary(ctx context.Context) (*Summary, error) {
+	summary := &Summary{APIVersion: SummaryAPIVersion, Resources: []ResourceSummary{}}
+	for _, object := range o.desiredObjects() {
+		desired, err := apimeta.Accessor(object)
+		if err != nil {
+			return nil, fmt.Errorf("summarize %T: %w", object, err)
+		}
+		kind := object.GetObjectKind().GroupVersionKind().Kind
+
+		o.actionsMu.Lock()
+		action, recorded := o.actions[strings.ToLower(kind)]
+		o.actionsMu.Unlock()
+		if !recorded {
+			action = ActionUnchanged
+		}
+		resource := ResourceSummary{Kind: kind, Namespace: desired.GetNamespace(), Name: desired.GetName(), Action: action}
+
+		live, err := o.liveObject(ctx, kind, desired.GetName())
+		switch {
+		case kerror.IsNotFound(err) && !recorded:
+			// Such as a ServiceMonitor without the resource installed, skipped by the run.
+			continue
+		case kerror.IsNotFound(err):
+			// Not persisted by a server dry-run.
+		case err != nil:
+			return nil, fmt.Errorf("get %s: %w", strings.ToLower(kind), err)
+		case live != nil:
+			resource.UID = string(live.GetUID())
+		}
+		switch live := live.(type) {
+		case *apps.Deployment:
+			resource.ObservedGeneration = live.Status.ObservedGeneration
+		case *apps.DaemonSet:
+			resource.ObservedGeneration = live.Status.ObservedGeneration
+		case *api.Service:
+			resource.ClusterIP = live.Spec.ClusterIP
+		}
+		summary.Resources = append(summary.Resources, resource)
+	}
+
+	if !o.hostPort && !o.serverDryRun {
+		endpoints, err := o.serviceEndpoints(ctx)
+		if err != nil {
+			return nil, err
+		}
+		summary.Endpoints = endpoints
+	}
+	return summary, nil
+}
+
+// liveObject gets the managed resource of the kind, nil for the custom resources without dynamic client.
+func (o *Operator) liveObject(ctx context.Context, kind, name string) (meta.Object, error) {
+	var live meta.Object
+	err := o.api.call(ctx, strings.ToLower(kind), "get", func(ctx context.Context) error {
+		var err error
+		switch kind {
+		case "Namespace":
+			live, err = o.client.CoreV1().Namespaces().Get(ctx, name, meta.GetOptions{})
+		case "ResourceQuota":
+			live, err = o.getResourceQuota(ctx, name)
+		case "LimitRange":
+			live, err = o.getLimitRange(ctx, name)
+		case "Secret":
+			live, err = o.client.CoreV1().Secrets(o.namespace).Get(ctx, name, meta.GetOptions{})
+		case "Deployment":
+			live, err = o.client.AppsV1().Deployments(o.namespace).Get(ctx, name, meta.GetOptions{})
+		case "DaemonSet":
+			live, err = o.client.AppsV1().DaemonSets(o.namespace).Get(ctx, name, meta.GetOptions{})
+		case "Service":
+			live, err = o.client.CoreV1().Services(o.namespace).Get(ctx, name, meta.GetOptions{})
+		case "ServiceMonitor", "Route":
+			if o.dynamicClient == nil {
+				return nil
+			}
+			resource := serviceMonitorResource
+			if kind == "Route" {
+				resource = routeResource
+			}
+			live, err = o.dynamicClient.Resource(resource).Namespace(o.namespace).Get(ctx, name, meta.GetOptions{})
+		}
+		return err
+	})
+	return live, err
+}
+
+// printSummary writes the summary of the run in the output format.
+func (o *Operator) printSummary(ctx context.Context, w io.Writer) error {
+	summary, err := o.Summary(ctx)
+	if err != nil {
+		return err
+	}
+
+	if o.output == OutputYAML {
+		raw, err := yaml.Marshal(summary)
+		if err != nil {
+			return fmt.Errorf("marshal summary: %w", err)
+		}
+		_, err = w.Write(raw)
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+// humanOutput returns where the messages meant for humans are written: the standard output is left to the
+// summary with the json and yaml outputs.
+func (o *Operator) humanOutput() io.Writer {
+	if o.output != OutputText {
+		return os.Stderr
+	}
+	return os.Stdout
+}
@@ -0,0 +1,74 @@
+package operator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// TopologySpread spreads the greeting server pods across a topology domain.
+type TopologySpread struct {
+	// TopologyKey is the node label defining the topology domains.
+	TopologyKey string
+	// MaxSkew is the maximum difference of pods between two domains.
+	MaxSkew int32
+	// WhenUnsatisfiable tells the scheduler what to do if the constraint can't be honored.
+	WhenUnsatisfiable api.UnsatisfiableConstraintAction
+}
+
+// ParseTopologySpreads parses the topology spread constraints formatted as key=maxSkew[:whenUnsatisfiable].
+func ParseTopologySpreads(values []string) ([]TopologySpread, error) {
+	spreads := make([]TopologySpread, 0, len(values))
+	for _, value := range values {
+		key, rest, found := strings.Cut(value, "=")
+		if !found {
+			return nil, fmt.Errorf("topology spread %q: expected key=maxSkew[:whenUnsatisfiable]", value)
+		}
+
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("topology spread %q: invalid key: %s", value, strings.Join(errs, ", "))
+		}
+
+		rawSkew, rawAction, _ := strings.Cut(rest, ":")
+		maxSkew, err := strconv.ParseInt(rawSkew, 10, 32)
+		if err != nil || maxSkew < 1 {
+			return nil, fmt.Errorf("topology spread %q: maxSkew must be an integer greater or equal to 1", value)
+		}
+
+		action := api.DoNotSchedule
+		switch api.UnsatisfiableConstraintAction(rawAction) {
+		case "", api.DoNotSchedule:
+		case api.ScheduleAnyway:
+			action = api.ScheduleAnyway
+		default:
+			return nil, fmt.Errorf("topology spread %q: whenUnsatisfiable must be %s or %s", value, api.DoNotSchedule, api.ScheduleAnyway)
+		}
+
+		spreads = append(spreads, TopologySpread{
+			TopologyKey:       key,
+			MaxSkew:           int32(maxSkew),
+			WhenUnsatisfiable: action,
+		})
+	}
+
+	return spreads, nil
+}
+
+// topologySpreadConstraints returns the constraints of the pod, always selecting the pods managed by the operator.
+func (o *Operator) topologySpreadConstraints() []api.TopologySpreadConstraint {
+	var constraints []api.TopologySpreadConstraint
+	for _, s := range o.topologySpreads {
+		constraints = append(constraints, api.TopologySpreadConstraint{
+			MaxSkew:           s.MaxSkew,
+			TopologyKey:       s.TopologyKey,
+			WhenUnsatisfiable: s.WhenUnsatisfiable,
+			LabelSelector:     &meta.LabelSelector{MatchLabels: o.selectorLabels()},
+		})
+	}
+
+	return constraints
+}
@@ -0,0 +1,150 @@
+package operator
+
+import (
+	"context"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// updateDeployment replaces the spec of the live deployment by the desired one, retrying on conflicts.
+// Foreign labels and annotations are kept, and so are the replicas unless they are managed.
+func (o *Operator) updateDeployment(ctx context.Context, desired *apps.Deployment) error {
+	deploymentClient := o.client.AppsV1().Deployments(o.namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var current *apps.Deployment
+		err := o.api.call(ctx, "deployment", "get", func(ctx context.Context) error {
+			var err error
+			current, err = deploymentClient.Get(ctx, desired.Name, meta.GetOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		updated := desired.DeepCopy()
+		updated.ResourceVersion = current.ResourceVersion
+		updated.Labels = mergeStringMaps(current.Labels, desired.Labels)
+		updated.Annotations = mergeStringMaps(current.Annotations, desired.Annotations)
+		updated.Spec.Template.Annotations = mergeStringMaps(current.Spec.Template.Annotations, desired.Spec.Template.Annotations)
+		if !o.manageReplicas {
+			updated.Spec.Replicas = current.Spec.Replicas
+		}
+
+		return o.api.call(ctx, "deployment", "update", func(ctx context.Context) error {
+			written, err := deploymentClient.Update(ctx, updated, o.updateOptions())
+			if err == nil {
+				o.logDryRun("deployment", written)
+			}
+			return err
+		})
+	})
+}
+
+// updateDaemonSet replaces the spec of the live daemonset by the desired one, retrying on conflicts.
+// Foreign labels and annotations are kept.
+func (o *Operator) updateDaemonSet(ctx context.Context, desired *apps.DaemonSet) error {
+	daemonSetClient := o.client.AppsV1().DaemonSets(o.namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var current *apps.DaemonSet
+		err := o.api.call(ctx, "daemonset", "get", func(ctx context.Context) error {
+			var err error
+			current, err = daemonSetClient.Get(ctx, desired.Name, meta.GetOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		updated := desired.DeepCopy()
+		updated.ResourceVersion = current.ResourceVersion
+		updated.Labels = mergeStringMaps(current.Labels, desired.Labels)
+		updated.Annotations = mergeStringMaps(current.Annotations, desired.Annotations)
+		updated.Spec.Template.Annotations = mergeStringMaps(current.Spec.Template.Annotations, desired.Spec.Template.Annotations)
+
+		return o.api.call(ctx, "daemonset", "update", func(ctx context.Context) error {
+			written, err := daemonSetClient.Update(ctx, updated, o.updateOptions())
+			if err == nil {
+				o.logDryRun("daemonset", written)
+			}
+			return err
+		})
+	})
+}
+
+// updateService replaces the spec of the live service by the desired one, retrying on conflicts.
+// The fields allocated by the API server, which are immutable, and the foreign labels and annotations
+// are kept from the live service.
+func (o *Operator) updateService(ctx context.Context, desired *api.Service) error {
+	serviceClient := o.client.CoreV1().Services(o.namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var current *api.Service
+		err := o.api.call(ctx, "service", "get", func(ctx context.Context) error {
+			var err error
+			current, err = serviceClient.Get(ctx, desired.Name, meta.GetOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		updated := desired.DeepCopy()
+		updated.ResourceVersion = current.ResourceVersion
+		updated.Labels = mergeStringMaps(current.Labels, desired.Labels)
+		updated.Annotations = mergeStringMaps(current.Annotations, desired.Annotations)
+		updated.Spec.ClusterIP = current.Spec.ClusterIP
+		updated.Spec.ClusterIPs = current.Spec.ClusterIPs
+		updated.Spec.IPFamilies = current.Spec.IPFamilies
+		updated.Spec.IPFamilyPolicy = current.Spec.IPFamilyPolicy
+		if updated.Spec.Type == current.Spec.Type {
+			updated.Spec.HealthCheckNodePort = current.Spec.HealthCheckNodePort
+			copyNodePorts(updated.Spec.Ports, current.Spec.Ports)
+		}
+
+		return o.api.call(ctx, "service", "update", func(ctx context.Context) error {
+			written, err := serviceClient.Update(ctx, updated, o.updateOptions())
+			if err == nil {
+				o.logDryRun("service", written)
+			}
+			return err
+		})
+	})
+}
+
+// copyNodePorts keeps the node ports allocated to the live service ports matching the desired ones by name.
+func copyNodePorts(desired, current []api.ServicePort) {
+	for i := range desired {
+		if desired[i].NodePort != 0 {
+			continue
+		}
+
+		for _, port := range current {
+			if port.Name == desired[i].Name {
+				desired[i].NodePort = port.NodePort
+				break
+			}
+		}
+	}
+}
+
+// mergeStringMaps returns the live labels or annotations, set by users or other controllers, overridden by the desired ones.
+func mergeStringMaps(current, desired map[string]string) map[string]string {
+	if len(current) == 0 && len(desired) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string, len(current)+len(desired))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range desired {
+		merged[k] = v
+	}
+
+	return merged
+}
@@ -0,0 +1,165 @@
+package operator
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// conflictOnce makes the first update of the resource fail with a conflict, as when another writer updated
+// the object between the get and the update, and counts the updates.
+func conflictOnce(resource string, updates *int) ktesting.ReactionFunc {
+	return func(ktesting.Action) (bool, runtime.Object, error) {
+		*updates++
+		if *updates == 1 {
+			return true, nil, kerror.NewConflict(api.Resource(resource), "greeting", nil)
+		}
+		return false, nil, nil
+	}
+}
+
+func TestUpdateDeploymentRetriesOnConflict(t *testing.T) {
+	var armed atomic.Bool
+	var updates int
+	client := newFakeClient()
+	client.PrependReactor("update", "deployments", armedReactor(&armed, conflictOnce("deployments", &updates)))
+	if err := newTestOperator(t, client, WithLegacyUpdate(true)).install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	armed.Store(true)
+	op := newTestOperator(t, client, WithLegacyUpdate(true), WithImage("greeting:2"))
+	if err := op.updateDeployment(context.Background(), op.desiredDeployment()); err != nil {
+		t.Fatalf("updateDeployment: %v", err)
+	}
+
+	if updates != 2 {
+		t.Errorf("updates = %d, want the conflict then the retry", updates)
+	}
+	live, err := client.AppsV1().Deployments("default").Get(context.Background(), "greeting", meta.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := deploymentImage(live); image != "greeting:2" {
+		t.Errorf("deployment image = %q, want greeting:2 once retried", image)
+	}
+}
+
+func TestUpdateServiceRetriesOnConflict(t *testing.T) {
+	var armed atomic.Bool
+	var updates int
+	client := newFakeClient()
+	client.PrependReactor("update", "services", armedReactor(&armed, conflictOnce("services", &updates)))
+	if err := newTestOperator(t, client, WithLegacyUpdate(true)).install(context.Background()); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	armed.Store(true)
+	op := newTestOperator(t, client, WithLegacyUpdate(true), WithPort(9090))
+	if err := op.updateService(context.Background(), op.desiredService()); err != nil {
+		t.Fatalf("updateService: %v", err)
+	}
+
+	if updates != 2 {
+		t.Errorf("updates = %d, want the conflict then the retry", updates)
+	}
+}
+
+// allocatedService returns the service of the operator as allocated by the API server, with a foreign label.
+func allocatedService(t *testing.T, serviceType api.ServiceType) *api.Service {
+	t.Helper()
+	service := newTestOperator(t, newFakeClient(), WithServiceType(serviceType)).desiredService()
+	service.Labels["team"] = "greeters"
+	policy := api.IPFamilyPolicySingleStack
+	service.Spec.ClusterIP = "10.0.0.10"
+	service.Spec.ClusterIPs = []string{"10.0.0.10"}
+	service.Spec.IPFamilies = []api.IPFamily{api.IPv4Protocol}
+	service.Spec.IPFamilyPolicy = &policy
+	service.Spec.Ports[0].NodePort = 30080
+	if serviceType == api.ServiceTypeLoadBalancer {
+		service.Spec.HealthCheckNodePort = 30999
+	}
+	return service
+}
+
+func TestUpdateServiceKeepsAllocatedFields(t *testing.T) {
+	tests := []struct {
+		name          string
+		live, desired api.ServiceType
+		keepNodePorts bool
+	}{
+		{"same type", api.ServiceTypeLoadBalancer, api.ServiceTypeLoadBalancer, true},
+		{"node port", api.ServiceTypeNodePort, api.ServiceTypeNodePort, true},
+		// The node ports of another type are not valid, the API server allocates new ones if needed.
+		{"type changed", api.ServiceTypeLoadBalancer, api.ServiceTypeClusterIP, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newFakeClient(allocatedService(t, test.live))
+			op := newTestOperator(t, client, WithLegacyUpdate(true), WithServiceType(test.desired))
+			if err := op.updateService(context.Background(), op.desiredService()); err != nil {
+				t.Fatalf("updateService: %v", err)
+			}
+
+			live, err := client.CoreV1().Services("default").Get(context.Background(), "greeting", meta.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if live.Spec.Type != test.desired {
+				t.Errorf("type = %s, want %s", live.Spec.Type, test.desired)
+			}
+			if live.Spec.ClusterIP != "10.0.0.10" || !reflect.DeepEqual(live.Spec.ClusterIPs, []string{"10.0.0.10"}) {
+				t.Errorf("cluster IPs = %q %v, want the allocated 10.0.0.10", live.Spec.ClusterIP, live.Spec.ClusterIPs)
+			}
+			if !reflect.DeepEqual(live.Spec.IPFamilies, []api.IPFamily{api.IPv4Protocol}) || live.Spec.IPFamilyPolicy == nil {
+				t.Errorf("IP families = %v %v, want the allocated single stack IPv4", live.Spec.IPFamilies, live.Spec.IPFamilyPolicy)
+			}
+			if live.Labels["team"] != "greeters" || live.Labels[managedByLabel] != managedByValue {
+				t.Errorf("labels = %v, want the foreign and the managed labels", live.Labels)
+			}
+
+			wantNodePort, wantHealthCheck := int32(0), int32(0)
+			if test.keepNodePorts {
+				wantNodePort = 30080
+				if test.live == api.ServiceTypeLoadBalancer {
+					wantHealthCheck = 30999
+				}
+			}
+			if nodePort := live.Spec.Ports[0].NodePort; nodePort != wantNodePort {
+				t.Errorf("node port = %d, want %d", nodePort, wantNodePort)
+			}
+			if live.Spec.HealthCheckNodePort != wantHealthCheck {
+				t.Errorf("health check node port = %d, want %d", live.Spec.HealthCheckNodePort, wantHealthCheck)
+			}
+		})
+	}
+}
+
+func TestMergeStringMaps(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, desired map[string]string
+		want             map[string]string
+	}{
+		{"both empty", nil, map[string]string{}, nil},
+		{"foreign kept", map[string]string{"team": "greeters"}, map[string]string{"app": "greeting"}, map[string]string{"team": "greeters", "app": "greeting"}},
+		{"desired wins", map[string]string{"app": "other"}, map[string]string{"app": "greeting"}, map[string]string{"app": "greeting"}},
+		{"nothing desired", map[string]string{"team": "greeters"}, nil, map[string]string{"team": "greeters"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mergeStringMaps(test.current, test.desired); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("mergeStringMaps(%v, %v) = %v, want %v", test.current, test.desired, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// imageReference matches a container image reference: an optional registry host,
+// a repository path, then an optional tag and digest, as accepted by the container runtimes.
+var imageReference = regexp.MustCompile(`^` +
+	`(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?` +
+	`(?:@[a-zA-Z][a-zA-Z0-9]*(?:[-_+.][a-zA-Z][a-zA-Z0-9]*)*:[0-9a-fA-F]{32,})?` +
+	`$`)
+
+// Validate checks the whole configuration, returning every problem found at once.
+func (c *Config) Validate() error {
+	errs := []error{c.validateInstance(), validateWorkload(c.Workload)}
+
+	// The names are unique across every container of the pod, the greeting server included.
+	kinds := map[string]string{}
+	checkName := func(name, kind string) {
+		if name == greetingContainerName {
+			errs = append(errs, fmt.Errorf("%s name %q is reserved for the greeting server", kind, name))
+			return
+		}
+		switch other, found := kinds[name]; {
+		case !found:
+			kinds[name] = kind
+		case other == kind:
+			errs = append(errs, fmt.Errorf("container name %q used by two %ss", name, kind))
+		default:
+			errs = append(errs, fmt.Errorf("container name %q used by both a sidecar and an init container", name))
+		}
+	}
+	for _, sidecar := range c.Sidecars {
+		checkName(sidecar.Name, "sidecar")
+	}
+	for _, initContainer := range c.InitContainers {
+		checkName(initContainer.Name, "init container")
+	}
+
+	if len(c.InitContainers) > 0 {
+		for _, m := range c.ConfigMapMounts {
+			if m.Path == path.Clean(c.SharedVolumePath) {
+				errs = append(errs, fmt.Errorf("configmap %q mounted on the shared volume path %q", m.Name, m.Path))
+			}
+		}
+	}
+
+	// The quotas of limits refuse the pods without limits, as the greeting server container.
+	for _, q := range []struct{ quota, limit api.ResourceName }{
+		{api.ResourceLimitsCPU, api.ResourceCPU},
+		{api.ResourceLimitsMemory, api.ResourceMemory},
+	} {
+		if _, quota := c.NamespaceQuota[q.quota]; quota {
+			if _, limit := c.DefaultContainerLimits[q.limit]; !limit {
+				errs = append(errs, fmt.Errorf("namespace %s quota requires a default container %s limit, the pods without limits being refused", q.limit, q.limit))
+			}
+		}
+	}
+
+	errs = append(errs, validateProbeType(c.ProbeType))
+	if c.ProbeType == ProbeExec && (len(c.ProbeExecCommand) == 0 || c.ProbeExecCommand[0] == "") {
+		errs = append(errs, errors.New("exec probes require a command"))
+	}
+	if c.ProbeType != ProbeExec && len(c.ProbeExecCommand) > 0 {
+		errs = append(errs, fmt.Errorf("probe command given for %s probes, only exec probes run it", c.ProbeType))
+	}
+
+	if c.MinReadySeconds < 0 {
+		errs = append(errs, errors.New("min ready seconds must be positive"))
+	}
+
+	if c.ProgressDeadlineSeconds < 0 {
+		errs = append(errs, errors.New("progress deadline seconds must be positive"))
+	}
+
+	progressDeadline := c.ProgressDeadlineSeconds
+	if progressDeadline == 0 {
+		progressDeadline = defaultProgressDeadlineSeconds
+	}
+
+	if c.Workload == WorkloadDeployment && progressDeadline <= c.MinReadySeconds {
+		errs = append(errs, fmt.Errorf("progress deadline (%ds) must be greater than min ready seconds (%ds)", progressDeadline, c.MinReadySeconds))
+	}
+
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("shutdown delay must be positive"))
+	}
+
+	if c.TerminationGracePeriodSeconds < 0 {
+		errs = append(errs, errors.New("termination grace period seconds must be positive"))
+	}
+
+	if shutdown := c.ShutdownDelay + greetingShutdownTimeout; c.TerminationGracePeriodSeconds > 0 && time.Duration(c.TerminationGracePeriodSeconds)*time.Second < shutdown {
+		errs = append(errs, fmt.Errorf("termination grace period (%ds) must cover the shutdown delay and the drain of the greeting server (%s)", c.TerminationGracePeriodSeconds, shutdown))
+	}
+
+	if c.RetryMaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("retry max attempts (%d) must be at least 1", c.RetryMaxAttempts))
+	}
+
+	switch c.Expose {
+	case ExposeNone:
+	case ExposeRoute:
+		if c.HostPort {
+			errs = append(errs, errors.New("route requires a service, which host port disables"))
+		}
+		if c.RouteHost != "" {
+			if msgs := validation.IsDNS1123Subdomain(c.RouteHost); len(msgs) > 0 {
+				errs = append(errs, fmt.Errorf("invalid route host %q: %s", c.RouteHost, strings.Join(msgs, ", ")))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown expose mode %q", c.Expose))
+	}
+
+	if c.ServiceMonitor {
+		if !strings.HasPrefix(c.MetricsPath, "/") {
+			errs = append(errs, fmt.Errorf("metrics path %q must start with /", c.MetricsPath))
+		}
+		if c.ScrapeInterval <= 0 {
+			errs = append(errs, errors.New("scrape interval must be positive"))
+		}
+		if c.HostPort {
+			errs = append(errs, errors.New("service monitor requires a service, which host port disables"))
+		}
+	}
+
+	// Nothing is persisted by a server dry-run, there is nothing to wait for nor to verify.
+	if c.ServerDryRun {
+		incompatible := []struct {
+			flag string
+			set  bool
+		}{{"wait", c.Wait}, {"wait-for-ip", c.WaitForIP}, {"verify", c.Verify}, {"watch", c.Watch}}
+		for _, option := range incompatible {
+			if option.set {
+				errs = append(errs, fmt.Errorf("server dry-run is incompatible with --%s", option.flag))
+			}
+		}
+	}
+
+	if c.RollbackOnFailure && !c.Wait {
+		errs = append(errs, errors.New("rollback on failure requires waiting for the rollout"))
+	}
+
+	if c.RollbackOnFailure && c.CrashLoopRestarts < 1 {
+		errs = append(errs, fmt.Errorf("crash loop restarts (%d) must be at least 1", c.CrashLoopRestarts))
+	}
+
+	if c.Output != OutputText && c.Output != OutputJSON && c.Output != OutputYAML {
+		errs = append(errs, fmt.Errorf("unknown output format %q", c.Output))
+	}
+
+	if c.Verify && c.VerifyTimeout <= 0 {
+		errs = append(errs, errors.New("verify timeout must be positive"))
+	}
+
+	if c.HostPort && c.Workload != WorkloadDaemonSet {
+		errs = append(errs, fmt.Errorf("host port is only supported by the %s workload", WorkloadDaemonSet))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateInstance checks the settings specific to a greeting server instance. It is shared
+// by the flags and the Greeting resources, so that both are validated the same way.
+func (c *Config) validateInstance() error {
+	var errs []error
+
+	errs = append(errs, validateImage(c.Image), validatePort(c.Port))
+	if c.GRPCPort != 0 && c.GRPCPort == c.Port {
+		errs = append(errs, fmt.Errorf("gRPC port %d is already the service port", c.GRPCPort))
+	}
+	errs = append(errs, validateImagePullSecret(c.ImagePullSecret), validateResolveDigest(c.ResolveDigest))
+	errs = append(errs, validateDNSLabel("namespace", c.Namespace))
+	// The resource name is also used as a label value.
+	errs = append(errs, validateDNSLabel("name", c.ResourceName))
+
+	if c.Workload != WorkloadDaemonSet && c.Replicas < 1 {
+		errs = append(errs, errors.New("replicas must be at least 1"))
+	}
+
+	if c.MaxReplicas > 0 && c.Replicas > c.MaxReplicas {
+		errs = append(errs, fmt.Errorf("replicas %d must not exceed %d", c.Replicas, c.MaxReplicas))
+	}
+
+	errs = append(errs, validateServiceType(c.ServiceType))
+
+	return errors.Join(errs...)
+}
+
+func validateImage(image string) error {
+	if image == "" {
+		return errors.New("image must not be empty")
+	}
+	if !imageReference.MatchString(image) {
+		return fmt.Errorf("invalid image reference %q", image)
+	}
+	return nil
+}
+
+func validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("port %d must be between 1 and 65535", port)
+	}
+	return nil
+}
+
+func validateImagePullSecret(secret string) error {
+	if secret == "" {
+		return nil
+	}
+	if msgs := validation.IsDNS1123Subdomain(secret); len(msgs) > 0 {
+		return fmt.Errorf("invalid image pull secret %q: %s", secret, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+func validateResolveDigest(mode string) error {
+	switch mode {
+	case "", ResolveDigestOff, ResolveDigestOn, ResolveDigestBestEffort:
+		return nil
+	}
+	return fmt.Errorf("unknown resolve digest mode %q, expected %s, %s or %s", mode, ResolveDigestOff, ResolveDigestOn, ResolveDigestBestEffort)
+}
+
+func validateDNSLabel(what, value string) error {
+	if msgs := validation.IsDNS1123Label(value); len(msgs) > 0 {
+		return fmt.Errorf("invalid %s %q: %s", what, value, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+func validateWorkload(workload string) error {
+	switch workload {
+	case WorkloadDeployment, WorkloadDaemonSet:
+		return nil
+	default:
+		return fmt.Errorf("unknown workload %q", workload)
+	}
+}
+
+func validateProbeType(probeType string) error {
+	switch probeType {
+	case ProbeHTTP, ProbeTCP, ProbeExec, ProbeNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown probe type %q, expected %s, %s, %s or %s", probeType, ProbeHTTP, ProbeTCP, ProbeExec, ProbeNone)
+	}
+}
+
+func validateServiceType(serviceType api.ServiceType) error {
+	switch serviceType {
+	case api.ServiceTypeClusterIP, api.ServiceTypeNodePort, api.ServiceTypeLoadBalancer:
+		return nil
+	default:
+		return fmt.Errorf("unsupported service type %q", serviceType)
+	}
+}
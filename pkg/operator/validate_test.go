@@ -0,0 +1,145 @@
+package operator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		change func(*Config)
+		err    string
+	}{
+		{name: "defaults", change: func(*Config) {}},
+		{name: "empty image", change: func(c *Config) { c.Image = "" }, err: "image must not be empty"},
+		{name: "invalid image", change: func(c *Config) { c.Image = "Greeting Server" }, err: "invalid image reference"},
+		{name: "port zero", change: func(c *Config) { c.Port = 0 }, err: "port 0 must be between 1 and 65535"},
+		{name: "port too large", change: func(c *Config) { c.Port = 65536 }, err: "must be between 1 and 65535"},
+		{name: "gRPC port on the service port", change: func(c *Config) { c.GRPCPort = c.Port }, err: "is already the service port"},
+		{name: "invalid namespace", change: func(c *Config) { c.Namespace = "Greeting" }, err: "invalid namespace"},
+		{name: "invalid resource name", change: func(c *Config) { c.ResourceName = "greeting_server" }, err: "invalid name"},
+		{name: "no replica", change: func(c *Config) { c.Replicas = 0 }, err: "replicas must be at least 1"},
+		{name: "daemonset without replica", change: func(c *Config) { c.Workload = WorkloadDaemonSet; c.Replicas = 0 }},
+		{name: "too many replicas", change: func(c *Config) { c.Replicas = 5; c.MaxReplicas = 3 }, err: "replicas 5 must not exceed 3"},
+		{name: "unknown workload", change: func(c *Config) { c.Workload = "statefulset" }, err: `unknown workload "statefulset"`},
+		{name: "unknown service type", change: func(c *Config) { c.ServiceType = api.ServiceTypeExternalName }, err: "unsupported service type"},
+		{name: "unknown resolve digest", change: func(c *Config) { c.ResolveDigest = "sometimes" }, err: "unknown resolve digest mode"},
+		{name: "invalid image pull secret", change: func(c *Config) { c.ImagePullSecret = "Pull Secret" }, err: "invalid image pull secret"},
+		{name: "sidecar named like an init container", change: func(c *Config) {
+			c.InitContainers = []InitContainer{{Name: "helper", Image: "busybox"}}
+			c.Sidecars = []Sidecar{{Name: "helper", Image: "busybox"}}
+		}, err: `container name "helper" used by both a sidecar and an init container`},
+		{name: "sidecar named like the greeting server", change: func(c *Config) {
+			c.Sidecars = []Sidecar{{Name: greetingContainerName, Image: "busybox"}}
+		}, err: `sidecar name "greeting" is reserved for the greeting server`},
+		{name: "init container named like the greeting server", change: func(c *Config) {
+			c.InitContainers = []InitContainer{{Name: greetingContainerName, Image: "busybox"}}
+		}, err: `init container name "greeting" is reserved for the greeting server`},
+		{name: "duplicated sidecars", change: func(c *Config) {
+			c.Sidecars = []Sidecar{{Name: "proxy", Image: "envoy"}, {Name: "proxy", Image: "nginx"}}
+		}, err: `container name "proxy" used by two sidecars`},
+		{name: "duplicated init containers", change: func(c *Config) {
+			c.InitContainers = []InitContainer{{Name: "helper", Image: "busybox"}, {Name: "helper", Image: "alpine"}}
+		}, err: `container name "helper" used by two init containers`},
+		{name: "configmap on the shared volume", change: func(c *Config) {
+			c.InitContainers = []InitContainer{{Name: "helper", Image: "busybox"}}
+			c.ConfigMapMounts = []ConfigMapMount{{Name: "settings", Path: c.SharedVolumePath}}
+		}, err: "mounted on the shared volume path"},
+		{name: "cpu quota without default limit", change: func(c *Config) {
+			c.NamespaceQuota = api.ResourceList{api.ResourceLimitsCPU: resource.MustParse("2")}
+		}, err: "namespace cpu quota requires a default container cpu limit"},
+		{name: "cpu quota with default limit", change: func(c *Config) {
+			c.NamespaceQuota = api.ResourceList{api.ResourceLimitsCPU: resource.MustParse("2")}
+			c.DefaultContainerLimits = api.ResourceList{api.ResourceCPU: resource.MustParse("500m")}
+		}},
+		{name: "negative min ready seconds", change: func(c *Config) { c.MinReadySeconds = -1 }, err: "min ready seconds must be positive"},
+		{name: "negative progress deadline", change: func(c *Config) { c.ProgressDeadlineSeconds = -1 }, err: "progress deadline seconds must be positive"},
+		{name: "progress deadline below min ready seconds", change: func(c *Config) {
+			c.MinReadySeconds = 30
+			c.ProgressDeadlineSeconds = 20
+		}, err: "progress deadline (20s) must be greater than min ready seconds (30s)"},
+		{name: "negative shutdown delay", change: func(c *Config) { c.ShutdownDelay = -time.Second }, err: "shutdown delay must be positive"},
+		{name: "grace period shorter than the shutdown", change: func(c *Config) {
+			c.ShutdownDelay = 10 * time.Second
+			c.TerminationGracePeriodSeconds = 5
+		}, err: "termination grace period (5s) must cover"},
+		{name: "no retry attempt", change: func(c *Config) { c.RetryMaxAttempts = 0 }, err: "retry max attempts (0) must be at least 1"},
+		{name: "unknown expose mode", change: func(c *Config) { c.Expose = "ingress" }, err: `unknown expose mode "ingress"`},
+		{name: "invalid route host", change: func(c *Config) { c.Expose = ExposeRoute; c.RouteHost = "Greeting.example.com" }, err: "invalid route host"},
+		{name: "route with host port", change: func(c *Config) {
+			c.Workload = WorkloadDaemonSet
+			c.HostPort = true
+			c.Expose = ExposeRoute
+		}, err: "route requires a service"},
+		{name: "service monitor with relative metrics path", change: func(c *Config) { c.ServiceMonitor = true; c.MetricsPath = "metrics" }, err: `metrics path "metrics" must start with /`},
+		{name: "server dry-run with wait", change: func(c *Config) { c.ServerDryRun = true; c.Wait = true }, err: "server dry-run is incompatible with --wait"},
+		{name: "rollback without wait", change: func(c *Config) { c.RollbackOnFailure = true }, err: "rollback on failure requires waiting for the rollout"},
+		{name: "unknown output", change: func(c *Config) { c.Output = "xml" }, err: `unknown output format "xml"`},
+		{name: "yaml output", change: func(c *Config) { c.Output = OutputYAML }},
+		{name: "verify without timeout", change: func(c *Config) { c.Verify = true; c.VerifyTimeout = 0 }, err: "verify timeout must be positive"},
+		{name: "host port with deployment", change: func(c *Config) { c.HostPort = true }, err: "host port is only supported by the daemonset workload"},
+		{name: "http probe", change: func(c *Config) { c.ProbeType = ProbeHTTP }},
+		{name: "tcp probe", change: func(c *Config) { c.ProbeType = ProbeTCP }},
+		{name: "no probe", change: func(c *Config) { c.ProbeType = ProbeNone }},
+		{name: "exec probe", change: func(c *Config) { c.ProbeType = ProbeExec; c.ProbeExecCommand = []string{"true"} }},
+		{name: "unknown probe type", change: func(c *Config) { c.ProbeType = "grpc" }, err: `unknown probe type "grpc"`},
+		{name: "empty probe type", change: func(c *Config) { c.ProbeType = "" }, err: `unknown probe type ""`},
+		{name: "exec probe without command", change: func(c *Config) { c.ProbeType = ProbeExec }, err: "exec probes require a command"},
+		{name: "exec probe with empty command", change: func(c *Config) { c.ProbeType = ProbeExec; c.ProbeExecCommand = []string{""} }, err: "exec probes require a command"},
+		{name: "command for http probe", change: func(c *Config) { c.ProbeExecCommand = []string{"true"} }, err: "probe command given for http probes"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tc.change(config)
+			err := config.Validate()
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("Validate() = %v, want no error", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("Validate() = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := DefaultConfig()
+	config.Image = ""
+	config.Port = 0
+	config.Output = "xml"
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{"image must not be empty", "port 0", "unknown output format"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, missing %q", err, want)
+		}
+	}
+}
+
+func TestWithProbe(t *testing.T) {
+	if _, err := NewConfig(WithProbe("grpc")); err == nil || !strings.Contains(err.Error(), `unknown probe type "grpc"`) {
+		t.Errorf("WithProbe(grpc) = %v, want the type refused", err)
+	}
+	config, err := NewConfig(WithProbe(ProbeExec))
+	if err != nil {
+		t.Fatalf("WithProbe(exec): %v", err)
+	}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "exec probes require a command") {
+		t.Errorf("Validate() = %v, want the exec probe without command refused", err)
+	}
+	config, err = NewConfig(WithProbe(ProbeExec, "cat", "/tmp/healthy"))
+	if err != nil {
+		t.Fatalf("WithProbe(exec, cat /tmp/healthy): %v", err)
+	}
+	if config.ProbeType != ProbeExec || strings.Join(config.ProbeExecCommand, " ") != "cat /tmp/healthy" {
+		t.Errorf("WithProbe(exec, cat /tmp/healthy) = %s %v", config.ProbeType, config.ProbeExecCommand)
+	}
+}
@@ -0,0 +1,117 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"edb-challenge/pkg/greeting"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+)
+
+// verifyServer checks that a ready greeting server pod answers on /health and greets with the configured name,
+// going through the API server pod proxy. The check is retried until the verify timeout expires.
+func (o *Operator) verifyServer(ctx context.Context) error {
+	verifyCtx, cancel := context.WithTimeout(ctx, o.verifyTimeout)
+	defer cancel()
+
+	logger := o.logger(o.workload)
+	logger.Info("Verifying the greeting server")
+
+	var reason string
+	err := wait.PollImmediateUntilWithContext(verifyCtx, waitInterval, func(ctx context.Context) (bool, error) {
+		why, err := o.verifyPod(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		if why != reason {
+			reason = why
+			if reason != "" {
+				logger.Info(reason)
+			}
+		}
+
+		return reason == "", nil
+	})
+	if err != nil {
+		err = fmt.Errorf("verify greeting server: %w", err)
+		if reason != "" {
+			err = fmt.Errorf("%w: %s", err, reason)
+		}
+		o.event(o.rolloutObject(ctx), api.EventTypeWarning, reasonVerificationFailed, err.Error())
+		return err
+	}
+
+	logger.Info("Greeting server verified")
+	return nil
+}
+
+// verifyPod queries one ready pod, returning why it does not behave as expected, or an empty reason.
+func (o *Operator) verifyPod(ctx context.Context) (string, error) {
+	selector := labels.SelectorFromSet(o.selectorLabels()).String()
+	pods, err := o.client.CoreV1().Pods(o.namespace).List(ctx, meta.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", fmt.Errorf("list pods: %w", err)
+	}
+
+	pod := readyPod(pods.Items)
+	if pod == nil {
+		return "Waiting for a ready greeting pod", nil
+	}
+
+	client, err := o.podClient(pod.Name)
+	if err != nil {
+		return "", err
+	}
+	if _, err = client.Health(ctx); err != nil {
+		return fmt.Sprintf("pod %s: %v", pod.Name, err), nil
+	}
+	answer, err := client.Greet(ctx, "", "")
+	if err != nil {
+		return fmt.Sprintf("pod %s: %v", pod.Name, err), nil
+	}
+	if answer.Name != o.name {
+		return fmt.Sprintf("pod %s: /greet answered the name %q, expected %q", pod.Name, answer.Name, o.name), nil
+	}
+
+	return "", nil
+}
+
+// podClient returns the client of the greeting server of the pod, calling it through the API server proxy
+// with the credentials of the operator.
+func (o *Operator) podClient(pod string) (*greeting.Client, error) {
+	restClient, ok := o.client.CoreV1().RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil || restClient.Client == nil {
+		return nil, errors.New("the Kubernetes client cannot proxy requests to the pods")
+	}
+
+	port := strconv.Itoa(int(o.greetingContainer().Ports[0].ContainerPort))
+	proxy := restClient.Get().
+		Namespace(o.namespace).
+		Resource("pods").
+		Name(pod + ":" + port).
+		SubResource("proxy").
+		URL()
+	return greeting.NewClient(proxy.String(), greeting.WithHTTPClient(restClient.Client)), nil
+}
+
+// readyPod returns the first running pod whose containers are all ready, nil when there is none.
+func readyPod(pods []api.Pod) *api.Pod {
+	for i, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != api.PodRunning {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == api.PodReady && condition.Status == api.ConditionTrue {
+				return &pods[i]
+			}
+		}
+	}
+	return nil
+}
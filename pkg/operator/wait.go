@@ -0,0 +1,247 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// waitInterval is the delay between two checks of a rollout.
+const waitInterval = 2 * time.Second
+
+// errRolloutFailed is returned when a rollout will never complete.
+var errRolloutFailed = errors.New("rollout failed")
+
+// waitForRollout blocks until the greeting server is available and reachable, or the wait timeout expires.
+func (o *Operator) waitForRollout(ctx context.Context) error {
+	waitCtx, cancel := context.WithTimeout(ctx, o.waitTimeout)
+	defer cancel()
+
+	var err error
+	switch o.workload {
+	case WorkloadDaemonSet:
+		err = o.waitFor(waitCtx, "daemonset", o.daemonSetRolledOut)
+	default:
+		err = o.waitFor(waitCtx, "deployment", o.deploymentRolledOut)
+	}
+
+	if err == nil && !o.hostPort {
+		err = o.waitFor(waitCtx, "service", o.serviceReachable)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errRolloutFailed) || errors.Is(err, wait.ErrWaitTimeout) {
+		o.event(o.rolloutObject(ctx), api.EventTypeWarning, reasonRolloutTimedOut, err.Error())
+
+		// The wait context is over, the pods are summarized with a fresh one.
+		summaryCtx, cancelSummary := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelSummary()
+
+		return fmt.Errorf("%w\n%s", err, o.podsSummary(summaryCtx))
+	}
+
+	return err
+}
+
+// rolloutObject returns the live workload, falling back to the desired one when it cannot be read.
+func (o *Operator) rolloutObject(ctx context.Context) runtime.Object {
+	if o.workload == WorkloadDaemonSet {
+		if daemonSet, err := o.client.AppsV1().DaemonSets(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{}); err == nil {
+			return daemonSet
+		}
+		return o.desiredDaemonSet()
+	}
+
+	if deployment, err := o.client.AppsV1().Deployments(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{}); err == nil {
+		return deployment
+	}
+	return o.desiredDeployment()
+}
+
+// waitFor polls the condition until it is met, keeping track of the last reason it was not.
+func (o *Operator) waitFor(ctx context.Context, kind string, condition func(context.Context) (bool, string, error)) error {
+	logger := o.logger(kind)
+	logger.Info("Waiting for rollout")
+
+	var reason string
+	err := wait.PollImmediateUntilWithContext(ctx, waitInterval, func(ctx context.Context) (bool, error) {
+		done, why, err := condition(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		if why != reason {
+			reason = why
+			logger.Info(reason)
+		}
+
+		return done, nil
+	})
+	if err != nil {
+		if reason != "" {
+			return fmt.Errorf("wait for %s: %w: %s", kind, err, reason)
+		}
+		return fmt.Errorf("wait for %s: %w", kind, err)
+	}
+
+	logger.Info("Rollout complete")
+	return nil
+}
+
+// deploymentRolledOut tells whether the latest generation of the deployment is fully available,
+// as `kubectl rollout status` does.
+func (o *Operator) deploymentRolledOut(ctx context.Context) (bool, string, error) {
+	deployment, err := o.client.AppsV1().Deployments(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("get deployment: %w", err)
+	}
+
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, "Waiting for the deployment spec update to be observed", nil
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == apps.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, "", fmt.Errorf("%w: %s", errRolloutFailed, condition.Message)
+		}
+	}
+
+	// Without the rollback, a crash looping pod is left to the progress deadline as kubectl does.
+	if o.rollbackOnFailure {
+		pod, restarts, err := o.crashLoopingPod(ctx)
+		if err != nil {
+			return false, "", err
+		}
+		if pod != "" {
+			return false, "", fmt.Errorf("%w: pod %s is crash looping (%d restarts)", errRolloutFailed, pod, restarts)
+		}
+	}
+
+	var desired int32 = 1
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < desired:
+		return false, fmt.Sprintf("%d out of %d new replicas have been updated", status.UpdatedReplicas, desired), nil
+	case status.Replicas > status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d old replicas are pending termination", status.Replicas-status.UpdatedReplicas), nil
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d of %d updated replicas are available", status.AvailableReplicas, status.UpdatedReplicas), nil
+	}
+
+	return true, "", nil
+}
+
+// daemonSetRolledOut tells whether the latest generation of the daemonset is available on every node.
+func (o *Operator) daemonSetRolledOut(ctx context.Context) (bool, string, error) {
+	daemonSet, err := o.client.AppsV1().DaemonSets(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("get daemonset: %w", err)
+	}
+
+	if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+		return false, "Waiting for the daemonset spec update to be observed", nil
+	}
+
+	status := daemonSet.Status
+	switch {
+	case status.UpdatedNumberScheduled < status.DesiredNumberScheduled:
+		return false, fmt.Sprintf("%d out of %d new pods have been updated", status.UpdatedNumberScheduled, status.DesiredNumberScheduled), nil
+	case status.NumberAvailable < status.DesiredNumberScheduled:
+		return false, fmt.Sprintf("%d of %d updated pods are available", status.NumberAvailable, status.DesiredNumberScheduled), nil
+	}
+
+	return true, "", nil
+}
+
+// serviceReachable tells whether the service has been given an address, which only matters for load balancers.
+func (o *Operator) serviceReachable(ctx context.Context) (bool, string, error) {
+	service, err := o.client.CoreV1().Services(o.namespace).Get(ctx, o.resourceName, meta.GetOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("get service: %w", err)
+	}
+
+	if service.Spec.Type != api.ServiceTypeLoadBalancer {
+		return true, "", nil
+	}
+
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" || ingress.Hostname != "" {
+			return true, "", nil
+		}
+	}
+
+	return false, "Waiting for the load balancer address", nil
+}
+
+// podsSummary describes the state of the greeting server pods and their latest events.
+func (o *Operator) podsSummary(ctx context.Context) string {
+	selector := labels.SelectorFromSet(o.selectorLabels()).String()
+	pods, err := o.client.CoreV1().Pods(o.namespace).List(ctx, meta.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Sprintf("unable to list pods: %s", err)
+	}
+
+	if len(pods.Items) == 0 {
+		return "no greeting pod found"
+	}
+
+	var b strings.Builder
+	for _, pod := range pods.Items {
+		fmt.Fprintf(&b, "pod %s: %s\n", pod.Name, pod.Status.Phase)
+
+		for _, condition := range pod.Status.Conditions {
+			if condition.Status != api.ConditionTrue {
+				fmt.Fprintf(&b, "  condition %s=%s: %s %s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+			}
+		}
+
+		for _, container := range pod.Status.ContainerStatuses {
+			if waiting := container.State.Waiting; waiting != nil {
+				fmt.Fprintf(&b, "  container %s waiting: %s %s\n", container.Name, waiting.Reason, waiting.Message)
+			}
+			if terminated := container.LastTerminationState.Terminated; terminated != nil {
+				fmt.Fprintf(&b, "  container %s last terminated: %s (exit code %d, %d restarts)\n", container.Name, terminated.Reason, terminated.ExitCode, container.RestartCount)
+			}
+		}
+
+		events, err := o.client.CoreV1().Events(o.namespace).List(ctx, meta.ListOptions{
+			FieldSelector: fmt.Sprintf("involvedObject.kind=Pod,involvedObject.name=%s", pod.Name),
+		})
+		if err != nil {
+			fmt.Fprintf(&b, "  unable to list events: %s\n", err)
+			continue
+		}
+
+		sort.Slice(events.Items, func(i, j int) bool {
+			return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+		})
+
+		const maxEvents = 5
+		if len(events.Items) > maxEvents {
+			events.Items = events.Items[len(events.Items)-maxEvents:]
+		}
+
+		for _, event := range events.Items {
+			fmt.Fprintf(&b, "  event %s %s: %s\n", event.Type, event.Reason, event.Message)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
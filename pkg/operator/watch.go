@@ -0,0 +1,222 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Keys of the watched resources in the reconcile queue.
+const (
+	deploymentKey = "deployment"
+	daemonSetKey  = "daemonset"
+	serviceKey    = "service"
+	nameKey       = "name"
+)
+
+// watcher re-applies the desired state whenever a managed resource drifts from it or disappears.
+type watcher struct {
+	operator *Operator
+	factory  informers.SharedInformerFactory
+	queue    workqueue.RateLimitingInterface
+
+	// nameFactory watches the ConfigMap holding the greeting name, nil without name source.
+	nameFactory informers.SharedInformerFactory
+	// mu keeps the name updates apart from the reconciliations rendering the resources.
+	mu sync.RWMutex
+}
+
+// Watch reconciles the managed resources until the context is cancelled.
+func (o *Operator) Watch(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(o.client, o.resyncPeriod,
+		informers.WithNamespace(o.namespace),
+		informers.WithTweakListOptions(func(opts *meta.ListOptions) {
+			opts.LabelSelector = managedSelector()
+		}),
+	)
+
+	w := &watcher{
+		operator: o,
+		factory:  factory,
+		queue:    newQueue("watch_" + o.namespace + "_" + o.resourceName),
+	}
+	defer w.queue.ShutDown()
+
+	if o.workload == WorkloadDaemonSet {
+		w.handle(factory.Apps().V1().DaemonSets().Informer(), daemonSetKey)
+	} else {
+		w.handle(factory.Apps().V1().Deployments().Informer(), deploymentKey)
+	}
+	if !o.hostPort {
+		w.handle(factory.Core().V1().Services().Informer(), serviceKey)
+	}
+
+	// The name source may live in another namespace, only its ConfigMap is watched.
+	factories := []informers.SharedInformerFactory{factory}
+	if o.nameSource != nil {
+		w.nameFactory = informers.NewSharedInformerFactoryWithOptions(o.client, o.resyncPeriod,
+			informers.WithNamespace(o.nameSource.Namespace),
+			informers.WithTweakListOptions(func(opts *meta.ListOptions) {
+				opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", o.nameSource.Name).String()
+			}),
+		)
+		w.handle(w.nameFactory.Core().V1().ConfigMaps().Informer(), nameKey)
+		factories = append(factories, w.nameFactory)
+	}
+
+	for _, f := range factories {
+		f.Start(ctx.Done())
+		defer f.Shutdown()
+	}
+
+	setCachesState(cachesSyncing)
+	defer setCachesState(cachesIdle)
+
+	for _, f := range factories {
+		for informerType, synced := range f.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				return fmt.Errorf("sync %v informer cache: %w", informerType, ctx.Err())
+			}
+		}
+	}
+
+	setCachesState(cachesSynced)
+	log.WithField("namespace", o.namespace).WithField("resync", o.resyncPeriod).Info("Watching managed resources")
+
+	runWorkers(ctx, w.queue, o.maxConcurrentReconciles, w.processNextItem)
+
+	log.WithField("namespace", o.namespace).Info("Stopped watching managed resources")
+	return nil
+}
+
+// handle enqueues the key of the resource on every event, the periodic resyncs included.
+func (w *watcher) handle(informer cache.SharedIndexInformer, key string) {
+	enqueue := func(interface{}) { w.queue.Add(key) }
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, _ interface{}) { w.queue.Add(key) },
+		DeleteFunc: enqueue,
+	})
+}
+
+// processNextItem reconciles the next key of the queue, and returns false once the queue is shut down.
+func (w *watcher) processNextItem(ctx context.Context) bool {
+	item, shutdown := w.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer w.queue.Done(item)
+
+	// The keys left in the queue at shutdown are dropped, the next run reconciles them again.
+	if ctx.Err() != nil {
+		w.queue.Forget(item)
+		return true
+	}
+
+	key := item.(string)
+	err := w.reconcile(ctx, key)
+	observeReconcile(w.operator.namespace, err)
+	if err != nil {
+		log.WithError(err).WithField("kind", key).Warning("Reconciliation failed, retrying")
+		w.queue.AddRateLimited(key)
+		return true
+	}
+
+	w.queue.Forget(key)
+	return true
+}
+
+// reconcile re-applies the resource behind the key if it is missing or drifted.
+func (w *watcher) reconcile(ctx context.Context, key string) error {
+	o := w.operator
+	logger := o.logger(key)
+
+	if key == nameKey {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	} else {
+		w.mu.RLock()
+		defer w.mu.RUnlock()
+	}
+
+	switch key {
+	case deploymentKey:
+		live, err := w.factory.Apps().V1().Deployments().Lister().Deployments(o.namespace).Get(o.resourceName)
+		if err != nil && !kerror.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			desired := o.desiredDeployment()
+			o.preserveReplicas(live, desired)
+			diff := deploymentDiff(live, desired)
+			if len(diff) == 0 {
+				return nil
+			}
+			logger.WithField("diff", diff).Info("Deployment drifted, restoring it")
+		} else {
+			logger.Info("Deployment deleted, recreating it")
+		}
+		return o.createDeployment(ctx)
+
+	case daemonSetKey:
+		live, err := w.factory.Apps().V1().DaemonSets().Lister().DaemonSets(o.namespace).Get(o.resourceName)
+		if err != nil && !kerror.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			diff := daemonSetDiff(live, o.desiredDaemonSet())
+			if len(diff) == 0 {
+				return nil
+			}
+			logger.WithField("diff", diff).Info("DaemonSet drifted, restoring it")
+		} else {
+			logger.Info("DaemonSet deleted, recreating it")
+		}
+		return o.createDaemonSet(ctx)
+
+	case serviceKey:
+		live, err := w.factory.Core().V1().Services().Lister().Services(o.namespace).Get(o.resourceName)
+		if err != nil && !kerror.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			diff := serviceDiff(live, o.desiredService())
+			if len(diff) == 0 {
+				return nil
+			}
+			logger.WithField("diff", diff).Info("Service drifted, restoring it")
+		} else {
+			logger.Info("Service deleted, recreating it")
+		}
+		return o.createService(ctx)
+
+	case nameKey:
+		configMap, err := w.nameFactory.Core().V1().ConfigMaps().Lister().ConfigMaps(o.nameSource.Namespace).Get(o.nameSource.Name)
+		if kerror.IsNotFound(err) {
+			log.WithField("source", o.nameSource.String()).WithField("name", o.name).Error("Name source configmap deleted, keeping the last known name")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// The new name rolls the pods through their environment.
+		if o.updateName(configMap) {
+			if o.workload == WorkloadDaemonSet {
+				w.queue.Add(daemonSetKey)
+			} else {
+				w.queue.Add(deploymentKey)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown key %q", key)
+}
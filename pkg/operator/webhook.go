@@ -0,0 +1,152 @@
+package operator
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	admission "k8s.io/api/admission/v1"
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// validatePath is the path of the validating webhook endpoint.
+const validatePath = "/validate"
+
+// WebhookServer rejects the invalid Greeting resources at admission.
+type WebhookServer struct {
+	controller *GreetingController
+	addr       string
+	certDir    string
+}
+
+// NewWebhookServer creates a WebhookServer validating the Greeting resources as the controller would.
+// The certDir holds the tls.crt and tls.key files of the server.
+func NewWebhookServer(controller *GreetingController, addr, certDir string) *WebhookServer {
+	return &WebhookServer{controller: controller, addr: addr, certDir: certDir}
+}
+
+// Run serves the webhook over HTTPS until the context is cancelled.
+func (s *WebhookServer) Run(ctx context.Context) error {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(s.certDir, "tls.crt"), filepath.Join(s.certDir, "tls.key"))
+	if err != nil {
+		return fmt.Errorf("load webhook certificate: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(validatePath, s.HandleValidate)
+
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+	}
+
+	log.WithField("addr", s.addr).Info("Starting webhook server")
+	serve := func() error { return server.ListenAndServeTLS("", "") }
+	if err = serveUntilDone(ctx, server, serve); err != nil {
+		return fmt.Errorf("serve webhook: %w", err)
+	}
+
+	return nil
+}
+
+// HandleValidate answers an AdmissionReview, allowing the Greeting only when valid.
+func (s *WebhookServer) HandleValidate(rw http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		http.Error(rw, "unable to read body", http.StatusBadRequest)
+		return
+	}
+
+	var review admission.AdmissionReview
+	if err = json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(rw, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	response := &admission.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+	if err = s.validate(review.Request); err != nil {
+		log.WithError(err).WithField("greeting", review.Request.Namespace+"/"+review.Request.Name).Info("Greeting rejected")
+		response.Allowed = false
+		response.Result = &meta.Status{
+			Status:  meta.StatusFailure,
+			Message: err.Error(),
+			Reason:  meta.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		}
+	}
+
+	review.Response = response
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(rw).Encode(&review); err != nil {
+		log.WithError(err).Warning("Unable to write admission review")
+	}
+}
+
+func (s *WebhookServer) validate(req *admission.AdmissionRequest) error {
+	var greeting Greeting
+	if err := json.Unmarshal(req.Object.Raw, &greeting); err != nil {
+		return fmt.Errorf("decode greeting: %w", err)
+	}
+
+	// The name is not set when generated by the API server on creation.
+	if greeting.Name == "" {
+		greeting.Name = req.Name
+	}
+	if greeting.Namespace == "" {
+		greeting.Namespace = req.Namespace
+	}
+
+	_, err := NewOffline(s.controller.instanceConfig(&greeting))
+	return err
+}
+
+// GreetingWebhookConfiguration returns the ValidatingWebhookConfiguration sending the Greeting resources to the webhook server.
+func GreetingWebhookConfiguration(serviceName, serviceNamespace string, servicePort int32, caBundle []byte) *admissionregistration.ValidatingWebhookConfiguration {
+	path := validatePath
+	failurePolicy := admissionregistration.Fail
+	sideEffects := admissionregistration.SideEffectClassNone
+	scope := admissionregistration.NamespacedScope
+
+	return &admissionregistration.ValidatingWebhookConfiguration{
+		TypeMeta: meta.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1", Kind: "ValidatingWebhookConfiguration"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:   "greeting-operator",
+			Labels: map[string]string{managedByLabel: managedByValue},
+		},
+		Webhooks: []admissionregistration.ValidatingWebhook{{
+			Name: "validate." + greetingResource.Resource + "." + greetingResource.Group,
+			ClientConfig: admissionregistration.WebhookClientConfig{
+				Service: &admissionregistration.ServiceReference{
+					Name:      serviceName,
+					Namespace: serviceNamespace,
+					Path:      &path,
+					Port:      &servicePort,
+				},
+				CABundle: caBundle,
+			},
+			Rules: []admissionregistration.RuleWithOperations{{
+				Operations: []admissionregistration.OperationType{admissionregistration.Create, admissionregistration.Update},
+				Rule: admissionregistration.Rule{
+					APIGroups:   []string{greetingResource.Group},
+					APIVersions: []string{greetingResource.Version},
+					Resources:   []string{greetingResource.Resource},
+					Scope:       &scope,
+				},
+			}},
+			FailurePolicy:           &failurePolicy,
+			SideEffects:             &sideEffects,
+			AdmissionReviewVersions: []string{"v1"},
+		}},
+	}
+}
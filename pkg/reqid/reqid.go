@@ -0,0 +1,71 @@
+// Package reqid identifies the HTTP requests, so that the logs of a request can be correlated with
+// what its client saw.
+package reqid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// Header carries the ID of the request, given by the client or generated, and echoed in the response.
+const Header = "X-Request-ID"
+
+// maxLength is the maximum length of the IDs given by the clients.
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a random version 4 UUID.
+func New() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("read random request ID: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// WithID returns a copy of the context holding the request ID.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// From returns the request ID held by the context, empty when none.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Valid tells whether an ID given by a client is safe to log and echo: not empty, at most 128
+// characters among letters, digits and -_.:/+=.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Handler serves the requests with their ID in the context and the response header, the one of the
+// request header when valid and a new one otherwise.
+func Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(Header)
+		if !Valid(id) {
+			id = New()
+		}
+
+		rw.Header().Set(Header, id)
+		handler.ServeHTTP(rw, req.WithContext(WithID(req.Context(), id)))
+	})
+}
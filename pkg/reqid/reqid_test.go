@@ -0,0 +1,110 @@
+package reqid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNew(t *testing.T) {
+	if id := New(); !uuidV4.MatchString(id) {
+		t.Errorf("New() = %q, want a version 4 UUID", id)
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", true},
+		{"trace:1/span_2+x=y.z", true},
+		{strings.Repeat("a", maxLength), true},
+		{strings.Repeat("a", maxLength+1), false},
+		{"with space", false},
+		{"new\nline", false},
+		{"<script>", false},
+		{"é", false},
+	}
+
+	for _, test := range tests {
+		if got := Valid(test.id); got != test.want {
+			t.Errorf("Valid(%q) = %t, want %t", test.id, got, test.want)
+		}
+	}
+}
+
+// servedID returns the ID of the request seen by the handler, and the one of the response.
+func servedID(t *testing.T, given string) (string, string) {
+	t.Helper()
+	var seen string
+	handler := Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		seen = From(req.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if given != "" {
+		req.Header.Set(Header, given)
+	}
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	return seen, rw.Header().Get(Header)
+}
+
+func TestHandlerPassthrough(t *testing.T) {
+	seen, echoed := servedID(t, "client-id-42")
+	if seen != "client-id-42" || echoed != "client-id-42" {
+		t.Errorf("ID seen %q, echoed %q, want the client one", seen, echoed)
+	}
+}
+
+func TestHandlerGeneration(t *testing.T) {
+	for _, given := range []string{"", "bad id\r\nX-Injected: 1", strings.Repeat("a", maxLength+1)} {
+		seen, echoed := servedID(t, given)
+		if !uuidV4.MatchString(seen) || echoed != seen {
+			t.Errorf("given %q: ID seen %q, echoed %q, want the same generated UUID", given, seen, echoed)
+		}
+	}
+}
+
+func TestFrom(t *testing.T) {
+	if id := From(context.Background()); id != "" {
+		t.Errorf("From(empty context) = %q, want none", id)
+	}
+	if id := From(WithID(context.Background(), "abc")); id != "abc" {
+		t.Errorf("From(WithID(abc)) = %q", id)
+	}
+}
+
+func TestHandlerDistinctIDs(t *testing.T) {
+	const goroutines, requests = 16, 200
+
+	var mu sync.Mutex
+	ids := make(map[string]bool, goroutines*requests)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				seen, _ := servedID(t, "")
+				mu.Lock()
+				ids[seen] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(ids) != goroutines*requests {
+		t.Errorf("%d distinct IDs for %d requests", len(ids), goroutines*requests)
+	}
+}
@@ -0,0 +1,76 @@
+// Package version holds the build metadata shared by the greeting server and operator,
+// injected at build time with -ldflags "-X edb-challenge/pkg/version.Version=...".
+package version
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, overridden at build time.
+var (
+	// Version is the semantic version of the build.
+	Version = "dev"
+	// Commit is the git commit the build comes from.
+	Commit = "unknown"
+	// BuildDate is the RFC 3339 date of the build.
+	BuildDate = "unknown"
+)
+
+// Info describes a build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+	// ClientGo is the version of the Kubernetes client library, empty when it is not linked.
+	ClientGo string `json:"clientGo,omitempty"`
+}
+
+// Get returns the metadata of the running build.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		ClientGo:  moduleVersion("k8s.io/client-go"),
+	}
+}
+
+// String returns the version with the commit and build date.
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s)", i.Version, i.Commit, i.BuildDate)
+}
+
+// WriteText writes the metadata as aligned lines.
+func (i Info) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Version:    %s\nCommit:     %s\nBuild date: %s\nGo:         %s\nPlatform:   %s\n",
+		i.Version, i.Commit, i.BuildDate, i.GoVersion, i.Platform)
+	if err == nil && i.ClientGo != "" {
+		_, err = fmt.Fprintf(w, "client-go:  %s\n", i.ClientGo)
+	}
+	return err
+}
+
+// moduleVersion returns the version of a module linked in the binary, empty when it is not.
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}